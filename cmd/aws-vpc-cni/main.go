@@ -69,10 +69,14 @@ const (
 	defaultMTU                   = "9001"
 	defaultEnablePodEni          = false
 	defaultPodSGEnforcingMode    = "strict"
+	defaultEnableEBPFPodRouting  = false
+	defaultPodVethNameScheme     = "pod-name"
+	defaultEnableNetworkPolicy   = false
 	defaultPluginLogFile         = "/var/log/aws-routed-eni/plugin.log"
 	defaultEgressV4PluginLogFile = "/var/log/aws-routed-eni/egress-v4-plugin.log"
 	defaultEgressV6PluginLogFile = "/var/log/aws-routed-eni/egress-v6-plugin.log"
 	defaultPluginLogLevel        = "Debug"
+	defaultEnableIPv4            = false
 	defaultEnableIPv6            = false
 	defaultEnableIPv6Egress      = false
 	defaultRandomizeSNAT         = "prng"
@@ -89,6 +93,9 @@ const (
 	envEniMTU                = "AWS_VPC_ENI_MTU"
 	envEnablePodEni          = "ENABLE_POD_ENI"
 	envPodSGEnforcingMode    = "POD_SECURITY_GROUP_ENFORCING_MODE"
+	envEnableEBPFPodRouting  = "ENABLE_EBPF_POD_ROUTING"
+	envPodVethNameScheme     = "POD_VETH_NAME_SCHEME"
+	envEnableNetworkPolicy   = "ENABLE_NETWORK_POLICY"
 	envPluginLogFile         = "AWS_VPC_K8S_PLUGIN_LOG_FILE"
 	envPluginLogLevel        = "AWS_VPC_K8S_PLUGIN_LOG_LEVEL"
 	envEgressV4PluginLogFile = "AWS_VPC_K8S_EGRESS_V4_PLUGIN_LOG_FILE"
@@ -98,6 +105,7 @@ const (
 	envMinIPTarget           = "MINIMUM_IP_TARGET"
 	envWarmPrefixTarget      = "WARM_PREFIX_TARGET"
 	envEnBandwidthPlugin     = "ENABLE_BANDWIDTH_PLUGIN"
+	envEnIPv4                = "ENABLE_IPv4"
 	envEnIPv6                = "ENABLE_IPv6"
 	envEnIPv6Egress          = "ENABLE_V6_EGRESS"
 	envRandomizeSNAT         = "AWS_VPC_K8S_CNI_RANDOMIZESNAT"
@@ -140,6 +148,10 @@ type NetConf struct {
 
 	PodSGEnforcingMode string `json:"podSGEnforcingMode,omitempty"`
 
+	EBPFPodRouting string `json:"ebpfPodRouting,omitempty"`
+
+	PodVethNameScheme string `json:"podVethNameScheme,omitempty"`
+
 	RandomizeSNAT string `json:"randomizeSNAT,omitempty"`
 
 	// MTU for eth0
@@ -148,6 +160,8 @@ type NetConf struct {
 	PluginLogFile string `json:"pluginLogFile,omitempty"`
 
 	PluginLogLevel string `json:"pluginLogLevel,omitempty"`
+
+	IpamdSocketPath string `json:"ipamdSocketPath,omitempty"`
 }
 
 // IPAMConfig references containernetworking structure defined at https://github.com/containernetworking/plugins/blob/main/plugins/ipam/host-local/backend/allocator/config.go
@@ -237,6 +251,8 @@ func generateJSON(jsonFile string, outFile string, getPrimaryIP func(ipv4 bool)
 	// enabledIPv6 is to determine if EKS cluster is IPv4 or IPv6 cluster
 	// if this EKS cluster is IPv6 cluster, egress-cni-plugin will enable IPv4 egress by default
 	// if this EKS cluster is IPv4 cluster, egress-cni-plugin will only enable IPv6 egress if env var "ENABLE_V6_EGRESS" is "true"
+	// if both IPv4 and IPv6 are enabled, pods get both addresses from the VPC and egress-cni-plugin is not needed
+	enabledIPv4 := utils.GetBoolAsStringEnvVar(envEnIPv4, defaultEnableIPv4)
 	enabledIPv6 := utils.GetBoolAsStringEnvVar(envEnIPv6, defaultEnableIPv6)
 	var egressIPAMSubnet string
 	var egressIPAMDst string
@@ -244,7 +260,7 @@ func generateJSON(jsonFile string, outFile string, getPrimaryIP func(ipv4 bool)
 	var egressEnabled bool
 	var egressPluginLogFile string
 	var nodeIP = ""
-	if enabledIPv6 {
+	if enabledIPv6 && !enabledIPv4 {
 		// EKS IPv6 cluster
 		egressIPAMSubnet = egressPluginIpamSubnetV4
 		egressIPAMDst = egressPluginIpamDstV4
@@ -258,12 +274,12 @@ func generateJSON(jsonFile string, outFile string, getPrimaryIP func(ipv4 bool)
 			return err
 		}
 	} else {
-		// EKS IPv4 cluster
+		// EKS IPv4 or dual-stack cluster
 		egressIPAMSubnet = egressPluginIpamSubnetV6
 		egressIPAMDst = egressPluginIpamDstV6
 		egressIPAMDataDir = egressPluginIpamDataDirV6
 		egressPluginLogFile = utils.GetEnv(envEgressV6PluginLogFile, defaultEgressV6PluginLogFile)
-		egressEnabled = utils.GetBoolAsStringEnvVar(envEnIPv6Egress, defaultEnableIPv6Egress)
+		egressEnabled = !enabledIPv6 && utils.GetBoolAsStringEnvVar(envEnIPv6Egress, defaultEnableIPv6Egress)
 		if egressEnabled {
 			nodeIP, err = getPrimaryIP(false)
 			if err != nil {
@@ -275,6 +291,9 @@ func generateJSON(jsonFile string, outFile string, getPrimaryIP func(ipv4 bool)
 	vethPrefix := utils.GetEnv(envVethPrefix, defaultVethPrefix)
 	mtu := utils.GetEnv(envEniMTU, defaultMTU)
 	podSGEnforcingMode := utils.GetEnv(envPodSGEnforcingMode, defaultPodSGEnforcingMode)
+	// ipamd does not load the eBPF pod routing program in IPv6 mode
+	ebpfPodRouting := !enabledIPv6 && utils.GetBoolAsStringEnvVar(envEnableEBPFPodRouting, defaultEnableEBPFPodRouting)
+	podVethNameScheme := utils.GetEnv(envPodVethNameScheme, defaultPodVethNameScheme)
 	pluginLogFile := utils.GetEnv(envPluginLogFile, defaultPluginLogFile)
 	pluginLogLevel := utils.GetEnv(envPluginLogLevel, defaultPluginLogLevel)
 	randomizeSNAT := utils.GetEnv(envRandomizeSNAT, defaultRandomizeSNAT)
@@ -283,6 +302,8 @@ func generateJSON(jsonFile string, outFile string, getPrimaryIP func(ipv4 bool)
 	netconf = strings.Replace(netconf, "__VETHPREFIX__", vethPrefix, -1)
 	netconf = strings.Replace(netconf, "__MTU__", mtu, -1)
 	netconf = strings.Replace(netconf, "__PODSGENFORCINGMODE__", podSGEnforcingMode, -1)
+	netconf = strings.Replace(netconf, "__EBPFPODROUTING__", strconv.FormatBool(ebpfPodRouting), -1)
+	netconf = strings.Replace(netconf, "__PODVETHNAMESCHEME__", podVethNameScheme, -1)
 	netconf = strings.Replace(netconf, "__PLUGINLOGFILE__", pluginLogFile, -1)
 	netconf = strings.Replace(netconf, "__PLUGINLOGLEVEL__", pluginLogLevel, -1)
 	netconf = strings.Replace(netconf, "__EGRESSPLUGINLOGFILE__", egressPluginLogFile, -1)
@@ -373,6 +394,17 @@ func validateEnvVars() bool {
 		}
 	}
 
+	podVethNameScheme := utils.GetEnv(envPodVethNameScheme, defaultPodVethNameScheme)
+	if podVethNameScheme != "pod-name" && podVethNameScheme != "container-id" {
+		log.Errorf("%s must be set to either 'pod-name' or 'container-id'", envPodVethNameScheme)
+		return false
+	}
+	// The network policy agent finds the host veth of a pod by the namespace and name of the pod
+	if podVethNameScheme == "container-id" && utils.GetBoolAsStringEnvVar(envEnableNetworkPolicy, defaultEnableNetworkPolicy) {
+		log.Errorf("%s cannot be set to 'container-id' when %s is true", envPodVethNameScheme, envEnableNetworkPolicy)
+		return false
+	}
+
 	// Validate that IP_COOLDOWN_PERIOD is a valid integer
 	ipCooldownPeriod, err, input := utils.GetIntFromStringEnvVar(envIPCooldownPeriod, defaultIPCooldownPeriod)
 	if err != nil || ipCooldownPeriod < 0 {
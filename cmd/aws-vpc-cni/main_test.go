@@ -1,7 +1,9 @@
 package main
 
 import (
+	"encoding/json"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -47,3 +49,29 @@ func TestGenerateJSONPlusBandwidthAndTuning(t *testing.T) {
 	err := generateJSON(awsConflist, devNull, getPrimaryIPMock)
 	assert.NoError(t, err)
 }
+
+// Validate that the rendered conflist lets the runtime call CHECK on the plugins
+func TestGenerateJSONAllowsCheck(t *testing.T) {
+	outFile := filepath.Join(t.TempDir(), "10-aws.conflist")
+	err := generateJSON(awsConflist, outFile, getPrimaryIPMock)
+	assert.NoError(t, err)
+
+	byteValue, err := os.ReadFile(outFile)
+	assert.NoError(t, err)
+	var conflist NetConfList
+	err = json.Unmarshal(byteValue, &conflist)
+	assert.NoError(t, err)
+	assert.False(t, conflist.DisableCheck)
+	assert.Equal(t, "0.4.0", conflist.CNIVersion)
+}
+
+// Validate that the container-id veth name scheme is refused when network policy is enabled
+func TestValidateEnvVarsPodVethNameScheme(t *testing.T) {
+	_ = os.Setenv(envPodVethNameScheme, "container-id")
+	defer os.Unsetenv(envPodVethNameScheme)
+	assert.True(t, validateEnvVars())
+
+	_ = os.Setenv(envEnableNetworkPolicy, "true")
+	defer os.Unsetenv(envEnableNetworkPolicy)
+	assert.False(t, validateEnvVars())
+}
@@ -24,9 +24,19 @@ import (
 	"time"
 
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/utils/logger"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/amazon-vpc-cni-k8s/cmd/cni-metrics-helper/metrics"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/awsutils/awssession"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ec2metadatawrapper"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ec2wrapper"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/k8sapi"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/publisher"
 )
@@ -40,6 +50,66 @@ type options struct {
 	help     bool
 }
 
+// getEnvInt returns the integer value of the env var, or the default value if it is not set
+func getEnvInt(log logger.Logger, key string, defaultValue int) int {
+	value, found := os.LookupEnv(key)
+	if !found {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("%s (%s) format invalid. Integer required: %s", key, value, err)
+	}
+	return parsed
+}
+
+// startSubnetMetrics periodically publishes the capacity of the subnets used by the cluster
+func startSubnetMetrics(ctx context.Context, log logger.Logger, region string, clientSet kubernetes.Interface,
+	k8sClient client.Client, cw publisher.Publisher, submitCW bool) {
+	interval := getEnvInt(log, "SUBNET_METRICS_INTERVAL", 300)
+	if interval <= 0 {
+		log.Fatalf("SUBNET_METRICS_INTERVAL (%d) invalid. A number of seconds greater than 0 is required", interval)
+	}
+	thresholdPercent := getEnvInt(log, "SUBNET_IP_THRESHOLD_PERCENT", 10)
+	prefixThreshold := getEnvInt(log, "SUBNET_PREFIX_THRESHOLD", 0)
+
+	sess := awssession.New()
+	if region == "" {
+		val, err := ec2metadatawrapper.New(sess).Region()
+		if err != nil {
+			log.Fatalf("Unable to obtain region for subnet metrics: %v", err)
+		}
+		region = val
+	}
+	ec2Client := ec2wrapper.New(sess.Copy(aws.NewConfig().WithRegion(region)))
+
+	// Threshold events are raised on the cni-metrics-helper pod
+	var recorder events.EventRecorder
+	var eventObject runtime.Object
+	podName, podNamespace := os.Getenv("MY_POD_NAME"), os.Getenv("MY_POD_NAMESPACE")
+	if podNamespace == "" {
+		podNamespace = metav1.NamespaceSystem
+	}
+	if podName != "" {
+		pod, err := clientSet.CoreV1().Pods(podNamespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			log.Errorf("Failed to find pod %s/%s, subnet capacity events are disabled: %v", podNamespace, podName, err)
+		} else {
+			eventBroadcaster := events.NewBroadcaster(&events.EventSinkImpl{Interface: clientSet.EventsV1()})
+			eventBroadcaster.StartRecordingToSink(ctx.Done())
+			recorder = eventBroadcaster.NewRecorder(clientgoscheme.Scheme, appName)
+			eventObject = pod
+		}
+	}
+
+	log.Infof("Starting subnet metrics. Interval %d seconds, IP threshold %d%%, prefix threshold %d", interval, thresholdPercent, prefixThreshold)
+	subnetMetric := metrics.SubnetMetricsNew(ec2Client, k8sClient, cw, submitCW, recorder, eventObject, thresholdPercent, prefixThreshold, log)
+	for range time.Tick(time.Duration(interval) * time.Second) {
+		log.Info("Collecting subnet metrics ...")
+		metrics.SubnetHandler(ctx, subnetMetric)
+	}
+}
+
 func main() {
 	// Do not add anything before initializing logger
 	logLevel := logger.GetLogLevel()
@@ -129,6 +199,11 @@ func main() {
 		defer cw.Stop()
 	}
 
+	enableSubnetMetrics, _ := strconv.ParseBool(os.Getenv("ENABLE_SUBNET_METRICS"))
+	if enableSubnetMetrics {
+		go startSubnetMetrics(ctx, log, region, clientSet, k8sClient, cw, options.submitCW)
+	}
+
 	podWatcher := metrics.NewDefaultPodWatcher(k8sClient, log)
 	var cniMetric = metrics.CNIMetricsNew(clientSet, cw, options.submitCW, log, podWatcher)
 
@@ -0,0 +1,346 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// This file handles the subnet capacity metrics of the cluster
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/apis/crd/v1alpha1"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ec2wrapper"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/publisher"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/utils/logger"
+)
+
+const (
+	// subnetIDDimension is the CloudWatch dimension of the subnet metrics
+	subnetIDDimension = "SubnetId"
+
+	// awsReservedIPs is the number of addresses EC2 reserves in every subnet, the first four and the last one
+	awsReservedIPs = 5
+
+	// ipv4PrefixLength is the size of the prefixes assigned to ENIs with prefix delegation
+	ipv4PrefixLength = 28
+
+	// maxFilterValues is the maximum number of values EC2 accepts in a single filter
+	maxFilterValues = 200
+
+	// Event reasons for the subnet capacity threshold
+	subnetCapacityLowReason       = "SubnetIPCapacityLow"
+	subnetCapacityRecoveredReason = "SubnetIPCapacityRecovered"
+	subnetCapacityEventAction     = "SubnetCapacityCheck"
+)
+
+// SubnetCapacity is the IP capacity of a subnet used by the cluster
+type SubnetCapacity struct {
+	SubnetID string
+	CIDR     string
+	// TotalIPs is the number of usable addresses in the subnet, excluding the ones reserved by EC2
+	TotalIPs int
+	// AvailableIPs is the number of addresses that are not in use, as reported by EC2
+	AvailableIPs int
+	// FreePrefixes is the number of /28 blocks without any address in use, which is what prefix delegation needs
+	FreePrefixes int
+}
+
+// SubnetMetricsTarget reports the capacity of every subnet used by the nodes and ENIConfigs of the cluster
+type SubnetMetricsTarget struct {
+	ec2Client          ec2wrapper.EC2
+	k8sClient          client.Client
+	cwMetricsPublisher publisher.Publisher
+	submitCW           bool
+	recorder           events.EventRecorder
+	eventObject        runtime.Object
+	// thresholdPercent raises an event when the available IPs of a subnet fall below this percentage of its total IPs
+	thresholdPercent int
+	// prefixThreshold raises an event when the free /28 blocks of a subnet fall below this number, 0 disables it
+	prefixThreshold int
+	belowThreshold  map[string]bool
+	log             logger.Logger
+}
+
+// SubnetMetricsNew creates a new SubnetMetricsTarget. Events are only raised when both recorder and eventObject are set.
+func SubnetMetricsNew(ec2Client ec2wrapper.EC2, k8sClient client.Client, cw publisher.Publisher, submitCW bool,
+	recorder events.EventRecorder, eventObject runtime.Object, thresholdPercent, prefixThreshold int, l logger.Logger) *SubnetMetricsTarget {
+	return &SubnetMetricsTarget{
+		ec2Client:          ec2Client,
+		k8sClient:          k8sClient,
+		cwMetricsPublisher: cw,
+		submitCW:           submitCW,
+		recorder:           recorder,
+		eventObject:        eventObject,
+		thresholdPercent:   thresholdPercent,
+		prefixThreshold:    prefixThreshold,
+		belowThreshold:     make(map[string]bool),
+		log:                l,
+	}
+}
+
+// SubnetHandler collects the capacity of the subnets used by the cluster, publishes it and checks the thresholds
+func SubnetHandler(ctx context.Context, t *SubnetMetricsTarget) {
+	subnets, err := t.getSubnetCapacities(ctx)
+	if err != nil {
+		t.log.Errorf("Failed to collect subnet capacity: %v", err)
+		return
+	}
+	for _, subnet := range subnets {
+		t.log.Infof("Subnet %s (%s): available IPs %d/%d, free /28 prefixes %d",
+			subnet.SubnetID, subnet.CIDR, subnet.AvailableIPs, subnet.TotalIPs, subnet.FreePrefixes)
+		if t.submitCW {
+			t.publish(subnet)
+		}
+		t.checkThresholds(subnet)
+	}
+}
+
+// getSubnetIDs returns the sorted IDs of the subnets of the ENIs attached to cluster nodes and of all ENIConfigs
+func (t *SubnetMetricsTarget) getSubnetIDs(ctx context.Context) ([]string, error) {
+	subnetIDs := make(map[string]bool)
+
+	var eniConfigs v1alpha1.ENIConfigList
+	if err := t.k8sClient.List(ctx, &eniConfigs); err != nil {
+		// Custom networking is optional, so the ENIConfig CRD may not be installed
+		t.log.Debugf("Unable to list ENIConfigs: %v", err)
+	}
+	for _, eniConfig := range eniConfigs.Items {
+		if eniConfig.Spec.Subnet != "" {
+			subnetIDs[eniConfig.Spec.Subnet] = true
+		}
+	}
+
+	var nodes corev1.NodeList
+	if err := t.k8sClient.List(ctx, &nodes); err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+	var instanceIDs []*string
+	for _, node := range nodes.Items {
+		if instanceID := instanceIDFromProviderID(node.Spec.ProviderID); instanceID != "" {
+			instanceIDs = append(instanceIDs, aws.String(instanceID))
+		}
+	}
+	for start := 0; start < len(instanceIDs); start += maxFilterValues {
+		end := start + maxFilterValues
+		if end > len(instanceIDs) {
+			end = len(instanceIDs)
+		}
+		input := &ec2.DescribeNetworkInterfacesInput{
+			Filters: []*ec2.Filter{{
+				Name:   aws.String("attachment.instance-id"),
+				Values: instanceIDs[start:end],
+			}},
+		}
+		err := t.ec2Client.DescribeNetworkInterfacesPagesWithContext(ctx, input,
+			func(page *ec2.DescribeNetworkInterfacesOutput, lastPage bool) bool {
+				for _, eni := range page.NetworkInterfaces {
+					subnetIDs[aws.StringValue(eni.SubnetId)] = true
+				}
+				return true
+			})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to describe node ENIs")
+		}
+	}
+
+	result := make([]string, 0, len(subnetIDs))
+	for subnetID := range subnetIDs {
+		if subnetID != "" {
+			result = append(result, subnetID)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// getSubnetCapacities calls DescribeSubnets for all subnets used by the cluster and computes their capacity
+func (t *SubnetMetricsTarget) getSubnetCapacities(ctx context.Context) ([]SubnetCapacity, error) {
+	subnetIDs, err := t.getSubnetIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(subnetIDs) == 0 {
+		t.log.Info("No subnets found for the cluster")
+		return nil, nil
+	}
+
+	output, err := t.ec2Client.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{SubnetIds: aws.StringSlice(subnetIDs)})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to describe subnets")
+	}
+
+	var capacities []SubnetCapacity
+	for _, subnet := range output.Subnets {
+		subnetID := aws.StringValue(subnet.SubnetId)
+		_, cidr, err := net.ParseCIDR(aws.StringValue(subnet.CidrBlock))
+		if err != nil {
+			t.log.Warnf("Skipping subnet %s with invalid CIDR %q: %v", subnetID, aws.StringValue(subnet.CidrBlock), err)
+			continue
+		}
+		used, err := t.getUsedAddresses(ctx, subnetID)
+		if err != nil {
+			return nil, err
+		}
+		ones, bits := cidr.Mask.Size()
+		capacities = append(capacities, SubnetCapacity{
+			SubnetID:     subnetID,
+			CIDR:         cidr.String(),
+			TotalIPs:     (1 << uint(bits-ones)) - awsReservedIPs,
+			AvailableIPs: int(aws.Int64Value(subnet.AvailableIpAddressCount)),
+			FreePrefixes: countFreePrefixes(cidr, used),
+		})
+	}
+	return capacities, nil
+}
+
+// getUsedAddresses returns the private IPs and IPv4 prefixes of all ENIs in the subnet
+func (t *SubnetMetricsTarget) getUsedAddresses(ctx context.Context, subnetID string) ([]*net.IPNet, error) {
+	var used []*net.IPNet
+	input := &ec2.DescribeNetworkInterfacesInput{
+		Filters: []*ec2.Filter{{
+			Name:   aws.String("subnet-id"),
+			Values: []*string{aws.String(subnetID)},
+		}},
+	}
+	err := t.ec2Client.DescribeNetworkInterfacesPagesWithContext(ctx, input,
+		func(page *ec2.DescribeNetworkInterfacesOutput, lastPage bool) bool {
+			for _, eni := range page.NetworkInterfaces {
+				for _, addr := range eni.PrivateIpAddresses {
+					if ip := net.ParseIP(aws.StringValue(addr.PrivateIpAddress)).To4(); ip != nil {
+						used = append(used, &net.IPNet{IP: ip, Mask: net.CIDRMask(32, 32)})
+					}
+				}
+				for _, prefix := range eni.Ipv4Prefixes {
+					if _, prefixNet, err := net.ParseCIDR(aws.StringValue(prefix.Ipv4Prefix)); err == nil {
+						used = append(used, prefixNet)
+					}
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe ENIs in subnet %s", subnetID)
+	}
+	return used, nil
+}
+
+// countFreePrefixes returns the number of /28 blocks in the subnet that don't contain any used or EC2 reserved address
+func countFreePrefixes(subnet *net.IPNet, used []*net.IPNet) int {
+	ones, bits := subnet.Mask.Size()
+	if bits != 32 || ones > ipv4PrefixLength {
+		return 0
+	}
+	base := ipToUint32(subnet.IP.To4())
+	blockSize := uint32(1) << (32 - ipv4PrefixLength)
+	numBlocks := uint32(1) << uint(ipv4PrefixLength-ones)
+
+	usedBlocks := make(map[uint32]bool)
+	// The first four addresses and the last address of the subnet are reserved by EC2
+	usedBlocks[0] = true
+	usedBlocks[numBlocks-1] = true
+	for _, addr := range used {
+		if !subnet.Contains(addr.IP) {
+			continue
+		}
+		start := ipToUint32(addr.IP.To4()) - base
+		addrOnes, _ := addr.Mask.Size()
+		size := uint32(1) << uint(32-addrOnes)
+		for offset := start - start%blockSize; offset < start+size; offset += blockSize {
+			usedBlocks[offset/blockSize] = true
+		}
+	}
+	return int(numBlocks) - len(usedBlocks)
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	return uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])
+}
+
+// instanceIDFromProviderID returns the EC2 instance ID from a provider ID like aws:///us-west-2a/i-0123456789abcdef0
+func instanceIDFromProviderID(providerID string) string {
+	if !strings.HasPrefix(providerID, "aws://") {
+		return ""
+	}
+	instanceID := providerID[strings.LastIndex(providerID, "/")+1:]
+	if !strings.HasPrefix(instanceID, "i-") {
+		return ""
+	}
+	return instanceID
+}
+
+func (t *SubnetMetricsTarget) publish(subnet SubnetCapacity) {
+	dimensions := []*cloudwatch.Dimension{{
+		Name:  aws.String(subnetIDDimension),
+		Value: aws.String(subnet.SubnetID),
+	}}
+	values := map[string]int{
+		"subnetTotalIPAddresses":     subnet.TotalIPs,
+		"subnetAvailableIPAddresses": subnet.AvailableIPs,
+		"subnetFreeIPv4Prefixes":     subnet.FreePrefixes,
+	}
+	for name, value := range values {
+		t.cwMetricsPublisher.Publish(&cloudwatch.MetricDatum{
+			MetricName: aws.String(name),
+			Unit:       aws.String(cloudwatch.StandardUnitCount),
+			Value:      aws.Float64(float64(value)),
+			Dimensions: dimensions,
+		})
+	}
+}
+
+// checkThresholds raises a warning event when a subnet drops below the thresholds and a normal event once it is back
+// above them. No event is raised while the state doesn't change.
+func (t *SubnetMetricsTarget) checkThresholds(subnet SubnetCapacity) {
+	var reasons []string
+	if subnet.TotalIPs > 0 && subnet.AvailableIPs*100 < subnet.TotalIPs*t.thresholdPercent {
+		reasons = append(reasons, fmt.Sprintf("%d of %d IP addresses available, below %d%%",
+			subnet.AvailableIPs, subnet.TotalIPs, t.thresholdPercent))
+	}
+	if t.prefixThreshold > 0 && subnet.FreePrefixes < t.prefixThreshold {
+		reasons = append(reasons, fmt.Sprintf("%d free /28 prefixes, below %d", subnet.FreePrefixes, t.prefixThreshold))
+	}
+
+	below := len(reasons) > 0
+	if below == t.belowThreshold[subnet.SubnetID] {
+		return
+	}
+	t.belowThreshold[subnet.SubnetID] = below
+	if below {
+		message := fmt.Sprintf("Subnet %s (%s) is running out of capacity: %s", subnet.SubnetID, subnet.CIDR, strings.Join(reasons, ", "))
+		t.log.Warn(message)
+		t.sendEvent(corev1.EventTypeWarning, subnetCapacityLowReason, message)
+	} else {
+		message := fmt.Sprintf("Subnet %s (%s) is back above the capacity thresholds", subnet.SubnetID, subnet.CIDR)
+		t.log.Info(message)
+		t.sendEvent(corev1.EventTypeNormal, subnetCapacityRecoveredReason, message)
+	}
+}
+
+func (t *SubnetMetricsTarget) sendEvent(eventType, reason, message string) {
+	if t.recorder == nil || t.eventObject == nil {
+		return
+	}
+	t.recorder.Eventf(t.eventObject, nil, eventType, reason, subnetCapacityEventAction, message)
+}
@@ -0,0 +1,151 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	testclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	eniconfigscheme "github.com/aws/amazon-vpc-cni-k8s/pkg/apis/crd/v1alpha1"
+	mock_ec2wrapper "github.com/aws/amazon-vpc-cni-k8s/pkg/ec2wrapper/mocks"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/publisher/mock_publisher"
+)
+
+func TestCountFreePrefixes(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("10.0.0.0/26")
+	_, prefix, _ := net.ParseCIDR("10.0.0.16/28")
+	_, outside, _ := net.ParseCIDR("10.0.1.0/28")
+
+	// 4 blocks, the first and the last contain addresses reserved by EC2
+	assert.Equal(t, 2, countFreePrefixes(subnet, nil))
+	assert.Equal(t, 1, countFreePrefixes(subnet, []*net.IPNet{prefix}))
+	assert.Equal(t, 1, countFreePrefixes(subnet, []*net.IPNet{{IP: net.ParseIP("10.0.0.33").To4(), Mask: net.CIDRMask(32, 32)}}))
+	assert.Equal(t, 0, countFreePrefixes(subnet, []*net.IPNet{prefix, {IP: net.ParseIP("10.0.0.40").To4(), Mask: net.CIDRMask(32, 32)}}))
+	assert.Equal(t, 2, countFreePrefixes(subnet, []*net.IPNet{outside}))
+
+	_, small, _ := net.ParseCIDR("10.0.0.0/28")
+	assert.Equal(t, 0, countFreePrefixes(small, nil))
+}
+
+func TestInstanceIDFromProviderID(t *testing.T) {
+	assert.Equal(t, "i-0123456789abcdef0", instanceIDFromProviderID("aws:///us-west-2a/i-0123456789abcdef0"))
+	assert.Equal(t, "", instanceIDFromProviderID("aws:///us-west-2a/fargate-ip-10-0-0-1"))
+	assert.Equal(t, "", instanceIDFromProviderID("kind://docker/kind/kind-control-plane"))
+	assert.Equal(t, "", instanceIDFromProviderID(""))
+}
+
+func TestSubnetHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx := context.Background()
+
+	k8sSchema := runtime.NewScheme()
+	clientgoscheme.AddToScheme(k8sSchema)
+	eniconfigscheme.AddToScheme(k8sSchema)
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Spec:       v1.NodeSpec{ProviderID: "aws:///us-west-2a/i-0123456789abcdef0"},
+	}
+	eniConfig := &eniconfigscheme.ENIConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "us-west-2a"},
+		Spec:       eniconfigscheme.ENIConfigSpec{Subnet: "subnet-2"},
+	}
+	k8sClient := testclient.NewClientBuilder().WithScheme(k8sSchema).WithObjects(node, eniConfig).Build()
+
+	mockEC2 := mock_ec2wrapper.NewMockEC2(ctrl)
+	mockPublisher := mock_publisher.NewMockPublisher(ctrl)
+	fakeRecorder := events.NewFakeRecorder(10)
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "cni-metrics-helper", Namespace: metav1.NamespaceSystem}}
+
+	nodeENIs := &ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: []*ec2.NetworkInterface{{SubnetId: aws.String("subnet-1")}}}
+	subnet1ENIs := &ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: []*ec2.NetworkInterface{{
+		SubnetId:           aws.String("subnet-1"),
+		PrivateIpAddresses: []*ec2.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: aws.String("10.0.0.20")}},
+		Ipv4Prefixes:       []*ec2.Ipv4PrefixSpecification{{Ipv4Prefix: aws.String("10.0.0.32/28")}},
+	}}}
+	mockEC2.EXPECT().DescribeNetworkInterfacesPagesWithContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input *ec2.DescribeNetworkInterfacesInput, fn func(*ec2.DescribeNetworkInterfacesOutput, bool) bool, _ ...interface{}) error {
+			switch aws.StringValue(input.Filters[0].Name) {
+			case "attachment.instance-id":
+				assert.Equal(t, "i-0123456789abcdef0", aws.StringValue(input.Filters[0].Values[0]))
+				fn(nodeENIs, true)
+			case "subnet-id":
+				if aws.StringValue(input.Filters[0].Values[0]) == "subnet-1" {
+					fn(subnet1ENIs, true)
+				} else {
+					fn(&ec2.DescribeNetworkInterfacesOutput{}, true)
+				}
+			}
+			return nil
+		}).AnyTimes()
+	subnets := &ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+		{SubnetId: aws.String("subnet-1"), CidrBlock: aws.String("10.0.0.0/26"), AvailableIpAddressCount: aws.Int64(2)},
+		{SubnetId: aws.String("subnet-2"), CidrBlock: aws.String("10.0.1.0/24"), AvailableIpAddressCount: aws.Int64(251)},
+	}}
+	mockEC2.EXPECT().DescribeSubnetsWithContext(gomock.Any(), &ec2.DescribeSubnetsInput{
+		SubnetIds: aws.StringSlice([]string{"subnet-1", "subnet-2"}),
+	}).Return(subnets, nil).Times(2)
+
+	var published []*cloudwatch.MetricDatum
+	mockPublisher.EXPECT().Publish(gomock.Any()).Do(func(datum ...*cloudwatch.MetricDatum) {
+		published = append(published, datum...)
+	}).Times(6)
+
+	target := SubnetMetricsNew(mockEC2, k8sClient, mockPublisher, true, fakeRecorder, pod, 10, 0, testLog)
+	SubnetHandler(ctx, target)
+
+	assert.Len(t, published, 6)
+	for _, datum := range published {
+		assert.Equal(t, subnetIDDimension, aws.StringValue(datum.Dimensions[0].Name))
+		if aws.StringValue(datum.Dimensions[0].Value) == "subnet-1" && aws.StringValue(datum.MetricName) == "subnetFreeIPv4Prefixes" {
+			assert.Equal(t, float64(0), aws.Float64Value(datum.Value))
+		}
+	}
+
+	// Only subnet-1 is below 10%, and the event is not repeated on the next run
+	assert.Len(t, fakeRecorder.Events, 1)
+	assert.Contains(t, <-fakeRecorder.Events, subnetCapacityLowReason)
+	target.submitCW = false
+	SubnetHandler(ctx, target)
+	assert.Len(t, fakeRecorder.Events, 0)
+}
+
+func TestSubnetCheckThresholdsRecovery(t *testing.T) {
+	fakeRecorder := events.NewFakeRecorder(10)
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "cni-metrics-helper", Namespace: metav1.NamespaceSystem}}
+	target := SubnetMetricsNew(nil, nil, nil, false, fakeRecorder, pod, 10, 4, testLog)
+
+	subnet := SubnetCapacity{SubnetID: "subnet-1", CIDR: "10.0.0.0/24", TotalIPs: 251, AvailableIPs: 100, FreePrefixes: 2}
+	target.checkThresholds(subnet)
+	got := <-fakeRecorder.Events
+	assert.Contains(t, got, subnetCapacityLowReason)
+	assert.Contains(t, got, "2 free /28 prefixes")
+
+	subnet.FreePrefixes = 5
+	target.checkThresholds(subnet)
+	assert.Contains(t, <-fakeRecorder.Events, subnetCapacityRecoveredReason)
+}
@@ -100,6 +100,15 @@ func NewEgressDelContext(nsPath string) egressContext {
 	}
 }
 
+// NewEgressCheckContext create a context to check container egress traffic
+func NewEgressCheckContext(nsPath string) egressContext {
+	return egressContext{
+		Link:   netlinkwrapper.NewNetLink(),
+		Ns:     nswrapper.NewNS(),
+		NsPath: nsPath,
+	}
+}
+
 func (ec *egressContext) setupContainerVethV4() (*current.Interface, *current.Interface, error) {
 	// The IPAM result will be something like IP=192.168.3.5/24, GW=192.168.3.1.
 	// What we want is really a point-to-point link but veth does not support IFF_POINTTOPOINT.
@@ -22,6 +22,7 @@ import (
 	"github.com/containernetworking/cni/pkg/types"
 	"github.com/containernetworking/cni/pkg/types/current"
 	cniversion "github.com/containernetworking/cni/pkg/version"
+	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/containernetworking/plugins/pkg/utils"
 )
 
@@ -35,7 +36,7 @@ func init() {
 }
 
 func main() {
-	skel.PluginMain(cmdAdd, nil, cmdDel, cniversion.All, fmt.Sprintf("egress CNI plugin %s", version))
+	skel.PluginMain(cmdAdd, cmdCheck, cmdDel, cniversion.All, fmt.Sprintf("egress CNI plugin %s", version))
 }
 
 func cmdAdd(args *skel.CmdArgs) error {
@@ -147,3 +148,40 @@ func del(args *skel.CmdArgs, ec *egressContext) (err error) {
 
 	return ec.cmdDelEgress(ipv4)
 }
+
+func cmdCheck(args *skel.CmdArgs) error {
+	ec := NewEgressCheckContext(args.Netns)
+	return check(args, &ec)
+}
+
+// check verifies that the egress interface added by ADD is still in the network namespace of the container
+func check(args *skel.CmdArgs, ec *egressContext) (err error) {
+	ec.NetConf, ec.Log, err = LoadConf(args.StdinData)
+	if err != nil {
+		return fmt.Errorf("failed to parse config: %v", err)
+	}
+	ec.Log.Debugf("Received a Check request: nsPath: %s conf=%+v", ec.NsPath, *ec.NetConf)
+
+	// We only need this plugin to kick in if egress is enabled
+	if ec.NetConf.Enabled != "true" {
+		ec.Log.Debugf("egress-cni plugin is disabled")
+		return nil
+	}
+	if ec.NetConf.PrevResult == nil {
+		return fmt.Errorf("must be called as a chained plugin")
+	}
+
+	ifName := egressIPv4InterfaceName
+	if ec.NetConf.NodeIP.To4() == nil { // NodeIP is not IPv4 address
+		ifName = egressIPv6InterfaceName
+	}
+	err = ec.Ns.WithNetNSPath(ec.NsPath, func(ns.NetNS) error {
+		_, err := ec.Link.LinkByName(ifName)
+		return err
+	})
+	if err != nil {
+		ec.Log.Errorf("egress interface %s not found in %s: %v", ifName, ec.NsPath, err)
+		return fmt.Errorf("egress interface %s not found: %v", ifName, err)
+	}
+	return nil
+}
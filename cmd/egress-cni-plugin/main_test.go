@@ -14,12 +14,16 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
 	"testing"
 
 	"github.com/containernetworking/cni/pkg/skel"
+	_ns "github.com/containernetworking/plugins/pkg/ns"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
 
 	mock_ipamwrapper "github.com/aws/amazon-vpc-cni-k8s/pkg/hostipamwrapper/mocks"
 	mock_iptables "github.com/aws/amazon-vpc-cni-k8s/pkg/iptableswrapper/mocks"
@@ -50,7 +54,7 @@ func TestCmdAddV4(t *testing.T) {
 				"enabled":"true",
 				"nodeIP": "192.168.1.123",
 				"ipam": {"type":"host-local","ranges":[[{"subnet": "169.254.172.0/22"}]],"routes":[{"dst":"0.0.0.0"}],"dataDir":"/run/cni/v6pd/egress-v4-ipam"},
-				"pluginLogFile":"egress-plugin.log",
+				"pluginLogFile":"` + filepath.Join(t.TempDir(), "egress-plugin.log") + `",
 				"pluginLogLevel":"DEBUG",
 				"podSGEnforcingMode":"strict",
 				"prevResult":
@@ -121,7 +125,7 @@ func TestCmdDelV4(t *testing.T) {
 				"enabled":"true",
 				"nodeIP": "192.168.1.123",
 				"ipam": {"type":"host-local","ranges":[[{"subnet": "169.254.172.0/22"}]],"routes":[{"dst":"0.0.0.0"}],"dataDir":"/run/cni/v6pd/egress-v4-ipam"},
-				"pluginLogFile":"egress-plugin.log",
+				"pluginLogFile":"` + filepath.Join(t.TempDir(), "egress-plugin.log") + `",
 				"pluginLogLevel":"DEBUG",
 				"podSGEnforcingMode":"strict",
 				"prevResult":
@@ -176,7 +180,7 @@ func TestCmdAddV6(t *testing.T) {
 				"enabled":"true",
 				"nodeIP": "2600::",
 				"ipam": {"type":"host-local","ranges":[[{"subnet": "fd00::ac:00/118"}]],"routes":[{"dst":"::/0"}],"dataDir":"/run/cni/v4pd/egress-v6-ipam"},
-				"pluginLogFile":"egress-plugin.log",
+				"pluginLogFile":"` + filepath.Join(t.TempDir(), "egress-plugin.log") + `",
 				"pluginLogLevel":"DEBUG",
 				"podSGEnforcingMode":"strict",
 				"prevResult":
@@ -244,7 +248,7 @@ func TestCmdDelV6(t *testing.T) {
 				"enabled":"true",
 				"nodeIP": "2600::",
 				"ipam": {"type":"host-local","ranges":[[{"subnet": "fd00::ac:00/118"}]],"routes":[{"dst":"::/0"}],"dataDir":"/run/cni/v4pd/egress-v6-ipam"},
-				"pluginLogFile":"egress-plugin.log",
+				"pluginLogFile":"` + filepath.Join(t.TempDir(), "egress-plugin.log") + `",
 				"pluginLogLevel":"DEBUG",
 				"podSGEnforcingMode":"strict",
 				"prevResult":
@@ -284,3 +288,58 @@ func TestCmdDelV6(t *testing.T) {
 		fmt.Sprintf("del chain nat %s", snatChainV6)}
 	assert.EqualValues(t, expectIptablesDel, actualIptablesDel)
 }
+
+func TestCmdCheckV4(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	args := &skel.CmdArgs{
+		ContainerID: containerIDV4,
+		IfName:      "eth0",
+		StdinData: []byte(`{
+				"cniVersion":"0.4.0",
+				"mtu":"9001",
+				"name":"aws-cni",
+				"enabled":"true",
+				"nodeIP": "192.168.1.123",
+				"ipam": {"type":"host-local","ranges":[[{"subnet": "169.254.172.0/22"}]],"routes":[{"dst":"0.0.0.0"}],"dataDir":"/run/cni/v6pd/egress-v4-ipam"},
+				"pluginLogFile":"` + filepath.Join(t.TempDir(), "egress-plugin.log") + `",
+				"pluginLogLevel":"DEBUG",
+				"prevResult":
+					{
+					"cniVersion":"0.4.0",
+					"interfaces":
+						[
+							{"name":"eni36e5b0ee702"},
+							{"name":"eth0","sandbox":"/var/run/netns/cni-266298c1-b141-9c7f-f26b-97ff084f3fcc"},
+							{"name":"dummy36e5b0ee702","mac":"0","sandbox":"0"}],
+					"ips":
+						[{"version":"6","interface":1,"address":"2600:1f16:828:c404:af46:9f44:d2ea:4569/128"}],
+					"dns":{}
+					},
+				"type":"aws-cni",
+				"vethPrefix":"eni"
+		}`),
+	}
+
+	ec := egressContext{
+		Ns:     mock_nswrapper.NewMockNS(ctrl),
+		NsPath: "/var/run/netns/cni-xxxx",
+		Link:   mock_netlinkwrapper.NewMockNetLink(ctrl),
+	}
+	nsParent, err := _ns.GetCurrentNS()
+	assert.NoError(t, err)
+	ec.Ns.(*mock_nswrapper.MockNS).EXPECT().WithNetNSPath(ec.NsPath, gomock.Any()).DoAndReturn(
+		func(_ string, f func(_ns.NetNS) error) error {
+			return f(nsParent)
+		}).Times(2)
+
+	ec.Link.(*mock_netlinkwrapper.MockNetLink).EXPECT().LinkByName(egressIPv4InterfaceName).Return(
+		&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: egressIPv4InterfaceName}}, nil)
+	err = check(args, &ec)
+	assert.NoError(t, err)
+
+	// The check fails once the egress interface is gone
+	ec.Link.(*mock_netlinkwrapper.MockNetLink).EXPECT().LinkByName(egressIPv4InterfaceName).Return(nil, errors.New("link not found"))
+	err = check(args, &ec)
+	assert.Error(t, err)
+}
@@ -0,0 +1,103 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// max-pods-calculator prints the recommended kubelet --max-pods value for an instance type, based on the ENI and IP
+// limits known to the VPC CNI. It is meant to be called from node bootstrap scripts.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/vpc"
+)
+
+const (
+	// StatusInvalidArguments indicates specified invalid arguments.
+	StatusInvalidArguments = 1
+	// StatusUnknownInstanceType indicates the limits of the instance type are unknown.
+	StatusUnknownInstanceType = 2
+	// StatusCalculationFailure indicates max pods could not be calculated for the given configuration.
+	StatusCalculationFailure = 3
+
+	outputText = "text"
+	outputJSON = "json"
+)
+
+var (
+	instanceType string
+	limitsFile   string
+	output       string
+	opts         vpc.MaxPodsOptions
+)
+
+func init() {
+	flag.StringVar(&instanceType, "instance-type", "", "(required) EC2 instance type, for example m5.large")
+	flag.StringVar(&limitsFile, "limits-file", "", "JSON file with limits for instance types unknown to this version, in the INSTANCE_LIMITS_OVERRIDE_FILE format")
+	flag.StringVar(&output, "output", outputText, "output format: text (max pods only) or json")
+	flag.BoolVar(&opts.PrefixDelegation, "prefix-delegation", false, "ENABLE_PREFIX_DELEGATION is set")
+	flag.BoolVar(&opts.CustomNetworking, "custom-networking", false, "AWS_VPC_K8S_CNI_CUSTOM_NETWORK_CFG is set, so the primary ENI is not used for pods")
+	flag.BoolVar(&opts.PodENI, "pod-eni", false, "ENABLE_POD_ENI is set, so one ENI is used as trunk ENI")
+	flag.BoolVar(&opts.AllNetworkCards, "all-network-cards", false, "count the ENIs of all network cards instead of only the default network card")
+	flag.IntVar(&opts.CPUs, "cpus", 0, "number of vCPUs; caps max pods at 110 below 30 vCPUs and at 250 otherwise")
+	flag.IntVar(&opts.MaxPodsCap, "max-pods-cap", 0, "explicit upper bound for max pods")
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	flag.Parse()
+
+	if instanceType == "" {
+		fmt.Fprintln(os.Stderr, "error: --instance-type not specified")
+		return StatusInvalidArguments
+	}
+	if output != outputText && output != outputJSON {
+		fmt.Fprintf(os.Stderr, "error: unsupported --output %q\n", output)
+		return StatusInvalidArguments
+	}
+	if opts.CPUs < 0 || opts.MaxPodsCap < 0 {
+		fmt.Fprintln(os.Stderr, "error: --cpus and --max-pods-cap must not be negative")
+		return StatusInvalidArguments
+	}
+
+	if _, ok := vpc.GetInstance(instanceType); !ok && limitsFile != "" {
+		if _, err := vpc.LoadInstanceFromFile(limitsFile, instanceType); err != nil {
+			fmt.Fprintf(os.Stderr, "error: failed to load %s: %v\n", limitsFile, err)
+			return StatusInvalidArguments
+		}
+	}
+
+	result, err := vpc.CalculateMaxPods(instanceType, opts)
+	if err == vpc.ErrInstanceTypeNotExist {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", instanceType, err)
+		return StatusUnknownInstanceType
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return StatusCalculationFailure
+	}
+
+	if output == outputJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return StatusCalculationFailure
+		}
+		return 0
+	}
+	fmt.Println(result.MaxPods)
+	return 0
+}
@@ -34,8 +34,11 @@ import (
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
 
 	"github.com/aws/amazon-vpc-cni-k8s/cmd/routed-eni-cni-plugin/driver"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/bpfrouting"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/grpcwrapper"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/networkutils"
@@ -49,6 +52,37 @@ const ipamdAddress = "127.0.0.1:50051"
 
 const dummyInterfacePrefix = "dummy"
 
+// passthroughENISandbox is packed in the Sandbox of the dummy interface, instead of the device number, when a whole ENI
+// is moved into the pod
+const passthroughENISandbox = "passthrough"
+
+// Plugin specific CNI error codes of the reasons that ipamd failed a request
+const (
+	errMaxENIsReached uint = 100 + iota
+	errSubnetExhausted
+	errBranchENIAnnotationMissing
+	errTrunkENIMissing
+	errVersionMismatch
+	errPodNotFound
+	errInvalidRequest
+)
+
+// ipamdErrors are the CNI error code and message of each reason that ipamd failed a request
+var ipamdErrors = map[pb.ErrorReason]struct {
+	code uint
+	msg  string
+}{
+	pb.ErrorReason_POOL_EMPTY:                    {types.ErrTryAgainLater, "no IP addresses are available on the node yet"},
+	pb.ErrorReason_MAX_ENIS_REACHED:              {errMaxENIsReached, "no IP addresses are available and the node has the maximum number of ENIs"},
+	pb.ErrorReason_SUBNET_EXHAUSTED:              {errSubnetExhausted, "no IP addresses are available in the subnet"},
+	pb.ErrorReason_BRANCH_ENI_ANNOTATION_MISSING: {errBranchENIAnnotationMissing, "the branch ENI of the pod is not allocated yet"},
+	pb.ErrorReason_TRUNK_ENI_MISSING:             {errTrunkENIMissing, "no trunk ENI is attached to the node"},
+	pb.ErrorReason_VERSION_MISMATCH:              {errVersionMismatch, "the versions of the CNI plugin and ipamd differ"},
+	pb.ErrorReason_POD_NOT_FOUND:                 {errPodNotFound, "the pod is not found"},
+	pb.ErrorReason_INVALID_REQUEST:               {errInvalidRequest, "invalid request to ipamd"},
+	pb.ErrorReason_INTERNAL_ERROR:                {types.ErrInternal, "ipamd failed the request"},
+}
+
 var version string
 
 // NetConf stores the common network config for the CNI plugin
@@ -66,9 +100,22 @@ type NetConf struct {
 	// PodSGEnforcingMode is the enforcing mode for Security groups for pods feature
 	PodSGEnforcingMode sgpp.EnforcingMode `json:"podSGEnforcingMode"`
 
+	// EBPFPodRouting is "true" when the traffic from normal ENI based pods is routed by the eBPF pod routing program that
+	// ipamd loads, instead of a rule per pod
+	EBPFPodRouting string `json:"ebpfPodRouting"`
+
+	// PodVethNameScheme is the way the hostVeths of new pods are named, after the pod or after its sandbox
+	PodVethNameScheme networkutils.VethNameScheme `json:"podVethNameScheme"`
+
 	PluginLogFile string `json:"pluginLogFile"`
 
 	PluginLogLevel string `json:"pluginLogLevel"`
+
+	// IpamdSocketPath is the Unix socket of ipamd. The plugin calls ipamd on its TCP port when it is not set.
+	IpamdSocketPath string `json:"ipamdSocketPath"`
+
+	// IpamdTLS is used to call ipamd with mutual TLS, it is preferred over IpamdSocketPath
+	IpamdTLS *grpcwrapper.TLSConfig `json:"ipamdTLS,omitempty"`
 }
 
 // K8sArgs is the valid CNI_ARGS used for Kubernetes
@@ -98,6 +145,7 @@ func LoadNetConf(bytes []byte) (*NetConf, logger.Logger, error) {
 		MTU:                "9001",
 		VethPrefix:         "eni",
 		PodSGEnforcingMode: sgpp.DefaultEnforcingMode,
+		PodVethNameScheme:  networkutils.VethNameSchemePodName,
 	}
 
 	if err := json.Unmarshal(bytes, &conf); err != nil {
@@ -119,6 +167,13 @@ func LoadNetConf(bytes []byte) (*NetConf, logger.Logger, error) {
 	if len(conf.VethPrefix) > 4 {
 		return nil, nil, errors.New("conf.VethPrefix can be at most 4 characters long")
 	}
+	switch conf.PodVethNameScheme {
+	case "":
+		conf.PodVethNameScheme = networkutils.VethNameSchemePodName
+	case networkutils.VethNameSchemePodName, networkutils.VethNameSchemeContainerID:
+	default:
+		return nil, nil, errors.Errorf("conf.PodVethNameScheme must be %q or %q", networkutils.VethNameSchemePodName, networkutils.VethNameSchemeContainerID)
+	}
 	return &conf, log, nil
 }
 
@@ -149,7 +204,7 @@ func add(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrap
 	log.Debugf("MTU value set is %d:", mtu)
 
 	// Set up a connection to the ipamD server.
-	conn, err := grpcClient.Dial(ipamdAddress, grpc.WithInsecure())
+	conn, err := dialIpamd(grpcClient, conf)
 	if err != nil {
 		log.Errorf("Failed to connect to backend server for container %s: %v",
 			args.ContainerID, err)
@@ -173,37 +228,51 @@ func add(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrap
 
 	if err != nil {
 		log.Errorf("Error received from AddNetwork grpc call for containerID %s: %v", args.ContainerID, err)
+		if grpcstatus.Code(err) == codes.FailedPrecondition {
+			return newIpamdError("add", pb.ErrorReason_VERSION_MISMATCH, grpcstatus.Convert(err).Message())
+		}
 		return errors.Wrap(err, "add cmd: Error received from AddNetwork gRPC call")
 	}
 
 	if !r.Success {
-		log.Errorf("Failed to assign an IP address to container %s",
-			args.ContainerID)
-		return errors.New("add cmd: failed to assign an IP address to container")
+		log.Errorf("Failed to assign an IP address to container %s: %s %s",
+			args.ContainerID, r.ErrorReason, r.ErrorMessage)
+		if r.ErrorReason == pb.ErrorReason_NONE {
+			return errors.New("add cmd: failed to assign an IP address to container")
+		}
+		return newIpamdError("add", r.ErrorReason, r.ErrorMessage)
 	}
 
 	log.Infof("Received add network response from ipamd for container %s interface %s: %+v",
 		args.ContainerID, args.IfName, r)
 
 	// We will let the values in result struct guide us in terms of IP Address Family configured.
-	var v4Addr, v6Addr, addr *net.IPNet
-	var addrFamily string
+	// In dual-stack mode, both the v4 and the v6 address are set.
+	var v4Addr, v6Addr *net.IPNet
+	containerInterfaceIndex := 1
+	var ips []*current.IPConfig
 
-	// We don't support dual stack mode currently so it has to be either v4 or v6 mode.
 	if r.IPv4Addr != "" {
 		v4Addr = &net.IPNet{
 			IP:   net.ParseIP(r.IPv4Addr),
 			Mask: net.CIDRMask(32, 32),
 		}
-		addrFamily = "4"
-		addr = v4Addr
-	} else if r.IPv6Addr != "" {
+		ips = append(ips, &current.IPConfig{
+			Version:   "4",
+			Address:   *v4Addr,
+			Interface: &containerInterfaceIndex,
+		})
+	}
+	if r.IPv6Addr != "" {
 		v6Addr = &net.IPNet{
 			IP:   net.ParseIP(r.IPv6Addr),
 			Mask: net.CIDRMask(128, 128),
 		}
-		addrFamily = "6"
-		addr = v6Addr
+		ips = append(ips, &current.IPConfig{
+			Version:   "6",
+			Address:   *v6Addr,
+			Interface: &containerInterfaceIndex,
+		})
 	}
 
 	var hostVethName string
@@ -212,19 +281,29 @@ func add(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrap
 	// The dummy interface is purely virtual and is stored in the prevResult struct to assist in cleanup during the DEL command.
 	dummyInterfaceName := networkutils.GeneratePodHostVethName(dummyInterfacePrefix, string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_NAME))
 
-	// Non-zero value means pods are using branch ENI
-	if r.PodVlanId != 0 {
+	if r.PassthroughENI {
+		// The ENI itself is the container's interface, nothing is set up on the host
+		err = driverClient.SetupPassthroughPodNetwork(args.IfName, args.Netns, v4Addr, r.PodENIMAC, r.PodENISubnetGW, mtu, log)
+		dummyInterface = &current.Interface{Name: dummyInterfaceName, Mac: fmt.Sprint(0), Sandbox: passthroughENISandbox}
+	} else if r.PodVlanId != 0 {
+		// Non-zero value means pods are using branch ENI
 		hostVethNamePrefix := sgpp.BuildHostVethNamePrefix(conf.VethPrefix, conf.PodSGEnforcingMode)
-		hostVethName = networkutils.GeneratePodHostVethName(hostVethNamePrefix, string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_NAME))
+		hostVethName = podHostVethName(conf.PodVethNameScheme, hostVethNamePrefix, k8sArgs, args.ContainerID, networkutils.PrimaryPodInterfaceName)
 		err = driverClient.SetupBranchENIPodNetwork(hostVethName, args.IfName, args.Netns, v4Addr, v6Addr, int(r.PodVlanId), r.PodENIMAC,
 			r.PodENISubnetGW, int(r.ParentIfIndex), mtu, conf.PodSGEnforcingMode, log)
 		// For branch ENI mode, the pod VLAN ID is packed in Interface.Mac
 		dummyInterface = &current.Interface{Name: dummyInterfaceName, Mac: fmt.Sprint(r.PodVlanId)}
 	} else {
-		// build hostVethName
+		// build hostVethName, each secondary interface of the pod has its own
 		// Note: the maximum length for linux interface name is 15
-		hostVethName = networkutils.GeneratePodHostVethName(conf.VethPrefix, string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_NAME))
-		err = driverClient.SetupPodNetwork(hostVethName, args.IfName, args.Netns, v4Addr, v6Addr, int(r.DeviceNumber), mtu, log)
+		hostVethName = podHostVethName(conf.PodVethNameScheme, conf.VethPrefix, k8sArgs, args.ContainerID, args.IfName)
+		if r.SecondaryInterface {
+			err = driverClient.SetupSecondaryPodNetwork(hostVethName, args.IfName, args.Netns, v4Addr, v6Addr, int(r.DeviceNumber), mtu, log)
+		} else if conf.EBPFPodRouting == "true" && v4Addr != nil && v6Addr == nil && bpfrouting.Routable(int(r.DeviceNumber)) {
+			err = driverClient.SetupBPFRoutedPodNetwork(hostVethName, args.IfName, args.Netns, v4Addr, int(r.DeviceNumber), mtu, log)
+		} else {
+			err = driverClient.SetupPodNetwork(hostVethName, args.IfName, args.Netns, v4Addr, v6Addr, int(r.DeviceNumber), mtu, log)
+		}
 		// For non-branch ENI, the pod VLAN ID value of 0 is packed in Interface.Mac, while the interface device number is packed in Interface.Sandbox
 		dummyInterface = &current.Interface{Name: dummyInterfaceName, Mac: fmt.Sprint(0), Sandbox: fmt.Sprint(r.DeviceNumber)}
 	}
@@ -254,25 +333,17 @@ func add(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrap
 		return errors.Wrap(err, "add command: failed to setup network")
 	}
 
-	containerInterfaceIndex := 1
-	ips := []*current.IPConfig{
-		{
-			Version:   addrFamily,
-			Address:   *addr,
-			Interface: &containerInterfaceIndex,
-		},
-	}
-
-	hostInterface := &current.Interface{Name: hostVethName}
 	containerInterface := &current.Interface{Name: args.IfName, Sandbox: args.Netns}
 
-	result := &current.Result{
-		IPs: ips,
-		Interfaces: []*current.Interface{
-			hostInterface,
-			containerInterface,
-		},
+	result := &current.Result{IPs: ips}
+	if r.PassthroughENI {
+		// Without a host interface, the container's interface is the first one
+		containerInterfaceIndex = 0
+		containerInterface.Mac = r.PodENIMAC
+	} else {
+		result.Interfaces = append(result.Interfaces, &current.Interface{Name: hostVethName})
 	}
+	result.Interfaces = append(result.Interfaces, containerInterface)
 
 	// dummy interface is appended to PrevResult for use during cleanup
 	result.Interfaces = append(result.Interfaces, dummyInterface)
@@ -316,7 +387,7 @@ func del(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrap
 
 	// notify local IP address manager to free secondary IP
 	// Set up a connection to the server.
-	conn, err := grpcClient.Dial(ipamdAddress, grpc.WithInsecure())
+	conn, err := dialIpamd(grpcClient, conf)
 	if err != nil {
 		log.Errorf("Failed to connect to backend server for container %s: %v",
 			args.ContainerID, err)
@@ -360,20 +431,26 @@ func del(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrap
 
 		// DelNetworkRequest may return a connection error, so try to delete using PrevResult whenever an error is returned. As with the case above, do
 		// not return error to kubelet, as there is no guarantee that delete is retried.
-		if teardownPodNetworkWithPrevResult(driverClient, conf, k8sArgs, args.IfName, log) {
-			log.Infof("Handled pod teardown using prevResult: ContainerID(%s) Netns(%s) IfName(%s) PodNamespace(%s) PodName(%s)",
-				args.ContainerID, args.Netns, args.IfName, string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_NAME))
-		} else {
-			log.Infof("Could not teardown pod using prevResult: ContainerID(%s) Netns(%s) IfName(%s) PodNamespace(%s) PodName(%s)",
-				args.ContainerID, args.Netns, args.IfName, string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_NAME))
-		}
+		tryTeardownPodNetworkWithPrevResult(driverClient, conf, k8sArgs, args, log)
 		return nil
 	}
 
+	if !r.Success && r.ErrorReason == pb.ErrorReason_POD_NOT_FOUND {
+		log.Infof("Container %s not found", args.ContainerID)
+		return nil
+	}
 	if !r.Success {
-		log.Errorf("Failed to process delete request for container %s: Success == false",
-			args.ContainerID)
-		return errors.New("del cmd: failed to process delete request")
+		log.Errorf("Failed to process delete request for container %s: Success == false, %s %s",
+			args.ContainerID, r.ErrorReason, r.ErrorMessage)
+		if r.ErrorReason == pb.ErrorReason_NONE {
+			return errors.New("del cmd: failed to process delete request")
+		}
+		if r.ErrorReason == pb.ErrorReason_INTERNAL_ERROR {
+			// The pod network is torn down like after a failed call, the error is still returned so that the runtime
+			// retries and ipamd releases the IP
+			tryTeardownPodNetworkWithPrevResult(driverClient, conf, k8sArgs, args, log)
+		}
+		return newIpamdError("del", r.ErrorReason, r.ErrorMessage)
 	}
 
 	log.Infof("Received del network response from ipamd for pod %s namespace %s sandbox %s: %+v", string(k8sArgs.K8S_POD_NAME),
@@ -395,8 +472,16 @@ func del(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrap
 			Mask: net.CIDRMask(maskLen, maskLen),
 		}
 
-		// vlanID != 0 means pod using security group
-		if r.PodVlanId != 0 {
+		if r.PassthroughENI {
+			// The ENI is returned to the pool by ipamd once it is back on the host. Without a netns, the kernel moved
+			// it back already.
+			if isNetnsEmpty(args.Netns) {
+				log.Infof("Ignoring TeardownPassthroughPodNetwork as Netns is empty for pod: %s namespace: %s containerID: %s", k8sArgs.K8S_POD_NAME, k8sArgs.K8S_POD_NAMESPACE, k8sArgs.K8S_POD_INFRA_CONTAINER_ID)
+				return nil
+			}
+			err = driverClient.TeardownPassthroughPodNetwork(args.IfName, args.Netns, log)
+		} else if r.PodVlanId != 0 {
+			// vlanID != 0 means pod using security group
 			if isNetnsEmpty(args.Netns) {
 				log.Infof("Ignoring TeardownPodENI as Netns is empty for SG pod:%s namespace: %s containerID:%s", k8sArgs.K8S_POD_NAME, k8sArgs.K8S_POD_NAMESPACE, k8sArgs.K8S_POD_INFRA_CONTAINER_ID)
 				return nil
@@ -404,6 +489,14 @@ func del(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrap
 			err = driverClient.TeardownBranchENIPodNetwork(addr, int(r.PodVlanId), conf.PodSGEnforcingMode, log)
 		} else {
 			err = driverClient.TeardownPodNetwork(addr, int(r.DeviceNumber), log)
+			if err == nil && r.IPv4Addr != "" && r.IPv6Addr != "" {
+				// In dual-stack mode, the v6 address comes from the primary ENI
+				v6Addr := &net.IPNet{
+					IP:   net.ParseIP(r.IPv6Addr),
+					Mask: net.CIDRMask(128, 128),
+				}
+				err = driverClient.TeardownPodNetwork(v6Addr, 0, log)
+			}
 		}
 
 		if err != nil {
@@ -417,6 +510,182 @@ func del(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrap
 	return nil
 }
 
+func cmdCheck(args *skel.CmdArgs) error {
+	return check(args, typeswrapper.New(), grpcwrapper.New(), rpcwrapper.New(), driver.New())
+}
+
+// check verifies that the pod network described by prevResult is still set up, and that ipamd still has the
+// addresses of the container assigned. Any difference is returned as an error.
+func check(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrapper.GRPC, rpcClient rpcwrapper.RPC,
+	driverClient driver.NetworkAPIs) error {
+
+	conf, log, err := LoadNetConf(args.StdinData)
+	if err != nil {
+		return errors.Wrap(err, "check cmd: error loading config from args")
+	}
+
+	log.Infof("Received CNI check request: ContainerID(%s) Netns(%s) IfName(%s) Args(%s) Path(%s) argsStdinData(%s)",
+		args.ContainerID, args.Netns, args.IfName, args.Args, args.Path, args.StdinData)
+
+	var k8sArgs K8sArgs
+	if err := cniTypes.LoadArgs(args.Args, &k8sArgs); err != nil {
+		log.Errorf("Failed to load k8s config from args: %v", err)
+		return errors.Wrap(err, "check cmd: failed to load k8s config from args")
+	}
+
+	prevResult, ok := conf.PrevResult.(*current.Result)
+	if !ok {
+		return errors.New("check cmd: prevResult is required")
+	}
+	dummyIfaceName := networkutils.GeneratePodHostVethName(dummyInterfacePrefix, string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_NAME))
+	_, dummyIface, found := cniutils.FindInterfaceByName(prevResult.Interfaces, dummyIfaceName)
+	if !found {
+		return errors.Errorf("check cmd: cannot find dummy interface %s in prevResult", dummyIfaceName)
+	}
+	podVlanID, err := strconv.Atoi(dummyIface.Mac)
+	if err != nil {
+		return errors.Errorf("check cmd: malformed vlanID in prevResult: %s", dummyIface.Mac)
+	}
+	containerIPs, err := getContainerIPs(prevResult, args.IfName)
+	if err != nil {
+		return errors.Wrap(err, "check cmd: failed to get container IPs")
+	}
+	var v4Addr, v6Addr *net.IPNet
+	for i := range containerIPs {
+		if containerIPs[i].IP.To4() != nil {
+			v4Addr = &containerIPs[i]
+		} else {
+			v6Addr = &containerIPs[i]
+		}
+	}
+
+	// A passthrough ENI pod has no hostVeth
+	passthroughENI := dummyIface.Sandbox == passthroughENISandbox
+	var hostVethName string
+	if podVlanID != 0 {
+		hostVethNamePrefix := sgpp.BuildHostVethNamePrefix(conf.VethPrefix, conf.PodSGEnforcingMode)
+		if hostVethName, found = findPodHostVethName(prevResult, hostVethNamePrefix, k8sArgs, args.ContainerID, networkutils.PrimaryPodInterfaceName); !found {
+			return errors.New("check cmd: cannot find hostVeth of branch ENI pod in prevResult")
+		}
+	} else if !passthroughENI {
+		if hostVethName, found = findPodHostVethName(prevResult, conf.VethPrefix, k8sArgs, args.ContainerID, args.IfName); !found {
+			return errors.Errorf("check cmd: cannot find hostVeth of interface %s in prevResult", args.IfName)
+		}
+	}
+
+	// Set up a connection to the ipamD server.
+	conn, err := dialIpamd(grpcClient, conf)
+	if err != nil {
+		log.Errorf("Failed to connect to backend server for container %s: %v",
+			args.ContainerID, err)
+		return errors.Wrap(err, "check cmd: failed to connect to backend server")
+	}
+	defer conn.Close()
+
+	c := rpcClient.NewCNIBackendClient(conn)
+
+	r, err := c.CheckNetwork(context.Background(), &pb.CheckNetworkRequest{
+		ClientVersion:              version,
+		K8S_POD_NAME:               string(k8sArgs.K8S_POD_NAME),
+		K8S_POD_NAMESPACE:          string(k8sArgs.K8S_POD_NAMESPACE),
+		K8S_POD_INFRA_CONTAINER_ID: string(k8sArgs.K8S_POD_INFRA_CONTAINER_ID),
+		ContainerID:                args.ContainerID,
+		IfName:                     args.IfName,
+		NetworkName:                conf.Name,
+	})
+	if err != nil {
+		log.Errorf("Error received from CheckNetwork gRPC call for container %s: %v", args.ContainerID, err)
+		return errors.Wrap(err, "check cmd: Error received from CheckNetwork gRPC call")
+	}
+	if !r.Success {
+		log.Errorf("No IP address assigned to container %s in ipamd", args.ContainerID)
+		return errors.New("check cmd: no IP address assigned to container in ipamd")
+	}
+	if !isSameIP(v4Addr, r.IPv4Addr) || !isSameIP(v6Addr, r.IPv6Addr) || int(r.PodVlanId) != podVlanID || r.PassthroughENI != passthroughENI {
+		log.Errorf("Network of container %s in prevResult (%v, %v, vlan %d) differs from ipamd (%s, %s, vlan %d)",
+			args.ContainerID, v4Addr, v6Addr, podVlanID, r.IPv4Addr, r.IPv6Addr, r.PodVlanId)
+		return errors.New("check cmd: container addresses differ from the ones assigned in ipamd")
+	}
+
+	if passthroughENI {
+		err = driverClient.CheckPassthroughPodNetwork(args.IfName, args.Netns, v4Addr, log)
+	} else if podVlanID != 0 {
+		err = driverClient.CheckBranchENIPodNetwork(hostVethName, args.IfName, args.Netns, v4Addr, v6Addr, podVlanID, conf.PodSGEnforcingMode, log)
+	} else {
+		// For non-branch ENI, the device number is packed in Interface.Sandbox
+		deviceNumber, convErr := strconv.Atoi(dummyIface.Sandbox)
+		if convErr != nil {
+			return errors.Errorf("check cmd: malformed device number in prevResult: %s", dummyIface.Sandbox)
+		}
+		if deviceNumber != int(r.DeviceNumber) {
+			log.Errorf("Device number %d of container %s in prevResult differs from ipamd (%d)", deviceNumber, args.ContainerID, r.DeviceNumber)
+			return errors.New("check cmd: container device number differs from the one assigned in ipamd")
+		}
+		if r.SecondaryInterface {
+			err = driverClient.CheckSecondaryPodNetwork(hostVethName, args.IfName, args.Netns, v4Addr, v6Addr, deviceNumber, log)
+		} else {
+			err = driverClient.CheckPodNetwork(hostVethName, args.IfName, args.Netns, v4Addr, v6Addr, deviceNumber, log)
+		}
+	}
+	if err != nil {
+		log.Errorf("Failed to check pod network of container %s: %v", args.ContainerID, err)
+		return errors.Wrap(err, "check cmd: failed to check pod network")
+	}
+
+	log.Infof("Pod network of container %s is set up", args.ContainerID)
+	return nil
+}
+
+// newIpamdError returns the CNI error of the reason that ipamd failed a request, with the message of ipamd as details
+func newIpamdError(cmd string, reason pb.ErrorReason, message string) *types.Error {
+	e, ok := ipamdErrors[reason]
+	if !ok {
+		e = ipamdErrors[pb.ErrorReason_INTERNAL_ERROR]
+	}
+	return types.NewError(e.code, fmt.Sprintf("%s cmd: %s", cmd, e.msg), message)
+}
+
+// dialIpamd connects to ipamd with mutual TLS or on its Unix socket if the network config has one
+func dialIpamd(grpcClient grpcwrapper.GRPC, conf *NetConf) (*grpc.ClientConn, error) {
+	if conf.IpamdTLS != nil {
+		tlsOption, err := grpcwrapper.WithTLS(conf.IpamdTLS)
+		if err != nil {
+			return nil, err
+		}
+		return grpcClient.Dial(conf.IpamdTLS.Address, tlsOption)
+	}
+	if conf.IpamdSocketPath != "" {
+		return grpcClient.Dial("unix://"+conf.IpamdSocketPath, grpc.WithInsecure())
+	}
+	return grpcClient.Dial(ipamdAddress, grpc.WithInsecure())
+}
+
+// isSameIP returns true if the address from prevResult is the IP that ipamd returned. A missing address matches an
+// empty IP.
+func isSameIP(addr *net.IPNet, ip string) bool {
+	if addr == nil {
+		return ip == ""
+	}
+	return addr.IP.Equal(net.ParseIP(ip))
+}
+
+// getContainerIPs returns the addresses of the container's veth, which has both a v4 and a v6 address in dual-stack mode
+func getContainerIPs(prevResult *current.Result, contVethName string) ([]net.IPNet, error) {
+	containerIfaceIndex, _, found := cniutils.FindInterfaceByName(prevResult.Interfaces, contVethName)
+	if !found {
+		return nil, errors.Errorf("cannot find contVethName %s in prevResult", contVethName)
+	}
+	containerIPs := cniutils.FindIPConfigsByIfaceIndex(prevResult.IPs, containerIfaceIndex)
+	if len(containerIPs) == 0 || len(containerIPs) > 2 {
+		return nil, errors.Errorf("found %d containerIPs for %v in prevResult", len(containerIPs), contVethName)
+	}
+	var addrs []net.IPNet
+	for _, containerIP := range containerIPs {
+		addrs = append(addrs, containerIP.Address)
+	}
+	return addrs, nil
+}
+
 func getContainerIP(prevResult *current.Result, contVethName string) (net.IPNet, error) {
 	containerIfaceIndex, _, found := cniutils.FindInterfaceByName(prevResult.Interfaces, contVethName)
 	if !found {
@@ -429,6 +698,26 @@ func getContainerIP(prevResult *current.Result, contVethName string) (net.IPNet,
 	return containerIPs[0].Address, nil
 }
 
+// podHostVethName returns the name of the hostVeth of a pod interface under a naming scheme
+func podHostVethName(scheme networkutils.VethNameScheme, prefix string, k8sArgs K8sArgs, containerID string, contIfName string) string {
+	if scheme == networkutils.VethNameSchemeContainerID {
+		return networkutils.GenerateSandboxHostVethName(prefix, containerID, contIfName)
+	}
+	return networkutils.GeneratePodInterfaceHostVethName(prefix, string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_NAME), contIfName)
+}
+
+// findPodHostVethName looks up the hostVeth of a pod interface in prevResult. The pod may have been set up before the
+// naming scheme of the node changed, so the names of all schemes are tried.
+func findPodHostVethName(prevResult *current.Result, prefix string, k8sArgs K8sArgs, containerID string, contIfName string) (string, bool) {
+	for _, scheme := range networkutils.VethNameSchemes {
+		hostVethName := podHostVethName(scheme, prefix, k8sArgs, containerID, contIfName)
+		if _, _, found := cniutils.FindInterfaceByName(prevResult.Interfaces, hostVethName); found {
+			return hostVethName, true
+		}
+	}
+	return "", false
+}
+
 // tryDelWithPrevResult will try to process CNI delete request without IPAMD.
 // returns true if the del request is handled.
 func tryDelWithPrevResult(driverClient driver.NetworkAPIs, conf *NetConf, k8sArgs K8sArgs, contVethName string, netNS string, log logger.Logger) (bool, error) {
@@ -469,6 +758,18 @@ func tryDelWithPrevResult(driverClient driver.NetworkAPIs, conf *NetConf, k8sArg
 
 // teardownPodNetworkWithPrevResult will try to process CNI delete for non-branch ENIs without IPAMD.
 // Returns true if pod network is torn down
+// tryTeardownPodNetworkWithPrevResult tears down the pod network with the information from PrevResult when ipamd could
+// not handle the DEL request, and logs whether it succeeded
+func tryTeardownPodNetworkWithPrevResult(driverClient driver.NetworkAPIs, conf *NetConf, k8sArgs K8sArgs, args *skel.CmdArgs, log logger.Logger) {
+	if teardownPodNetworkWithPrevResult(driverClient, conf, k8sArgs, args.IfName, log) {
+		log.Infof("Handled pod teardown using prevResult: ContainerID(%s) Netns(%s) IfName(%s) PodNamespace(%s) PodName(%s)",
+			args.ContainerID, args.Netns, args.IfName, string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_NAME))
+	} else {
+		log.Infof("Could not teardown pod using prevResult: ContainerID(%s) Netns(%s) IfName(%s) PodNamespace(%s) PodName(%s)",
+			args.ContainerID, args.Netns, args.IfName, string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_NAME))
+	}
+}
+
 func teardownPodNetworkWithPrevResult(driverClient driver.NetworkAPIs, conf *NetConf, k8sArgs K8sArgs, contVethName string, log logger.Logger) bool {
 	// For non-branch ENI, prevResult is only available in v1.12.1+
 	prevResult, ok := conf.PrevResult.(*current.Result)
@@ -481,6 +782,11 @@ func teardownPodNetworkWithPrevResult(driverClient driver.NetworkAPIs, conf *Net
 	if !found {
 		return false
 	}
+	if dummyIface.Sandbox == passthroughENISandbox {
+		// Nothing to clean up on the host, the kernel moves the ENI back once the network namespace is deleted
+		log.Infof("No pod network to teardown on the host for passthrough ENI pod")
+		return false
+	}
 	// For non-branch ENI, VLAN ID of 0 is encoded in Mac and device number is encoded in Sandbox
 	podVlanID, err := strconv.Atoi(dummyIface.Mac)
 	if err != nil || podVlanID != 0 {
@@ -492,15 +798,22 @@ func teardownPodNetworkWithPrevResult(driverClient driver.NetworkAPIs, conf *Net
 		log.Errorf("Invalid device number for pod: %s", dummyIface.Sandbox)
 		return false
 	}
-	containerIP, err := getContainerIP(prevResult, contVethName)
+	containerIPs, err := getContainerIPs(prevResult, contVethName)
 	if err != nil {
 		log.Errorf("Failed to get container IP: %v", err)
 		return false
 	}
 
-	if err := driverClient.TeardownPodNetwork(&containerIP, deviceNumber, log); err != nil {
-		log.Errorf("Failed to teardown pod network: %v", err)
-		return false
+	for i := range containerIPs {
+		// In dual-stack mode, the device number is the one of the v4 address and the v6 address comes from the primary ENI
+		ipDeviceNumber := deviceNumber
+		if len(containerIPs) > 1 && containerIPs[i].IP.To4() == nil {
+			ipDeviceNumber = 0
+		}
+		if err := driverClient.TeardownPodNetwork(&containerIPs[i], ipDeviceNumber, log); err != nil {
+			log.Errorf("Failed to teardown pod network: %v", err)
+			return false
+		}
 	}
 	return true
 }
@@ -517,7 +830,7 @@ func main() {
 	log := logger.DefaultLogger()
 	about := fmt.Sprintf("AWS CNI %s", version)
 	exitCode := 0
-	if e := skel.PluginMainWithError(cmdAdd, nil, cmdDel, cniSpecVersion.All, about); e != nil {
+	if e := skel.PluginMainWithError(cmdAdd, cmdCheck, cmdDel, cniSpecVersion.All, about); e != nil {
 		if err := e.Print(); err != nil {
 			log.Errorf("Failed to write error to stdout: %v", err)
 		}
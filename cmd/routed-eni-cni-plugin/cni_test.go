@@ -14,11 +14,16 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
 	"testing"
 
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/bpfrouting"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/grpcwrapper"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/networkutils"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/sgpp"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/utils/logger"
 	"github.com/aws/aws-sdk-go/aws"
@@ -29,6 +34,8 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
 
 	mock_driver "github.com/aws/amazon-vpc-cni-k8s/cmd/routed-eni-cni-plugin/driver/mocks"
 	mock_grpcwrapper "github.com/aws/amazon-vpc-cni-k8s/pkg/grpcwrapper/mocks"
@@ -137,6 +144,41 @@ func TestCmdAddNetworkErr(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestCmdAddErrorReason(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	stdinData, _ := json.Marshal(netConf)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil).Times(3)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil).Times(3)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC).Times(3)
+
+	addNetworkReply := &rpc.AddNetworkReply{Success: false, ErrorReason: rpc.ErrorReason_MAX_ENIS_REACHED, ErrorMessage: "no room"}
+	mockC.EXPECT().AddNetwork(gomock.Any(), gomock.Any()).Return(addNetworkReply, nil)
+	err := add(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Equal(t, types.NewError(errMaxENIsReached, "add cmd: no IP addresses are available and the node has the maximum number of ENIs", "no room"), err)
+
+	// The version mismatch is a gRPC error
+	mockC.EXPECT().AddNetwork(gomock.Any(), gomock.Any()).Return(nil, grpcstatus.Error(codes.FailedPrecondition, "wrong client version"))
+	err = add(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Equal(t, errVersionMismatch, err.(*types.Error).Code)
+
+	// An older ipamd doesn't set the reason
+	mockC.EXPECT().AddNetwork(gomock.Any(), gomock.Any()).Return(&rpc.AddNetworkReply{Success: false}, nil)
+	err = add(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.EqualError(t, err, "add cmd: failed to assign an IP address to container")
+}
+
 func TestCmdAddErrSetupPodNetwork(t *testing.T) {
 	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
 	defer ctrl.Finish()
@@ -176,6 +218,277 @@ func TestCmdAddErrSetupPodNetwork(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestCmdAddSecondaryInterface(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	secondaryNetConf := *netConf
+	secondaryNetConf.Name = "net-a"
+	stdinData, _ := json.Marshal(secondaryNetConf)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    "net1",
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	addNetworkReply := &rpc.AddNetworkReply{Success: true, IPv4Addr: ipAddr, DeviceNumber: devNum, SecondaryInterface: true}
+	mockC.EXPECT().AddNetwork(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, in *rpc.AddNetworkRequest, _ ...grpc.CallOption) (*rpc.AddNetworkReply, error) {
+		assert.Equal(t, "net-a", in.NetworkName)
+		assert.Equal(t, "net1", in.IfName)
+		return addNetworkReply, nil
+	})
+
+	v4Addr := &net.IPNet{
+		IP:   net.ParseIP(addNetworkReply.IPv4Addr),
+		Mask: net.IPv4Mask(255, 255, 255, 255),
+	}
+	// The secondary interface has its own host veth
+	hostVethName := networkutils.GeneratePodInterfaceHostVethName(netConf.VethPrefix, "", "", "net1")
+	assert.NotEqual(t, networkutils.GeneratePodHostVethName(netConf.VethPrefix, "", ""), hostVethName)
+	mocksNetwork.EXPECT().SetupSecondaryPodNetwork(hostVethName, cmdArgs.IfName, cmdArgs.Netns,
+		v4Addr, nil, int(addNetworkReply.DeviceNumber), gomock.Any(), gomock.Any()).Return(nil)
+
+	mocksTypes.EXPECT().PrintResult(gomock.Any(), gomock.Any()).Return(nil)
+
+	err := add(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
+func TestCmdAddExtraInterface(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	stdinData, _ := json.Marshal(netConf)
+
+	// e.g. a Multus delegate of a network that is not one of SECONDARY_NETWORKS
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    "net1",
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	addNetworkReply := &rpc.AddNetworkReply{Success: true, IPv4Addr: ipAddr, DeviceNumber: devNum}
+	mockC.EXPECT().AddNetwork(gomock.Any(), gomock.Any()).Return(addNetworkReply, nil)
+
+	v4Addr := &net.IPNet{
+		IP:   net.ParseIP(addNetworkReply.IPv4Addr),
+		Mask: net.IPv4Mask(255, 255, 255, 255),
+	}
+	// The interface is set up like the main one, under a host veth name of its own
+	hostVethName := networkutils.GeneratePodInterfaceHostVethName(netConf.VethPrefix, "", "", "net1")
+	mocksNetwork.EXPECT().SetupPodNetwork(hostVethName, cmdArgs.IfName, cmdArgs.Netns,
+		v4Addr, nil, int(addNetworkReply.DeviceNumber), gomock.Any(), gomock.Any()).Return(nil)
+
+	mocksTypes.EXPECT().PrintResult(gomock.Any(), gomock.Any()).Return(nil)
+
+	err := add(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
+func TestCmdAddPassthroughENI(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	stdinData, _ := json.Marshal(netConf)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	addNetworkReply := &rpc.AddNetworkReply{Success: true, IPv4Addr: ipAddr, DeviceNumber: -1, PodENIMAC: "12:ef:2a:98:e5:5b",
+		PodENISubnetGW: "10.0.0.1", PassthroughENI: true}
+	mockC.EXPECT().AddNetwork(gomock.Any(), gomock.Any()).Return(addNetworkReply, nil)
+
+	v4Addr := &net.IPNet{
+		IP:   net.ParseIP(addNetworkReply.IPv4Addr),
+		Mask: net.IPv4Mask(255, 255, 255, 255),
+	}
+	mocksNetwork.EXPECT().SetupPassthroughPodNetwork(cmdArgs.IfName, cmdArgs.Netns, v4Addr, addNetworkReply.PodENIMAC,
+		addNetworkReply.PodENISubnetGW, gomock.Any(), gomock.Any()).Return(nil)
+
+	mocksTypes.EXPECT().PrintResult(gomock.Any(), gomock.Any()).DoAndReturn(func(result types.Result, _ string) error {
+		// There is no host interface, the dummy interface marks the ENI as passed through
+		r := result.(*current.Result)
+		assert.Len(t, r.Interfaces, 2)
+		assert.Equal(t, ifName, r.Interfaces[0].Name)
+		assert.Equal(t, addNetworkReply.PodENIMAC, r.Interfaces[0].Mac)
+		assert.Equal(t, 0, *r.IPs[0].Interface)
+		assert.Equal(t, passthroughENISandbox, r.Interfaces[1].Sandbox)
+		return nil
+	})
+
+	err := add(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
+func TestCmdDelPassthroughENI(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	stdinData, _ := json.Marshal(netConf)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	delNetworkReply := &rpc.DelNetworkReply{Success: true, IPv4Addr: ipAddr, DeviceNumber: -1, PassthroughENI: true}
+	mockC.EXPECT().DelNetwork(gomock.Any(), gomock.Any()).Return(delNetworkReply, nil)
+
+	mocksNetwork.EXPECT().TeardownPassthroughPodNetwork(cmdArgs.IfName, cmdArgs.Netns, gomock.Any()).Return(nil)
+
+	err := del(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
+func TestCmdAddBPFPodRouting(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	bpfNetConf := *netConf
+	bpfNetConf.EBPFPodRouting = "true"
+	stdinData, _ := json.Marshal(bpfNetConf)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	addNetworkReply := &rpc.AddNetworkReply{Success: true, IPv4Addr: ipAddr, DeviceNumber: devNum}
+	mockC.EXPECT().AddNetwork(gomock.Any(), gomock.Any()).Return(addNetworkReply, nil)
+
+	v4Addr := &net.IPNet{
+		IP:   net.ParseIP(addNetworkReply.IPv4Addr),
+		Mask: net.IPv4Mask(255, 255, 255, 255),
+	}
+	mocksNetwork.EXPECT().SetupBPFRoutedPodNetwork(gomock.Any(), cmdArgs.IfName, cmdArgs.Netns, v4Addr, devNum, gomock.Any(), gomock.Any()).Return(nil)
+
+	mocksTypes.EXPECT().PrintResult(gomock.Any(), gomock.Any()).Return(nil)
+
+	err := add(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
+func TestCmdAddBPFPodRoutingTableOutOfMark(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	bpfNetConf := *netConf
+	bpfNetConf.EBPFPodRouting = "true"
+	stdinData, _ := json.Marshal(bpfNetConf)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	// The route table of the ENI does not fit in the mark, the pod gets a rule of its own
+	highDevNum := bpfrouting.MaxTable
+	addNetworkReply := &rpc.AddNetworkReply{Success: true, IPv4Addr: ipAddr, DeviceNumber: int32(highDevNum)}
+	mockC.EXPECT().AddNetwork(gomock.Any(), gomock.Any()).Return(addNetworkReply, nil)
+
+	v4Addr := &net.IPNet{
+		IP:   net.ParseIP(addNetworkReply.IPv4Addr),
+		Mask: net.IPv4Mask(255, 255, 255, 255),
+	}
+	mocksNetwork.EXPECT().SetupPodNetwork(gomock.Any(), cmdArgs.IfName, cmdArgs.Netns, v4Addr, nil, highDevNum, gomock.Any(), gomock.Any()).Return(nil)
+
+	mocksTypes.EXPECT().PrintResult(gomock.Any(), gomock.Any()).Return(nil)
+
+	err := add(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
+func TestCmdAddContainerIDVethNameScheme(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	schemeNetConf := *netConf
+	schemeNetConf.PodVethNameScheme = networkutils.VethNameSchemeContainerID
+	stdinData, _ := json.Marshal(schemeNetConf)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	addNetworkReply := &rpc.AddNetworkReply{Success: true, IPv4Addr: ipAddr, DeviceNumber: devNum}
+	mockC.EXPECT().AddNetwork(gomock.Any(), gomock.Any()).Return(addNetworkReply, nil)
+
+	// The hostVeth is named after the sandbox, not the pod
+	hostVethName := networkutils.GenerateSandboxHostVethName(netConf.VethPrefix, containerID, ifName)
+	mocksNetwork.EXPECT().SetupPodNetwork(hostVethName, cmdArgs.IfName, cmdArgs.Netns, gomock.Any(), nil, devNum, gomock.Any(), gomock.Any()).Return(nil)
+
+	mocksTypes.EXPECT().PrintResult(gomock.Any(), gomock.Any()).DoAndReturn(func(result types.Result, _ string) error {
+		assert.Equal(t, hostVethName, result.(*current.Result).Interfaces[0].Name)
+		return nil
+	})
+
+	err := add(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
+func TestLoadNetConfErrPodVethNameScheme(t *testing.T) {
+	_, _, err := LoadNetConf([]byte(`{"cniVersion": "1.0", "name": "aws-cni", "podVethNameScheme": "random"}`))
+	assert.Error(t, err)
+}
+
 func TestCmdDel(t *testing.T) {
 	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
 	defer ctrl.Finish()
@@ -237,6 +550,55 @@ func TestCmdDelErrDelNetwork(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestCmdDelPodNotFound(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	stdinData, _ := json.Marshal(netConf)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	delNetworkReply := &rpc.DelNetworkReply{Success: false, ErrorReason: rpc.ErrorReason_POD_NOT_FOUND, ErrorMessage: "datastore: unknown pod"}
+
+	mockC.EXPECT().DelNetwork(gomock.Any(), gomock.Any()).Return(delNetworkReply, nil)
+
+	// DEL succeeds when ipamd has no address for the sandbox
+	err := del(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+	delNetworkReply = &rpc.DelNetworkReply{Success: false, ErrorReason: rpc.ErrorReason_INTERNAL_ERROR, ErrorMessage: "failed"}
+	mockC.EXPECT().DelNetwork(gomock.Any(), gomock.Any()).Return(delNetworkReply, nil)
+
+	err = del(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Equal(t, types.ErrInternal, err.(*types.Error).Code)
+
+	// On an internal error, the pod network is still torn down with the prevResult
+	cmdArgs.StdinData = checkStdinData(t, "eni", 0)
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+	mockC.EXPECT().DelNetwork(gomock.Any(), gomock.Any()).Return(delNetworkReply, nil)
+	addr := &net.IPNet{IP: net.ParseIP(ipAddr), Mask: net.CIDRMask(32, 32)}
+	mocksNetwork.EXPECT().TeardownPodNetwork(addr, devNum, gomock.Any()).Return(nil)
+
+	err = del(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Equal(t, types.ErrInternal, err.(*types.Error).Code)
+}
+
 func TestCmdDelErrTeardown(t *testing.T) {
 	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
 	defer ctrl.Finish()
@@ -341,6 +703,176 @@ func TestCmdDelForPodENINetwork(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+// checkStdinData returns the stdin of a CHECK command, with the prevResult that add returned for the pod
+func checkStdinData(t *testing.T, hostVethPrefix string, vlanID int) []byte {
+	containerIfaceIndex := 1
+	prevResult := &current.Result{
+		CNIVersion: "0.4.0",
+		Interfaces: []*current.Interface{
+			{Name: networkutils.GeneratePodHostVethName(hostVethPrefix, "", "")},
+			{Name: ifName, Sandbox: netNS},
+			{Name: networkutils.GeneratePodHostVethName(dummyInterfacePrefix, "", ""), Mac: fmt.Sprint(vlanID), Sandbox: fmt.Sprint(devNum)},
+		},
+		IPs: []*current.IPConfig{
+			{Version: "4", Address: net.IPNet{IP: net.ParseIP(ipAddr), Mask: net.CIDRMask(32, 32)}, Interface: &containerIfaceIndex},
+		},
+	}
+	rawPrevResult, err := json.Marshal(prevResult)
+	assert.NoError(t, err)
+	var prevResultMap map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rawPrevResult, &prevResultMap))
+
+	conf := *netConf
+	conf.CNIVersion = "0.4.0"
+	conf.VethPrefix = "eni"
+	conf.RawPrevResult = prevResultMap
+	stdinData, err := json.Marshal(conf)
+	assert.NoError(t, err)
+	return stdinData
+}
+
+func TestCmdCheck(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: checkStdinData(t, "eni", 0)}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	checkNetworkReply := &rpc.CheckNetworkReply{Success: true, IPv4Addr: ipAddr, DeviceNumber: devNum}
+	mockC.EXPECT().CheckNetwork(gomock.Any(), gomock.Any()).Return(checkNetworkReply, nil)
+
+	mocksNetwork.EXPECT().CheckPodNetwork(networkutils.GeneratePodHostVethName("eni", "", ""), ifName, netNS, gomock.Any(), nil, devNum, gomock.Any()).
+		DoAndReturn(func(_, _, _ string, v4Addr, _ *net.IPNet, _ int, _ logger.Logger) error {
+			assert.Equal(t, ipAddr+"/32", v4Addr.String())
+			return nil
+		})
+
+	err := check(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
+func TestCmdCheckContainerIDVethNameScheme(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	// The pod was set up before the node switched to hostVeths named after the sandbox
+	var conf map[string]interface{}
+	assert.NoError(t, json.Unmarshal(checkStdinData(t, "eni", 0), &conf))
+	conf["podVethNameScheme"] = string(networkutils.VethNameSchemeContainerID)
+	stdinData, err := json.Marshal(conf)
+	assert.NoError(t, err)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	checkNetworkReply := &rpc.CheckNetworkReply{Success: true, IPv4Addr: ipAddr, DeviceNumber: devNum}
+	mockC.EXPECT().CheckNetwork(gomock.Any(), gomock.Any()).Return(checkNetworkReply, nil)
+
+	mocksNetwork.EXPECT().CheckPodNetwork(networkutils.GeneratePodHostVethName("eni", "", ""), ifName, netNS, gomock.Any(), nil, devNum, gomock.Any()).Return(nil)
+
+	err = check(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
+func TestCmdCheckErrDrift(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: checkStdinData(t, "eni", 0)}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil).Times(3)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil).Times(3)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC).Times(3)
+
+	// ipamd no longer has the allocation
+	mockC.EXPECT().CheckNetwork(gomock.Any(), gomock.Any()).Return(&rpc.CheckNetworkReply{Success: false}, nil)
+	err := check(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Error(t, err)
+
+	// ipamd assigned another IP to the container
+	mockC.EXPECT().CheckNetwork(gomock.Any(), gomock.Any()).Return(&rpc.CheckNetworkReply{Success: true, IPv4Addr: "10.0.1.16", DeviceNumber: devNum}, nil)
+	err = check(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Error(t, err)
+
+	// The ip rules of the pod are gone
+	mockC.EXPECT().CheckNetwork(gomock.Any(), gomock.Any()).Return(&rpc.CheckNetworkReply{Success: true, IPv4Addr: ipAddr, DeviceNumber: devNum}, nil)
+	mocksNetwork.EXPECT().CheckPodNetwork(gomock.Any(), ifName, netNS, gomock.Any(), nil, devNum, gomock.Any()).Return(errors.New("fromContainer rule is missing"))
+	err = check(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Error(t, err)
+}
+
+func TestCmdCheckErrNoPrevResult(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	stdinData, _ := json.Marshal(netConf)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	err := check(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Error(t, err)
+}
+
+func TestCmdCheckForPodENINetwork(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	hostVethPrefix := sgpp.BuildHostVethNamePrefix("eni", sgpp.DefaultEnforcingMode)
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: checkStdinData(t, hostVethPrefix, 1)}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	checkNetworkReply := &rpc.CheckNetworkReply{Success: true, IPv4Addr: ipAddr, PodVlanId: 1}
+	mockC.EXPECT().CheckNetwork(gomock.Any(), gomock.Any()).Return(checkNetworkReply, nil)
+
+	mocksNetwork.EXPECT().CheckBranchENIPodNetwork(networkutils.GeneratePodHostVethName(hostVethPrefix, "", ""), ifName, netNS, gomock.Any(), nil, 1,
+		sgpp.DefaultEnforcingMode, gomock.Any()).Return(nil)
+
+	err := check(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
 func Test_tryDelWithPrevResult(t *testing.T) {
 	type teardownBranchENIPodNetworkCall struct {
 		containerAddr      *net.IPNet
@@ -1052,3 +1584,24 @@ func Test_teardownPodNetworkWithPrevResult(t *testing.T) {
 		})
 	}
 }
+
+func Test_dialIpamd(t *testing.T) {
+	ctrl, _, mocksGRPC, _, _ := setup(t)
+	defer ctrl.Finish()
+
+	conf := *netConf
+	mocksGRPC.EXPECT().Dial(ipamdAddress, gomock.Any()).Return(nil, nil)
+	_, err := dialIpamd(mocksGRPC, &conf)
+	assert.NoError(t, err)
+
+	// The Unix socket is preferred
+	conf.IpamdSocketPath = "/var/run/aws-node/ipamd.sock"
+	mocksGRPC.EXPECT().Dial("unix:///var/run/aws-node/ipamd.sock", gomock.Any()).Return(nil, nil)
+	_, err = dialIpamd(mocksGRPC, &conf)
+	assert.NoError(t, err)
+
+	// Mutual TLS is preferred, and fails without a client certificate
+	conf.IpamdTLS = &grpcwrapper.TLSConfig{Address: "127.0.0.1:50052", CertFile: "/nonexistent/tls.crt", KeyFile: "/nonexistent/tls.key"}
+	_, err = dialIpamd(mocksGRPC, &conf)
+	assert.Error(t, err)
+}
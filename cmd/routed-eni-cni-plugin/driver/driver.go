@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/sgpp"
@@ -28,6 +29,7 @@ import (
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/vishvananda/netlink"
 
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/bpfrouting"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipwrapper"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/netlinkwrapper"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/networkutils"
@@ -43,6 +45,10 @@ const (
 	//Time duration CNI waits for an IPv6 address assigned to an interface
 	//to move to stable state before error'ing out.
 	v6DADTimeout = 10 * time.Second
+
+	// secondaryInterfaceRouteTableBase is added to the link index of a secondary pod interface to get the route table
+	// of its default route inside the container's namespace
+	secondaryInterfaceRouteTableBase = 1000
 )
 
 // NetworkAPIs defines network API calls
@@ -52,25 +58,50 @@ type NetworkAPIs interface {
 	// TeardownPodNetwork clean up pod network for normal ENI based pods
 	TeardownPodNetwork(containerAddr *net.IPNet, deviceNumber int, log logger.Logger) error
 
+	// SetupSecondaryPodNetwork sets up an additional pod interface, which has its own route table inside the pod
+	SetupSecondaryPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet, deviceNumber int, mtu int, log logger.Logger) error
+
+	// SetupBPFRoutedPodNetwork sets up pod network for normal ENI based pods whose traffic is routed by eBPF pod routing
+	// instead of a rule per pod, it is torn down by TeardownPodNetwork
+	SetupBPFRoutedPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, deviceNumber int, mtu int, log logger.Logger) error
+
 	// SetupBranchENIPodNetwork sets up pod network for branch ENI based pods
 	SetupBranchENIPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet, vlanID int, eniMAC string,
 		subnetGW string, parentIfIndex int, mtu int, podSGEnforcingMode sgpp.EnforcingMode, log logger.Logger) error
 	// TeardownBranchENIPodNetwork cleans up pod network for branch ENI based pods
 	TeardownBranchENIPodNetwork(containerAddr *net.IPNet, vlanID int, podSGEnforcingMode sgpp.EnforcingMode, log logger.Logger) error
+
+	// SetupPassthroughPodNetwork moves a whole ENI into the pod's network namespace
+	SetupPassthroughPodNetwork(contIfName string, netnsPath string, v4Addr *net.IPNet, eniMAC string, subnetGW string, mtu int, log logger.Logger) error
+	// TeardownPassthroughPodNetwork moves the ENI of a pod back to the host
+	TeardownPassthroughPodNetwork(contIfName string, netnsPath string, log logger.Logger) error
+
+	// CheckPodNetwork verifies the pod network of normal ENI based pods
+	CheckPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet, deviceNumber int, log logger.Logger) error
+	// CheckSecondaryPodNetwork verifies an additional pod interface
+	CheckSecondaryPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet, deviceNumber int, log logger.Logger) error
+	// CheckBranchENIPodNetwork verifies the pod network of branch ENI based pods
+	CheckBranchENIPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet, vlanID int,
+		podSGEnforcingMode sgpp.EnforcingMode, log logger.Logger) error
+	// CheckPassthroughPodNetwork verifies the ENI inside the pod's network namespace
+	CheckPassthroughPodNetwork(contIfName string, netnsPath string, v4Addr *net.IPNet, log logger.Logger) error
 }
 
 type linuxNetwork struct {
 	netLink netlinkwrapper.NetLink
 	ns      nswrapper.NS
 	procSys procsyswrapper.ProcSys
+	// openBPFProgram returns a file descriptor of the eBPF pod routing program
+	openBPFProgram func() (int, error)
 }
 
 // New creates linuxNetwork object
 func New() NetworkAPIs {
 	return &linuxNetwork{
-		netLink: netlinkwrapper.NewNetLink(),
-		ns:      nswrapper.NewNS(),
-		procSys: procsyswrapper.NewProcSys(),
+		netLink:        netlinkwrapper.NewNetLink(),
+		ns:             nswrapper.NewNS(),
+		procSys:        procsyswrapper.NewProcSys(),
+		openBPFProgram: bpfrouting.OpenProgram,
 	}
 }
 
@@ -85,18 +116,21 @@ type createVethPairContext struct {
 	ip           ipwrapper.IP
 	mtu          int
 	procSys      procsyswrapper.ProcSys
+	// secondaryInterface puts the default route of the veth in its own route table, used for traffic from its addresses
+	secondaryInterface bool
 }
 
-func newCreateVethPairContext(contVethName string, hostVethName string, v4Addr *net.IPNet, v6Addr *net.IPNet, mtu int) *createVethPairContext {
+func newCreateVethPairContext(contVethName string, hostVethName string, v4Addr *net.IPNet, v6Addr *net.IPNet, mtu int, secondaryInterface bool) *createVethPairContext {
 	return &createVethPairContext{
-		contVethName: contVethName,
-		hostVethName: hostVethName,
-		v4Addr:       v4Addr,
-		v6Addr:       v6Addr,
-		netLink:      netlinkwrapper.NewNetLink(),
-		ip:           ipwrapper.NewIP(),
-		mtu:          mtu,
-		procSys:      procsyswrapper.NewProcSys(),
+		contVethName:       contVethName,
+		hostVethName:       hostVethName,
+		v4Addr:             v4Addr,
+		v6Addr:             v6Addr,
+		netLink:            netlinkwrapper.NewNetLink(),
+		ip:                 ipwrapper.NewIP(),
+		mtu:                mtu,
+		procSys:            procsyswrapper.NewProcSys(),
+		secondaryInterface: secondaryInterface,
 	}
 }
 
@@ -153,49 +187,101 @@ func (createVethContext *createVethPairContext) run(hostNS ns.NetNS) error {
 		}
 	}
 
+	// In dual-stack mode, both the v4 and the v6 address are added to the container's veth
+	if createVethContext.v4Addr != nil {
+		if err = createVethContext.addContainerAddr(hostVeth, contVeth, createVethContext.v4Addr); err != nil {
+			return err
+		}
+	}
+	if createVethContext.v6Addr != nil {
+		if err = createVethContext.addContainerAddr(hostVeth, contVeth, createVethContext.v6Addr); err != nil {
+			return err
+		}
+	}
+
+	if createVethContext.v6Addr != nil && createVethContext.v6Addr.IP.To16() != nil {
+		if err := cniutils.WaitForAddressesToBeStable(createVethContext.netLink, createVethContext.contVethName, v6DADTimeout, WAIT_INTERVAL); err != nil {
+			return errors.Wrap(err, "setup NS network: failed while waiting for v6 addresses to be stable")
+		}
+	}
+
+	// Now that the everything has been successfully set up in the container, move the "host" end of the
+	// veth into the host namespace.
+	if err = createVethContext.netLink.LinkSetNsFd(hostVeth, int(hostNS.Fd())); err != nil {
+		return errors.Wrap(err, "setup NS network: failed to move veth to host netns")
+	}
+	return nil
+}
+
+// addContainerAddr adds the address to the container's veth, along with a default route via a dummy next hop of the
+// same address family
+func (createVethContext *createVethPairContext) addContainerAddr(hostVeth netlink.Link, contVeth netlink.Link, containerAddr *net.IPNet) error {
 	// Add a connected route to a dummy next hop (169.254.1.1 or fe80::1)
 	// # ip route show
 	// default via 169.254.1.1 dev eth0
 	// 169.254.1.1 dev eth0
+	//
+	// For a secondary interface, the routes are added to its own route table, which is used for traffic from its address
+	// # ip rule show
+	// 512:	from 10.1.2.3 lookup 1003
+	// # ip route show table 1003
+	// default via 169.254.1.1 dev net1
+	// 169.254.1.1 dev net1
 
 	var gw net.IP
 	var maskLen int
-	var addr *netlink.Addr
 	var defNet *net.IPNet
 
-	if createVethContext.v4Addr != nil {
+	if containerAddr.IP.To4() != nil {
 		gw = net.IPv4(169, 254, 1, 1)
 		maskLen = 32
-		addr = &netlink.Addr{IPNet: createVethContext.v4Addr}
 		defNet = &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, maskLen)}
-	} else if createVethContext.v6Addr != nil {
+	} else {
 		gw = net.IP{0xfe, 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
 		maskLen = 128
-		addr = &netlink.Addr{IPNet: createVethContext.v6Addr}
 		defNet = &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, maskLen)}
 	}
+	addr := &netlink.Addr{IPNet: containerAddr}
 
 	gwNet := &net.IPNet{IP: gw, Mask: net.CIDRMask(maskLen, maskLen)}
 
-	if err = createVethContext.netLink.RouteReplace(&netlink.Route{
+	// Routes with Table 0 are added to the main route table
+	var rtTable int
+	if createVethContext.secondaryInterface {
+		rtTable = secondaryInterfaceRouteTable(contVeth)
+	}
+
+	if err := createVethContext.netLink.RouteReplace(&netlink.Route{
 		LinkIndex: contVeth.Attrs().Index,
 		Scope:     netlink.SCOPE_LINK,
-		Dst:       gwNet}); err != nil {
+		Dst:       gwNet,
+		Table:     rtTable}); err != nil {
 		return errors.Wrap(err, "setup NS network: failed to add default gateway")
 	}
 
 	// Add a default route via dummy next hop(169.254.1.1 or fe80::1). Then all outgoing traffic will be routed by this
 	// default route via dummy next hop (169.254.1.1 or fe80::1)
-	if err = createVethContext.netLink.RouteAdd(&netlink.Route{
+	if err := createVethContext.netLink.RouteAdd(&netlink.Route{
 		LinkIndex: contVeth.Attrs().Index,
 		Scope:     netlink.SCOPE_UNIVERSE,
 		Dst:       defNet,
 		Gw:        gw,
+		Table:     rtTable,
 	}); err != nil {
 		return errors.Wrap(err, "setup NS network: failed to add default route")
 	}
 
-	if err = createVethContext.netLink.AddrAdd(contVeth, addr); err != nil {
+	if createVethContext.secondaryInterface {
+		fromContainerAddrRule := createVethContext.netLink.NewRule()
+		fromContainerAddrRule.Src = containerAddr
+		fromContainerAddrRule.Priority = networkutils.FromPodRulePriority
+		fromContainerAddrRule.Table = rtTable
+		if err := createVethContext.netLink.RuleAdd(fromContainerAddrRule); err != nil && !networkutils.IsRuleExistsError(err) {
+			return errors.Wrapf(err, "setup NS network: failed to add rule for %s", containerAddr.String())
+		}
+	}
+
+	if err := createVethContext.netLink.AddrAdd(contVeth, addr); err != nil {
 		return errors.Wrapf(err, "setup NS network: failed to add IP addr to %q", createVethContext.contVethName)
 	}
 
@@ -209,24 +295,235 @@ func (createVethContext *createVethPairContext) run(hostNS ns.NetNS) error {
 		HardwareAddr: hostVeth.Attrs().HardwareAddr,
 	}
 
-	if err = createVethContext.netLink.NeighAdd(neigh); err != nil {
+	if err := createVethContext.netLink.NeighAdd(neigh); err != nil {
 		return errors.Wrap(err, "setup NS network: failed to add static ARP")
 	}
+	return nil
+}
 
-	if createVethContext.v6Addr != nil && createVethContext.v6Addr.IP.To16() != nil {
-		if err := cniutils.WaitForAddressesToBeStable(createVethContext.netLink, createVethContext.contVethName, v6DADTimeout, WAIT_INTERVAL); err != nil {
-			return errors.Wrap(err, "setup NS network: failed while waiting for v6 addresses to be stable")
+// checkVethContext wraps the parameters and the method to check the veth inside the container's namespace
+type checkVethContext struct {
+	contVethName       string
+	v4Addr             *net.IPNet
+	v6Addr             *net.IPNet
+	netLink            netlinkwrapper.NetLink
+	secondaryInterface bool
+}
+
+func newCheckVethContext(contVethName string, v4Addr *net.IPNet, v6Addr *net.IPNet, netLink netlinkwrapper.NetLink, secondaryInterface bool) *checkVethContext {
+	return &checkVethContext{
+		contVethName:       contVethName,
+		v4Addr:             v4Addr,
+		v6Addr:             v6Addr,
+		netLink:            netLink,
+		secondaryInterface: secondaryInterface,
+	}
+}
+
+// run defines the closure to execute within the container's namespace to check the veth
+func (checkContext *checkVethContext) run(hostNS ns.NetNS) error {
+	contVeth, err := checkContext.netLink.LinkByName(checkContext.contVethName)
+	if err != nil {
+		return errors.Wrapf(err, "check NS network: failed to find link %q", checkContext.contVethName)
+	}
+	if contVeth.Attrs().Flags&net.FlagUp == 0 {
+		return errors.Errorf("check NS network: link %q is down", checkContext.contVethName)
+	}
+	for _, containerAddr := range []*net.IPNet{checkContext.v4Addr, checkContext.v6Addr} {
+		if containerAddr == nil {
+			continue
+		}
+		if err := checkContext.checkContainerAddr(contVeth, containerAddr); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	// Now that the everything has been successfully set up in the container, move the "host" end of the
-	// veth into the host namespace.
-	if err = createVethContext.netLink.LinkSetNsFd(hostVeth, int(hostNS.Fd())); err != nil {
-		return errors.Wrap(err, "setup NS network: failed to move veth to host netns")
+// checkContainerAddr checks that the container's veth has the address and the default route via the dummy next hop
+// that addContainerAddr set up
+func (checkContext *checkVethContext) checkContainerAddr(contVeth netlink.Link, containerAddr *net.IPNet) error {
+	family := netlink.FAMILY_V4
+	gw := net.IPv4(169, 254, 1, 1)
+	if containerAddr.IP.To4() == nil {
+		family = netlink.FAMILY_V6
+		gw = net.IP{0xfe, 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+	}
+
+	addrs, err := checkContext.netLink.AddrList(contVeth, family)
+	if err != nil {
+		return errors.Wrapf(err, "check NS network: failed to list addresses of %q", checkContext.contVethName)
+	}
+	found := false
+	for _, addr := range addrs {
+		if ipNetEqual(addr.IPNet, containerAddr) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return errors.Errorf("check NS network: IP addr %s is missing on %q", containerAddr.String(), checkContext.contVethName)
+	}
+
+	if checkContext.secondaryInterface {
+		// The routes of a secondary interface are in its own route table, which is not listed by netlink's RouteList,
+		// so only the rule that selects the table is checked
+		rtTable := secondaryInterfaceRouteTable(contVeth)
+		rules, err := checkContext.netLink.RuleList(family)
+		if err != nil {
+			return errors.Wrap(err, "check NS network: failed to list rules")
+		}
+		if !containsRule(rules, func(rule netlink.Rule) bool {
+			return ipNetEqual(rule.Src, containerAddr) && rule.Priority == networkutils.FromPodRulePriority && rule.Table == rtTable
+		}) {
+			return errors.Errorf("check NS network: rule from %s to route table %d is missing", containerAddr.String(), rtTable)
+		}
+		return nil
+	}
+
+	routes, err := checkContext.netLink.RouteList(contVeth, family)
+	if err != nil {
+		return errors.Wrapf(err, "check NS network: failed to list routes of %q", checkContext.contVethName)
+	}
+	for _, route := range routes {
+		if route.Gw.Equal(gw) && (route.Dst == nil || isDefaultRoute(route.Dst)) {
+			return nil
+		}
+	}
+	return errors.Errorf("check NS network: default route via %s is missing on %q", gw.String(), checkContext.contVethName)
+}
+
+// passthroughENIContext wraps the parameters and the methods to move the link of an ENI between the host and the
+// container's namespace
+type passthroughENIContext struct {
+	contIfName string
+	eniMAC     string
+	v4Addr     *net.IPNet
+	subnetGW   net.IP
+	mtu        int
+	netLink    netlinkwrapper.NetLink
+}
+
+func newPassthroughENIContext(contIfName string, eniMAC string, v4Addr *net.IPNet, subnetGW string, mtu int, netLink netlinkwrapper.NetLink) *passthroughENIContext {
+	return &passthroughENIContext{
+		contIfName: contIfName,
+		eniMAC:     eniMAC,
+		v4Addr:     v4Addr,
+		subnetGW:   net.ParseIP(subnetGW),
+		mtu:        mtu,
+		netLink:    netLink,
+	}
+}
+
+// setup defines the closure to execute within the container's namespace to move the ENI in and configure it
+func (passthroughContext *passthroughENIContext) setup(hostNS ns.NetNS) error {
+	// The link keeps its name on the host in its alias, so that it gets it back when it is moved out again
+	err := hostNS.Do(func(contNS ns.NetNS) error {
+		link, err := passthroughContext.linkByMAC()
+		if err != nil {
+			return err
+		}
+		if err := passthroughContext.netLink.LinkSetDown(link); err != nil {
+			return errors.Wrapf(err, "failed to set link %q down", link.Attrs().Name)
+		}
+		if err := passthroughContext.netLink.LinkSetAlias(link, link.Attrs().Name); err != nil {
+			return errors.Wrapf(err, "failed to set the alias of link %q", link.Attrs().Name)
+		}
+		if err := passthroughContext.netLink.LinkSetNsFd(link, int(contNS.Fd())); err != nil {
+			return errors.Wrapf(err, "failed to move link %q to the container's namespace", link.Attrs().Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "setup NS network")
+	}
+
+	link, err := passthroughContext.linkByMAC()
+	if err != nil {
+		return errors.Wrap(err, "setup NS network")
+	}
+	if err := passthroughContext.netLink.LinkSetName(link, passthroughContext.contIfName); err != nil {
+		return errors.Wrapf(err, "setup NS network: failed to rename the link of ENI %s to %q", passthroughContext.eniMAC, passthroughContext.contIfName)
+	}
+	if passthroughContext.mtu > 0 {
+		if err := passthroughContext.netLink.LinkSetMTU(link, passthroughContext.mtu); err != nil {
+			return errors.Wrapf(err, "setup NS network: failed to set the MTU of %q", passthroughContext.contIfName)
+		}
+	}
+	if err := passthroughContext.netLink.LinkSetUp(link); err != nil {
+		return errors.Wrapf(err, "setup NS network: failed to set link %q up", passthroughContext.contIfName)
+	}
+	if err := passthroughContext.netLink.AddrAdd(link, &netlink.Addr{IPNet: passthroughContext.v4Addr}); err != nil {
+		return errors.Wrapf(err, "setup NS network: failed to add IP addr to %q", passthroughContext.contIfName)
+	}
+	// The subnet gateway is reached on-link, since the pod only has a /32 address
+	for _, r := range buildRoutesForVlan(unix.RT_TABLE_MAIN, link.Attrs().Index, passthroughContext.subnetGW) {
+		if err := passthroughContext.netLink.RouteReplace(&r); err != nil {
+			return errors.Wrapf(err, "setup NS network: failed to replace route entry %s via %s", r.Dst.IP.String(), passthroughContext.subnetGW.String())
+		}
 	}
 	return nil
 }
 
+// teardown defines the closure to execute within the container's namespace to move the ENI back to the host
+func (passthroughContext *passthroughENIContext) teardown(hostNS ns.NetNS) error {
+	link, err := passthroughContext.netLink.LinkByName(passthroughContext.contIfName)
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); ok {
+			// Already moved back
+			return nil
+		}
+		return errors.Wrapf(err, "teardown NS network: failed to find link %q", passthroughContext.contIfName)
+	}
+	if err := passthroughContext.netLink.LinkSetDown(link); err != nil {
+		return errors.Wrapf(err, "teardown NS network: failed to set link %q down", passthroughContext.contIfName)
+	}
+	if alias := link.Attrs().Alias; alias != "" {
+		if err := passthroughContext.netLink.LinkSetName(link, alias); err != nil {
+			return errors.Wrapf(err, "teardown NS network: failed to rename link %q to %q", passthroughContext.contIfName, alias)
+		}
+	}
+	if err := passthroughContext.netLink.LinkSetNsFd(link, int(hostNS.Fd())); err != nil {
+		return errors.Wrapf(err, "teardown NS network: failed to move link %q to the host", passthroughContext.contIfName)
+	}
+	return nil
+}
+
+// check defines the closure to execute within the container's namespace to check the ENI
+func (passthroughContext *passthroughENIContext) check(hostNS ns.NetNS) error {
+	link, err := passthroughContext.netLink.LinkByName(passthroughContext.contIfName)
+	if err != nil {
+		return errors.Wrapf(err, "check NS network: failed to find link %q", passthroughContext.contIfName)
+	}
+	if link.Attrs().Flags&net.FlagUp == 0 {
+		return errors.Errorf("check NS network: link %q is down", passthroughContext.contIfName)
+	}
+	addrs, err := passthroughContext.netLink.AddrList(link, netlink.FAMILY_V4)
+	if err != nil {
+		return errors.Wrapf(err, "check NS network: failed to list addresses of %q", passthroughContext.contIfName)
+	}
+	for _, addr := range addrs {
+		if ipNetEqual(addr.IPNet, passthroughContext.v4Addr) {
+			return nil
+		}
+	}
+	return errors.Errorf("check NS network: IP addr %s is missing on %q", passthroughContext.v4Addr.String(), passthroughContext.contIfName)
+}
+
+// linkByMAC returns the link of the ENI in the current namespace
+func (passthroughContext *passthroughENIContext) linkByMAC() (netlink.Link, error) {
+	links, err := passthroughContext.netLink.LinkList()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list links")
+	}
+	for _, link := range links {
+		if strings.EqualFold(link.Attrs().HardwareAddr.String(), passthroughContext.eniMAC) {
+			return link, nil
+		}
+	}
+	return nil, errors.Errorf("failed to find the link of ENI %s", passthroughContext.eniMAC)
+}
+
 // SetupPodNetwork wires up linux networking for a pod's network
 // we expect v4Addr and v6Addr to have correct IPAddress Family.
 func (n *linuxNetwork) SetupPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet,
@@ -234,28 +531,148 @@ func (n *linuxNetwork) SetupPodNetwork(hostVethName string, contVethName string,
 	log.Debugf("SetupPodNetwork: hostVethName=%s, contVethName=%s, netnsPath=%s, v4Addr=%v, v6Addr=%v, deviceNumber=%d, mtu=%d",
 		hostVethName, contVethName, netnsPath, v4Addr, v6Addr, deviceNumber, mtu)
 
-	hostVeth, err := n.setupVeth(hostVethName, contVethName, netnsPath, v4Addr, v6Addr, mtu, log)
+	if err := n.setupPodNetwork(hostVethName, contVethName, netnsPath, v4Addr, v6Addr, deviceNumber, mtu, false, log); err != nil {
+		return errors.Wrap(err, "SetupPodNetwork")
+	}
+	return nil
+}
+
+// SetupSecondaryPodNetwork wires up linux networking for an additional interface of a pod. Its default route is in a
+// route table of its own inside the pod, which is used for traffic from its addresses, so the default route of the
+// pod's main interface is kept. On the host, it is set up like the main interface.
+func (n *linuxNetwork) SetupSecondaryPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet,
+	deviceNumber int, mtu int, log logger.Logger) error {
+	log.Debugf("SetupSecondaryPodNetwork: hostVethName=%s, contVethName=%s, netnsPath=%s, v4Addr=%v, v6Addr=%v, deviceNumber=%d, mtu=%d",
+		hostVethName, contVethName, netnsPath, v4Addr, v6Addr, deviceNumber, mtu)
+
+	if err := n.setupPodNetwork(hostVethName, contVethName, netnsPath, v4Addr, v6Addr, deviceNumber, mtu, true, log); err != nil {
+		return errors.Wrap(err, "SetupSecondaryPodNetwork")
+	}
+	return nil
+}
+
+func (n *linuxNetwork) setupPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet,
+	deviceNumber int, mtu int, secondaryInterface bool, log logger.Logger) error {
+	hostVeth, err := n.setupVeth(hostVethName, contVethName, netnsPath, v4Addr, v6Addr, mtu, secondaryInterface, log)
 	if err != nil {
-		return errors.Wrapf(err, "SetupPodNetwork: failed to setup veth pair")
+		return errors.Wrapf(err, "failed to setup veth pair")
 	}
 
-	var containerAddr *net.IPNet
+	rtTable := unix.RT_TABLE_MAIN
+	if deviceNumber > 0 {
+		rtTable = deviceNumber + 1
+	}
 	if v4Addr != nil {
-		containerAddr = v4Addr
-	} else if v6Addr != nil {
-		containerAddr = v6Addr
+		if err := n.setupIPBasedContainerRouteRules(hostVeth, v4Addr, rtTable, log); err != nil {
+			return errors.Wrapf(err, "unable to setup IP based container routes and rules")
+		}
+	}
+	if v6Addr != nil {
+		// In dual-stack mode, the device number is the one of the v4 address. The v6 address comes from the primary
+		// ENI, so its traffic is routed via the main route table.
+		v6RTTable := rtTable
+		if v4Addr != nil {
+			v6RTTable = unix.RT_TABLE_MAIN
+		}
+		if err := n.setupIPBasedContainerRouteRules(hostVeth, v6Addr, v6RTTable, log); err != nil {
+			return errors.Wrapf(err, "unable to setup IP based container routes and rules")
+		}
+	}
+	return nil
+}
+
+// SetupBPFRoutedPodNetwork wires up linux networking for a pod's network like SetupPodNetwork, but without the rule
+// for the traffic from the pod. The eBPF pod routing program on the hostVeth puts the route table of the ENI in the
+// mark instead, which is matched by one rule per route table.
+func (n *linuxNetwork) SetupBPFRoutedPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet,
+	deviceNumber int, mtu int, log logger.Logger) error {
+	log.Debugf("SetupBPFRoutedPodNetwork: hostVethName=%s, contVethName=%s, netnsPath=%s, v4Addr=%v, deviceNumber=%d, mtu=%d",
+		hostVethName, contVethName, netnsPath, v4Addr, deviceNumber, mtu)
+
+	hostVeth, err := n.setupVeth(hostVethName, contVethName, netnsPath, v4Addr, nil, mtu, false, log)
+	if err != nil {
+		return errors.Wrapf(err, "SetupBPFRoutedPodNetwork: failed to setup veth pair")
+	}
+	// Only the route and the toContainer rule are set up with the main route table
+	if err := n.setupIPBasedContainerRouteRules(hostVeth, v4Addr, unix.RT_TABLE_MAIN, log); err != nil {
+		return errors.Wrapf(err, "SetupBPFRoutedPodNetwork: unable to setup IP based container routes and rules")
 	}
 
 	rtTable := unix.RT_TABLE_MAIN
 	if deviceNumber > 0 {
 		rtTable = deviceNumber + 1
 	}
-	if err := n.setupIPBasedContainerRouteRules(hostVeth, containerAddr, rtTable, log); err != nil {
-		return errors.Wrapf(err, "SetupPodNetwork: unable to setup IP based container routes and rules")
+	if rtTable != unix.RT_TABLE_MAIN {
+		// ipamd adds the rule of the route table as well, but maybe not yet for a new ENI
+		markRule := n.netLink.NewRule()
+		markRule.Mark = bpfrouting.Mark(rtTable)
+		markRule.Mask = bpfrouting.MarkMask
+		markRule.Priority = networkutils.FromPodRulePriority
+		markRule.Table = rtTable
+		if err := n.netLink.RuleAdd(markRule); err != nil && !networkutils.IsRuleExistsError(err) {
+			return errors.Wrapf(err, "SetupBPFRoutedPodNetwork: failed to setup mark rule, rtTable=%v", rtTable)
+		}
+		log.Debugf("Successfully setup mark rule, rtTable=%v", rtTable)
+	}
+
+	if err := n.attachBPFProgram(hostVeth); err != nil {
+		return errors.Wrapf(err, "SetupBPFRoutedPodNetwork: failed to attach eBPF pod routing program to hostVeth %s", hostVethName)
+	}
+	log.Debugf("Successfully attached eBPF pod routing program to hostVeth %s", hostVethName)
+	return nil
+}
+
+// attachBPFProgram attaches the eBPF pod routing program to the ingress of the hostVeth, which is the traffic from the pod
+func (n *linuxNetwork) attachBPFProgram(hostVeth netlink.Link) error {
+	fd, err := n.openBPFProgram()
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	qdisc := &netlink.GenericQdisc{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: hostVeth.Attrs().Index,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_CLSACT,
+		},
+		QdiscType: "clsact",
+	}
+	if err := n.netLink.QdiscReplace(qdisc); err != nil {
+		return errors.Wrap(err, "failed to add clsact qdisc")
+	}
+	filter := &netlink.BpfFilter{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: hostVeth.Attrs().Index,
+			Parent:    netlink.HANDLE_MIN_INGRESS,
+			Handle:    netlink.MakeHandle(0, 1),
+			Protocol:  unix.ETH_P_ALL,
+			Priority:  1,
+		},
+		Fd:           fd,
+		Name:         bpfrouting.ProgramName,
+		DirectAction: true,
+	}
+	if err := n.netLink.FilterReplace(filter); err != nil {
+		return errors.Wrap(err, "failed to add ingress filter")
 	}
 	return nil
 }
 
+// hasBPFProgram returns true if the eBPF pod routing program is attached to the hostVeth
+func (n *linuxNetwork) hasBPFProgram(hostVeth netlink.Link) (bool, error) {
+	filters, err := n.netLink.FilterList(hostVeth, netlink.HANDLE_MIN_INGRESS)
+	if err != nil {
+		return false, err
+	}
+	for _, filter := range filters {
+		if bpfFilter, ok := filter.(*netlink.BpfFilter); ok && bpfFilter.Name == bpfrouting.ProgramName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // TeardownPodNetwork cleanup ip rules
 func (n *linuxNetwork) TeardownPodNetwork(containerAddr *net.IPNet, deviceNumber int, log logger.Logger) error {
 	log.Debugf("TeardownPodNetwork: containerAddr=%s, deviceNumber=%d", containerAddr.String(), deviceNumber)
@@ -277,7 +694,7 @@ func (n *linuxNetwork) SetupBranchENIPodNetwork(hostVethName string, contVethNam
 	log.Debugf("SetupBranchENIPodNetwork: hostVethName=%s, contVethName=%s, netnsPath=%s, v4Addr=%v, v6Addr=%v, vlanID=%d, eniMAC=%s, subnetGW=%s, parentIfIndex=%d, mtu=%d, podSGEnforcingMode=%v",
 		hostVethName, contVethName, netnsPath, v4Addr, v6Addr, vlanID, eniMAC, subnetGW, parentIfIndex, mtu, podSGEnforcingMode)
 
-	hostVeth, err := n.setupVeth(hostVethName, contVethName, netnsPath, v4Addr, v6Addr, mtu, log)
+	hostVeth, err := n.setupVeth(hostVethName, contVethName, netnsPath, v4Addr, v6Addr, mtu, false, log)
 	if err != nil {
 		return errors.Wrapf(err, "SetupBranchENIPodNetwork: failed to setup veth pair")
 	}
@@ -340,8 +757,178 @@ func (n *linuxNetwork) TeardownBranchENIPodNetwork(containerAddr *net.IPNet, vla
 	return nil
 }
 
+// SetupPassthroughPodNetwork moves the link of an ENI from the host into the pod's network namespace and gives it the
+// primary IP of the ENI. Nothing is set up on the host, the traffic of the pod does not go through it.
+func (n *linuxNetwork) SetupPassthroughPodNetwork(contIfName string, netnsPath string, v4Addr *net.IPNet, eniMAC string, subnetGW string,
+	mtu int, log logger.Logger) error {
+	log.Debugf("SetupPassthroughPodNetwork: contIfName=%s, netnsPath=%s, v4Addr=%v, eniMAC=%s, subnetGW=%s, mtu=%d",
+		contIfName, netnsPath, v4Addr, eniMAC, subnetGW, mtu)
+
+	passthroughContext := newPassthroughENIContext(contIfName, eniMAC, v4Addr, subnetGW, mtu, n.netLink)
+	if err := n.ns.WithNetNSPath(netnsPath, passthroughContext.setup); err != nil {
+		return errors.Wrap(err, "SetupPassthroughPodNetwork: failed to move the ENI into the pod")
+	}
+	return nil
+}
+
+// TeardownPassthroughPodNetwork moves the link of an ENI out of the pod's network namespace back to the host. When the
+// namespace is gone already, the kernel has moved the link back itself.
+func (n *linuxNetwork) TeardownPassthroughPodNetwork(contIfName string, netnsPath string, log logger.Logger) error {
+	log.Debugf("TeardownPassthroughPodNetwork: contIfName=%s, netnsPath=%s", contIfName, netnsPath)
+
+	passthroughContext := newPassthroughENIContext(contIfName, "", nil, "", 0, n.netLink)
+	if err := n.ns.WithNetNSPath(netnsPath, passthroughContext.teardown); err != nil {
+		if _, ok := err.(ns.NSPathNotExistErr); ok {
+			log.Debugf("TeardownPassthroughPodNetwork: netns %s does not exist", netnsPath)
+			return nil
+		}
+		return errors.Wrap(err, "TeardownPassthroughPodNetwork: failed to move the ENI back to the host")
+	}
+	return nil
+}
+
+// CheckPodNetwork checks that the veth pair, the routes and the rules of a pod are still set up
+func (n *linuxNetwork) CheckPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet,
+	deviceNumber int, log logger.Logger) error {
+	log.Debugf("CheckPodNetwork: hostVethName=%s, contVethName=%s, netnsPath=%s, v4Addr=%v, v6Addr=%v, deviceNumber=%d",
+		hostVethName, contVethName, netnsPath, v4Addr, v6Addr, deviceNumber)
+
+	if err := n.checkPodNetwork(hostVethName, contVethName, netnsPath, v4Addr, v6Addr, deviceNumber, false); err != nil {
+		return errors.Wrap(err, "CheckPodNetwork")
+	}
+	log.Debugf("CheckPodNetwork: pod network of %s is set up", hostVethName)
+	return nil
+}
+
+// CheckSecondaryPodNetwork checks that the veth pair, the routes and the rules of an additional pod interface are still set up
+func (n *linuxNetwork) CheckSecondaryPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet,
+	deviceNumber int, log logger.Logger) error {
+	log.Debugf("CheckSecondaryPodNetwork: hostVethName=%s, contVethName=%s, netnsPath=%s, v4Addr=%v, v6Addr=%v, deviceNumber=%d",
+		hostVethName, contVethName, netnsPath, v4Addr, v6Addr, deviceNumber)
+
+	if err := n.checkPodNetwork(hostVethName, contVethName, netnsPath, v4Addr, v6Addr, deviceNumber, true); err != nil {
+		return errors.Wrap(err, "CheckSecondaryPodNetwork")
+	}
+	log.Debugf("CheckSecondaryPodNetwork: pod network of %s is set up", hostVethName)
+	return nil
+}
+
+func (n *linuxNetwork) checkPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet,
+	deviceNumber int, secondaryInterface bool) error {
+	hostVeth, err := n.checkVeth(hostVethName, contVethName, netnsPath, v4Addr, v6Addr, secondaryInterface)
+	if err != nil {
+		return errors.Wrapf(err, "veth pair is not set up")
+	}
+
+	rtTable := unix.RT_TABLE_MAIN
+	if deviceNumber > 0 {
+		rtTable = deviceNumber + 1
+	}
+	if v4Addr != nil {
+		if err := n.checkIPBasedContainerRouteRules(hostVeth, v4Addr, rtTable); err != nil {
+			return errors.Wrapf(err, "IP based container routes and rules are not set up")
+		}
+	}
+	if v6Addr != nil {
+		// In dual-stack mode, the v6 address is routed via the main route table, see SetupPodNetwork
+		v6RTTable := rtTable
+		if v4Addr != nil {
+			v6RTTable = unix.RT_TABLE_MAIN
+		}
+		if err := n.checkIPBasedContainerRouteRules(hostVeth, v6Addr, v6RTTable); err != nil {
+			return errors.Wrapf(err, "IP based container routes and rules are not set up")
+		}
+	}
+	return nil
+}
+
+// CheckBranchENIPodNetwork checks that the veth pair, the vlan, the routes and the rules of a branch ENI pod are still set up
+func (n *linuxNetwork) CheckBranchENIPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet,
+	vlanID int, podSGEnforcingMode sgpp.EnforcingMode, log logger.Logger) error {
+	log.Debugf("CheckBranchENIPodNetwork: hostVethName=%s, contVethName=%s, netnsPath=%s, v4Addr=%v, v6Addr=%v, vlanID=%d, podSGEnforcingMode=%v",
+		hostVethName, contVethName, netnsPath, v4Addr, v6Addr, vlanID, podSGEnforcingMode)
+
+	hostVeth, err := n.checkVeth(hostVethName, contVethName, netnsPath, v4Addr, v6Addr, false)
+	if err != nil {
+		return errors.Wrapf(err, "CheckBranchENIPodNetwork: veth pair is not set up")
+	}
+
+	vlanLink, err := n.checkVlan(vlanID)
+	if err != nil {
+		return errors.Wrapf(err, "CheckBranchENIPodNetwork: vlan is not set up")
+	}
+
+	var containerAddr *net.IPNet
+	if v4Addr != nil {
+		containerAddr = v4Addr
+	} else if v6Addr != nil {
+		containerAddr = v6Addr
+	}
+
+	rtTable := vlanID + 100
+	switch podSGEnforcingMode {
+	case sgpp.EnforcingModeStrict:
+		if err := n.checkIIFBasedContainerRouteRules(hostVeth, containerAddr, vlanLink, rtTable); err != nil {
+			return errors.Wrapf(err, "CheckBranchENIPodNetwork: IIF based container rules are not set up")
+		}
+	case sgpp.EnforcingModeStandard:
+		if err := n.checkIPBasedContainerRouteRules(hostVeth, containerAddr, rtTable); err != nil {
+			return errors.Wrapf(err, "CheckBranchENIPodNetwork: IP based container routes and rules are not set up")
+		}
+	}
+	log.Debugf("CheckBranchENIPodNetwork: pod network of %s is set up", hostVethName)
+	return nil
+}
+
+// CheckPassthroughPodNetwork checks that the ENI of a pod is up inside its network namespace and has the pod address
+func (n *linuxNetwork) CheckPassthroughPodNetwork(contIfName string, netnsPath string, v4Addr *net.IPNet, log logger.Logger) error {
+	log.Debugf("CheckPassthroughPodNetwork: contIfName=%s, netnsPath=%s, v4Addr=%v", contIfName, netnsPath, v4Addr)
+
+	passthroughContext := newPassthroughENIContext(contIfName, "", v4Addr, "", 0, n.netLink)
+	if err := n.ns.WithNetNSPath(netnsPath, passthroughContext.check); err != nil {
+		return errors.Wrap(err, "CheckPassthroughPodNetwork")
+	}
+	log.Debugf("CheckPassthroughPodNetwork: ENI of %s is set up", contIfName)
+	return nil
+}
+
+// checkVeth checks that the hostVeth is up, and that the container's veth has the pod addresses and default routes
+func (n *linuxNetwork) checkVeth(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet,
+	secondaryInterface bool) (netlink.Link, error) {
+	hostVeth, err := n.netLink.LinkByName(hostVethName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find hostVeth %s", hostVethName)
+	}
+	if hostVeth.Attrs().Flags&net.FlagUp == 0 {
+		return nil, errors.Errorf("hostVeth %s is down", hostVethName)
+	}
+
+	checkContext := newCheckVethContext(contVethName, v4Addr, v6Addr, n.netLink, secondaryInterface)
+	if err := n.ns.WithNetNSPath(netnsPath, checkContext.run); err != nil {
+		return nil, errors.Wrap(err, "failed to check veth network")
+	}
+	return hostVeth, nil
+}
+
+// checkVlan checks that the vlan link of a branch ENI exists and is up
+func (n *linuxNetwork) checkVlan(vlanID int) (netlink.Link, error) {
+	vlanLinkName := buildVlanLinkName(vlanID)
+	link, err := n.netLink.LinkByName(vlanLinkName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find vlan link %s", vlanLinkName)
+	}
+	if vlan, ok := link.(*netlink.Vlan); !ok || vlan.VlanId != vlanID {
+		return nil, errors.Errorf("link %s is not a vlan with ID %d", vlanLinkName, vlanID)
+	}
+	if link.Attrs().Flags&net.FlagUp == 0 {
+		return nil, errors.Errorf("vlan link %s is down", vlanLinkName)
+	}
+	return link, nil
+}
+
 // setupVeth sets up veth for the pod.
-func (n *linuxNetwork) setupVeth(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet, mtu int, log logger.Logger) (netlink.Link, error) {
+func (n *linuxNetwork) setupVeth(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet, mtu int,
+	secondaryInterface bool, log logger.Logger) (netlink.Link, error) {
 	// Clean up if hostVeth exists.
 	if oldHostVeth, err := n.netLink.LinkByName(hostVethName); err == nil {
 		if err = n.netLink.LinkDel(oldHostVeth); err != nil {
@@ -350,7 +937,7 @@ func (n *linuxNetwork) setupVeth(hostVethName string, contVethName string, netns
 		log.Debugf("Successfully deleted old hostVeth %s", hostVethName)
 	}
 
-	createVethContext := newCreateVethPairContext(contVethName, hostVethName, v4Addr, v6Addr, mtu)
+	createVethContext := newCreateVethPairContext(contVethName, hostVethName, v4Addr, v6Addr, mtu, secondaryInterface)
 	if err := n.ns.WithNetNSPath(netnsPath, createVethContext.run); err != nil {
 		return nil, errors.Wrap(err, "failed to setup veth network")
 	}
@@ -507,6 +1094,59 @@ func (n *linuxNetwork) teardownIPBasedContainerRouteRules(containerAddr *net.IPN
 	return nil
 }
 
+// checkIPBasedContainerRouteRules checks the routes and route rules that setupIPBasedContainerRouteRules sets up
+func (n *linuxNetwork) checkIPBasedContainerRouteRules(hostVeth netlink.Link, containerAddr *net.IPNet, rtTable int) error {
+	family := netlink.FAMILY_V4
+	if containerAddr.IP.To4() == nil {
+		family = netlink.FAMILY_V6
+	}
+
+	routes, err := n.netLink.RouteList(hostVeth, family)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list routes of hostVeth %s", hostVeth.Attrs().Name)
+	}
+	found := false
+	for _, route := range routes {
+		if route.Scope == netlink.SCOPE_LINK && ipNetEqual(route.Dst, containerAddr) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return errors.Errorf("container route is missing, containerAddr=%s, hostVeth=%s, rtTable=%v",
+			containerAddr.String(), hostVeth.Attrs().Name, "main")
+	}
+
+	rules, err := n.netLink.RuleList(family)
+	if err != nil {
+		return errors.Wrap(err, "failed to list rules")
+	}
+	if !containsRule(rules, func(rule netlink.Rule) bool {
+		return ipNetEqual(rule.Dst, containerAddr) && rule.Priority == networkutils.ToContainerRulePriority && rule.Table == unix.RT_TABLE_MAIN
+	}) {
+		return errors.Errorf("toContainer rule is missing, containerAddr=%s, rtTable=%v", containerAddr.String(), "main")
+	}
+	if rtTable != unix.RT_TABLE_MAIN && !containsRule(rules, func(rule netlink.Rule) bool {
+		return ipNetEqual(rule.Src, containerAddr) && rule.Priority == networkutils.FromPodRulePriority && rule.Table == rtTable
+	}) {
+		// A pod set up by SetupBPFRoutedPodNetwork has no fromContainer rule
+		bpfRouted, err := n.hasBPFProgram(hostVeth)
+		if err != nil {
+			return errors.Wrapf(err, "failed to list filters of hostVeth %s", hostVeth.Attrs().Name)
+		}
+		if !bpfRouted {
+			return errors.Errorf("fromContainer rule is missing, containerAddr=%s, rtTable=%v", containerAddr.String(), rtTable)
+		}
+		if !containsRule(rules, func(rule netlink.Rule) bool {
+			return rule.Mark == bpfrouting.Mark(rtTable) && rule.Mask == bpfrouting.MarkMask &&
+				rule.Priority == networkutils.FromPodRulePriority && rule.Table == rtTable
+		}) {
+			return errors.Errorf("mark rule is missing, rtTable=%v", rtTable)
+		}
+	}
+	return nil
+}
+
 // setupIIFBasedContainerRouteRules setups the routes and route rules for containers based on input network interface.
 // traffic to container(iif hostVlan) will be routed via the specified rtTable.
 // traffic from container(iif hostVeth) will be routed via the specified rtTable.
@@ -558,6 +1198,58 @@ func (n *linuxNetwork) teardownIIFBasedContainerRouteRules(rtTable int, log logg
 	return nil
 }
 
+// checkIIFBasedContainerRouteRules checks the route rules that setupIIFBasedContainerRouteRules sets up. The container
+// route is in the vlan's route table, which is not listed by netlink's RouteList, so it is not checked.
+func (n *linuxNetwork) checkIIFBasedContainerRouteRules(hostVeth netlink.Link, containerAddr *net.IPNet, hostVlan netlink.Link, rtTable int) error {
+	family := netlink.FAMILY_V4
+	if containerAddr.IP.To4() == nil {
+		family = netlink.FAMILY_V6
+	}
+	rules, err := n.netLink.RuleList(family)
+	if err != nil {
+		return errors.Wrap(err, "failed to list rules")
+	}
+	for _, iifName := range []string{hostVlan.Attrs().Name, hostVeth.Attrs().Name} {
+		if !containsRule(rules, func(rule netlink.Rule) bool {
+			return rule.IifName == iifName && rule.Priority == networkutils.VlanRulePriority && rule.Table == rtTable
+		}) {
+			return errors.Errorf("IIF based rule is missing, iif=%s, rtTable=%v", iifName, rtTable)
+		}
+	}
+	return nil
+}
+
+// containsRule returns true if one of the rules matches
+func containsRule(rules []netlink.Rule, match func(rule netlink.Rule) bool) bool {
+	for _, rule := range rules {
+		if match(rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// ipNetEqual returns true if both networks have the same address and prefix length
+func ipNetEqual(a *net.IPNet, b *net.IPNet) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	aOnes, _ := a.Mask.Size()
+	bOnes, _ := b.Mask.Size()
+	return a.IP.Equal(b.IP) && aOnes == bOnes
+}
+
+// secondaryInterfaceRouteTable returns the route table of a secondary pod interface inside the container's namespace
+func secondaryInterfaceRouteTable(contVeth netlink.Link) int {
+	return secondaryInterfaceRouteTableBase + contVeth.Attrs().Index
+}
+
+// isDefaultRoute returns true if the destination is 0.0.0.0/0 or ::/0
+func isDefaultRoute(dst *net.IPNet) bool {
+	ones, _ := dst.Mask.Size()
+	return ones == 0 && dst.IP.IsUnspecified()
+}
+
 // buildRoutesForVlan builds routes required for the vlan link.
 func buildRoutesForVlan(vlanTableID int, vlanIndex int, gw net.IP) []netlink.Route {
 	return []netlink.Route{
@@ -18,6 +18,7 @@ import (
 	"syscall"
 	"testing"
 
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/bpfrouting"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/cninswrapper/mock_ns"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/netlinkwrapper/mock_netlink"
 	mock_netlinkwrapper "github.com/aws/amazon-vpc-cni-k8s/pkg/netlinkwrapper/mocks"
@@ -26,6 +27,7 @@ import (
 	mock_procsyswrapper "github.com/aws/amazon-vpc-cni-k8s/pkg/procsyswrapper/mocks"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/sgpp"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/utils/logger"
+	cnins "github.com/containernetworking/plugins/pkg/ns"
 	"github.com/golang/mock/gomock"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -62,6 +64,16 @@ func Test_linuxNetwork_SetupPodNetwork(t *testing.T) {
 	fromContainerRuleForRTTable4.Priority = networkutils.FromPodRulePriority
 	fromContainerRuleForRTTable4.Table = 4
 
+	containerV6Addr := &net.IPNet{
+		IP:   net.ParseIP("2001:db8::42"),
+		Mask: net.CIDRMask(128, 128),
+	}
+
+	toContainerV6Rule := netlink.NewRule()
+	toContainerV6Rule.Dst = containerV6Addr
+	toContainerV6Rule.Priority = networkutils.ToContainerRulePriority
+	toContainerV6Rule.Table = unix.RT_TABLE_MAIN
+
 	type linkByNameCall struct {
 		linkName string
 		link     netlink.Link
@@ -238,6 +250,79 @@ func Test_linuxNetwork_SetupPodNetwork(t *testing.T) {
 				mtu:          9001,
 			},
 		},
+		{
+			name: "successfully setup dual-stack pod network - pod sponsored by eth3",
+			fields: fields{
+				linkByNameCalls: []linkByNameCall{
+					{
+						linkName: "eni8ea2c11fe35",
+						err:      errors.New("not exists"),
+					},
+					{
+						linkName: "eni8ea2c11fe35",
+						link:     hostVethWithIndex9,
+					},
+				},
+				linkSetupCalls: []linkSetupCall{
+					{
+						link: hostVethWithIndex9,
+					},
+				},
+				routeReplaceCalls: []routeReplaceCall{
+					{
+						route: &netlink.Route{
+							LinkIndex: hostVethWithIndex9.Index,
+							Scope:     netlink.SCOPE_LINK,
+							Dst:       containerAddr,
+							Table:     unix.RT_TABLE_MAIN,
+						},
+					},
+					{
+						route: &netlink.Route{
+							LinkIndex: hostVethWithIndex9.Index,
+							Scope:     netlink.SCOPE_LINK,
+							Dst:       containerV6Addr,
+							Table:     unix.RT_TABLE_MAIN,
+						},
+					},
+				},
+				ruleAddCalls: []ruleAddCall{
+					{
+						rule: toContainerRule,
+					},
+					{
+						rule: fromContainerRuleForRTTable4,
+					},
+					{
+						rule: toContainerV6Rule,
+					},
+				},
+				withNetNSPathCalls: []withNetNSPathCall{
+					{
+						netNSPath: "/proc/42/ns/net",
+					},
+				},
+				procSysSetCalls: []procSysSetCall{
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/accept_ra",
+						value: "0",
+					},
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/accept_redirects",
+						value: "0",
+					},
+				},
+			},
+			args: args{
+				hostVethName: "eni8ea2c11fe35",
+				contVethName: "eth0",
+				netnsPath:    "/proc/42/ns/net",
+				v4Addr:       containerAddr,
+				v6Addr:       containerV6Addr,
+				deviceNumber: 3,
+				mtu:          9001,
+			},
+		},
 		{
 			name: "failed to setup vethPair",
 			fields: fields{
@@ -504,6 +589,269 @@ func Test_linuxNetwork_TeardownPodNetwork(t *testing.T) {
 	}
 }
 
+func Test_linuxNetwork_CheckPodNetwork(t *testing.T) {
+	hostVeth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:  "eni8ea2c11fe35",
+			Index: 9,
+			Flags: net.FlagUp,
+		},
+	}
+	contVeth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:  "eth0",
+			Index: 1,
+			Flags: net.FlagUp,
+		},
+	}
+	containerAddr := &net.IPNet{
+		IP:   net.ParseIP("192.168.100.42"),
+		Mask: net.CIDRMask(32, 32),
+	}
+	containerAddrs := []netlink.Addr{{IPNet: containerAddr}}
+	containerRoutes := []netlink.Route{
+		{LinkIndex: 1, Scope: netlink.SCOPE_LINK, Dst: &net.IPNet{IP: net.IPv4(169, 254, 1, 1), Mask: net.CIDRMask(32, 32)}},
+		{LinkIndex: 1, Scope: netlink.SCOPE_UNIVERSE, Gw: net.IPv4(169, 254, 1, 1)},
+	}
+	hostRoutes := []netlink.Route{
+		{LinkIndex: 9, Scope: netlink.SCOPE_LINK, Dst: containerAddr, Table: unix.RT_TABLE_MAIN},
+	}
+	toContainerRule := netlink.NewRule()
+	toContainerRule.Dst = containerAddr
+	toContainerRule.Priority = networkutils.ToContainerRulePriority
+	toContainerRule.Table = unix.RT_TABLE_MAIN
+	fromContainerRule := netlink.NewRule()
+	fromContainerRule.Src = containerAddr
+	fromContainerRule.Priority = networkutils.FromPodRulePriority
+	fromContainerRule.Table = 4
+
+	type fields struct {
+		hostRoutes []netlink.Route
+		rules      []netlink.Rule
+		contAddrs  []netlink.Addr
+	}
+	type args struct {
+		deviceNumber int
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantErr error
+	}{
+		{
+			name: "pod network is set up - pod sponsored by eth0",
+			fields: fields{
+				hostRoutes: hostRoutes,
+				rules:      []netlink.Rule{*toContainerRule},
+				contAddrs:  containerAddrs,
+			},
+			args: args{
+				deviceNumber: 0,
+			},
+		},
+		{
+			name: "pod network is set up - pod sponsored by eth3",
+			fields: fields{
+				hostRoutes: hostRoutes,
+				rules:      []netlink.Rule{*toContainerRule, *fromContainerRule},
+				contAddrs:  containerAddrs,
+			},
+			args: args{
+				deviceNumber: 3,
+			},
+		},
+		{
+			name: "container address is missing",
+			fields: fields{
+				contAddrs: nil,
+			},
+			args: args{
+				deviceNumber: 0,
+			},
+			wantErr: errors.New("CheckPodNetwork: veth pair is not set up: failed to check veth network: check NS network: IP addr 192.168.100.42/32 is missing on \"eth0\""),
+		},
+		{
+			name: "fromContainer rule is missing",
+			fields: fields{
+				hostRoutes: hostRoutes,
+				rules:      []netlink.Rule{*toContainerRule},
+				contAddrs:  containerAddrs,
+			},
+			args: args{
+				deviceNumber: 3,
+			},
+			wantErr: errors.New("CheckPodNetwork: IP based container routes and rules are not set up: fromContainer rule is missing, containerAddr=192.168.100.42/32, rtTable=4"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			netLink := mock_netlinkwrapper.NewMockNetLink(ctrl)
+			ns := mock_nswrapper.NewMockNS(ctrl)
+			netLink.EXPECT().LinkByName("eni8ea2c11fe35").Return(hostVeth, nil)
+			netLink.EXPECT().LinkByName("eth0").Return(contVeth, nil)
+			netLink.EXPECT().AddrList(contVeth, netlink.FAMILY_V4).Return(tt.fields.contAddrs, nil)
+			netLink.EXPECT().RouteList(contVeth, netlink.FAMILY_V4).Return(containerRoutes, nil).AnyTimes()
+			netLink.EXPECT().RouteList(hostVeth, netlink.FAMILY_V4).Return(tt.fields.hostRoutes, nil).AnyTimes()
+			netLink.EXPECT().RuleList(netlink.FAMILY_V4).Return(tt.fields.rules, nil).AnyTimes()
+			netLink.EXPECT().FilterList(hostVeth, uint32(netlink.HANDLE_MIN_INGRESS)).Return(nil, nil).AnyTimes()
+			ns.EXPECT().WithNetNSPath("/proc/42/ns/net", gomock.Any()).DoAndReturn(func(_ string, toRun func(cnins.NetNS) error) error {
+				return toRun(nil)
+			})
+
+			n := &linuxNetwork{
+				netLink: netLink,
+				ns:      ns,
+			}
+			err := n.CheckPodNetwork("eni8ea2c11fe35", "eth0", "/proc/42/ns/net", containerAddr, nil, tt.args.deviceNumber, testLogger)
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_linuxNetwork_CheckSecondaryPodNetwork(t *testing.T) {
+	hostVeth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:  "enibb07af34158",
+			Index: 10,
+			Flags: net.FlagUp,
+		},
+	}
+	contVeth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:  "net1",
+			Index: 3,
+			Flags: net.FlagUp,
+		},
+	}
+	containerAddr := &net.IPNet{
+		IP:   net.ParseIP("192.168.200.42"),
+		Mask: net.CIDRMask(32, 32),
+	}
+	hostRoutes := []netlink.Route{
+		{LinkIndex: 10, Scope: netlink.SCOPE_LINK, Dst: containerAddr, Table: unix.RT_TABLE_MAIN},
+	}
+	toContainerRule := netlink.NewRule()
+	toContainerRule.Dst = containerAddr
+	toContainerRule.Priority = networkutils.ToContainerRulePriority
+	toContainerRule.Table = unix.RT_TABLE_MAIN
+	fromContainerRule := netlink.NewRule()
+	fromContainerRule.Src = containerAddr
+	fromContainerRule.Priority = networkutils.FromPodRulePriority
+	fromContainerRule.Table = 3
+	// Inside the pod, traffic from the secondary interface uses the route table of its link index
+	fromContainerAddrRule := netlink.NewRule()
+	fromContainerAddrRule.Src = containerAddr
+	fromContainerAddrRule.Priority = networkutils.FromPodRulePriority
+	fromContainerAddrRule.Table = 1003
+
+	tests := []struct {
+		name           string
+		containerRules []netlink.Rule
+		wantErr        error
+	}{
+		{
+			name:           "secondary interface is set up",
+			containerRules: []netlink.Rule{*fromContainerAddrRule},
+		},
+		{
+			name:    "rule of the route table of the secondary interface is missing",
+			wantErr: errors.New("CheckSecondaryPodNetwork: veth pair is not set up: failed to check veth network: check NS network: rule from 192.168.200.42/32 to route table 1003 is missing"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			netLink := mock_netlinkwrapper.NewMockNetLink(ctrl)
+			ns := mock_nswrapper.NewMockNS(ctrl)
+			netLink.EXPECT().LinkByName("enibb07af34158").Return(hostVeth, nil)
+			netLink.EXPECT().LinkByName("net1").Return(contVeth, nil)
+			netLink.EXPECT().AddrList(contVeth, netlink.FAMILY_V4).Return([]netlink.Addr{{IPNet: containerAddr}}, nil)
+			inContainer := false
+			netLink.EXPECT().RuleList(netlink.FAMILY_V4).DoAndReturn(func(int) ([]netlink.Rule, error) {
+				if inContainer {
+					return tt.containerRules, nil
+				}
+				return []netlink.Rule{*toContainerRule, *fromContainerRule}, nil
+			}).AnyTimes()
+			netLink.EXPECT().RouteList(hostVeth, netlink.FAMILY_V4).Return(hostRoutes, nil).AnyTimes()
+			ns.EXPECT().WithNetNSPath("/proc/42/ns/net", gomock.Any()).DoAndReturn(func(_ string, toRun func(cnins.NetNS) error) error {
+				inContainer = true
+				defer func() { inContainer = false }()
+				return toRun(nil)
+			})
+
+			n := &linuxNetwork{
+				netLink: netLink,
+				ns:      ns,
+			}
+			err := n.CheckSecondaryPodNetwork("enibb07af34158", "net1", "/proc/42/ns/net", containerAddr, nil, 2, testLogger)
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_createVethPairContext_addContainerAddrSecondaryInterface(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	contVeth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "net1", Index: 3}}
+	hostVeth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "enibb07af34158", Index: 10, HardwareAddr: net.HardwareAddr("00:00:5e:00:53:af")}}
+	containerAddr := &net.IPNet{IP: net.ParseIP("192.168.200.42"), Mask: net.CIDRMask(32, 32)}
+	gw := net.IPv4(169, 254, 1, 1)
+
+	netLink := mock_netlinkwrapper.NewMockNetLink(ctrl)
+	// The routes are added to the route table of the interface instead of the main one
+	netLink.EXPECT().RouteReplace(&netlink.Route{
+		LinkIndex: 3,
+		Scope:     netlink.SCOPE_LINK,
+		Dst:       &net.IPNet{IP: gw, Mask: net.CIDRMask(32, 32)},
+		Table:     1003,
+	}).Return(nil)
+	netLink.EXPECT().RouteAdd(&netlink.Route{
+		LinkIndex: 3,
+		Scope:     netlink.SCOPE_UNIVERSE,
+		Dst:       &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)},
+		Gw:        gw,
+		Table:     1003,
+	}).Return(nil)
+	netLink.EXPECT().NewRule().Return(netlink.NewRule())
+	fromContainerAddrRule := netlink.NewRule()
+	fromContainerAddrRule.Src = containerAddr
+	fromContainerAddrRule.Priority = networkutils.FromPodRulePriority
+	fromContainerAddrRule.Table = 1003
+	netLink.EXPECT().RuleAdd(fromContainerAddrRule).Return(nil)
+	netLink.EXPECT().AddrAdd(contVeth, &netlink.Addr{IPNet: containerAddr}).Return(nil)
+	netLink.EXPECT().NeighAdd(&netlink.Neigh{
+		LinkIndex:    3,
+		State:        netlink.NUD_PERMANENT,
+		IP:           gw,
+		HardwareAddr: hostVeth.HardwareAddr,
+	}).Return(nil)
+
+	createVethContext := &createVethPairContext{
+		contVethName:       "net1",
+		hostVethName:       "enibb07af34158",
+		v4Addr:             containerAddr,
+		netLink:            netLink,
+		secondaryInterface: true,
+	}
+	assert.NoError(t, createVethContext.addContainerAddr(hostVeth, contVeth, containerAddr))
+}
+
 func Test_linuxNetwork_SetupBranchENIPodNetwork(t *testing.T) {
 	vlanID := 7
 	eniMac := "00:00:5e:00:53:af"
@@ -2711,6 +3059,141 @@ func Test_createVethPairContext_run(t *testing.T) {
 	}
 }
 
+func Test_passthroughENIContext_setup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	eniMAC, _ := net.ParseMAC("12:ef:2a:98:e5:5b")
+	hostLink := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "ens6", Index: 3, HardwareAddr: eniMAC}}
+	contLink := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "ens6", Index: 2, HardwareAddr: eniMAC}}
+	otherLink := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "lo", Index: 1}}
+	v4Addr := &net.IPNet{IP: net.ParseIP("192.168.120.1"), Mask: net.CIDRMask(32, 32)}
+
+	netLink := mock_netlinkwrapper.NewMockNetLink(ctrl)
+	hostNS := mock_ns.NewMockNetNS(ctrl)
+	contNS := mock_ns.NewMockNetNS(ctrl)
+	gomock.InOrder(
+		// on the host
+		hostNS.EXPECT().Do(gomock.Any()).DoAndReturn(func(toRun func(cnins.NetNS) error) error {
+			return toRun(contNS)
+		}),
+		netLink.EXPECT().LinkList().Return([]netlink.Link{otherLink, hostLink}, nil),
+		netLink.EXPECT().LinkSetDown(hostLink).Return(nil),
+		netLink.EXPECT().LinkSetAlias(hostLink, "ens6").Return(nil),
+		contNS.EXPECT().Fd().Return(uintptr(42)),
+		netLink.EXPECT().LinkSetNsFd(hostLink, 42).Return(nil),
+		// in the container
+		netLink.EXPECT().LinkList().Return([]netlink.Link{otherLink, contLink}, nil),
+		netLink.EXPECT().LinkSetName(contLink, "eth0").Return(nil),
+		netLink.EXPECT().LinkSetMTU(contLink, 9001).Return(nil),
+		netLink.EXPECT().LinkSetUp(contLink).Return(nil),
+		netLink.EXPECT().AddrAdd(contLink, &netlink.Addr{IPNet: v4Addr}).Return(nil),
+	)
+	for _, route := range buildRoutesForVlan(unix.RT_TABLE_MAIN, 2, net.ParseIP("192.168.120.1")) {
+		r := route
+		netLink.EXPECT().RouteReplace(&r).Return(nil)
+	}
+
+	passthroughContext := newPassthroughENIContext("eth0", "12:ef:2a:98:e5:5b", v4Addr, "192.168.120.1", 9001, netLink)
+	assert.NoError(t, passthroughContext.setup(hostNS))
+
+	// The link of the ENI is not on the host
+	hostNS = mock_ns.NewMockNetNS(ctrl)
+	hostNS.EXPECT().Do(gomock.Any()).DoAndReturn(func(toRun func(cnins.NetNS) error) error {
+		return toRun(contNS)
+	})
+	netLink.EXPECT().LinkList().Return([]netlink.Link{otherLink}, nil)
+	err := passthroughContext.setup(hostNS)
+	assert.EqualError(t, err, "setup NS network: failed to find the link of ENI 12:ef:2a:98:e5:5b")
+}
+
+func Test_passthroughENIContext_teardown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	contLink := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "eth0", Index: 2, Alias: "ens6"}}
+
+	netLink := mock_netlinkwrapper.NewMockNetLink(ctrl)
+	hostNS := mock_ns.NewMockNetNS(ctrl)
+	gomock.InOrder(
+		netLink.EXPECT().LinkByName("eth0").Return(contLink, nil),
+		netLink.EXPECT().LinkSetDown(contLink).Return(nil),
+		netLink.EXPECT().LinkSetName(contLink, "ens6").Return(nil),
+		hostNS.EXPECT().Fd().Return(uintptr(42)),
+		netLink.EXPECT().LinkSetNsFd(contLink, 42).Return(nil),
+	)
+	passthroughContext := newPassthroughENIContext("eth0", "", nil, "", 0, netLink)
+	assert.NoError(t, passthroughContext.teardown(hostNS))
+
+	// Already moved back
+	netLink.EXPECT().LinkByName("eth0").Return(nil, netlink.LinkNotFoundError{})
+	assert.NoError(t, passthroughContext.teardown(hostNS))
+}
+
+func Test_linuxNetwork_attachBPFProgram(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// attachBPFProgram closes the file descriptor of the program
+	fd, err := unix.Open("/dev/null", unix.O_RDONLY, 0)
+	assert.NoError(t, err)
+	netLink := mock_netlinkwrapper.NewMockNetLink(ctrl)
+	n := &linuxNetwork{
+		netLink:        netLink,
+		openBPFProgram: func() (int, error) { return fd, nil },
+	}
+	hostVeth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "eni8ea2c11fe35", Index: 9}}
+
+	netLink.EXPECT().QdiscReplace(&netlink.GenericQdisc{
+		QdiscAttrs: netlink.QdiscAttrs{LinkIndex: 9, Handle: netlink.MakeHandle(0xffff, 0), Parent: netlink.HANDLE_CLSACT},
+		QdiscType:  "clsact",
+	}).Return(nil)
+	netLink.EXPECT().FilterReplace(&netlink.BpfFilter{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: 9,
+			Parent:    netlink.HANDLE_MIN_INGRESS,
+			Handle:    netlink.MakeHandle(0, 1),
+			Protocol:  unix.ETH_P_ALL,
+			Priority:  1,
+		},
+		Fd:           fd,
+		Name:         bpfrouting.ProgramName,
+		DirectAction: true,
+	}).Return(nil)
+	assert.NoError(t, n.attachBPFProgram(hostVeth))
+
+	n.openBPFProgram = func() (int, error) { return -1, errors.New("program is not pinned") }
+	assert.EqualError(t, n.attachBPFProgram(hostVeth), "program is not pinned")
+}
+
+func Test_linuxNetwork_checkIPBasedContainerRouteRulesBPFRouted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	netLink := mock_netlinkwrapper.NewMockNetLink(ctrl)
+	n := &linuxNetwork{netLink: netLink}
+	hostVeth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "eni8ea2c11fe35", Index: 9}}
+	containerAddr := &net.IPNet{IP: net.ParseIP("192.168.100.42"), Mask: net.CIDRMask(32, 32)}
+	toContainerRule := netlink.Rule{Dst: containerAddr, Priority: networkutils.ToContainerRulePriority, Table: unix.RT_TABLE_MAIN}
+	markRule := netlink.Rule{Mark: 0x0400, Mask: 0x3f00, Priority: networkutils.FromPodRulePriority, Table: 4}
+
+	netLink.EXPECT().RouteList(hostVeth, netlink.FAMILY_V4).Return([]netlink.Route{{Dst: containerAddr, Scope: netlink.SCOPE_LINK}}, nil).Times(3)
+	netLink.EXPECT().RuleList(netlink.FAMILY_V4).Return([]netlink.Rule{toContainerRule, markRule}, nil).Times(3)
+
+	// The program is attached and the rule of the route table is there
+	netLink.EXPECT().FilterList(hostVeth, uint32(netlink.HANDLE_MIN_INGRESS)).Return([]netlink.Filter{&netlink.BpfFilter{Name: bpfrouting.ProgramName}}, nil)
+	assert.NoError(t, n.checkIPBasedContainerRouteRules(hostVeth, containerAddr, 4))
+
+	// The rule of another route table does not match
+	netLink.EXPECT().FilterList(hostVeth, uint32(netlink.HANDLE_MIN_INGRESS)).Return([]netlink.Filter{&netlink.BpfFilter{Name: bpfrouting.ProgramName}}, nil)
+	assert.EqualError(t, n.checkIPBasedContainerRouteRules(hostVeth, containerAddr, 3), "mark rule is missing, rtTable=3")
+
+	// Without the program, the fromContainer rule is missing
+	netLink.EXPECT().FilterList(hostVeth, uint32(netlink.HANDLE_MIN_INGRESS)).Return(nil, nil)
+	assert.EqualError(t, n.checkIPBasedContainerRouteRules(hostVeth, containerAddr, 4),
+		"fromContainer rule is missing, containerAddr=192.168.100.42/32, rtTable=4")
+}
+
 func Test_linuxNetwork_setupVeth(t *testing.T) {
 	hostVethWithIndex9 := &netlink.Veth{
 		LinkAttrs: netlink.LinkAttrs{
@@ -3109,7 +3592,7 @@ func Test_linuxNetwork_setupVeth(t *testing.T) {
 				ns:      ns,
 				procSys: procSys,
 			}
-			got, err := n.setupVeth(tt.args.hostVethName, tt.args.contVethName, tt.args.netnsPath, nil, nil, tt.args.mtu, testLogger)
+			got, err := n.setupVeth(tt.args.hostVethName, tt.args.contVethName, tt.args.netnsPath, nil, nil, tt.args.mtu, false, testLogger)
 			if tt.wantErr != nil {
 				assert.EqualError(t, err, tt.wantErr.Error())
 			} else {
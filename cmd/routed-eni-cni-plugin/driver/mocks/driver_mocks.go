@@ -50,6 +50,76 @@ func (m *MockNetworkAPIs) EXPECT() *MockNetworkAPIsMockRecorder {
 	return m.recorder
 }
 
+// CheckBranchENIPodNetwork mocks base method.
+func (m *MockNetworkAPIs) CheckBranchENIPodNetwork(arg0, arg1, arg2 string, arg3, arg4 *net.IPNet, arg5 int, arg6 sgpp.EnforcingMode, arg7 logger.Logger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckBranchENIPodNetwork", arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckBranchENIPodNetwork indicates an expected call of CheckBranchENIPodNetwork.
+func (mr *MockNetworkAPIsMockRecorder) CheckBranchENIPodNetwork(arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckBranchENIPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).CheckBranchENIPodNetwork), arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+}
+
+// CheckPassthroughPodNetwork mocks base method.
+func (m *MockNetworkAPIs) CheckPassthroughPodNetwork(arg0, arg1 string, arg2 *net.IPNet, arg3 logger.Logger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckPassthroughPodNetwork", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckPassthroughPodNetwork indicates an expected call of CheckPassthroughPodNetwork.
+func (mr *MockNetworkAPIsMockRecorder) CheckPassthroughPodNetwork(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckPassthroughPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).CheckPassthroughPodNetwork), arg0, arg1, arg2, arg3)
+}
+
+// CheckPodNetwork mocks base method.
+func (m *MockNetworkAPIs) CheckPodNetwork(arg0, arg1, arg2 string, arg3, arg4 *net.IPNet, arg5 int, arg6 logger.Logger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckPodNetwork", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckPodNetwork indicates an expected call of CheckPodNetwork.
+func (mr *MockNetworkAPIsMockRecorder) CheckPodNetwork(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).CheckPodNetwork), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+}
+
+// CheckSecondaryPodNetwork mocks base method.
+func (m *MockNetworkAPIs) CheckSecondaryPodNetwork(arg0, arg1, arg2 string, arg3, arg4 *net.IPNet, arg5 int, arg6 logger.Logger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckSecondaryPodNetwork", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckSecondaryPodNetwork indicates an expected call of CheckSecondaryPodNetwork.
+func (mr *MockNetworkAPIsMockRecorder) CheckSecondaryPodNetwork(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckSecondaryPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).CheckSecondaryPodNetwork), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+}
+
+// SetupBPFRoutedPodNetwork mocks base method.
+func (m *MockNetworkAPIs) SetupBPFRoutedPodNetwork(arg0, arg1, arg2 string, arg3 *net.IPNet, arg4, arg5 int, arg6 logger.Logger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetupBPFRoutedPodNetwork", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetupBPFRoutedPodNetwork indicates an expected call of SetupBPFRoutedPodNetwork.
+func (mr *MockNetworkAPIsMockRecorder) SetupBPFRoutedPodNetwork(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetupBPFRoutedPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).SetupBPFRoutedPodNetwork), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+}
+
 // SetupBranchENIPodNetwork mocks base method.
 func (m *MockNetworkAPIs) SetupBranchENIPodNetwork(arg0, arg1, arg2 string, arg3, arg4 *net.IPNet, arg5 int, arg6, arg7 string, arg8, arg9 int, arg10 sgpp.EnforcingMode, arg11 logger.Logger) error {
 	m.ctrl.T.Helper()
@@ -64,6 +134,20 @@ func (mr *MockNetworkAPIsMockRecorder) SetupBranchENIPodNetwork(arg0, arg1, arg2
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetupBranchENIPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).SetupBranchENIPodNetwork), arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11)
 }
 
+// SetupPassthroughPodNetwork mocks base method.
+func (m *MockNetworkAPIs) SetupPassthroughPodNetwork(arg0, arg1 string, arg2 *net.IPNet, arg3, arg4 string, arg5 int, arg6 logger.Logger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetupPassthroughPodNetwork", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetupPassthroughPodNetwork indicates an expected call of SetupPassthroughPodNetwork.
+func (mr *MockNetworkAPIsMockRecorder) SetupPassthroughPodNetwork(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetupPassthroughPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).SetupPassthroughPodNetwork), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+}
+
 // SetupPodNetwork mocks base method.
 func (m *MockNetworkAPIs) SetupPodNetwork(arg0, arg1, arg2 string, arg3, arg4 *net.IPNet, arg5, arg6 int, arg7 logger.Logger) error {
 	m.ctrl.T.Helper()
@@ -78,6 +162,20 @@ func (mr *MockNetworkAPIsMockRecorder) SetupPodNetwork(arg0, arg1, arg2, arg3, a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetupPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).SetupPodNetwork), arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
 }
 
+// SetupSecondaryPodNetwork mocks base method.
+func (m *MockNetworkAPIs) SetupSecondaryPodNetwork(arg0, arg1, arg2 string, arg3, arg4 *net.IPNet, arg5, arg6 int, arg7 logger.Logger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetupSecondaryPodNetwork", arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetupSecondaryPodNetwork indicates an expected call of SetupSecondaryPodNetwork.
+func (mr *MockNetworkAPIsMockRecorder) SetupSecondaryPodNetwork(arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetupSecondaryPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).SetupSecondaryPodNetwork), arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+}
+
 // TeardownBranchENIPodNetwork mocks base method.
 func (m *MockNetworkAPIs) TeardownBranchENIPodNetwork(arg0 *net.IPNet, arg1 int, arg2 sgpp.EnforcingMode, arg3 logger.Logger) error {
 	m.ctrl.T.Helper()
@@ -92,6 +190,20 @@ func (mr *MockNetworkAPIsMockRecorder) TeardownBranchENIPodNetwork(arg0, arg1, a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TeardownBranchENIPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).TeardownBranchENIPodNetwork), arg0, arg1, arg2, arg3)
 }
 
+// TeardownPassthroughPodNetwork mocks base method.
+func (m *MockNetworkAPIs) TeardownPassthroughPodNetwork(arg0, arg1 string, arg2 logger.Logger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TeardownPassthroughPodNetwork", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TeardownPassthroughPodNetwork indicates an expected call of TeardownPassthroughPodNetwork.
+func (mr *MockNetworkAPIsMockRecorder) TeardownPassthroughPodNetwork(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TeardownPassthroughPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).TeardownPassthroughPodNetwork), arg0, arg1, arg2)
+}
+
 // TeardownPodNetwork mocks base method.
 func (m *MockNetworkAPIs) TeardownPodNetwork(arg0 *net.IPNet, arg1 int, arg2 logger.Logger) error {
 	m.ctrl.T.Helper()
@@ -57,6 +57,10 @@ const (
 	eniClusterTagKey        = "cluster.k8s.amazonaws.com/name"
 	additionalEniTagsEnvVar = "ADDITIONAL_ENI_TAGS"
 	reservedTagKeyPrefix    = "k8s.amazonaws.com"
+
+	// ENIConfigTagKey is the tag key for the name of the ENIConfig an ENI was created for on behalf of pods
+	ENIConfigTagKey = "node.k8s.amazonaws.com/eniConfig"
+
 	// UnknownInstanceType indicates that the instance type is not yet supported
 	UnknownInstanceType = "vpc ip resource(eni ip limit): unknown instance type"
 
@@ -66,6 +70,13 @@ const (
 
 	// the default page size when paginating the DescribeNetworkInterfaces call
 	describeENIPageSize = 1000
+
+	// instanceLimitsOverrideFileEnvVar points to an operator supplied JSON file with limits for instance types that
+	// are missing from vpc_ip_resource_limit.go
+	instanceLimitsOverrideFileEnvVar = "INSTANCE_LIMITS_OVERRIDE_FILE"
+	// instanceLimitsCacheFileEnvVar points to the file where limits fetched from EC2 are cached on the node
+	instanceLimitsCacheFileEnvVar  = "INSTANCE_LIMITS_CACHE_FILE"
+	defaultInstanceLimitsCacheFile = "/var/run/aws-node/instance-limits.json"
 )
 
 var (
@@ -123,7 +134,7 @@ var (
 // APIs defines interfaces calls for adding/getting/deleting ENIs/secondary IPs. The APIs are not thread-safe.
 type APIs interface {
 	// AllocENI creates an ENI and attaches it to the instance
-	AllocENI(useCustomCfg bool, sg []*string, subnet string) (eni string, err error)
+	AllocENI(useCustomCfg bool, sg []*string, subnet string, eniConfigName string) (eni string, err error)
 
 	// FreeENI detaches ENI interface and deletes it
 	FreeENI(eniName string) error
@@ -152,6 +163,9 @@ type APIs interface {
 	// AllocIPAddresses allocates numIPs IP addresses on a ENI
 	AllocIPAddresses(eniID string, numIPs int) (*ec2.AssignPrivateIpAddressesOutput, error)
 
+	// AllocSecondaryIPAddresses allocates numIPs secondary IP addresses on a ENI, even if prefix delegation is enabled
+	AllocSecondaryIPAddresses(eniID string, numIPs int) (*ec2.AssignPrivateIpAddressesOutput, error)
+
 	// DeallocIPAddresses deallocates the list of IP addresses from a ENI
 	DeallocIPAddresses(eniID string, ips []string) error
 
@@ -161,9 +175,15 @@ type APIs interface {
 	//AllocIPv6Prefixes allocates IPv6 prefixes to the ENI passed in
 	AllocIPv6Prefixes(eniID string) ([]*string, error)
 
+	// DeallocIPv6Prefixes deallocates the list of IPv6 prefixes from a ENI
+	DeallocIPv6Prefixes(eniID string, prefixes []string) error
+
 	// GetVPCIPv4CIDRs returns VPC's IPv4 CIDRs from instance metadata
 	GetVPCIPv4CIDRs() ([]string, error)
 
+	// DescribeVPCIPv4CIDRs returns the IPv4 CIDRs that are associated with the VPC according to EC2
+	DescribeVPCIPv4CIDRs() ([]string, error)
+
 	// GetLocalIPv4 returns the primary IPv4 address on the primary ENI interface
 	GetLocalIPv4() net.IP
 
@@ -179,6 +199,9 @@ type APIs interface {
 	// GetENILimit returns the number of ENIs that can be attached to an instance
 	GetENILimit() int
 
+	// GetNetworkCards returns the network cards of the instance type and the number of ENIs each of them supports
+	GetNetworkCards() []vpc.NetworkCard
+
 	// GetPrimaryENImac returns the mac address of the primary ENI
 	GetPrimaryENImac() string
 
@@ -215,7 +238,14 @@ type APIs interface {
 	// GetInstanceID returns the instance ID
 	GetInstanceID() string
 
-	// FetchInstanceTypeLimits Verify if the InstanceNetworkingLimits has the ENI limits else make EC2 call to fill cache.
+	// GetAvailabilityZone returns the Availability Zone of the instance
+	GetAvailabilityZone() string
+
+	// GetSubnetAvailabilityZone returns the Availability Zone of a subnet
+	GetSubnetAvailabilityZone(subnetID string) (string, error)
+
+	// FetchInstanceTypeLimits Verify if the InstanceNetworkingLimits has the ENI limits else load them from the override or
+	// cache file, or make EC2 call to fill cache.
 	FetchInstanceTypeLimits() error
 
 	IsPrefixDelegationSupported() bool
@@ -226,6 +256,7 @@ type EC2InstanceMetadataCache struct {
 	// metadata info
 	securityGroups   StringSet
 	subnetID         string
+	vpcID            string
 	localIPv4        net.IP
 	v4Enabled        bool
 	v6Enabled        bool
@@ -240,10 +271,18 @@ type EC2InstanceMetadataCache struct {
 	useCustomNetworking    bool
 	cniunmanagedENIs       StringSet
 	enablePrefixDelegation bool
+	enableMultiNetworkCard bool
 
 	clusterName       string
 	additionalENITags map[string]string
 
+	instanceLimitsOverrideFile string
+	instanceLimitsCacheFile    string
+
+	// subnetAZs caches the Availability Zones of subnets, which never change
+	subnetAZs     map[string]string
+	subnetAZsLock sync.Mutex
+
 	imds   TypedIMDS
 	ec2SVC ec2wrapper.EC2
 }
@@ -259,6 +298,9 @@ type ENIMetadata struct {
 	// DeviceNumber is the  device number of network interface
 	DeviceNumber int // 0 means it is primary interface
 
+	// NetworkCard is the index of the network card the interface is attached to
+	NetworkCard int
+
 	// SubnetIPv4CIDR is the IPv4 CIDR of network interface
 	SubnetIPv4CIDR string
 
@@ -276,6 +318,9 @@ type ENIMetadata struct {
 
 	// IPv6 Prefixes allocated for the network interface
 	IPv6Prefixes []*ec2.Ipv6PrefixSpecification
+
+	// ENIConfigName is the name of the ENIConfig the network interface was created for on behalf of pods
+	ENIConfigName string
 }
 
 // PrimaryIPv4Address returns the primary IPv4 address of this node
@@ -383,7 +428,7 @@ func (i instrumentedIMDS) GetMetadataWithContext(ctx context.Context, p string)
 }
 
 // New creates an EC2InstanceMetadataCache
-func New(useCustomNetworking, disableLeakedENICleanup, v4Enabled, v6Enabled bool) (*EC2InstanceMetadataCache, error) {
+func New(useCustomNetworking, disableLeakedENICleanup, v4Enabled, v6Enabled, enableMultiNetworkCard bool) (*EC2InstanceMetadataCache, error) {
 	// ctx is passed to initWithEC2Metadata func to cancel spawned go-routines when tests are run
 	ctx := context.Background()
 
@@ -396,6 +441,8 @@ func New(useCustomNetworking, disableLeakedENICleanup, v4Enabled, v6Enabled bool
 	cache.imds = TypedIMDS{instrumentedIMDS{ec2Metadata}}
 	cache.clusterName = os.Getenv(clusterNameEnvVar)
 	cache.additionalENITags = loadAdditionalENITags()
+	cache.instanceLimitsOverrideFile = os.Getenv(instanceLimitsOverrideFileEnvVar)
+	cache.instanceLimitsCacheFile = instanceLimitsCacheFile()
 
 	region, err := ec2Metadata.Region()
 	if err != nil {
@@ -408,6 +455,8 @@ func New(useCustomNetworking, disableLeakedENICleanup, v4Enabled, v6Enabled bool
 	log.Infof("Custom networking enabled %v", cache.useCustomNetworking)
 	cache.v4Enabled = v4Enabled
 	cache.v6Enabled = v6Enabled
+	cache.enableMultiNetworkCard = enableMultiNetworkCard
+	log.Infof("Multiple network cards enabled %v", cache.enableMultiNetworkCard)
 
 	awsCfg := aws.NewConfig().WithRegion(region)
 	sess = sess.Copy(awsCfg)
@@ -627,7 +676,16 @@ func (cache *EC2InstanceMetadataCache) getENIMetadata(eniMAC string) (ENIMetadat
 		deviceNum = 0
 	}
 
-	log.Debugf("Found ENI: %s, MAC %s, device %d", eniID, eniMAC, deviceNum)
+	networkCard := 0
+	if cache.enableMultiNetworkCard {
+		networkCard, err = cache.imds.GetNetworkCard(ctx, eniMAC)
+		if err != nil {
+			awsAPIErrInc("GetNetworkCard", err)
+			return ENIMetadata{}, err
+		}
+	}
+
+	log.Debugf("Found ENI: %s, MAC %s, device %d, network card %d", eniID, eniMAC, deviceNum, networkCard)
 
 	cidr, err := cache.imds.GetSubnetIPv4CIDRBlock(ctx, eniMAC)
 	if err != nil {
@@ -653,7 +711,7 @@ func (cache *EC2InstanceMetadataCache) getENIMetadata(eniMAC string) (ENIMetadat
 	var ec2ipv4Prefixes []*ec2.Ipv4PrefixSpecification
 	var ec2ipv6Prefixes []*ec2.Ipv6PrefixSpecification
 
-	// If IPv6 is enabled, get attached v6 prefixes.
+	// If IPv6 is enabled, get attached v6 prefixes. In dual-stack mode, both v4 and v6 prefixes are needed.
 	if cache.v6Enabled {
 		imdsIPv6Prefixes, err := cache.imds.GetIPv6Prefixes(ctx, eniMAC)
 		if err != nil {
@@ -665,7 +723,8 @@ func (cache *EC2InstanceMetadataCache) getENIMetadata(eniMAC string) (ENIMetadat
 				Ipv6Prefix: aws.String(ipv6prefix.String()),
 			})
 		}
-	} else if cache.v4Enabled && ((eniMAC == primaryMAC && !cache.useCustomNetworking) || (eniMAC != primaryMAC)) {
+	}
+	if cache.v4Enabled && ((eniMAC == primaryMAC && !cache.useCustomNetworking) || (eniMAC != primaryMAC)) {
 		// Get prefix on primary ENI when custom networking is enabled is not needed.
 		// If primary ENI has prefixes attached and then we move to custom networking, we don't need to fetch
 		// the prefix since recommendation is to terminate the nodes and that would have deleted the prefix on the
@@ -686,6 +745,7 @@ func (cache *EC2InstanceMetadataCache) getENIMetadata(eniMAC string) (ENIMetadat
 		ENIID:          eniID,
 		MAC:            eniMAC,
 		DeviceNumber:   deviceNum,
+		NetworkCard:    networkCard,
 		SubnetIPv4CIDR: cidr.String(),
 		IPv4Addresses:  ec2ip4s,
 		IPv4Prefixes:   ec2ipv4Prefixes,
@@ -693,8 +753,11 @@ func (cache *EC2InstanceMetadataCache) getENIMetadata(eniMAC string) (ENIMetadat
 	}, nil
 }
 
-// awsGetFreeDeviceNumber calls EC2 API DescribeInstances to get the next free device index
-func (cache *EC2InstanceMetadataCache) awsGetFreeDeviceNumber() (int, error) {
+// awsGetFreeDeviceNumber calls EC2 API DescribeInstances to get the next free device index and the network card to
+// attach the next ENI to. Device numbers are kept unique across all network cards, since they determine the route
+// table of the ENI on the host. When multiple network cards are enabled, the card with the most free ENI slots is
+// chosen so that ENIs are spread across the cards.
+func (cache *EC2InstanceMetadataCache) awsGetFreeDeviceNumber() (deviceNumber int, networkCard int, err error) {
 	input := &ec2.DescribeInstancesInput{
 		InstanceIds: []*string{aws.String(cache.instanceID)},
 	}
@@ -708,17 +771,19 @@ func (cache *EC2InstanceMetadataCache) awsGetFreeDeviceNumber() (int, error) {
 		awsAPIErrInc("DescribeInstances", err)
 		ec2ApiErr.WithLabelValues("DescribeInstances").Inc()
 		log.Errorf("awsGetFreeDeviceNumber: Unable to retrieve instance data from EC2 control plane %v", err)
-		return 0, errors.Wrap(err,
+		return 0, 0, errors.Wrap(err,
 			"find a free device number for ENI: not able to retrieve instance data from EC2 control plane")
 	}
 
 	if len(result.Reservations) != 1 {
-		return 0, errors.Errorf("awsGetFreeDeviceNumber: invalid instance id %s", cache.instanceID)
+		return 0, 0, errors.Errorf("awsGetFreeDeviceNumber: invalid instance id %s", cache.instanceID)
 	}
 
 	inst := result.Reservations[0].Instances[0]
 	var device [maxENIs]bool
+	enisPerCard := make(map[int]int)
 	for _, eni := range inst.NetworkInterfaces {
+		enisPerCard[int(aws.Int64Value(eni.Attachment.NetworkCardIndex))]++
 		if aws.Int64Value(eni.Attachment.DeviceIndex) > maxENIs {
 			log.Warnf("The Device Index %d of the attached ENI %s > instance max slot %d",
 				aws.Int64Value(eni.Attachment.DeviceIndex), aws.StringValue(eni.NetworkInterfaceId),
@@ -729,19 +794,54 @@ func (cache *EC2InstanceMetadataCache) awsGetFreeDeviceNumber() (int, error) {
 		}
 	}
 
+	networkCard, err = cache.freeNetworkCard(enisPerCard)
+	if err != nil {
+		return 0, 0, err
+	}
+
 	for freeDeviceIndex := 0; freeDeviceIndex < maxENIs; freeDeviceIndex++ {
 		if !device[freeDeviceIndex] {
-			log.Debugf("Found a free device number: %d", freeDeviceIndex)
-			return freeDeviceIndex, nil
+			log.Debugf("Found a free device number: %d on network card %d", freeDeviceIndex, networkCard)
+			return freeDeviceIndex, networkCard, nil
+		}
+	}
+	return 0, 0, errors.New("awsGetFreeDeviceNumber: no available device number")
+}
+
+// freeNetworkCard returns the network card the next ENI should be attached to, given the number of ENIs already
+// attached to each card. Without multiple network cards enabled this is always the default network card.
+func (cache *EC2InstanceMetadataCache) freeNetworkCard(enisPerCard map[int]int) (int, error) {
+	defaultCard, err := vpc.GetDefaultNetworkCardIndex(cache.instanceType)
+	if err != nil {
+		return 0, nil
+	}
+	if !cache.enableMultiNetworkCard {
+		return defaultCard, nil
+	}
+	networkCards, err := vpc.GetNetworkCards(cache.instanceType)
+	if err != nil {
+		return defaultCard, nil
+	}
+
+	bestCard, bestFree := -1, 0
+	for _, card := range networkCards {
+		index := int(card.NetworkCardIndex)
+		free := int(card.MaximumNetworkInterfaces) - enisPerCard[index]
+		// Prefer the default card on ties, then the lowest index
+		if free > bestFree || (free == bestFree && free > 0 && index == defaultCard) {
+			bestCard, bestFree = index, free
 		}
 	}
-	return 0, errors.New("awsGetFreeDeviceNumber: no available device number")
+	if bestCard < 0 {
+		return 0, errors.New("awsGetFreeDeviceNumber: no network card with a free ENI slot")
+	}
+	return bestCard, nil
 }
 
 // AllocENI creates an ENI and attaches it to the instance
 // returns: newly created ENI ID
-func (cache *EC2InstanceMetadataCache) AllocENI(useCustomCfg bool, sg []*string, subnet string) (string, error) {
-	eniID, err := cache.createENI(useCustomCfg, sg, subnet)
+func (cache *EC2InstanceMetadataCache) AllocENI(useCustomCfg bool, sg []*string, subnet string, eniConfigName string) (string, error) {
+	eniID, err := cache.createENI(useCustomCfg, sg, subnet, eniConfigName)
 	if err != nil {
 		return "", errors.Wrap(err, "AllocENI: failed to create ENI")
 	}
@@ -787,7 +887,7 @@ func (cache *EC2InstanceMetadataCache) AllocENI(useCustomCfg bool, sg []*string,
 // attachENI calls EC2 API to attach the ENI and returns the attachment id
 func (cache *EC2InstanceMetadataCache) attachENI(eniID string) (string, error) {
 	// attach to instance
-	freeDevice, err := cache.awsGetFreeDeviceNumber()
+	freeDevice, networkCard, err := cache.awsGetFreeDeviceNumber()
 	if err != nil {
 		return "", errors.Wrap(err, "attachENI: failed to get a free device number")
 	}
@@ -797,6 +897,9 @@ func (cache *EC2InstanceMetadataCache) attachENI(eniID string) (string, error) {
 		InstanceId:         aws.String(cache.instanceID),
 		NetworkInterfaceId: aws.String(eniID),
 	}
+	if cache.enableMultiNetworkCard {
+		attachInput.NetworkCardIndex = aws.Int64(int64(networkCard))
+	}
 	start := time.Now()
 	attachOutput, err := cache.ec2SVC.AttachNetworkInterfaceWithContext(context.Background(), attachInput)
 	ec2ApiReq.WithLabelValues("AttachNetworkInterface").Inc()
@@ -812,7 +915,7 @@ func (cache *EC2InstanceMetadataCache) attachENI(eniID string) (string, error) {
 }
 
 // return ENI id, error
-func (cache *EC2InstanceMetadataCache) createENI(useCustomCfg bool, sg []*string, subnet string) (string, error) {
+func (cache *EC2InstanceMetadataCache) createENI(useCustomCfg bool, sg []*string, subnet string, eniConfigName string) (string, error) {
 	eniDescription := eniDescriptionPrefix + cache.instanceID
 	tags := map[string]string{
 		eniCreatedAtTagKey: time.Now().Format(time.RFC3339),
@@ -820,6 +923,10 @@ func (cache *EC2InstanceMetadataCache) createENI(useCustomCfg bool, sg []*string
 	for key, value := range cache.buildENITags() {
 		tags[key] = value
 	}
+	// An ENI that only serves pods of one ENIConfig keeps the name in a tag, so that ipamd finds it after a restart
+	if eniConfigName != "" {
+		tags[ENIConfigTagKey] = eniConfigName
+	}
 	tagSpec := []*ec2.TagSpecification{
 		{
 			ResourceType: aws.String(ec2.ResourceTypeNetworkInterface),
@@ -1205,12 +1312,6 @@ func (cache *EC2InstanceMetadataCache) DescribeAllENIs() (DescribeAllENIsResult,
 		return DescribeAllENIsResult{}, err
 	}
 
-	// Collect the verified ENIs
-	var verifiedENIs []ENIMetadata
-	for _, eniMetadata := range eniMap {
-		verifiedENIs = append(verifiedENIs, eniMetadata)
-	}
-
 	// Collect ENI response into ENI metadata and tags.
 	var trunkENI string
 	var multiCardENIIDs []string
@@ -1225,14 +1326,14 @@ func (cache *EC2InstanceMetadataCache) DescribeAllENIs() (DescribeAllENIsResult,
 			if aws.Int64Value(attachment.DeviceIndex) == 0 && !aws.BoolValue(attachment.DeleteOnTermination) {
 				log.Warn("Primary ENI will not get deleted when node terminates because 'delete_on_termination' is set to false")
 			}
-			if aws.Int64Value(attachment.NetworkCardIndex) > 0 {
+			if aws.Int64Value(attachment.NetworkCardIndex) > 0 && !cache.isManagedMultiCardENI(ec2res) {
 				multiCardENIIDs = append(multiCardENIIDs, eniID)
 			}
 		} else {
 			log.Infof("Got empty attachment for ENI %v", eniID)
 		}
 
-		eniMetadata := eniMap[eniID]
+		eniMetadata, found := eniMap[eniID]
 		interfaceType := aws.StringValue(ec2res.InterfaceType)
 		log.Infof("%s is of type: %s", eniID, interfaceType)
 
@@ -1246,6 +1347,16 @@ func (cache *EC2InstanceMetadataCache) DescribeAllENIs() (DescribeAllENIsResult,
 		// Check IPv4 addresses
 		logOutOfSyncState(eniID, eniMetadata.IPv4Addresses, ec2res.PrivateIpAddresses)
 		tagMap[eniMetadata.ENIID] = convertSDKTagsToTags(ec2res.TagSet)
+		if eniConfigName, ok := tagMap[eniMetadata.ENIID][ENIConfigTagKey]; found && ok {
+			eniMetadata.ENIConfigName = eniConfigName
+			eniMap[eniID] = eniMetadata
+		}
+	}
+
+	// Collect the verified ENIs
+	var verifiedENIs []ENIMetadata
+	for _, eniMetadata := range eniMap {
+		verifiedENIs = append(verifiedENIs, eniMetadata)
 	}
 	return DescribeAllENIsResult{
 		ENIMetadata:     verifiedENIs,
@@ -1388,6 +1499,16 @@ func (cache *EC2InstanceMetadataCache) FetchInstanceTypeLimits() error {
 		return nil
 	}
 
+	// Operator supplied limits take precedence over anything cached or fetched from EC2
+	if cache.loadInstanceTypeLimitsFromFile(cache.instanceLimitsOverrideFile) {
+		log.Infof("Using instance type limits for %s from override file %s", cache.instanceType, cache.instanceLimitsOverrideFile)
+		return nil
+	}
+	if cache.loadInstanceTypeLimitsFromFile(cache.instanceLimitsCacheFile) {
+		log.Infof("Using instance type limits for %s from cache file %s", cache.instanceType, cache.instanceLimitsCacheFile)
+		return nil
+	}
+
 	log.Debugf("Instance type limits are missing from vpc_ip_limits.go hence making an EC2 call to fetch the limits")
 	describeInstanceTypesInput := &ec2.DescribeInstanceTypesInput{InstanceTypes: []*string{aws.String(cache.instanceType)}}
 	output, err := cache.ec2SVC.DescribeInstanceTypesWithContext(context.Background(), describeInstanceTypesInput)
@@ -1401,18 +1522,23 @@ func (cache *EC2InstanceMetadataCache) FetchInstanceTypeLimits() error {
 	// Ignore any missing values
 	instanceType := aws.StringValue(info.InstanceType)
 	eniLimit := int(aws.Int64Value(info.NetworkInfo.MaximumNetworkInterfaces))
+	// Match scripts/gen_vpc_ip_limits.go and use the limit of the default card when more than one card is present
+	if len(info.NetworkInfo.NetworkCards) > 1 {
+		defaultCardIndex := aws.Int64Value(info.NetworkInfo.DefaultNetworkCardIndex)
+		eniLimit = int(aws.Int64Value(info.NetworkInfo.NetworkCards[defaultCardIndex].MaximumNetworkInterfaces))
+	}
 	ipv4Limit := int(aws.Int64Value(info.NetworkInfo.Ipv4AddressesPerInterface))
 	isBareMetalInstance := aws.BoolValue(info.BareMetal)
 	hypervisorType := aws.StringValue(info.Hypervisor)
 	if hypervisorType == "" {
 		hypervisorType = "unknown"
 	}
-	networkCards := make([]vpc.NetworkCard, aws.Int64Value(info.NetworkInfo.MaximumNetworkCards))
+	networkCards := make([]vpc.NetworkCard, len(info.NetworkInfo.NetworkCards))
 	defaultNetworkCardIndex := int(aws.Int64Value(info.NetworkInfo.DefaultNetworkCardIndex))
 	for idx := 0; idx < len(networkCards); idx += 1 {
 		networkCards[idx] = vpc.NetworkCard{
-			MaximumNetworkInterfaces: *info.NetworkInfo.NetworkCards[idx].MaximumNetworkInterfaces,
-			NetworkCardIndex:         *info.NetworkInfo.NetworkCards[idx].NetworkCardIndex,
+			MaximumNetworkInterfaces: aws.Int64Value(info.NetworkInfo.NetworkCards[idx].MaximumNetworkInterfaces),
+			NetworkCardIndex:         aws.Int64Value(info.NetworkInfo.NetworkCards[idx].NetworkCardIndex),
 		}
 	}
 	//Not checking for empty hypervisorType since have seen certain instances not getting this filled.
@@ -1421,9 +1547,59 @@ func (cache *EC2InstanceMetadataCache) FetchInstanceTypeLimits() error {
 	} else {
 		return errors.New(fmt.Sprintf("%s: %s", UnknownInstanceType, cache.instanceType))
 	}
+	cache.saveInstanceTypeLimitsToCache(instanceType)
 	return nil
 }
 
+// loadInstanceTypeLimitsFromFile returns true if valid limits for the instance type were found in the given file
+func (cache *EC2InstanceMetadataCache) loadInstanceTypeLimitsFromFile(path string) bool {
+	if path == "" {
+		return false
+	}
+	found, err := vpc.LoadInstanceFromFile(path, cache.instanceType)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnf("Failed to load instance type limits from %s: %v", path, err)
+		}
+		return false
+	}
+	return found
+}
+
+// saveInstanceTypeLimitsToCache persists limits fetched from EC2, so that the next ipamd start on this node does not
+// depend on DescribeInstanceTypes. Failures are logged and otherwise ignored.
+func (cache *EC2InstanceMetadataCache) saveInstanceTypeLimitsToCache(instanceType string) {
+	if cache.instanceLimitsCacheFile == "" {
+		return
+	}
+	instance, ok := vpc.GetInstance(instanceType)
+	if !ok {
+		return
+	}
+	limits, err := vpc.LoadLimitsFile(cache.instanceLimitsCacheFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnf("Overwriting unreadable instance limits cache %s: %v", cache.instanceLimitsCacheFile, err)
+		}
+		limits = make(map[string]vpc.InstanceTypeLimits)
+	}
+	limits[instanceType] = instance
+	if err := vpc.SaveLimitsFile(cache.instanceLimitsCacheFile, limits); err != nil {
+		log.Warnf("Failed to cache instance type limits for %s in %s: %v", instanceType, cache.instanceLimitsCacheFile, err)
+		return
+	}
+	log.Infof("Cached instance type limits for %s in %s", instanceType, cache.instanceLimitsCacheFile)
+}
+
+// instanceLimitsCacheFile returns the path of the on-node instance limits cache. Setting the env var to an empty
+// string disables the cache.
+func instanceLimitsCacheFile() string {
+	if value, found := os.LookupEnv(instanceLimitsCacheFileEnvVar); found {
+		return value
+	}
+	return defaultInstanceLimitsCacheFile
+}
+
 // GetENIIPv4Limit return IP address limit per ENI based on EC2 instance type
 func (cache *EC2InstanceMetadataCache) GetENIIPv4Limit() int {
 	ipv4Limit, err := vpc.GetIPv4Limit(cache.instanceType)
@@ -1443,6 +1619,29 @@ func (cache *EC2InstanceMetadataCache) GetENILimit() int {
 	return eniLimit
 }
 
+// GetNetworkCards returns the network cards of the instance type and the number of ENIs each of them supports
+func (cache *EC2InstanceMetadataCache) GetNetworkCards() []vpc.NetworkCard {
+	networkCards, err := vpc.GetNetworkCards(cache.instanceType)
+	if err != nil {
+		return nil
+	}
+	return networkCards
+}
+
+// isManagedMultiCardENI returns whether an ENI attached to a non-default network card was created by ipamd on this
+// node with multiple network cards enabled. Other ENIs on those cards, such as EFA interfaces, are left alone.
+func (cache *EC2InstanceMetadataCache) isManagedMultiCardENI(eni *ec2.NetworkInterface) bool {
+	if !cache.enableMultiNetworkCard {
+		return false
+	}
+	for _, tag := range eni.TagSet {
+		if aws.StringValue(tag.Key) == eniNodeTagKey && aws.StringValue(tag.Value) == cache.instanceID {
+			return true
+		}
+	}
+	return false
+}
+
 // GetInstanceHypervisorFamily returns hypervisor of EC2 instance type
 func (cache *EC2InstanceMetadataCache) GetInstanceHypervisorFamily() string {
 	hypervisor, err := vpc.GetHypervisorType(cache.instanceType)
@@ -1480,6 +1679,16 @@ func (cache *EC2InstanceMetadataCache) IsPrefixDelegationSupported() bool {
 
 // AllocIPAddresses allocates numIPs of IP address on an ENI
 func (cache *EC2InstanceMetadataCache) AllocIPAddresses(eniID string, numIPs int) (*ec2.AssignPrivateIpAddressesOutput, error) {
+	return cache.allocIPAddresses(eniID, numIPs, cache.enablePrefixDelegation)
+}
+
+// AllocSecondaryIPAddresses allocates numIPs of secondary IP addresses on an ENI. With prefix delegation enabled, it is
+// used when the subnet is too fragmented to carve out another /28 prefix.
+func (cache *EC2InstanceMetadataCache) AllocSecondaryIPAddresses(eniID string, numIPs int) (*ec2.AssignPrivateIpAddressesOutput, error) {
+	return cache.allocIPAddresses(eniID, numIPs, false)
+}
+
+func (cache *EC2InstanceMetadataCache) allocIPAddresses(eniID string, numIPs int, usePrefixes bool) (*ec2.AssignPrivateIpAddressesOutput, error) {
 	var needIPs = numIPs
 
 	ipLimit := cache.GetENIIPv4Limit()
@@ -1494,10 +1703,10 @@ func (cache *EC2InstanceMetadataCache) AllocIPAddresses(eniID string, numIPs int
 	}
 
 	log.Infof("Trying to allocate %d IP addresses on ENI %s", needIPs, eniID)
-	log.Debugf("PD enabled - %t", cache.enablePrefixDelegation)
+	log.Debugf("PD enabled - %t, use prefixes - %t", cache.enablePrefixDelegation, usePrefixes)
 	input := &ec2.AssignPrivateIpAddressesInput{}
 
-	if cache.enablePrefixDelegation {
+	if usePrefixes {
 		needPrefixes := needIPs
 		input = &ec2.AssignPrivateIpAddressesInput{
 			NetworkInterfaceId: aws.String(eniID),
@@ -1523,7 +1732,7 @@ func (cache *EC2InstanceMetadataCache) AllocIPAddresses(eniID string, numIPs int
 		return nil, err
 	}
 	if output != nil {
-		if cache.enablePrefixDelegation {
+		if usePrefixes {
 			log.Infof("Allocated %d private IP prefixes", len(output.AssignedIpv4Prefixes))
 		} else {
 			log.Infof("Allocated %d private IP addresses", len(output.AssignedPrivateIpAddresses))
@@ -1580,7 +1789,8 @@ func (cache *EC2InstanceMetadataCache) waitForENIAndIPsAttached(eni string, want
 					returnedENI.IPv4Addresses, returnedENI.IPv4Prefixes, returnedENI.IPv6Prefixes)
 				if cache.enablePrefixDelegation {
 					eniIPCount = len(returnedENI.IPv4Prefixes)
-					if cache.v6Enabled {
+					// In dual-stack mode, ENIs are attached for the IPv4 pool
+					if cache.v6Enabled && !cache.v4Enabled {
 						eniIPCount = len(returnedENI.IPv6Prefixes)
 					}
 				} else {
@@ -1682,6 +1892,33 @@ func (cache *EC2InstanceMetadataCache) DeallocPrefixAddresses(eniID string, pref
 	return nil
 }
 
+// DeallocIPv6Prefixes frees the IPv6 prefixes of an ENI
+func (cache *EC2InstanceMetadataCache) DeallocIPv6Prefixes(eniID string, prefixes []string) error {
+	if len(prefixes) == 0 {
+		return nil
+	}
+	log.Infof("Trying to unassign the following IPv6 Prefixes %v from ENI %s", prefixes, eniID)
+
+	input := &ec2.UnassignIpv6AddressesInput{
+		NetworkInterfaceId: aws.String(eniID),
+		Ipv6Prefixes:       aws.StringSlice(prefixes),
+	}
+
+	start := time.Now()
+	_, err := cache.ec2SVC.UnassignIpv6AddressesWithContext(context.Background(), input)
+	ec2ApiReq.WithLabelValues("UnassignIpv6Addresses").Inc()
+	awsAPILatency.WithLabelValues("UnassignIpv6Addresses", fmt.Sprint(err != nil), awsReqStatus(err)).Observe(msSince(start))
+	if err != nil {
+		checkAPIErrorAndBroadcastEvent(err, "ec2:UnassignIpv6Addresses")
+		awsAPIErrInc("UnassignIpv6Addresses", err)
+		ec2ApiErr.WithLabelValues("UnassignIpv6Addresses").Inc()
+		log.Errorf("Failed to deallocate IPv6 Prefixes %v", err)
+		return errors.Wrap(err, fmt.Sprintf("deallocate IPv6 prefix: failed to deallocate IPv6 Prefixes: %v", prefixes))
+	}
+	log.Debugf("Successfully freed IPv6 Prefixes %v from ENI %s", prefixes, eniID)
+	return nil
+}
+
 func (cache *EC2InstanceMetadataCache) cleanUpLeakedENIs() {
 	cache.cleanUpLeakedENIsInternal(time.Duration(rand.Intn(eniCleanupStartupDelayMax)) * time.Second)
 }
@@ -1840,6 +2077,47 @@ func (cache *EC2InstanceMetadataCache) GetVPCIPv4CIDRs() ([]string, error) {
 	return asStrs, nil
 }
 
+// DescribeVPCIPv4CIDRs returns the IPv4 CIDRs of the VPC from EC2. Unlike instance metadata, EC2 reports a CIDR as
+// soon as it is disassociated, so only CIDRs in the associated state are returned.
+func (cache *EC2InstanceMetadataCache) DescribeVPCIPv4CIDRs() ([]string, error) {
+	ctx := context.TODO()
+
+	if cache.vpcID == "" {
+		vpcID, err := cache.imds.GetVpcID(ctx, cache.primaryENImac)
+		if err != nil {
+			awsAPIErrInc("GetVpcID", err)
+			return nil, err
+		}
+		cache.vpcID = vpcID
+	}
+
+	input := &ec2.DescribeVpcsInput{
+		VpcIds: []*string{aws.String(cache.vpcID)},
+	}
+	start := time.Now()
+	output, err := cache.ec2SVC.DescribeVpcsWithContext(ctx, input)
+	ec2ApiReq.WithLabelValues("DescribeVpcs").Inc()
+	awsAPILatency.WithLabelValues("DescribeVpcs", fmt.Sprint(err != nil), awsReqStatus(err)).Observe(msSince(start))
+	if err != nil {
+		checkAPIErrorAndBroadcastEvent(err, "ec2:DescribeVpcs")
+		awsAPIErrInc("DescribeVpcs", err)
+		ec2ApiErr.WithLabelValues("DescribeVpcs").Inc()
+		return nil, errors.Wrapf(err, "failed to describe VPC %s", cache.vpcID)
+	}
+	if len(output.Vpcs) != 1 {
+		return nil, errors.Errorf("expected one VPC with ID %s, found %d", cache.vpcID, len(output.Vpcs))
+	}
+
+	var cidrs []string
+	for _, assoc := range output.Vpcs[0].CidrBlockAssociationSet {
+		if assoc.CidrBlockState == nil || aws.StringValue(assoc.CidrBlockState.State) != ec2.VpcCidrBlockStateCodeAssociated {
+			continue
+		}
+		cidrs = append(cidrs, aws.StringValue(assoc.CidrBlock))
+	}
+	return cidrs, nil
+}
+
 // GetLocalIPv4 returns the primary IP address on the primary interface
 func (cache *EC2InstanceMetadataCache) GetLocalIPv4() net.IP {
 	return cache.localIPv4
@@ -1883,6 +2161,42 @@ func (cache *EC2InstanceMetadataCache) GetInstanceID() string {
 	return cache.instanceID
 }
 
+// GetAvailabilityZone returns the Availability Zone of the instance
+func (cache *EC2InstanceMetadataCache) GetAvailabilityZone() string {
+	return cache.availabilityZone
+}
+
+// GetSubnetAvailabilityZone returns the Availability Zone of a subnet. It calls EC2 once per subnet.
+func (cache *EC2InstanceMetadataCache) GetSubnetAvailabilityZone(subnetID string) (string, error) {
+	cache.subnetAZsLock.Lock()
+	defer cache.subnetAZsLock.Unlock()
+	if availabilityZone, ok := cache.subnetAZs[subnetID]; ok {
+		return availabilityZone, nil
+	}
+
+	input := &ec2.DescribeSubnetsInput{SubnetIds: []*string{aws.String(subnetID)}}
+	start := time.Now()
+	result, err := cache.ec2SVC.DescribeSubnetsWithContext(context.Background(), input)
+	ec2ApiReq.WithLabelValues("DescribeSubnets").Inc()
+	awsAPILatency.WithLabelValues("DescribeSubnets", fmt.Sprint(err != nil), awsReqStatus(err)).Observe(msSince(start))
+	if err != nil {
+		checkAPIErrorAndBroadcastEvent(err, "ec2:DescribeSubnets")
+		awsAPIErrInc("DescribeSubnets", err)
+		ec2ApiErr.WithLabelValues("DescribeSubnets").Inc()
+		return "", errors.Wrapf(err, "failed to describe subnet %s", subnetID)
+	}
+	if len(result.Subnets) == 0 {
+		return "", errors.Errorf("subnet %s not found", subnetID)
+	}
+
+	availabilityZone := aws.StringValue(result.Subnets[0].AvailabilityZone)
+	if cache.subnetAZs == nil {
+		cache.subnetAZs = make(map[string]string)
+	}
+	cache.subnetAZs[subnetID] = availabilityZone
+	return availabilityZone, nil
+}
+
 // IsUnmanagedENI returns if the eni is unmanaged
 func (cache *EC2InstanceMetadataCache) IsUnmanagedENI(eniID string) bool {
 	if len(eniID) != 0 {
@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"testing"
@@ -33,6 +34,7 @@ import (
 
 	mock_ec2wrapper "github.com/aws/amazon-vpc-cni-k8s/pkg/ec2wrapper/mocks"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/utils/eventrecorder"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/vpc"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -238,7 +240,7 @@ func TestAWSGetFreeDeviceNumberOnErr(t *testing.T) {
 	mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("error on DescribeInstancesWithContext"))
 
 	cache := &EC2InstanceMetadataCache{ec2SVC: mockEC2}
-	_, err := cache.awsGetFreeDeviceNumber()
+	_, _, err := cache.awsGetFreeDeviceNumber()
 	assert.Error(t, err)
 }
 
@@ -261,10 +263,37 @@ func TestAWSGetFreeDeviceNumberNoDevice(t *testing.T) {
 	mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(result, nil)
 
 	cache := &EC2InstanceMetadataCache{ec2SVC: mockEC2}
-	_, err := cache.awsGetFreeDeviceNumber()
+	_, _, err := cache.awsGetFreeDeviceNumber()
 	assert.Error(t, err)
 }
 
+func TestAWSGetFreeDeviceNumberMultiNetworkCard(t *testing.T) {
+	ctrl, mockEC2 := setup(t)
+	defer ctrl.Finish()
+
+	// Primary ENI on card 0 and one more ENI on card 1, so cards 2 and 3 have the most free slots
+	ec2ENIs := []*ec2.InstanceNetworkInterface{
+		{Attachment: &ec2.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int64(0), NetworkCardIndex: aws.Int64(0)}},
+		{Attachment: &ec2.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int64(1), NetworkCardIndex: aws.Int64(1)}},
+	}
+	result := &ec2.DescribeInstancesOutput{
+		Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{{NetworkInterfaces: ec2ENIs}}}}}
+	mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(result, nil).Times(2)
+
+	cache := &EC2InstanceMetadataCache{ec2SVC: mockEC2, instanceType: "p4d.24xlarge", enableMultiNetworkCard: true}
+	deviceNumber, networkCard, err := cache.awsGetFreeDeviceNumber()
+	assert.NoError(t, err)
+	// Device numbers stay unique across cards
+	assert.Equal(t, 2, deviceNumber)
+	assert.Equal(t, 2, networkCard)
+
+	cache.enableMultiNetworkCard = false
+	deviceNumber, networkCard, err = cache.awsGetFreeDeviceNumber()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deviceNumber)
+	assert.Equal(t, 0, networkCard)
+}
+
 func TestGetENIAttachmentID(t *testing.T) {
 	ctrl, mockEC2 := setup(t)
 	defer ctrl.Finish()
@@ -405,7 +434,7 @@ func TestAllocENI(t *testing.T) {
 		imds:   TypedIMDS{mockMetadata},
 	}
 
-	_, err := cache.AllocENI(false, nil, "")
+	_, err := cache.AllocENI(false, nil, "", "")
 	assert.NoError(t, err)
 }
 
@@ -439,7 +468,7 @@ func TestAllocENINoFreeDevice(t *testing.T) {
 		imds:   TypedIMDS{mockMetadata},
 	}
 
-	_, err := cache.AllocENI(false, nil, "")
+	_, err := cache.AllocENI(false, nil, "", "")
 	assert.Error(t, err)
 }
 
@@ -475,7 +504,7 @@ func TestAllocENIMaxReached(t *testing.T) {
 		imds:   TypedIMDS{mockMetadata},
 	}
 
-	_, err := cache.AllocENI(false, nil, "")
+	_, err := cache.AllocENI(false, nil, "", "")
 	assert.Error(t, err)
 }
 
@@ -582,6 +611,113 @@ func TestDescribeInstanceTypes(t *testing.T) {
 	assert.Equal(t, 98, pv4Limit)
 }
 
+func TestDescribeInstanceTypesWritesCache(t *testing.T) {
+	ctrl, mockEC2 := setup(t)
+	defer ctrl.Finish()
+	mockEC2.EXPECT().DescribeInstanceTypesWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(&ec2.DescribeInstanceTypesOutput{
+		InstanceTypes: []*ec2.InstanceTypeInfo{
+			{InstanceType: aws.String("cached-type"), NetworkInfo: &ec2.NetworkInfo{
+				MaximumNetworkInterfaces:  aws.Int64(4),
+				Ipv4AddressesPerInterface: aws.Int64(15)},
+			},
+		},
+	}, nil).Times(1)
+
+	cacheFile := filepath.Join(t.TempDir(), "instance-limits.json")
+	cache := &EC2InstanceMetadataCache{ec2SVC: mockEC2, instanceLimitsCacheFile: cacheFile}
+	cache.instanceType = "cached-type"
+	err := cache.FetchInstanceTypeLimits()
+	assert.NoError(t, err)
+
+	limits, err := vpc.LoadLimitsFile(cacheFile)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, limits["cached-type"].ENILimit)
+	assert.Equal(t, 15, limits["cached-type"].IPv4Limit)
+}
+
+func TestFetchInstanceTypeLimitsFromFiles(t *testing.T) {
+	ctrl, mockEC2 := setup(t)
+	defer ctrl.Finish()
+	// Neither lookup should reach EC2
+	mockEC2.EXPECT().DescribeInstanceTypesWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	dir := t.TempDir()
+	overrideFile := filepath.Join(dir, "override.json")
+	cacheFile := filepath.Join(dir, "cache.json")
+	err := vpc.SaveLimitsFile(overrideFile, map[string]vpc.InstanceTypeLimits{
+		"override-type": vpc.New(5, 20, 0, nil, "nitro", false),
+	})
+	assert.NoError(t, err)
+	err = vpc.SaveLimitsFile(cacheFile, map[string]vpc.InstanceTypeLimits{
+		"override-type": vpc.New(2, 2, 0, nil, "nitro", false),
+		"cached-only":   vpc.New(6, 10, 0, nil, "nitro", false),
+	})
+	assert.NoError(t, err)
+
+	cache := &EC2InstanceMetadataCache{ec2SVC: mockEC2, instanceLimitsOverrideFile: overrideFile, instanceLimitsCacheFile: cacheFile}
+	cache.instanceType = "override-type"
+	assert.NoError(t, cache.FetchInstanceTypeLimits())
+	assert.Equal(t, 5, cache.GetENILimit())
+	assert.Equal(t, 19, cache.GetENIIPv4Limit())
+
+	cache.instanceType = "cached-only"
+	assert.NoError(t, cache.FetchInstanceTypeLimits())
+	assert.Equal(t, 6, cache.GetENILimit())
+	assert.Equal(t, 9, cache.GetENIIPv4Limit())
+}
+
+func TestDescribeVPCIPv4CIDRs(t *testing.T) {
+	ctrl, mockEC2 := setup(t)
+	defer ctrl.Finish()
+
+	vpcID := "vpc-0d5b8c5c0a2f4e7b1"
+	mockMetadata := testMetadata(map[string]interface{}{
+		metadataMACPath + primaryMAC + "/vpc-id": vpcID,
+	})
+	mockEC2.EXPECT().DescribeVpcsWithContext(gomock.Any(), &ec2.DescribeVpcsInput{VpcIds: []*string{aws.String(vpcID)}}, gomock.Any()).Return(&ec2.DescribeVpcsOutput{
+		Vpcs: []*ec2.Vpc{{
+			VpcId: aws.String(vpcID),
+			CidrBlockAssociationSet: []*ec2.VpcCidrBlockAssociation{
+				{CidrBlock: aws.String("192.168.0.0/16"), CidrBlockState: &ec2.VpcCidrBlockState{State: aws.String(ec2.VpcCidrBlockStateCodeAssociated)}},
+				{CidrBlock: aws.String("100.64.0.0/16"), CidrBlockState: &ec2.VpcCidrBlockState{State: aws.String(ec2.VpcCidrBlockStateCodeDisassociating)}},
+				{CidrBlock: aws.String("100.66.0.0/16"), CidrBlockState: &ec2.VpcCidrBlockState{State: aws.String(ec2.VpcCidrBlockStateCodeAssociated)}},
+			},
+		}},
+	}, nil)
+
+	cache := &EC2InstanceMetadataCache{imds: TypedIMDS{mockMetadata}, ec2SVC: mockEC2, primaryENImac: primaryMAC}
+	cidrs, err := cache.DescribeVPCIPv4CIDRs()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"192.168.0.0/16", "100.66.0.0/16"}, cidrs)
+	assert.Equal(t, vpcID, cache.vpcID)
+
+	mockEC2.EXPECT().DescribeVpcsWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("throttled"))
+	_, err = cache.DescribeVPCIPv4CIDRs()
+	assert.Error(t, err)
+}
+
+func TestGetSubnetAvailabilityZone(t *testing.T) {
+	ctrl, mockEC2 := setup(t)
+	defer ctrl.Finish()
+
+	subnetID := "subnet-0a1b2c3d"
+	// The Availability Zone of a subnet is only looked up once
+	mockEC2.EXPECT().DescribeSubnetsWithContext(gomock.Any(), &ec2.DescribeSubnetsInput{SubnetIds: []*string{aws.String(subnetID)}}, gomock.Any()).Return(&ec2.DescribeSubnetsOutput{
+		Subnets: []*ec2.Subnet{{SubnetId: aws.String(subnetID), AvailabilityZone: aws.String("us-west-2a")}},
+	}, nil)
+
+	cache := &EC2InstanceMetadataCache{ec2SVC: mockEC2}
+	for i := 0; i < 2; i++ {
+		availabilityZone, err := cache.GetSubnetAvailabilityZone(subnetID)
+		assert.NoError(t, err)
+		assert.Equal(t, "us-west-2a", availabilityZone)
+	}
+
+	mockEC2.EXPECT().DescribeSubnetsWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(&ec2.DescribeSubnetsOutput{}, nil)
+	_, err := cache.GetSubnetAvailabilityZone("subnet-missing")
+	assert.Error(t, err)
+}
+
 func TestAllocIPAddress(t *testing.T) {
 	ctrl, mockEC2 := setup(t)
 	defer ctrl.Finish()
@@ -677,6 +813,22 @@ func TestAllocPrefixAddresses(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestAllocSecondaryIPAddressesWithPrefixDelegation(t *testing.T) {
+	ctrl, mockEC2 := setup(t)
+	defer ctrl.Finish()
+
+	// Secondary IPs are requested even though prefix delegation is enabled
+	input := &ec2.AssignPrivateIpAddressesInput{
+		NetworkInterfaceId:             aws.String(eniID),
+		SecondaryPrivateIpAddressCount: aws.Int64(5),
+	}
+	mockEC2.EXPECT().AssignPrivateIpAddressesWithContext(gomock.Any(), input, gomock.Any()).Return(nil, nil)
+
+	cache := &EC2InstanceMetadataCache{ec2SVC: mockEC2, instanceType: "c5n.18xlarge", enablePrefixDelegation: true}
+	_, err := cache.AllocSecondaryIPAddresses(eniID, 5)
+	assert.NoError(t, err)
+}
+
 func TestAllocPrefixesAlreadyFull(t *testing.T) {
 	ctrl, mockEC2 := setup(t)
 	defer ctrl.Finish()
@@ -172,6 +172,24 @@ func (imds TypedIMDS) GetDeviceNumber(ctx context.Context, mac string) (int, err
 	return imds.getInt(ctx, key)
 }
 
+// GetNetworkCard returns the index of the network card the interface is attached to. Instance types with a single
+// network card do not report it, in which case 0 is returned.
+func (imds TypedIMDS) GetNetworkCard(ctx context.Context, mac string) (int, error) {
+	key := fmt.Sprintf("network/interfaces/macs/%s/network-card", mac)
+	data, err := imds.GetMetadataWithContext(ctx, key)
+	if err != nil {
+		if imdsErr, ok := err.(*imdsRequestError); ok {
+			if IsNotFound(imdsErr.err) {
+				return 0, nil
+			}
+			log.Warnf("%v", err)
+			return 0, imdsErr.err
+		}
+		return 0, err
+	}
+	return strconv.Atoi(data)
+}
+
 // GetSubnetID returns the ID of the subnet in which the interface resides.
 func (imds TypedIMDS) GetSubnetID(ctx context.Context, mac string) (string, error) {
 	key := fmt.Sprintf("network/interfaces/macs/%s/subnet-id", mac)
@@ -186,6 +204,20 @@ func (imds TypedIMDS) GetSubnetID(ctx context.Context, mac string) (string, erro
 	return subnetID, err
 }
 
+// GetVpcID returns the ID of the VPC in which the interface resides.
+func (imds TypedIMDS) GetVpcID(ctx context.Context, mac string) (string, error) {
+	key := fmt.Sprintf("network/interfaces/macs/%s/vpc-id", mac)
+	vpcID, err := imds.GetMetadataWithContext(ctx, key)
+	if err != nil {
+		if imdsErr, ok := err.(*imdsRequestError); ok {
+			log.Warnf("%v", err)
+			return vpcID, imdsErr.err
+		}
+		return "", err
+	}
+	return vpcID, err
+}
+
 // GetSecurityGroupIDs returns the IDs of the security groups to which the network interface belongs.
 func (imds TypedIMDS) GetSecurityGroupIDs(ctx context.Context, mac string) ([]string, error) {
 	key := fmt.Sprintf("network/interfaces/macs/%s/security-group-ids", mac)
@@ -23,6 +23,7 @@ import (
 	reflect "reflect"
 
 	awsutils "github.com/aws/amazon-vpc-cni-k8s/pkg/awsutils"
+	vpc "github.com/aws/amazon-vpc-cni-k8s/pkg/vpc"
 	ec2 "github.com/aws/aws-sdk-go/service/ec2"
 	gomock "github.com/golang/mock/gomock"
 )
@@ -51,18 +52,18 @@ func (m *MockAPIs) EXPECT() *MockAPIsMockRecorder {
 }
 
 // AllocENI mocks base method
-func (m *MockAPIs) AllocENI(arg0 bool, arg1 []*string, arg2 string) (string, error) {
+func (m *MockAPIs) AllocENI(arg0 bool, arg1 []*string, arg2, arg3 string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AllocENI", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "AllocENI", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // AllocENI indicates an expected call of AllocENI
-func (mr *MockAPIsMockRecorder) AllocENI(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockAPIsMockRecorder) AllocENI(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllocENI", reflect.TypeOf((*MockAPIs)(nil).AllocENI), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllocENI", reflect.TypeOf((*MockAPIs)(nil).AllocENI), arg0, arg1, arg2, arg3)
 }
 
 // AllocIPAddress mocks base method
@@ -109,6 +110,21 @@ func (mr *MockAPIsMockRecorder) AllocIPv6Prefixes(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllocIPv6Prefixes", reflect.TypeOf((*MockAPIs)(nil).AllocIPv6Prefixes), arg0)
 }
 
+// AllocSecondaryIPAddresses mocks base method
+func (m *MockAPIs) AllocSecondaryIPAddresses(arg0 string, arg1 int) (*ec2.AssignPrivateIpAddressesOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AllocSecondaryIPAddresses", arg0, arg1)
+	ret0, _ := ret[0].(*ec2.AssignPrivateIpAddressesOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AllocSecondaryIPAddresses indicates an expected call of AllocSecondaryIPAddresses
+func (mr *MockAPIsMockRecorder) AllocSecondaryIPAddresses(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllocSecondaryIPAddresses", reflect.TypeOf((*MockAPIs)(nil).AllocSecondaryIPAddresses), arg0, arg1)
+}
+
 // DeallocIPAddresses mocks base method
 func (m *MockAPIs) DeallocIPAddresses(arg0 string, arg1 []string) error {
 	m.ctrl.T.Helper()
@@ -123,6 +139,20 @@ func (mr *MockAPIsMockRecorder) DeallocIPAddresses(arg0, arg1 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeallocIPAddresses", reflect.TypeOf((*MockAPIs)(nil).DeallocIPAddresses), arg0, arg1)
 }
 
+// DeallocIPv6Prefixes mocks base method
+func (m *MockAPIs) DeallocIPv6Prefixes(arg0 string, arg1 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeallocIPv6Prefixes", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeallocIPv6Prefixes indicates an expected call of DeallocIPv6Prefixes
+func (mr *MockAPIsMockRecorder) DeallocIPv6Prefixes(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeallocIPv6Prefixes", reflect.TypeOf((*MockAPIs)(nil).DeallocIPv6Prefixes), arg0, arg1)
+}
+
 // DeallocPrefixAddresses mocks base method
 func (m *MockAPIs) DeallocPrefixAddresses(arg0 string, arg1 []string) error {
 	m.ctrl.T.Helper()
@@ -152,6 +182,21 @@ func (mr *MockAPIsMockRecorder) DescribeAllENIs() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeAllENIs", reflect.TypeOf((*MockAPIs)(nil).DescribeAllENIs))
 }
 
+// DescribeVPCIPv4CIDRs mocks base method
+func (m *MockAPIs) DescribeVPCIPv4CIDRs() ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DescribeVPCIPv4CIDRs")
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeVPCIPv4CIDRs indicates an expected call of DescribeVPCIPv4CIDRs
+func (mr *MockAPIsMockRecorder) DescribeVPCIPv4CIDRs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeVPCIPv4CIDRs", reflect.TypeOf((*MockAPIs)(nil).DescribeVPCIPv4CIDRs))
+}
+
 // FetchInstanceTypeLimits mocks base method
 func (m *MockAPIs) FetchInstanceTypeLimits() error {
 	m.ctrl.T.Helper()
@@ -180,6 +225,20 @@ func (mr *MockAPIsMockRecorder) FreeENI(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FreeENI", reflect.TypeOf((*MockAPIs)(nil).FreeENI), arg0)
 }
 
+// GetAvailabilityZone mocks base method
+func (m *MockAPIs) GetAvailabilityZone() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAvailabilityZone")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetAvailabilityZone indicates an expected call of GetAvailabilityZone
+func (mr *MockAPIsMockRecorder) GetAvailabilityZone() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAvailabilityZone", reflect.TypeOf((*MockAPIs)(nil).GetAvailabilityZone))
+}
+
 // GetAttachedENIs mocks base method
 func (m *MockAPIs) GetAttachedENIs() ([]awsutils.ENIMetadata, error) {
 	m.ctrl.T.Helper()
@@ -324,6 +383,20 @@ func (mr *MockAPIsMockRecorder) GetLocalIPv4() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocalIPv4", reflect.TypeOf((*MockAPIs)(nil).GetLocalIPv4))
 }
 
+// GetNetworkCards mocks base method
+func (m *MockAPIs) GetNetworkCards() []vpc.NetworkCard {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNetworkCards")
+	ret0, _ := ret[0].([]vpc.NetworkCard)
+	return ret0
+}
+
+// GetNetworkCards indicates an expected call of GetNetworkCards
+func (mr *MockAPIsMockRecorder) GetNetworkCards() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNetworkCards", reflect.TypeOf((*MockAPIs)(nil).GetNetworkCards))
+}
+
 // GetPrimaryENI mocks base method
 func (m *MockAPIs) GetPrimaryENI() string {
 	m.ctrl.T.Helper()
@@ -352,6 +425,21 @@ func (mr *MockAPIsMockRecorder) GetPrimaryENImac() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrimaryENImac", reflect.TypeOf((*MockAPIs)(nil).GetPrimaryENImac))
 }
 
+// GetSubnetAvailabilityZone mocks base method
+func (m *MockAPIs) GetSubnetAvailabilityZone(arg0 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubnetAvailabilityZone", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubnetAvailabilityZone indicates an expected call of GetSubnetAvailabilityZone
+func (mr *MockAPIsMockRecorder) GetSubnetAvailabilityZone(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubnetAvailabilityZone", reflect.TypeOf((*MockAPIs)(nil).GetSubnetAvailabilityZone), arg0)
+}
+
 // GetVPCIPv4CIDRs mocks base method
 func (m *MockAPIs) GetVPCIPv4CIDRs() ([]string, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,190 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package bpfrouting routes the traffic from pods by source IP with an eBPF program on the host veths, instead of an
+// ip rule per pod. The program looks up the route table of the source IP in a BPF map that ipamd keeps in sync with
+// its datastore, and puts the table in the packet mark. One rule per route table then matches the mark.
+package bpfrouting
+
+import (
+	"net"
+	"os"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// PinPath is the directory in the BPF file system that the map and the program are pinned to, so that they
+	// outlive ipamd and the plugin can attach the program
+	PinPath = "/sys/fs/bpf/aws-vpc-cni"
+
+	mapPinPath     = PinPath + "/pod_route_tables"
+	programPinPath = PinPath + "/pod_source_routing"
+
+	// ProgramName is the name of the tc filter of the program on the host veths
+	ProgramName = "aws-pod-routing"
+
+	// MarkMask is the part of the packet mark that holds the route table of the pod. The mark space is crowded, so it
+	// stays clear of the bits that others use by default:
+	// - kube-proxy uses 0x0000c000
+	// - Calico uses 0xffff0000
+	// - the CNI connmark is 0x80
+	MarkMask = 0x00003f00
+
+	markShift = 8
+
+	// MaxTable is the highest route table that fits in the mark
+	MaxTable = MarkMask >> markShift
+
+	// maxPodIPs is the maximum number of pod IPs in the map
+	maxPodIPs = 16384
+)
+
+// RouteMap is the BPF map of the route tables of the traffic from pod IPs. A pod IP of the main route table is in the
+// map as well, so that traffic from other pods to it is not marked.
+type RouteMap interface {
+	// Update sets the route table of the traffic from a pod IP
+	Update(ip net.IP, table int) error
+	// Delete removes a pod IP
+	Delete(ip net.IP) error
+	// List returns the route table of each pod IP
+	List() (map[string]int, error)
+}
+
+type routeMap struct {
+	fd int
+}
+
+// Load opens the pinned map, or creates and pins it the first time, so that the pod IPs survive a restart of ipamd.
+// The program is loaded again and replaces the pinned one, host veths that have the old program attached keep it.
+func Load() (RouteMap, error) {
+	if err := os.MkdirAll(PinPath, 0700); err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s", PinPath)
+	}
+
+	mapFD, err := objGet(mapPinPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "failed to open pinned map %s", mapPinPath)
+		}
+		mapFD, err = createMap(unix.BPF_MAP_TYPE_HASH, 4, 4, maxPodIPs)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create map")
+		}
+		if err := objPin(mapFD, mapPinPath); err != nil {
+			unix.Close(mapFD)
+			return nil, errors.Wrapf(err, "failed to pin map to %s", mapPinPath)
+		}
+	}
+
+	programFD, err := loadProgram(unix.BPF_PROG_TYPE_SCHED_CLS, sourceRoutingProgram(mapFD), "Apache-2.0")
+	if err != nil {
+		unix.Close(mapFD)
+		return nil, errors.Wrap(err, "failed to load program")
+	}
+	defer unix.Close(programFD)
+	if err := os.Remove(programPinPath); err != nil && !os.IsNotExist(err) {
+		unix.Close(mapFD)
+		return nil, errors.Wrapf(err, "failed to unpin old program %s", programPinPath)
+	}
+	if err := objPin(programFD, programPinPath); err != nil {
+		unix.Close(mapFD)
+		return nil, errors.Wrapf(err, "failed to pin program to %s", programPinPath)
+	}
+	return &routeMap{fd: mapFD}, nil
+}
+
+// OpenProgram returns a file descriptor of the program that ipamd pinned, to attach it to a host veth
+func OpenProgram() (int, error) {
+	fd, err := objGet(programPinPath)
+	if err != nil {
+		return -1, errors.Wrapf(err, "failed to open pinned program %s", programPinPath)
+	}
+	return fd, nil
+}
+
+// Mark returns the packet mark of the traffic of a route table
+func Mark(table int) int {
+	return table << markShift
+}
+
+// Routable reports whether the route table of the ENI with a device number fits in the mark. The pods of the ENIs
+// with a higher device number, which only happen with multiple network cards, keep a rule per pod IP.
+func Routable(deviceNumber int) bool {
+	return deviceNumber == 0 || deviceNumber+1 <= MaxTable
+}
+
+func (m *routeMap) Update(ip net.IP, table int) error {
+	if table > MaxTable && table != unix.RT_TABLE_MAIN {
+		return errors.Errorf("route table %d of %s does not fit in the mark", table, ip)
+	}
+	key, err := mapKey(ip)
+	if err != nil {
+		return err
+	}
+	value := uint32(table)
+	if err := mapUpdate(m.fd, unsafe.Pointer(&key[0]), unsafe.Pointer(&value)); err != nil {
+		return errors.Wrapf(err, "failed to set the route table of %s", ip)
+	}
+	return nil
+}
+
+func (m *routeMap) Delete(ip net.IP) error {
+	key, err := mapKey(ip)
+	if err != nil {
+		return err
+	}
+	if err := mapDelete(m.fd, unsafe.Pointer(&key[0])); err != nil && err != unix.ENOENT {
+		return errors.Wrapf(err, "failed to delete %s", ip)
+	}
+	return nil
+}
+
+func (m *routeMap) List() (map[string]int, error) {
+	tables := make(map[string]int)
+	var key, nextKey [4]byte
+	keyPtr := unsafe.Pointer(nil)
+	for {
+		err := mapGetNextKey(m.fd, keyPtr, unsafe.Pointer(&nextKey[0]))
+		if err == unix.ENOENT {
+			return tables, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to iterate over the map")
+		}
+		var value uint32
+		if err := mapLookup(m.fd, unsafe.Pointer(&nextKey[0]), unsafe.Pointer(&value)); err != nil {
+			if err == unix.ENOENT {
+				// Deleted in the meantime
+				continue
+			}
+			return nil, errors.Wrap(err, "failed to look up map entry")
+		}
+		tables[net.IP(nextKey[:]).String()] = int(value)
+		key = nextKey
+		keyPtr = unsafe.Pointer(&key[0])
+	}
+}
+
+// mapKey returns the key of a pod IP, the address in network byte order as the program reads it from the packet
+func mapKey(ip net.IP) ([4]byte, error) {
+	var key [4]byte
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return key, errors.Errorf("%s is not an IPv4 address", ip)
+	}
+	copy(key[:], ip4)
+	return key, nil
+}
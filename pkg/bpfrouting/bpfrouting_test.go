@@ -0,0 +1,79 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package bpfrouting
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestMapKey(t *testing.T) {
+	key, err := mapKey(net.ParseIP("10.1.2.3"))
+	assert.NoError(t, err)
+	assert.Equal(t, [4]byte{10, 1, 2, 3}, key)
+
+	_, err = mapKey(net.ParseIP("2001:db8::1"))
+	assert.Error(t, err)
+}
+
+func TestMark(t *testing.T) {
+	assert.Equal(t, 0x0200, Mark(2))
+	assert.Equal(t, 0, Mark(MaxTable)&^MarkMask)
+	// The mark of kube-proxy, Calico and the CNI connmark are left alone
+	assert.Equal(t, 0, MarkMask&0xffffc080)
+}
+
+func TestUpdateTableOutOfRange(t *testing.T) {
+	m := &routeMap{fd: -1}
+	assert.Error(t, m.Update(net.ParseIP("10.1.2.3"), MaxTable+1))
+
+	// The main route table is not put in the mark, there is no map behind the file descriptor
+	err := m.Update(net.ParseIP("10.1.2.3"), unix.RT_TABLE_MAIN)
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "does not fit in the mark")
+}
+
+func TestRoutable(t *testing.T) {
+	assert.True(t, Routable(0))
+	assert.True(t, Routable(MaxTable-1))
+	assert.False(t, Routable(MaxTable))
+}
+
+func TestSourceRoutingProgram(t *testing.T) {
+	insns := sourceRoutingProgram(7)
+	assert.Equal(t, 0, len(insns)%insnSize)
+	count := len(insns) / insnSize
+	out := count - 2
+
+	for i := 0; i < count; i++ {
+		insn := insns[i*insnSize : (i+1)*insnSize]
+		opcode := insn[0]
+		switch {
+		case opcode == unix.BPF_LD|unix.BPF_DW|unix.BPF_IMM:
+			// Both loads of the map get its file descriptor
+			assert.Equal(t, uint8(unix.BPF_PSEUDO_MAP_FD), insn[1]>>4)
+			assert.Equal(t, uint32(7), binary.LittleEndian.Uint32(insn[4:]))
+			i++
+		case opcode&0x07 == unix.BPF_JMP && opcode&0xf0 != unix.BPF_CALL && opcode&0xf0 != unix.BPF_EXIT:
+			// Every jump goes to the end of the program
+			off := int16(binary.LittleEndian.Uint16(insn[2:]))
+			assert.Equal(t, out, i+1+int(off), "jump at instruction %d", i)
+		}
+	}
+	assert.Equal(t, uint8(unix.BPF_JMP|unix.BPF_EXIT), insns[(count-1)*insnSize])
+}
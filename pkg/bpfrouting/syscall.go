@@ -0,0 +1,242 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package bpfrouting
+
+import (
+	"encoding/binary"
+	"os"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// The attributes of the bpf syscall commands, as in union bpf_attr of linux/bpf.h
+
+type mapCreateAttr struct {
+	mapType    uint32
+	keySize    uint32
+	valueSize  uint32
+	maxEntries uint32
+	mapFlags   uint32
+}
+
+type mapElemAttr struct {
+	mapFD uint32
+	_     uint32
+	key   unsafe.Pointer
+	value unsafe.Pointer // or the next key
+	flags uint64
+}
+
+type progLoadAttr struct {
+	progType    uint32
+	insnCnt     uint32
+	insns       unsafe.Pointer
+	license     unsafe.Pointer
+	logLevel    uint32
+	logSize     uint32
+	logBuf      unsafe.Pointer
+	kernVersion uint32
+	progFlags   uint32
+	progName    [unix.BPF_OBJ_NAME_LEN]byte
+}
+
+type objAttr struct {
+	pathname  unsafe.Pointer
+	bpfFD     uint32
+	fileFlags uint32
+}
+
+func bpf(cmd int, attr unsafe.Pointer, size uintptr) (int, error) {
+	r, _, errno := unix.Syscall(unix.SYS_BPF, uintptr(cmd), uintptr(attr), size)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(r), nil
+}
+
+func createMap(mapType uint32, keySize, valueSize, maxEntries uint32) (int, error) {
+	attr := mapCreateAttr{
+		mapType:    mapType,
+		keySize:    keySize,
+		valueSize:  valueSize,
+		maxEntries: maxEntries,
+	}
+	return bpf(unix.BPF_MAP_CREATE, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+}
+
+func mapLookup(fd int, key, value unsafe.Pointer) error {
+	attr := mapElemAttr{mapFD: uint32(fd), key: key, value: value}
+	_, err := bpf(unix.BPF_MAP_LOOKUP_ELEM, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	return err
+}
+
+func mapUpdate(fd int, key, value unsafe.Pointer) error {
+	attr := mapElemAttr{mapFD: uint32(fd), key: key, value: value, flags: unix.BPF_ANY}
+	_, err := bpf(unix.BPF_MAP_UPDATE_ELEM, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	return err
+}
+
+func mapDelete(fd int, key unsafe.Pointer) error {
+	attr := mapElemAttr{mapFD: uint32(fd), key: key}
+	_, err := bpf(unix.BPF_MAP_DELETE_ELEM, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	return err
+}
+
+// mapGetNextKey returns the first key if key is nil
+func mapGetNextKey(fd int, key, nextKey unsafe.Pointer) error {
+	attr := mapElemAttr{mapFD: uint32(fd), key: key, value: nextKey}
+	_, err := bpf(unix.BPF_MAP_GET_NEXT_KEY, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	return err
+}
+
+func loadProgram(progType uint32, insns []byte, license string) (int, error) {
+	licenseBytes := append([]byte(license), 0)
+	logBuf := make([]byte, 65536)
+	attr := progLoadAttr{
+		progType: progType,
+		insnCnt:  uint32(len(insns) / insnSize),
+		insns:    unsafe.Pointer(&insns[0]),
+		license:  unsafe.Pointer(&licenseBytes[0]),
+		logLevel: 1,
+		logSize:  uint32(len(logBuf)),
+		logBuf:   unsafe.Pointer(&logBuf[0]),
+	}
+	copy(attr.progName[:unix.BPF_OBJ_NAME_LEN-1], "pod_src_routing")
+	fd, err := bpf(unix.BPF_PROG_LOAD, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	if err != nil {
+		return -1, errors.Wrapf(err, "verifier log: %s", unix.ByteSliceToString(logBuf))
+	}
+	return fd, nil
+}
+
+func objPin(fd int, path string) error {
+	pathBytes := append([]byte(path), 0)
+	attr := objAttr{pathname: unsafe.Pointer(&pathBytes[0]), bpfFD: uint32(fd)}
+	_, err := bpf(unix.BPF_OBJ_PIN, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	return err
+}
+
+// objGet opens a pinned object. The error is an *os.PathError, so that os.IsNotExist works on it.
+func objGet(path string) (int, error) {
+	pathBytes := append([]byte(path), 0)
+	attr := objAttr{pathname: unsafe.Pointer(&pathBytes[0])}
+	fd, err := bpf(unix.BPF_OBJ_GET, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	if err != nil {
+		return -1, &os.PathError{Op: "bpf_obj_get", Path: path, Err: err}
+	}
+	return fd, nil
+}
+
+const insnSize = 8
+
+// asm assembles the eBPF instructions of a program
+type asm struct {
+	insns []byte
+}
+
+func (a *asm) insn(opcode uint8, dst, src uint8, off int16, imm int32) {
+	var insn [insnSize]byte
+	insn[0] = opcode
+	insn[1] = dst | src<<4
+	binary.LittleEndian.PutUint16(insn[2:], uint16(off))
+	binary.LittleEndian.PutUint32(insn[4:], uint32(imm))
+	a.insns = append(a.insns, insn[:]...)
+}
+
+// loadMapFD loads the file descriptor of a map into a register, it takes two instructions
+func (a *asm) loadMapFD(dst uint8, fd int) {
+	a.insn(unix.BPF_LD|unix.BPF_DW|unix.BPF_IMM, dst, unix.BPF_PSEUDO_MAP_FD, 0, int32(fd))
+	a.insn(0, 0, 0, 0, 0)
+}
+
+// Registers
+const (
+	r0 = iota
+	r1
+	r2
+	r3
+	r4
+	r5
+	r6
+	_
+	_
+	_
+	r10
+)
+
+// Offsets in struct __sk_buff
+const (
+	skbMark     = 8
+	skbProtocol = 16
+	skbData     = 76
+	skbDataEnd  = 80
+)
+
+const (
+	ethHeaderLen    = 14
+	ipv4SaddrOffset = ethHeaderLen + 12
+	ipv4DaddrOffset = ethHeaderLen + 16
+
+	// ethPIPv4 is ETH_P_IP in network byte order, as in skb->protocol
+	ethPIPv4 = 0x0008
+
+	mainRouteTable = unix.RT_TABLE_MAIN
+
+	// funcMapLookupElem is the helper function bpf_map_lookup_elem
+	funcMapLookupElem = 1
+)
+
+// sourceRoutingProgram returns the tc ingress program of the host veths. Traffic from a pod to another pod on the
+// node is left alone, so that it uses the main route table. Otherwise the route table of the source IP in the map is
+// put in the mark, unless it is the main route table.
+func sourceRoutingProgram(mapFD int) []byte {
+	var a asm
+	a.insn(unix.BPF_ALU64|unix.BPF_MOV|unix.BPF_X, r6, r1, 0, 0)
+	a.insn(unix.BPF_LDX|unix.BPF_W|unix.BPF_MEM, r2, r6, skbProtocol, 0)
+	a.insn(unix.BPF_JMP|unix.BPF_JNE|unix.BPF_K, r2, 0, 28, ethPIPv4) // to out
+	a.insn(unix.BPF_LDX|unix.BPF_W|unix.BPF_MEM, r2, r6, skbData, 0)
+	a.insn(unix.BPF_LDX|unix.BPF_W|unix.BPF_MEM, r3, r6, skbDataEnd, 0)
+	a.insn(unix.BPF_ALU64|unix.BPF_MOV|unix.BPF_X, r4, r2, 0, 0)
+	a.insn(unix.BPF_ALU64|unix.BPF_ADD|unix.BPF_K, r4, 0, 0, ipv4DaddrOffset+4)
+	a.insn(unix.BPF_JMP|unix.BPF_JGT|unix.BPF_X, r4, r3, 23, 0) // to out
+	a.insn(unix.BPF_LDX|unix.BPF_W|unix.BPF_MEM, r4, r2, ipv4DaddrOffset, 0)
+	a.insn(unix.BPF_STX|unix.BPF_W|unix.BPF_MEM, r10, r4, -4, 0)
+	a.insn(unix.BPF_LDX|unix.BPF_W|unix.BPF_MEM, r4, r2, ipv4SaddrOffset, 0)
+	a.insn(unix.BPF_STX|unix.BPF_W|unix.BPF_MEM, r10, r4, -8, 0)
+	// Destination is a pod on the node
+	a.loadMapFD(r1, mapFD)
+	a.insn(unix.BPF_ALU64|unix.BPF_MOV|unix.BPF_X, r2, r10, 0, 0)
+	a.insn(unix.BPF_ALU64|unix.BPF_ADD|unix.BPF_K, r2, 0, 0, -4)
+	a.insn(unix.BPF_JMP|unix.BPF_CALL, 0, 0, 0, funcMapLookupElem)
+	a.insn(unix.BPF_JMP|unix.BPF_JNE|unix.BPF_K, r0, 0, 13, 0) // to out
+	// Route table of the source
+	a.loadMapFD(r1, mapFD)
+	a.insn(unix.BPF_ALU64|unix.BPF_MOV|unix.BPF_X, r2, r10, 0, 0)
+	a.insn(unix.BPF_ALU64|unix.BPF_ADD|unix.BPF_K, r2, 0, 0, -8)
+	a.insn(unix.BPF_JMP|unix.BPF_CALL, 0, 0, 0, funcMapLookupElem)
+	a.insn(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, r0, 0, 7, 0) // to out
+	a.insn(unix.BPF_LDX|unix.BPF_W|unix.BPF_MEM, r1, r0, 0, 0)
+	a.insn(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, r1, 0, 5, mainRouteTable) // to out
+	a.insn(unix.BPF_ALU64|unix.BPF_LSH|unix.BPF_K, r1, 0, 0, markShift)
+	a.insn(unix.BPF_LDX|unix.BPF_W|unix.BPF_MEM, r2, r6, skbMark, 0)
+	a.insn(unix.BPF_ALU64|unix.BPF_AND|unix.BPF_K, r2, 0, 0, ^MarkMask) // sign-extended, the upper bits are kept
+	a.insn(unix.BPF_ALU64|unix.BPF_OR|unix.BPF_X, r2, r1, 0, 0)
+	a.insn(unix.BPF_STX|unix.BPF_W|unix.BPF_MEM, r6, r2, skbMark, 0)
+	// out: TC_ACT_OK
+	a.insn(unix.BPF_ALU64|unix.BPF_MOV|unix.BPF_K, r0, 0, 0, 0)
+	a.insn(unix.BPF_JMP|unix.BPF_EXIT, 0, 0, 0, 0)
+	return a.insns
+}
@@ -17,7 +17,11 @@ package eniconfig
 import (
 	"context"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/pkg/errors"
@@ -47,6 +51,12 @@ const (
 	//   This will set eniConfigLabelDef to eniConfigOverride
 	envEniConfigAnnotationDef = "ENI_CONFIG_ANNOTATION_DEF"
 	envEniConfigLabelDef      = "ENI_CONFIG_LABEL_DEF"
+
+	// when "ENI_CONFIG_AUTO_SELECT" is true, a node without an ENIConfig label or annotation uses the ENIConfig whose
+	// subnet is in the Availability Zone of the node. "ENI_CONFIG_SELECTOR" is an optional label selector that the
+	// ENIConfig has to match as well, e.g. "team=platform"
+	envEniConfigAutoSelect = "ENI_CONFIG_AUTO_SELECT"
+	envEniConfigSelector   = "ENI_CONFIG_SELECTOR"
 )
 
 // ENIConfig interface
@@ -55,6 +65,11 @@ type ENIConfig interface {
 	GetENIConfigName(context.Context, client.Client) (string, error)
 }
 
+// SubnetZoneResolver returns the Availability Zone of a subnet
+type SubnetZoneResolver interface {
+	GetSubnetAvailabilityZone(subnetID string) (string, error)
+}
+
 // ErrNoENIConfig is the missing ENIConfig error
 var ErrNoENIConfig = errors.New("eniconfig: eniconfig is not available")
 
@@ -69,20 +84,26 @@ type ENIConfigInfo struct {
 }
 
 // MyENIConfig returns the ENIConfig applicable to the particular node
-func MyENIConfig(ctx context.Context, k8sClient client.Client) (*v1alpha1.ENIConfigSpec, error) {
+func MyENIConfig(ctx context.Context, k8sClient client.Client, subnets SubnetZoneResolver, availabilityZone string) (*v1alpha1.ENIConfigSpec, error) {
 	node, err := k8sapi.GetNode(ctx, k8sClient)
 	if err != nil {
 		log.Debugf("Error while retrieving Node")
 	}
 
-	eniConfigName, err := GetNodeSpecificENIConfigName(node)
+	eniConfigName, err := GetNodeENIConfigName(ctx, k8sClient, subnets, node, availabilityZone)
 	if err != nil {
-		log.Debugf("Error while retrieving Node ENIConfig name")
+		log.Errorf("Error while retrieving Node ENIConfig name: %s", err)
+		return nil, ErrNoENIConfig
 	}
 
 	log.Infof("Found ENI Config Name: %s", eniConfigName)
+	return GetENIConfigSpec(ctx, k8sClient, eniConfigName)
+}
+
+// GetENIConfigSpec returns the ENIConfig with the given name
+func GetENIConfigSpec(ctx context.Context, k8sClient client.Client, eniConfigName string) (*v1alpha1.ENIConfigSpec, error) {
 	var eniConfig v1alpha1.ENIConfig
-	err = k8sClient.Get(ctx, types.NamespacedName{Name: eniConfigName}, &eniConfig)
+	err := k8sClient.Get(ctx, types.NamespacedName{Name: eniConfigName}, &eniConfig)
 	if err != nil {
 		log.Errorf("error while retrieving eniconfig: %s", err)
 		return nil, ErrNoENIConfig
@@ -122,21 +143,102 @@ func getEniConfigLabelDef() string {
 	return defaultEniConfigLabelDef
 }
 
+// isAutoSelectEnabled returns whether ENIConfigs are selected by Availability Zone for nodes without an ENIConfig
+// label or annotation
+func isAutoSelectEnabled() bool {
+	if inputStr, found := os.LookupEnv(envEniConfigAutoSelect); found && inputStr != "" {
+		if autoSelect, err := strconv.ParseBool(inputStr); err == nil {
+			return autoSelect
+		}
+		log.Warnf("Failed to parse %s %q, ENIConfigs are not selected automatically", envEniConfigAutoSelect, inputStr)
+	}
+	return false
+}
+
 func GetNodeSpecificENIConfigName(node corev1.Node) (string, error) {
-	var eniConfigName string
+	eniConfigName, ok := getNodeLabelledENIConfigName(node)
+	if !ok {
+		eniConfigName = EniConfigDefault
+	}
+	return eniConfigName, nil
+}
 
-	//Derive ENIConfig Name from either externally managed label, Node Annotations or Labels
-	labels := node.GetLabels()
-	eniConfigName, ok := labels[externalEniConfigLabel]
+// getNodeLabelledENIConfigName derives the ENIConfig name from either externally managed label, Node Annotations or Labels
+func getNodeLabelledENIConfigName(node corev1.Node) (string, bool) {
+	eniConfigName, ok := node.GetLabels()[externalEniConfigLabel]
 	if !ok {
 		eniConfigName, ok = node.GetAnnotations()[getEniConfigAnnotationDef()]
 		if !ok {
 			eniConfigName, ok = node.GetLabels()[getEniConfigLabelDef()]
-			if !ok {
-				eniConfigName = EniConfigDefault
-			}
 		}
 	}
+	return eniConfigName, ok
+}
 
-	return eniConfigName, nil
+// GetNodeENIConfigName returns the ENIConfig name of the node like GetNodeSpecificENIConfigName. With automatic
+// selection enabled, a node without an ENIConfig label or annotation gets the ENIConfig of its Availability Zone
+// instead of the default one.
+func GetNodeENIConfigName(ctx context.Context, k8sClient client.Client, subnets SubnetZoneResolver, node corev1.Node, availabilityZone string) (string, error) {
+	if _, ok := getNodeLabelledENIConfigName(node); ok || !isAutoSelectEnabled() {
+		return GetNodeSpecificENIConfigName(node)
+	}
+	return GetAZSpecificENIConfigName(ctx, k8sClient, subnets, availabilityZone)
+}
+
+// GetAZSpecificENIConfigName returns the name of the only ENIConfig that matches ENI_CONFIG_SELECTOR and whose subnet
+// is in the Availability Zone
+func GetAZSpecificENIConfigName(ctx context.Context, k8sClient client.Client, subnets SubnetZoneResolver, availabilityZone string) (string, error) {
+	if availabilityZone == "" {
+		return "", errors.New("eniconfig: availability zone of the node is unknown")
+	}
+	selector, err := labels.Parse(os.Getenv(envEniConfigSelector))
+	if err != nil {
+		return "", errors.Wrapf(err, "eniconfig: invalid %s", envEniConfigSelector)
+	}
+
+	var eniConfigs v1alpha1.ENIConfigList
+	if err := k8sClient.List(ctx, &eniConfigs, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return "", errors.Wrap(err, "eniconfig: failed to list eniconfigs")
+	}
+	var names []string
+	for _, eniConfig := range eniConfigs.Items {
+		subnetAZ, err := subnets.GetSubnetAvailabilityZone(eniConfig.Spec.Subnet)
+		if err != nil {
+			log.Warnf("Skipping ENIConfig %s, failed to get the availability zone of subnet %s: %v", eniConfig.Name, eniConfig.Spec.Subnet, err)
+			continue
+		}
+		if subnetAZ == availabilityZone {
+			names = append(names, eniConfig.Name)
+		}
+	}
+	switch len(names) {
+	case 0:
+		return "", errors.Errorf("eniconfig: no eniconfig matching %q has a subnet in %s", selector.String(), availabilityZone)
+	case 1:
+		log.Infof("Selected ENIConfig %s for availability zone %s", names[0], availabilityZone)
+		return names[0], nil
+	default:
+		sort.Strings(names)
+		return "", errors.Errorf("eniconfig: more than one eniconfig matching %q has a subnet in %s: %s", selector.String(), availabilityZone, strings.Join(names, ", "))
+	}
+}
+
+// GetPodSpecificENIConfigName returns the name of the ENIConfig a pod asks for, or an empty string when the pod uses
+// the ENIConfig of its node. The pod annotation takes precedence over the label of the pod's namespace.
+func GetPodSpecificENIConfigName(pod corev1.Pod, namespace corev1.Namespace) string {
+	if eniConfigName, ok := GetPodAnnotatedENIConfigName(pod); ok {
+		return eniConfigName
+	}
+	return GetNamespaceENIConfigName(namespace)
+}
+
+// GetPodAnnotatedENIConfigName returns the name of the ENIConfig in the annotation of a pod, if it has one
+func GetPodAnnotatedENIConfigName(pod corev1.Pod) (string, bool) {
+	eniConfigName, ok := pod.GetAnnotations()[getEniConfigAnnotationDef()]
+	return eniConfigName, ok
+}
+
+// GetNamespaceENIConfigName returns the name of the ENIConfig in the label of a namespace, or an empty string
+func GetNamespaceENIConfigName(namespace corev1.Namespace) string {
+	return namespace.GetLabels()[getEniConfigLabelDef()]
 }
@@ -236,7 +236,7 @@ func TestMyENIConfig(t *testing.T) {
 				assert.NoError(t, err)
 			}
 
-			myENIConfig, err := MyENIConfig(ctx, k8sClient)
+			myENIConfig, err := MyENIConfig(ctx, k8sClient, fakeSubnetZones{}, "")
 			if tt.wantErr != nil {
 				assert.EqualError(t, err, tt.wantErr.Error())
 			} else {
@@ -270,3 +270,128 @@ func TestGetEniConfigLabelDefCustom(t *testing.T) {
 	eniConfigLabelDef := getEniConfigLabelDef()
 	assert.Equal(t, eniConfigLabelDef, "k8s.amazonaws.com/eniConfigCustom")
 }
+
+func TestGetPodSpecificENIConfigName(t *testing.T) {
+	_ = os.Unsetenv(envEniConfigAnnotationDef)
+	_ = os.Unsetenv(envEniConfigLabelDef)
+
+	labelledNamespace := corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "team-a",
+			Labels: map[string]string{defaultEniConfigLabelDef: "az1-team-a"},
+		},
+	}
+	tests := []struct {
+		name      string
+		pod       corev1.Pod
+		namespace corev1.Namespace
+		want      string
+	}{
+		{
+			name:      "pod and namespace without eniconfig",
+			pod:       corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}},
+			namespace: corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+			want:      "",
+		},
+		{
+			name:      "eniconfig from namespace label",
+			pod:       corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "team-a"}},
+			namespace: labelledNamespace,
+			want:      "az1-team-a",
+		},
+		{
+			name: "pod annotation overrides namespace label",
+			pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:        "pod",
+				Namespace:   "team-a",
+				Annotations: map[string]string{defaultEniConfigAnnotationDef: "az1-special"},
+			}},
+			namespace: labelledNamespace,
+			want:      "az1-special",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, GetPodSpecificENIConfigName(tt.pod, tt.namespace))
+		})
+	}
+}
+
+func TestGetNodeENIConfigName(t *testing.T) {
+	_ = os.Unsetenv(envEniConfigAnnotationDef)
+	_ = os.Unsetenv(envEniConfigLabelDef)
+	defer os.Unsetenv(envEniConfigAutoSelect)
+	defer os.Unsetenv(envEniConfigSelector)
+
+	newENIConfig := func(name string, labels map[string]string) *v1alpha1.ENIConfig {
+		return &v1alpha1.ENIConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Spec:       v1alpha1.ENIConfigSpec{Subnet: "subnet-" + name},
+		}
+	}
+	// The zone label is not trusted, only the Availability Zone of the subnet counts
+	eniConfigs := []*v1alpha1.ENIConfig{
+		newENIConfig("az1-pods", map[string]string{"topology.kubernetes.io/zone": "us-west-2b", "team": "platform"}),
+		newENIConfig("az2-pods", map[string]string{"team": "platform"}),
+		newENIConfig("az2-batch", map[string]string{"team": "batch"}),
+		newENIConfig("unknown-subnet", map[string]string{"team": "platform"}),
+	}
+	subnetZones := fakeSubnetZones{
+		"subnet-az1-pods":  "us-west-2a",
+		"subnet-az2-pods":  "us-west-2b",
+		"subnet-az2-batch": "us-west-2b",
+	}
+
+	tests := []struct {
+		name             string
+		autoSelect       string
+		selector         string
+		nodeLabels       map[string]string
+		availabilityZone string
+		want             string
+		wantErr          bool
+	}{
+		{name: "auto select disabled", availabilityZone: "us-west-2a", want: EniConfigDefault},
+		{name: "node label wins", autoSelect: "true", nodeLabels: map[string]string{"k8s.amazonaws.com/eniConfig": "custom"}, availabilityZone: "us-west-2a", want: "custom"},
+		{name: "single eniconfig in zone", autoSelect: "true", availabilityZone: "us-west-2a", want: "az1-pods"},
+		{name: "selector picks one of several", autoSelect: "true", selector: "team=batch", availabilityZone: "us-west-2b", want: "az2-batch"},
+		{name: "several eniconfigs in zone", autoSelect: "true", availabilityZone: "us-west-2b", wantErr: true},
+		{name: "no eniconfig in zone", autoSelect: "true", availabilityZone: "us-west-2c", wantErr: true},
+		{name: "unknown zone", autoSelect: "true", wantErr: true},
+		{name: "invalid selector", autoSelect: "true", selector: "team in", availabilityZone: "us-west-2a", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			k8sSchema := runtime.NewScheme()
+			clientgoscheme.AddToScheme(k8sSchema)
+			eniconfigscheme.AddToScheme(k8sSchema)
+			k8sClient := testclient.NewClientBuilder().WithScheme(k8sSchema).Build()
+			for _, eniConfig := range eniConfigs {
+				assert.NoError(t, k8sClient.Create(ctx, eniConfig.DeepCopy()))
+			}
+			_ = os.Setenv(envEniConfigAutoSelect, tt.autoSelect)
+			_ = os.Setenv(envEniConfigSelector, tt.selector)
+
+			node := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test-node", Labels: tt.nodeLabels}}
+			got, err := GetNodeENIConfigName(ctx, k8sClient, subnetZones, node, tt.availabilityZone)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// fakeSubnetZones maps subnet IDs to their Availability Zones
+type fakeSubnetZones map[string]string
+
+func (f fakeSubnetZones) GetSubnetAvailabilityZone(subnetID string) (string, error) {
+	availabilityZone, ok := f[subnetID]
+	if !ok {
+		return "", errors.Errorf("subnet %s not found", subnetID)
+	}
+	return availabilityZone, nil
+}
@@ -0,0 +1,60 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package grpcwrapper
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+
+	"github.com/pkg/errors"
+	google_grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// TLSConfig is the mutual TLS configuration of an ipamd client
+type TLSConfig struct {
+	// Address is the address that ipamd serves the CNI backend on with mutual TLS
+	Address string `json:"address"`
+	// CertFile and KeyFile are the client certificate and key
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+	// CAFile is the CA that signs the certificate of ipamd
+	CAFile string `json:"caFile"`
+	// ServerName is the name that the certificate of ipamd is verified against, the host of Address by default
+	ServerName string `json:"serverName,omitempty"`
+}
+
+// WithTLS returns the DialOption to call ipamd with mutual TLS. The files are read on every call, so rotated
+// certificates are picked up by the next connection.
+func WithTLS(config *TLSConfig) (google_grpc.DialOption, error) {
+	cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the TLS client certificate")
+	}
+	caPEM, err := os.ReadFile(config.CAFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the CA")
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.Errorf("no certificate found in the CA %s", config.CAFile)
+	}
+	return google_grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      caPool,
+		ServerName:   config.ServerName,
+	})), nil
+}
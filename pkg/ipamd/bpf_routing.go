@@ -0,0 +1,101 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"net"
+	"sort"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/bpfrouting"
+)
+
+// podRouteTable returns the route table of the traffic from the pod IPs of an ENI
+func podRouteTable(deviceNumber int) int {
+	if deviceNumber == 0 {
+		return unix.RT_TABLE_MAIN
+	}
+	return deviceNumber + 1
+}
+
+// bpfRouteTable returns the route table of a pod IP in the BPF map. The pods of an ENI whose route table does not fit
+// in the mark are routed by a rule per pod IP, they are in the map with the main route table to leave their traffic
+// unmarked.
+func bpfRouteTable(deviceNumber int) int {
+	if !bpfrouting.Routable(deviceNumber) {
+		return unix.RT_TABLE_MAIN
+	}
+	return podRouteTable(deviceNumber)
+}
+
+// addBPFPodRoute adds a pod IP to the BPF map of eBPF pod routing. The plugin attaches the program to the host veth of
+// the pod after the reply, so that the first packet of the pod is routed by the map.
+func (c *IPAMContext) addBPFPodRoute(ipv4Addr string, deviceNumber int) {
+	if err := c.bpfRouteMap.Update(net.ParseIP(ipv4Addr), bpfRouteTable(deviceNumber)); err != nil {
+		// Retried by syncBPFPodRoutes
+		log.Errorf("Failed to add pod IP %s to the BPF route map: %v", ipv4Addr, err)
+	}
+}
+
+// deleteBPFPodRoute removes a pod IP from the BPF map of eBPF pod routing
+func (c *IPAMContext) deleteBPFPodRoute(ipv4Addr string) {
+	if err := c.bpfRouteMap.Delete(net.ParseIP(ipv4Addr)); err != nil {
+		log.Errorf("Failed to delete pod IP %s from the BPF route map: %v", ipv4Addr, err)
+	}
+}
+
+// syncBPFPodRoutes reconciles the BPF map of eBPF pod routing with the pod IPs in the datastore, and the rules that
+// match the mark with the route tables of the ENIs
+func (c *IPAMContext) syncBPFPodRoutes() {
+	wanted := make(map[string]int)
+	for _, info := range c.dataStore.AllocatedIPs() {
+		wanted[info.IP] = bpfRouteTable(info.DeviceNumber)
+	}
+	current, err := c.bpfRouteMap.List()
+	if err != nil {
+		log.Errorf("Failed to list the BPF route map: %v", err)
+		return
+	}
+	for ip := range current {
+		if _, ok := wanted[ip]; !ok {
+			log.Debugf("Deleting stale pod IP %s from the BPF route map", ip)
+			c.deleteBPFPodRoute(ip)
+		}
+	}
+	for ip, table := range wanted {
+		if current[ip] != table {
+			log.Debugf("Setting route table %d of pod IP %s in the BPF route map", table, ip)
+			if err := c.bpfRouteMap.Update(net.ParseIP(ip), table); err != nil {
+				log.Errorf("Failed to add pod IP %s to the BPF route map: %v", ip, err)
+			}
+		}
+	}
+
+	var tables []int
+	for _, eni := range c.dataStore.GetENIInfos().ENIs {
+		if eni.DeviceNumber > 0 && bpfrouting.Routable(eni.DeviceNumber) {
+			tables = append(tables, podRouteTable(eni.DeviceNumber))
+		}
+	}
+	sort.Ints(tables)
+	rules, err := c.networkClient.GetRuleList()
+	if err != nil {
+		log.Errorf("Failed to get the rules to update pod routing mark rules: %v", err)
+		return
+	}
+	if err := c.networkClient.UpdatePodRoutingMarkRules(rules, tables); err != nil {
+		log.Errorf("Failed to update pod routing mark rules: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/bpfrouting"
+	mock_bpfrouting "github.com/aws/amazon-vpc-cni-k8s/pkg/bpfrouting/mocks"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	pb "github.com/aws/amazon-vpc-cni-k8s/rpc"
+)
+
+func TestSyncBPFPodRoutes(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	routeMap := mock_bpfrouting.NewMockRouteMap(m.ctrl)
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	_ = ds.AddENI(primaryENIid, primaryDevice, true, false, false)
+	_ = ds.AddIPv4CidrToStore(primaryENIid, net.IPNet{IP: net.ParseIP(ipaddr02), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	_ = ds.AddENI(secENIid, secDevice, false, false, false)
+	_ = ds.AddIPv4CidrToStore(secENIid, net.IPNet{IP: net.ParseIP(ipaddr12), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	for _, containerID := range []string{"sandbox-1", "sandbox-2"} {
+		_, _, err := ds.AssignPodIPv4Address(datastore.IPAMKey{NetworkName: "net0", ContainerID: containerID, IfName: "eth0"}, datastore.IPAMMetadata{})
+		assert.NoError(t, err)
+	}
+
+	mockContext := &IPAMContext{
+		networkClient: m.network,
+		dataStore:     ds,
+		bpfRouteMap:   routeMap,
+	}
+
+	// The pod IP of the primary ENI is up to date, the one of the secondary ENI is missing and another one is stale
+	routeMap.EXPECT().List().Return(map[string]int{ipaddr02: 254, "10.10.30.12": 3}, nil)
+	routeMap.EXPECT().Delete(net.ParseIP("10.10.30.12")).Return(nil)
+	routeMap.EXPECT().Update(net.ParseIP(ipaddr12), secDevice+1).Return(nil)
+	rules := []netlink.Rule{}
+	m.network.EXPECT().GetRuleList().Return(rules, nil)
+	m.network.EXPECT().UpdatePodRoutingMarkRules(rules, []int{secDevice + 1}).Return(nil)
+
+	mockContext.syncBPFPodRoutes()
+}
+
+func TestSyncBPFPodRoutesTableOutOfMark(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	routeMap := mock_bpfrouting.NewMockRouteMap(m.ctrl)
+
+	// With multiple network cards, the route table of an ENI can be higher than the mark holds
+	highDevice := bpfrouting.MaxTable
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	_ = ds.AddENI(secENIid, highDevice, false, false, false)
+	_ = ds.AddIPv4CidrToStore(secENIid, net.IPNet{IP: net.ParseIP(ipaddr12), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	_, _, err := ds.AssignPodIPv4Address(datastore.IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"}, datastore.IPAMMetadata{})
+	assert.NoError(t, err)
+
+	mockContext := &IPAMContext{
+		networkClient: m.network,
+		dataStore:     ds,
+		bpfRouteMap:   routeMap,
+	}
+
+	// The pod has a rule of its own, its IP is in the map with the main route table and there is no mark rule
+	routeMap.EXPECT().List().Return(map[string]int{}, nil)
+	routeMap.EXPECT().Update(net.ParseIP(ipaddr12), unix.RT_TABLE_MAIN).Return(nil)
+	rules := []netlink.Rule{}
+	m.network.EXPECT().GetRuleList().Return(rules, nil)
+	m.network.EXPECT().UpdatePodRoutingMarkRules(rules, nil).Return(nil)
+
+	mockContext.syncBPFPodRoutes()
+}
+
+func TestConfigureIPRulesForPodsRestoresRules(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	_ = ds.AddENI(primaryENIid, primaryDevice, true, false, false)
+	_ = ds.AddIPv4CidrToStore(primaryENIid, net.IPNet{IP: net.ParseIP(ipaddr02), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	_ = ds.AddENI(secENIid, secDevice, false, false, false)
+	_ = ds.AddIPv4CidrToStore(secENIid, net.IPNet{IP: net.ParseIP(ipaddr12), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	for _, containerID := range []string{"sandbox-1", "sandbox-2"} {
+		_, _, err := ds.AssignPodIPv4Address(datastore.IPAMKey{NetworkName: "net0", ContainerID: containerID, IfName: "eth0"}, datastore.IPAMMetadata{})
+		assert.NoError(t, err)
+	}
+
+	// eBPF pod routing is disabled, the pod of the secondary ENI gets its rule back
+	mockContext := &IPAMContext{
+		networkClient: m.network,
+		dataStore:     ds,
+	}
+	rules := []netlink.Rule{}
+	m.network.EXPECT().GetRuleList().Return(rules, nil)
+	m.network.EXPECT().UpdateRuleListBySrc(rules, gomock.Any()).Times(2)
+	m.network.EXPECT().SetupRuleBySrc(rules, net.IPNet{IP: net.ParseIP(ipaddr12), Mask: net.IPv4Mask(255, 255, 255, 255)}, secDevice+1)
+	m.network.EXPECT().GetExternalServiceCIDRs().Return(nil)
+	m.network.EXPECT().UpdateExternalServiceIpRules(rules, nil)
+
+	assert.NoError(t, mockContext.configureIPRulesForPods())
+}
+
+func TestServer_AddDelNetworkBPFPodRoute(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	routeMap := mock_bpfrouting.NewMockRouteMap(m.ctrl)
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	_ = ds.AddENI(secENIid, secDevice, false, false, false)
+	_ = ds.AddIPv4CidrToStore(secENIid, net.IPNet{IP: net.ParseIP(ipaddr12), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+
+	mockContext := &IPAMContext{
+		awsClient:     m.awsutils,
+		k8sClient:     m.k8sClient,
+		networkClient: m.network,
+		enableIPv4:    true,
+		dataStore:     ds,
+		bpfRouteMap:   routeMap,
+	}
+	m.awsutils.EXPECT().GetVPCIPv4CIDRs().Return([]string{}, nil)
+	m.network.EXPECT().UseExternalSNAT().Return(true)
+	s := &server{version: "1.2.3", ipamContext: mockContext}
+
+	routeMap.EXPECT().Update(net.ParseIP(ipaddr12), secDevice+1).Return(nil)
+	resp, err := s.AddNetwork(context.Background(), &pb.AddNetworkRequest{
+		ClientVersion: "1.2.3",
+		NetworkName:   "net0",
+		ContainerID:   "cid",
+		IfName:        "eth0",
+	})
+	assert.NoError(t, err)
+	assert.True(t, resp.Success)
+
+	routeMap.EXPECT().Delete(net.ParseIP(ipaddr12)).Return(nil)
+	delResp, err := s.DelNetwork(context.Background(), &pb.DelNetworkRequest{
+		ClientVersion: "1.2.3",
+		NetworkName:   "net0",
+		ContainerID:   "cid",
+		IfName:        "eth0",
+	})
+	assert.NoError(t, err)
+	assert.True(t, delResp.Success)
+}
@@ -164,6 +164,14 @@ type ENI struct {
 	IsEFA bool
 	// DeviceNumber is the device number of ENI (0 means the primary ENI)
 	DeviceNumber int
+	// PrefixFallback indicates that the subnet had no free /28 block for this ENI, so secondary IPs
+	// are allocated on it instead of prefixes
+	PrefixFallback bool
+	// PrefixFallbackTime is the last time a prefix could not be allocated on this ENI
+	PrefixFallbackTime time.Time
+	// ENIConfig is the name of the ENIConfig the ENI was created for. Such an ENI only serves pods that ask for
+	// that ENIConfig, and is not part of the node's warm pool. It is empty for the node's ENIs.
+	ENIConfig string
 	// IPv4Addresses shows whether each address is assigned, the key is IP address, which must
 	// be in dot-decimal notation with no leading zeros and no whitespace(eg: "10.1.0.253")
 	// Key is the IP address - PD: "IP/28" and SIP: "IP/32"
@@ -200,18 +208,23 @@ func (cidr *CidrInfo) Size() int {
 }
 
 func (e *ENI) findAddressForSandbox(ipamKey IPAMKey) (*CidrInfo, *AddressInfo) {
-	// Either v4 or v6 for now.
-	// Check in V4 prefixes
-	for _, availableCidr := range e.AvailableIPv4Cidrs {
-		for _, addr := range availableCidr.IPAddresses {
-			if addr.IPAMKey == ipamKey {
-				return availableCidr, addr
-			}
-		}
+	// In dual-stack mode a sandbox has both a v4 and a v6 address, the v4 address is returned first.
+	if availableCidr, addr := e.findIPv4AddressForSandbox(ipamKey); addr != nil {
+		return availableCidr, addr
 	}
+	return e.findIPv6AddressForSandbox(ipamKey)
+}
+
+func (e *ENI) findIPv4AddressForSandbox(ipamKey IPAMKey) (*CidrInfo, *AddressInfo) {
+	return findAddressInCidrs(e.AvailableIPv4Cidrs, ipamKey)
+}
+
+func (e *ENI) findIPv6AddressForSandbox(ipamKey IPAMKey) (*CidrInfo, *AddressInfo) {
+	return findAddressInCidrs(e.IPv6Cidrs, ipamKey)
+}
 
-	// Check in V6 prefixes
-	for _, availableCidr := range e.IPv6Cidrs {
+func findAddressInCidrs(cidrs map[string]*CidrInfo, ipamKey IPAMKey) (*CidrInfo, *AddressInfo) {
+	for _, availableCidr := range cidrs {
 		for _, addr := range availableCidr.IPAddresses {
 			if addr.IPAMKey == ipamKey {
 				return availableCidr, addr
@@ -230,6 +243,15 @@ func (e *ENI) AssignedIPv4Addresses() int {
 	return count
 }
 
+// AssignedIPv6Addresses is the number of IPv6 addresses already assigned
+func (e *ENI) AssignedIPv6Addresses() int {
+	count := 0
+	for _, v6Cidr := range e.IPv6Cidrs {
+		count += v6Cidr.AssignedIPAddressesInCidr()
+	}
+	return count
+}
+
 // AssignedIPAddressesInCidr is the number of IP addresses already assigned in the IPv4 CIDR
 func (cidr *CidrInfo) AssignedIPAddressesInCidr() int {
 	count := 0
@@ -292,10 +314,27 @@ func (p *ENIPool) AssignedIPv4Addresses() int {
 	return count
 }
 
-// FindAddressForSandbox returns ENI and AddressInfo or (nil, nil) if not found
+// FindAddressForSandbox returns ENI and AddressInfo or (nil, nil) if not found. The v4 address of a dual-stack
+// sandbox is returned before its v6 address.
 func (p *ENIPool) FindAddressForSandbox(ipamKey IPAMKey) (*ENI, *CidrInfo, *AddressInfo) {
+	if eni, availableCidr, addr := p.findIPv4AddressForSandbox(ipamKey); addr != nil {
+		return eni, availableCidr, addr
+	}
+	return p.findIPv6AddressForSandbox(ipamKey)
+}
+
+func (p *ENIPool) findIPv4AddressForSandbox(ipamKey IPAMKey) (*ENI, *CidrInfo, *AddressInfo) {
 	for _, eni := range *p {
-		if availableCidr, addr := eni.findAddressForSandbox(ipamKey); addr != nil && availableCidr != nil {
+		if availableCidr, addr := eni.findIPv4AddressForSandbox(ipamKey); addr != nil && availableCidr != nil {
+			return eni, availableCidr, addr
+		}
+	}
+	return nil, nil, nil
+}
+
+func (p *ENIPool) findIPv6AddressForSandbox(ipamKey IPAMKey) (*ENI, *CidrInfo, *AddressInfo) {
+	for _, eni := range *p {
+		if availableCidr, addr := eni.findIPv6AddressForSandbox(ipamKey); addr != nil && availableCidr != nil {
 			return eni, availableCidr, addr
 		}
 	}
@@ -309,6 +348,8 @@ type PodIPInfo struct {
 	IP string
 	// DeviceNumber is the device number of the ENI
 	DeviceNumber int
+	// Metadata is the pod the IP is assigned to
+	Metadata IPAMMetadata
 }
 
 // DataStore contains node level ENI/IP
@@ -322,7 +363,19 @@ type DataStore struct {
 	backingStore     Checkpointer
 	netLink          netlinkwrapper.NetLink
 	isPDEnabled      bool
+	isHybridMode     bool
 	ipCooldownPeriod time.Duration
+	// passthroughENIs are the ENIs that are moved into the network namespace of a pod, keyed by ENI ID. An ENI stays
+	// reserved until its link is set up on the host again, even if ipamd no longer knows the ENI.
+	passthroughENIs map[string]*PassthroughENI
+}
+
+// SetHybridMode makes both prefixes and secondary IPs valid sources of pod IPs when PD is enabled. Warm and minimum
+// IP targets are then counted in IPs across both instead of being rounded up to whole prefixes.
+func (ds *DataStore) SetHybridMode(enabled bool) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	ds.isHybridMode = enabled
 }
 
 // ENIInfos contains ENI IP information
@@ -358,6 +411,7 @@ func NewDataStore(log logger.Logger, backingStore Checkpointer, isPDEnabled bool
 		netLink:          netlinkwrapper.NewNetLink(),
 		isPDEnabled:      isPDEnabled,
 		ipCooldownPeriod: getCooldownPeriod(),
+		passthroughENIs:  make(map[string]*PassthroughENI),
 	}
 }
 
@@ -380,11 +434,13 @@ type CheckpointEntry struct {
 	IPv6                string       `json:"ipv6,omitempty"`
 	AllocationTimestamp int64        `json:"allocationTimestamp"`
 	Metadata            IPAMMetadata `json:"metadata"`
+	// PassthroughENI is set when the sandbox has a whole ENI, IPv4 is then the primary IP of that ENI
+	PassthroughENI string `json:"passthroughENI,omitempty"`
 }
 
 // ReadBackingStore initializes the IP allocation state from the
 // configured backing store. Should be called before using data store.
-func (ds *DataStore) ReadBackingStore(isv6Enabled bool) error {
+func (ds *DataStore) ReadBackingStore(isv4Enabled, isv6Enabled bool) error {
 	var data CheckpointData
 
 	// Read from checkpoint file
@@ -412,15 +468,28 @@ func (ds *DataStore) ReadBackingStore(isv6Enabled bool) error {
 	defer ds.lock.Unlock()
 
 	for _, allocation := range data.Allocations {
+		if allocation.PassthroughENI != "" {
+			ds.passthroughENIs[allocation.PassthroughENI] = &PassthroughENI{
+				ENIID:        allocation.PassthroughENI,
+				IPv4:         allocation.IPv4,
+				IPAMKey:      allocation.IPAMKey,
+				IPAMMetadata: allocation.Metadata,
+				AssignedTime: time.Unix(0, allocation.AllocationTimestamp),
+			}
+			ds.log.Debugf("Recovered %s => passthrough ENI %s", allocation.IPAMKey, allocation.PassthroughENI)
+			continue
+		}
 		ipv4Addr := net.ParseIP(allocation.IPv4)
 		ipv6Addr := net.ParseIP(allocation.IPv6)
+		// In dual-stack mode, the v4 and v6 address of a sandbox are stored as separate entries
+		isv6Allocation := isv6Enabled && (!isv4Enabled || allocation.IPv6 != "")
 		var ipAddr net.IP
 		found := false
 	eniloop:
 		for _, eni := range ds.eniPool {
 			eniCidrs := eni.AvailableIPv4Cidrs
 			ipAddr = ipv4Addr
-			if isv6Enabled {
+			if isv6Allocation {
 				ds.log.Debugf("v6 is enabled")
 				eniCidrs = eni.IPv6Cidrs
 				ipAddr = ipv6Addr
@@ -493,6 +562,19 @@ func (ds *DataStore) writeBackingStoreUnsafe() error {
 		}
 	}
 
+	for _, passthroughENI := range ds.passthroughENIs {
+		if passthroughENI.Released {
+			continue
+		}
+		allocations = append(allocations, CheckpointEntry{
+			IPAMKey:             passthroughENI.IPAMKey,
+			IPv4:                passthroughENI.IPv4,
+			AllocationTimestamp: passthroughENI.AssignedTime.UnixNano(),
+			Metadata:            passthroughENI.IPAMMetadata,
+			PassthroughENI:      passthroughENI.ENIID,
+		})
+	}
+
 	data := CheckpointData{
 		Version:     CheckpointFormatVersion,
 		Allocations: allocations,
@@ -525,6 +607,19 @@ func (ds *DataStore) AddENI(eniID string, deviceNumber int, isPrimary, isTrunk,
 	return nil
 }
 
+// DeviceNumberInUse returns the ID of an ENI other than exceptENI that uses the device number, if any
+func (ds *DataStore) DeviceNumberInUse(deviceNumber int, exceptENI string) (string, bool) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	for eniID, eni := range ds.eniPool {
+		if eniID != exceptENI && eni.DeviceNumber == deviceNumber {
+			return eniID, true
+		}
+	}
+	return "", false
+}
+
 // AddIPv4AddressToStore adds IPv4 CIDR of an ENI to data store
 func (ds *DataStore) AddIPv4CidrToStore(eniID string, ipv4Cidr net.IPNet, isPrefix bool) error {
 	ds.lock.Lock()
@@ -656,10 +751,71 @@ func (ds *DataStore) AddIPv6CidrToStore(eniID string, ipv6Cidr net.IPNet, isPref
 	return nil
 }
 
+// DelIPv6CidrFromStore deletes an IPv6 CIDR of an ENI from data store
+func (ds *DataStore) DelIPv6CidrFromStore(eniID string, ipv6Cidr net.IPNet, force bool) error {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	curENI, ok := ds.eniPool[eniID]
+	if !ok {
+		ds.log.Debugf("Unknown ENI %s while deleting the IPv6 CIDR", eniID)
+		return errors.New(UnknownENIError)
+	}
+	strIPv6Cidr := ipv6Cidr.String()
+
+	deletableCidr, ok := curENI.IPv6Cidrs[strIPv6Cidr]
+	if !ok {
+		ds.log.Debugf("Unknown %s CIDR", strIPv6Cidr)
+		return errors.New(UnknownIPError)
+	}
+
+	updateBackingStore := false
+	for _, addr := range deletableCidr.IPAddresses {
+		if addr.Assigned() {
+			if !force {
+				return errors.New(IPInUseError)
+			}
+			forceRemovedIPs.Inc()
+			ds.unassignPodIPAddressUnsafe(addr)
+			updateBackingStore = true
+		}
+	}
+	if updateBackingStore {
+		if err := ds.writeBackingStoreUnsafe(); err != nil {
+			ds.log.Warnf("Unable to update backing store: %v", err)
+			// Continuing because 'force'
+		}
+	}
+	ds.total -= deletableCidr.Size()
+	if deletableCidr.IsPrefix {
+		ds.allocatedPrefix--
+		totalPrefixes.Set(float64(ds.allocatedPrefix))
+	}
+	totalIPs.Set(float64(ds.total))
+	delete(curENI.IPv6Cidrs, strIPv6Cidr)
+	ds.log.Infof("Deleted ENI(%s)'s IPv6 Prefix %s from datastore", eniID, strIPv6Cidr)
+
+	return nil
+}
+
 func (ds *DataStore) AssignPodIPAddress(ipamKey IPAMKey, ipamMetadata IPAMMetadata, isIPv4Enabled bool, isIPv6Enabled bool) (ipv4Address string,
 	ipv6Address string, deviceNumber int, err error) {
-	//Currently it's either v4 or v6. Dual Stack mode isn't supported.
-	if isIPv4Enabled {
+	if isIPv4Enabled && isIPv6Enabled {
+		// In dual-stack mode, the device number is the one of the v4 address. The v6 address comes from a prefix on
+		// the primary ENI, so it is routed through the main route table.
+		ipv4Address, deviceNumber, err = ds.AssignPodIPv4Address(ipamKey, ipamMetadata)
+		if err != nil {
+			return "", "", -1, err
+		}
+		ipv6Address, _, err = ds.AssignPodIPv6Address(ipamKey, ipamMetadata)
+		if err != nil {
+			// Important! Unwind the v4 assignment, the pod needs both addresses
+			if _, _, _, errUnassign := ds.UnassignPodIPAddress(ipamKey); errUnassign != nil {
+				ds.log.Warnf("Failed to unassign IPv4 address %s of sandbox %s: %v", ipv4Address, ipamKey, errUnassign)
+			}
+			return "", "", -1, err
+		}
+	} else if isIPv4Enabled {
 		ipv4Address, deviceNumber, err = ds.AssignPodIPv4Address(ipamKey, ipamMetadata)
 	} else if isIPv6Enabled {
 		ipv6Address, deviceNumber, err = ds.AssignPodIPv6Address(ipamKey, ipamMetadata)
@@ -677,41 +833,39 @@ func (ds *DataStore) AssignPodIPv6Address(ipamKey IPAMKey, ipamMetadata IPAMMeta
 	}
 	ds.log.Debugf("AssignIPv6Address: IPv6 address pool stats: assigned %d", ds.assigned)
 
-	if eni, _, addr := ds.eniPool.FindAddressForSandbox(ipamKey); addr != nil {
+	if eni, _, addr := ds.eniPool.findIPv6AddressForSandbox(ipamKey); addr != nil {
 		ds.log.Infof("AssignPodIPv6Address: duplicate pod assign for sandbox %s", ipamKey)
 		return addr.Address, eni.DeviceNumber, nil
 	}
 
-	// In IPv6 Prefix Delegation mode, eniPool will only have Primary ENI.
-	for _, eni := range ds.eniPool {
-		if len(eni.IPv6Cidrs) == 0 {
-			continue
-		}
-		for _, V6Cidr := range eni.IPv6Cidrs {
-			if !V6Cidr.IsPrefix {
-				continue
-			}
-			ipv6Address, err = ds.getFreeIPv6AddrFromCidr(V6Cidr)
-			if err != nil {
-				ds.log.Debugf("Unable to get IP address from prefix: %v", err)
-				//In v6 mode, we (should) only have one CIDR/Prefix. So, we can bail out but we will let the loop
-				//exit instead.
-				continue
-			}
-			ds.log.Debugf("New v6 IP from PD pool- %s", ipv6Address)
-			addr := &AddressInfo{Address: ipv6Address}
-			V6Cidr.IPAddresses[ipv6Address] = addr
-
-			ds.assignPodIPAddressUnsafe(addr, ipamKey, ipamMetadata, time.Now())
-			if err := ds.writeBackingStoreUnsafe(); err != nil {
-				ds.log.Warnf("Failed to update backing store: %v", err)
-				// Important! Unwind assignment
-				ds.unassignPodIPAddressUnsafe(addr)
-				//Remove the IP from eni DB
-				delete(V6Cidr.IPAddresses, addr.Address)
-				return "", -1, err
+	// A v6 prefix practically never runs out of addresses, so prefixes that already have pods are used first and
+	// the prefixes without pods are kept warm.
+	for _, inUse := range []bool{true, false} {
+		for _, eni := range ds.eniPool {
+			for _, V6Cidr := range eni.IPv6Cidrs {
+				if !V6Cidr.IsPrefix || (V6Cidr.AssignedIPAddressesInCidr() > 0) != inUse {
+					continue
+				}
+				ipv6Address, err = ds.getFreeIPv6AddrFromCidr(V6Cidr)
+				if err != nil {
+					ds.log.Debugf("Unable to get IP address from prefix: %v", err)
+					continue
+				}
+				ds.log.Debugf("New v6 IP from PD pool- %s", ipv6Address)
+				addr := &AddressInfo{Address: ipv6Address}
+				V6Cidr.IPAddresses[ipv6Address] = addr
+
+				ds.assignPodIPAddressUnsafe(addr, ipamKey, ipamMetadata, time.Now())
+				if err := ds.writeBackingStoreUnsafe(); err != nil {
+					ds.log.Warnf("Failed to update backing store: %v", err)
+					// Important! Unwind assignment
+					ds.unassignPodIPAddressUnsafe(addr)
+					//Remove the IP from eni DB
+					delete(V6Cidr.IPAddresses, addr.Address)
+					return "", -1, err
+				}
+				return addr.Address, eni.DeviceNumber, nil
 			}
-			return addr.Address, eni.DeviceNumber, nil
 		}
 	}
 	return "", -1, errors.New("assignPodIPv6AddressUnsafe: no available IP addresses")
@@ -720,23 +874,32 @@ func (ds *DataStore) AssignPodIPv6Address(ipamKey IPAMKey, ipamMetadata IPAMMeta
 // AssignPodIPv4Address assigns an IPv4 address to pod
 // It returns the assigned IPv4 address, device number, error
 func (ds *DataStore) AssignPodIPv4Address(ipamKey IPAMKey, ipamMetadata IPAMMetadata) (ipv4address string, deviceNumber int, err error) {
+	return ds.AssignPodIPv4AddressForENIConfig(ipamKey, ipamMetadata, "")
+}
+
+// AssignPodIPv4AddressForENIConfig assigns an IPv4 address to pod from the ENIs of the given ENIConfig. An empty
+// ENIConfig name means the node's ENIs.
+func (ds *DataStore) AssignPodIPv4AddressForENIConfig(ipamKey IPAMKey, ipamMetadata IPAMMetadata, eniConfig string) (ipv4address string, deviceNumber int, err error) {
 	ds.lock.Lock()
 	defer ds.lock.Unlock()
 
 	ds.log.Debugf("AssignIPv4Address: IP address pool stats: total %d, assigned %d", ds.total, ds.assigned)
 
-	if eni, _, addr := ds.eniPool.FindAddressForSandbox(ipamKey); addr != nil {
+	if eni, _, addr := ds.eniPool.findIPv4AddressForSandbox(ipamKey); addr != nil {
 		ds.log.Infof("AssignPodIPv4Address: duplicate pod assign for sandbox %s", ipamKey)
 		return addr.Address, eni.DeviceNumber, nil
 	}
 
 	for _, eni := range ds.eniPool {
+		if eni.ENIConfig != eniConfig || ds.isPassthroughENIUnsafe(eni.ID) {
+			continue
+		}
 		for _, availableCidr := range eni.AvailableIPv4Cidrs {
 			var addr *AddressInfo
 			var strPrivateIPv4 string
 			var err error
 
-			if (ds.isPDEnabled && availableCidr.IsPrefix) || (!ds.isPDEnabled && !availableCidr.IsPrefix) {
+			if ds.isUsableIPv4Cidr(eni, availableCidr) {
 				strPrivateIPv4, err = ds.getFreeIPv4AddrfromCidr(availableCidr)
 				if err != nil {
 					ds.log.Debugf("Unable to get IP address from CIDR: %v", err)
@@ -781,7 +944,11 @@ func (ds *DataStore) AssignPodIPv4Address(ipamKey IPAMKey, ipamMetadata IPAMMeta
 		ds.log.Debugf("AssignPodIPv4Address: ENI %s does not have available addresses", eni.ID)
 	}
 
-	ds.log.Errorf("DataStore has no available IP/Prefix addresses")
+	if eniConfig != "" {
+		ds.log.Errorf("DataStore has no available IP/Prefix addresses for ENIConfig %s", eniConfig)
+	} else {
+		ds.log.Errorf("DataStore has no available IP/Prefix addresses")
+	}
 	return "", -1, errors.New("assignPodIPv4AddressUnsafe: no available IP/Prefix addresses")
 }
 
@@ -838,21 +1005,31 @@ func (stats *DataStoreStats) AvailableAddresses() int {
 	return stats.TotalIPs - stats.AssignedIPs
 }
 
-// GetIPStats returns DataStoreStats for addressFamily
+// GetIPStats returns DataStoreStats for addressFamily of the node's ENIs
 func (ds *DataStore) GetIPStats(addressFamily string) *DataStoreStats {
+	return ds.GetENIConfigIPStats(addressFamily, "")
+}
+
+// GetENIConfigIPStats returns DataStoreStats for addressFamily of the ENIs of the given ENIConfig. An empty ENIConfig
+// name means the node's ENIs.
+func (ds *DataStore) GetENIConfigIPStats(addressFamily string, eniConfig string) *DataStoreStats {
 	ds.lock.Lock()
 	defer ds.lock.Unlock()
 
-	stats := &DataStoreStats{
-		TotalPrefixes: ds.allocatedPrefix,
-	}
+	stats := &DataStoreStats{}
 	for _, eni := range ds.eniPool {
+		if eni.ENIConfig != eniConfig || ds.isPassthroughENIUnsafe(eni.ID) {
+			continue
+		}
 		AssignedCIDRs := eni.AvailableIPv4Cidrs
 		if addressFamily == "6" {
 			AssignedCIDRs = eni.IPv6Cidrs
 		}
 		for _, cidr := range AssignedCIDRs {
-			if addressFamily == "4" && ((ds.isPDEnabled && cidr.IsPrefix) || (!ds.isPDEnabled && !cidr.IsPrefix)) {
+			if cidr.IsPrefix {
+				stats.TotalPrefixes++
+			}
+			if addressFamily == "4" && ds.isUsableIPv4Cidr(eni, cidr) {
 				cidrStats := cidr.GetIPStatsFromCidr(ds.ipCooldownPeriod)
 				stats.AssignedIPs += cidrStats.AssignedIPs
 				stats.CooldownIPs += cidrStats.CooldownIPs
@@ -866,6 +1043,24 @@ func (ds *DataStore) GetIPStats(addressFamily string) *DataStoreStats {
 	return stats
 }
 
+// GetIPv6PrefixCounts returns the number of IPv6 prefixes on the node, and how many of them have no pods assigned
+func (ds *DataStore) GetIPv6PrefixCounts() (total int, free int) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	for _, eni := range ds.eniPool {
+		for _, v6Cidr := range eni.IPv6Cidrs {
+			if !v6Cidr.IsPrefix {
+				continue
+			}
+			total++
+			if v6Cidr.AssignedIPAddressesInCidr() == 0 {
+				free++
+			}
+		}
+	}
+	return total, free
+}
+
 // GetTrunkENI returns the trunk ENI ID or an empty string
 func (ds *DataStore) GetTrunkENI() string {
 	ds.lock.Lock()
@@ -896,16 +1091,16 @@ func (ds *DataStore) GetEFAENIs() map[string]bool {
 func (ds *DataStore) isRequiredForWarmIPTarget(warmIPTarget int, eni *ENI) bool {
 	otherWarmIPs := 0
 	for _, other := range ds.eniPool {
-		if other.ID != eni.ID {
+		if other.ID != eni.ID && other.ENIConfig == "" && !ds.isPassthroughENIUnsafe(other.ID) {
 			for _, otherPrefixes := range other.AvailableIPv4Cidrs {
-				if (ds.isPDEnabled && otherPrefixes.IsPrefix) || (!ds.isPDEnabled && !otherPrefixes.IsPrefix) {
+				if ds.isUsableIPv4Cidr(other, otherPrefixes) {
 					otherWarmIPs += otherPrefixes.Size() - otherPrefixes.AssignedIPAddressesInCidr()
 				}
 			}
 		}
 	}
 
-	if ds.isPDEnabled {
+	if ds.isPDEnabled && !ds.isHybridMode {
 		_, numIPsPerPrefix, _ := GetPrefixDelegationDefaults()
 		numPrefixNeeded := DivCeil(warmIPTarget, numIPsPerPrefix)
 		warmIPTarget = numPrefixNeeded * numIPsPerPrefix
@@ -918,16 +1113,16 @@ func (ds *DataStore) isRequiredForWarmIPTarget(warmIPTarget int, eni *ENI) bool
 func (ds *DataStore) isRequiredForMinimumIPTarget(minimumIPTarget int, eni *ENI) bool {
 	otherIPs := 0
 	for _, other := range ds.eniPool {
-		if other.ID != eni.ID {
+		if other.ID != eni.ID && other.ENIConfig == "" && !ds.isPassthroughENIUnsafe(other.ID) {
 			for _, otherPrefixes := range other.AvailableIPv4Cidrs {
-				if (ds.isPDEnabled && otherPrefixes.IsPrefix) || (!ds.isPDEnabled && !otherPrefixes.IsPrefix) {
+				if ds.isUsableIPv4Cidr(other, otherPrefixes) {
 					otherIPs += otherPrefixes.Size()
 				}
 			}
 		}
 	}
 
-	if ds.isPDEnabled {
+	if ds.isPDEnabled && !ds.isHybridMode {
 		_, numIPsPerPrefix, _ := GetPrefixDelegationDefaults()
 		numPrefixNeeded := DivCeil(minimumIPTarget, numIPsPerPrefix)
 		minimumIPTarget = numPrefixNeeded * numIPsPerPrefix
@@ -938,9 +1133,14 @@ func (ds *DataStore) isRequiredForMinimumIPTarget(minimumIPTarget int, eni *ENI)
 // IsRequiredForWarmPrefixTarget determines if this ENI is necessary to fulfill whatever WARM_PREFIX_TARGET is
 // set to.
 func (ds *DataStore) isRequiredForWarmPrefixTarget(warmPrefixTarget int, eni *ENI) bool {
+	if ds.isHybridMode {
+		// In hybrid mode, WARM_PREFIX_TARGET is the number of free IPs in that many prefixes
+		_, numIPsPerPrefix, _ := GetPrefixDelegationDefaults()
+		return ds.isRequiredForWarmIPTarget(warmPrefixTarget*numIPsPerPrefix, eni)
+	}
 	freePrefixes := 0
 	for _, other := range ds.eniPool {
-		if other.ID != eni.ID {
+		if other.ID != eni.ID && other.ENIConfig == "" && !ds.isPassthroughENIUnsafe(other.ID) {
 			for _, otherPrefixes := range other.AvailableIPv4Cidrs {
 				if otherPrefixes.AssignedIPAddressesInCidr() == 0 {
 					freePrefixes++
@@ -958,6 +1158,16 @@ func (ds *DataStore) getDeletableENI(warmIPTarget, minimumIPTarget, warmPrefixTa
 			continue
 		}
 
+		if eni.ENIConfig != "" {
+			ds.log.Debugf("ENI %s is not part of the node's pool because it serves pods of ENIConfig %s", eni.ID, eni.ENIConfig)
+			continue
+		}
+
+		if ds.isPassthroughENIUnsafe(eni.ID) {
+			ds.log.Debugf("ENI %s cannot be deleted because it is moved into a pod", eni.ID)
+			continue
+		}
+
 		if eni.isTooYoung() {
 			ds.log.Debugf("ENI %s cannot be deleted because it is too young", eni.ID)
 			continue
@@ -973,6 +1183,11 @@ func (ds *DataStore) getDeletableENI(warmIPTarget, minimumIPTarget, warmPrefixTa
 			continue
 		}
 
+		if len(eni.IPv6Cidrs) > 0 {
+			ds.log.Debugf("ENI %s cannot be deleted because it has IPv6 prefixes attached", eni.ID)
+			continue
+		}
+
 		if warmIPTarget != 0 && ds.isRequiredForWarmIPTarget(warmIPTarget, eni) {
 			ds.log.Debugf("ENI %s cannot be deleted because it is required for WARM_IP_TARGET: %d", eni.ID, warmIPTarget)
 			continue
@@ -1018,19 +1233,35 @@ func (e *ENI) hasIPInCooling(ipCooldownPeriod time.Duration) bool {
 			}
 		}
 	}
+	for _, v6Cidr := range e.IPv6Cidrs {
+		for _, addr := range v6Cidr.IPAddresses {
+			if addr.inCoolingPeriod(ipCooldownPeriod) {
+				return true
+			}
+		}
+	}
 	return false
 }
 
 // HasPods returns true if the ENI has pods assigned to it.
 func (e *ENI) hasPods() bool {
-	return e.AssignedIPv4Addresses() != 0
+	return e.AssignedIPv4Addresses() != 0 || e.AssignedIPv6Addresses() != 0
 }
 
-// GetENINeedsIP finds an ENI in the datastore that needs more IP addresses allocated
+// GetENINeedsIP finds an ENI of the node in the datastore that needs more IP addresses allocated
 func (ds *DataStore) GetENINeedsIP(maxIPperENI int, skipPrimary bool) *ENI {
+	return ds.GetENIConfigENINeedsIP(maxIPperENI, skipPrimary, "")
+}
+
+// GetENIConfigENINeedsIP finds an ENI of the given ENIConfig in the datastore that needs more IP addresses allocated.
+// An empty ENIConfig name means the node's ENIs.
+func (ds *DataStore) GetENIConfigENINeedsIP(maxIPperENI int, skipPrimary bool, eniConfig string) *ENI {
 	ds.lock.Lock()
 	defer ds.lock.Unlock()
 	for _, eni := range ds.eniPool {
+		if eni.ENIConfig != eniConfig || ds.isPassthroughENIUnsafe(eni.ID) {
+			continue
+		}
 		if skipPrimary && eni.IsPrimary {
 			ds.log.Debugf("Skip the primary ENI for need IP check")
 			continue
@@ -1044,6 +1275,24 @@ func (ds *DataStore) GetENINeedsIP(maxIPperENI int, skipPrimary bool) *ENI {
 	return nil
 }
 
+// GetENINeedsIPv6Prefix finds an ENI in the datastore that has less IPv6 prefixes than maxPrefixesPerENI
+func (ds *DataStore) GetENINeedsIPv6Prefix(maxPrefixesPerENI int, skipPrimary bool) *ENI {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	for _, eni := range ds.eniPool {
+		if skipPrimary && eni.IsPrimary {
+			ds.log.Debugf("Skip the primary ENI for need IPv6 prefix check")
+			continue
+		}
+		if len(eni.IPv6Cidrs) < maxPrefixesPerENI {
+			ds.log.Debugf("Found ENI %s that has less than the maximum number of IPv6 prefixes allocated: cur=%d, max=%d",
+				eni.ID, len(eni.IPv6Cidrs), maxPrefixesPerENI)
+			return eni
+		}
+	}
+	return nil
+}
+
 // RemoveUnusedENIFromStore removes a deletable ENI from the data store.
 // It returns the name of the ENI which has been removed from the data store and needs to be deleted,
 // or empty string if no ENI could be removed.
@@ -1055,9 +1304,28 @@ func (ds *DataStore) RemoveUnusedENIFromStore(warmIPTarget, minimumIPTarget, war
 	if deletableENI == nil {
 		return ""
 	}
+	return ds.removeUnusedENIUnsafe(deletableENI.ID)
+}
 
-	removableENI := deletableENI.ID
+// RemoveUnusedENIConfigENIFromStore removes an ENI that was created for an ENIConfig and no longer has pods from the
+// data store. Warm targets do not apply to these ENIs. It returns the name of the ENI which has been removed from the
+// data store and needs to be deleted, or empty string if no ENI could be removed.
+func (ds *DataStore) RemoveUnusedENIConfigENIFromStore() string {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
 
+	for _, eni := range ds.eniPool {
+		if eni.ENIConfig == "" || eni.IsPrimary || eni.isTooYoung() || eni.hasIPInCooling(ds.ipCooldownPeriod) || eni.hasPods() {
+			continue
+		}
+		ds.log.Debugf("Found ENI %s of ENIConfig %s without pods", eni.ID, eni.ENIConfig)
+		return ds.removeUnusedENIUnsafe(eni.ID)
+	}
+	return ""
+}
+
+// removeUnusedENIUnsafe removes an ENI without pods from the data store and returns its name
+func (ds *DataStore) removeUnusedENIUnsafe(removableENI string) string {
 	for _, availableCidr := range ds.eniPool[removableENI].AvailableIPv4Cidrs {
 		ds.total -= availableCidr.Size()
 		if availableCidr.IsPrefix {
@@ -1065,6 +1333,13 @@ func (ds *DataStore) RemoveUnusedENIFromStore(warmIPTarget, minimumIPTarget, war
 			totalPrefixes.Set(float64(ds.allocatedPrefix))
 		}
 	}
+	for _, v6Cidr := range ds.eniPool[removableENI].IPv6Cidrs {
+		ds.total -= v6Cidr.Size()
+		if v6Cidr.IsPrefix {
+			ds.allocatedPrefix--
+			totalPrefixes.Set(float64(ds.allocatedPrefix))
+		}
+	}
 	ds.log.Infof("RemoveUnusedENIFromStore %s: IP/Prefix address pool stats: free %d addresses, total: %d, assigned: %d, total prefixes: %d",
 		removableENI, len(ds.eniPool[removableENI].AvailableIPv4Cidrs), ds.total, ds.assigned, ds.allocatedPrefix)
 
@@ -1086,6 +1361,10 @@ func (ds *DataStore) RemoveENIFromDataStore(eniID string, force bool) error {
 		return errors.New(UnknownENIError)
 	}
 
+	if !force && ds.isPassthroughENIUnsafe(eniID) {
+		return errors.New(ENIInUseError)
+	}
+
 	if eni.hasPods() {
 		if !force {
 			return errors.New(ENIInUseError)
@@ -1093,9 +1372,10 @@ func (ds *DataStore) RemoveENIFromDataStore(eniID string, force bool) error {
 		// This scenario can occur if the reconciliation process discovered this ENI was detached
 		// from the EC2 instance outside of the control of ipamd. If this happens, there's nothing
 		// we can do other than force all pods to be unassigned from the IPs on this ENI.
-		ds.log.Warnf("Force removing eni %s with %d assigned pods", eniID, eni.AssignedIPv4Addresses())
+		assignedIPs := eni.AssignedIPv4Addresses() + eni.AssignedIPv6Addresses()
+		ds.log.Warnf("Force removing eni %s with %d assigned pods", eniID, assignedIPs)
 		forceRemovedENIs.Inc()
-		forceRemovedIPs.Add(float64(eni.AssignedIPv4Addresses()))
+		forceRemovedIPs.Add(float64(assignedIPs))
 		for _, assignedaddr := range eni.AvailableIPv4Cidrs {
 			for _, addr := range assignedaddr.IPAddresses {
 				if addr.Assigned() {
@@ -1107,6 +1387,13 @@ func (ds *DataStore) RemoveENIFromDataStore(eniID string, force bool) error {
 				ds.allocatedPrefix--
 			}
 		}
+		for _, v6Cidr := range eni.IPv6Cidrs {
+			for _, addr := range v6Cidr.IPAddresses {
+				if addr.Assigned() {
+					ds.unassignPodIPAddressUnsafe(addr)
+				}
+			}
+		}
 		if err := ds.writeBackingStoreUnsafe(); err != nil {
 			ds.log.Warnf("Unable to update backing store: %v", err)
 			// Continuing, because 'force'
@@ -1119,6 +1406,12 @@ func (ds *DataStore) RemoveENIFromDataStore(eniID string, force bool) error {
 			ds.allocatedPrefix--
 		}
 	}
+	for _, v6Cidr := range eni.IPv6Cidrs {
+		ds.total -= v6Cidr.Size()
+		if v6Cidr.IsPrefix {
+			ds.allocatedPrefix--
+		}
+	}
 
 	ds.log.Infof("RemoveENIFromDataStore %s: IP/Prefix address pool stats: free %d addresses, total: %d, assigned: %d, total prefixes: %d",
 		eniID, len(eni.AvailableIPv4Cidrs), ds.total, ds.assigned, ds.allocatedPrefix)
@@ -1129,21 +1422,60 @@ func (ds *DataStore) RemoveENIFromDataStore(eniID string, force bool) error {
 	return nil
 }
 
+// LookupPodIPAddresses returns the addresses that are assigned to a sandbox without unassigning them. In dual-stack
+// mode both the v4 and the v6 address are returned, and the device number is the one of the v4 address.
+func (ds *DataStore) LookupPodIPAddresses(ipamKey IPAMKey) (ipv4Address string, ipv6Address string, deviceNumber int, err error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	for _, key := range []IPAMKey{ipamKey, {NetworkName: backfillNetworkName, ContainerID: ipamKey.ContainerID, IfName: backfillNetworkIface}} {
+		eni, _, addr := ds.eniPool.findIPv6AddressForSandbox(key)
+		if addr != nil {
+			ipv6Address = addr.Address
+			deviceNumber = eni.DeviceNumber
+		}
+		eni, _, addr = ds.eniPool.findIPv4AddressForSandbox(key)
+		if addr != nil {
+			ipv4Address = addr.Address
+			deviceNumber = eni.DeviceNumber
+		}
+		if ipv4Address != "" || ipv6Address != "" {
+			return ipv4Address, ipv6Address, deviceNumber, nil
+		}
+	}
+	return "", "", 0, ErrUnknownPod
+}
+
 // UnassignPodIPAddress a) find out the IP address based on PodName and PodNameSpace
 // b)  mark IP address as unassigned c) returns IP address, ENI's device number, error
 func (ds *DataStore) UnassignPodIPAddress(ipamKey IPAMKey) (e *ENI, ip string, deviceNumber int, err error) {
+	return ds.unassignPodAddress(ipamKey, ds.eniPool.FindAddressForSandbox)
+}
+
+// UnassignPodIPv4Address unassigns the IPv4 address of a dual-stack sandbox, leaving its IPv6 address assigned
+func (ds *DataStore) UnassignPodIPv4Address(ipamKey IPAMKey) (e *ENI, ip string, deviceNumber int, err error) {
+	return ds.unassignPodAddress(ipamKey, ds.eniPool.findIPv4AddressForSandbox)
+}
+
+// UnassignPodIPv6Address unassigns the IPv6 address of a dual-stack sandbox, leaving its IPv4 address assigned
+func (ds *DataStore) UnassignPodIPv6Address(ipamKey IPAMKey) (e *ENI, ip string, deviceNumber int, err error) {
+	return ds.unassignPodAddress(ipamKey, ds.eniPool.findIPv6AddressForSandbox)
+}
+
+func (ds *DataStore) unassignPodAddress(ipamKey IPAMKey,
+	findAddress func(IPAMKey) (*ENI, *CidrInfo, *AddressInfo)) (e *ENI, ip string, deviceNumber int, err error) {
 	ds.lock.Lock()
 	defer ds.lock.Unlock()
 	ds.log.Debugf("UnassignPodIPAddress: IP address pool stats: total %d, assigned %d, sandbox %s", ds.total, ds.assigned, ipamKey)
 
-	eni, availableCidr, addr := ds.eniPool.FindAddressForSandbox(ipamKey)
+	eni, availableCidr, addr := findAddress(ipamKey)
 	if addr == nil {
 		// If the entry is not present in state file, check if it is present under placeholder value.
 		// This scenario could happen if the pod was created by an older CNI version back when CRI read was done.
 		ds.log.Debugf("UnassignPodIPAddress: Failed to find IPAM entry under full key, trying CRI-migrated version")
 		ipamKey.NetworkName = backfillNetworkName
 		ipamKey.IfName = backfillNetworkIface
-		eni, availableCidr, addr = ds.eniPool.FindAddressForSandbox(ipamKey)
+		eni, availableCidr, addr = findAddress(ipamKey)
 
 		// If entry is still not found, IPAMD has no knowledge of this pod, so there is nothing to do.
 		if addr == nil {
@@ -1184,6 +1516,7 @@ func (ds *DataStore) AllocatedIPs() []PodIPInfo {
 						IPAMKey:      addr.IPAMKey,
 						IP:           addr.Address,
 						DeviceNumber: eni.DeviceNumber,
+						Metadata:     addr.IPAMMetadata,
 					}
 					ret = append(ret, info)
 				}
@@ -1280,6 +1613,67 @@ func (ds *DataStore) GetENIInfos() *ENIInfos {
 	return &eniInfos
 }
 
+// SetPrefixFallback records that no /28 prefix could be allocated on the ENI because the subnet is too fragmented.
+// Until it is cleared, secondary IPs on the ENI are used for pods even though prefix delegation is enabled.
+func (ds *DataStore) SetPrefixFallback(eniID string) error {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	eni, ok := ds.eniPool[eniID]
+	if !ok {
+		return errors.New(UnknownENIError)
+	}
+	if !eni.PrefixFallback {
+		ds.log.Infof("ENI %s falls back to secondary IPs since no prefix can be allocated", eniID)
+	}
+	eni.PrefixFallback = true
+	eni.PrefixFallbackTime = time.Now()
+	return nil
+}
+
+// SetENIConfig records that the ENI was created for the given ENIConfig, so that it only serves pods that ask for it
+func (ds *DataStore) SetENIConfig(eniID string, eniConfig string) error {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	eni, ok := ds.eniPool[eniID]
+	if !ok {
+		return errors.New(UnknownENIError)
+	}
+	if eni.ENIConfig != eniConfig {
+		ds.log.Infof("ENI %s serves pods of ENIConfig %s", eniID, eniConfig)
+	}
+	eni.ENIConfig = eniConfig
+	return nil
+}
+
+// ClearPrefixFallback records that prefixes can be allocated on the ENI again. Secondary IPs that are still assigned
+// to pods stay in use, but no new pods get one.
+func (ds *DataStore) ClearPrefixFallback(eniID string) error {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	eni, ok := ds.eniPool[eniID]
+	if !ok {
+		return errors.New(UnknownENIError)
+	}
+	if eni.PrefixFallback {
+		ds.log.Infof("ENI %s uses prefixes again", eniID)
+	}
+	eni.PrefixFallback = false
+	eni.PrefixFallbackTime = time.Time{}
+	return nil
+}
+
+// isUsableIPv4Cidr returns whether pod IPs can be assigned from the CIDR on the ENI. With PD enabled, secondary IPs are
+// only used on ENIs that fell back from prefixes; other ones are left over from an upgrade or a PD knob toggle.
+func (ds *DataStore) isUsableIPv4Cidr(eni *ENI, cidr *CidrInfo) bool {
+	if ds.isPDEnabled {
+		return cidr.IsPrefix || eni.PrefixFallback || ds.isHybridMode
+	}
+	return !cidr.IsPrefix
+}
+
 // GetENIs provides the number of ENI in the datastore
 func (ds *DataStore) GetENIs() int {
 	ds.lock.Lock()
@@ -1309,6 +1703,23 @@ func (ds *DataStore) GetENICIDRs(eniID string) ([]string, []string, error) {
 	return ipPool, prefixPool, nil
 }
 
+// GetENIIPv6CIDRs returns the IPv6 prefixes of an ENI in the datastore
+func (ds *DataStore) GetENIIPv6CIDRs(eniID string) ([]string, error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	eni, ok := ds.eniPool[eniID]
+	if !ok {
+		return nil, errors.New(UnknownENIError)
+	}
+
+	var prefixPool []string
+	for _, cidr := range eni.IPv6Cidrs {
+		prefixPool = append(prefixPool, cidr.Cidr.String())
+	}
+	return prefixPool, nil
+}
+
 // GetFreePrefixes return free prefixes
 func (ds *DataStore) GetFreePrefixes() int {
 	ds.lock.Lock()
@@ -1316,12 +1727,22 @@ func (ds *DataStore) GetFreePrefixes() int {
 
 	freePrefixes := 0
 	for _, other := range ds.eniPool {
+		if other.ENIConfig != "" || ds.isPassthroughENIUnsafe(other.ID) {
+			continue
+		}
+		freeFallbackIPs := 0
 		for _, otherPrefixes := range other.AvailableIPv4Cidrs {
 			if otherPrefixes.IsPrefix && otherPrefixes.AssignedIPAddressesInCidr() == 0 {
 				freePrefixes++
+			} else if !otherPrefixes.IsPrefix && other.PrefixFallback && otherPrefixes.AssignedIPAddressesInCidr() == 0 {
+				freeFallbackIPs++
 			}
 		}
-
+		// Secondary IPs on an ENI that fell back from prefixes count as free prefixes once they add up to the size of one
+		if freeFallbackIPs > 0 {
+			_, numIPsPerPrefix, _ := GetPrefixDelegationDefaults()
+			freePrefixes += freeFallbackIPs / numIPsPerPrefix
+		}
 	}
 	return freePrefixes
 }
@@ -1443,6 +1864,89 @@ func (ds *DataStore) FindFreeableCidrs(eniID string) []CidrInfo {
 
 }
 
+// FindFreeableIPv6Cidrs returns the IPv6 prefixes of the ENI that have no pods assigned and no IPs in cooldown
+func (ds *DataStore) FindFreeableIPv6Cidrs(eniID string) []CidrInfo {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	eni := ds.eniPool[eniID]
+	if eni == nil {
+		return nil
+	}
+
+	var freeable []CidrInfo
+	for _, v6Cidr := range eni.IPv6Cidrs {
+		if v6Cidr.GetIPStatsFromCidr(ds.ipCooldownPeriod) == (CidrStats{}) {
+			freeable = append(freeable, CidrInfo{
+				Cidr:          v6Cidr.Cidr,
+				IsPrefix:      v6Cidr.IsPrefix,
+				AddressFamily: v6Cidr.AddressFamily,
+			})
+		}
+	}
+	return freeable
+}
+
+// FindFreeableLeftoverCidrs returns the CIDRs on the ENI that are not handed out to pods in the current IP allocation
+// mode and are no longer used by any pod: secondary IPs with PD enabled and prefixes with PD disabled.
+func (ds *DataStore) FindFreeableLeftoverCidrs(eniID string) []CidrInfo {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	eni := ds.eniPool[eniID]
+	if eni == nil {
+		return nil
+	}
+
+	var freeable []CidrInfo
+	for _, cidr := range eni.AvailableIPv4Cidrs {
+		if !ds.isUsableIPv4Cidr(eni, cidr) && cidr.AssignedIPAddressesInCidr() == 0 {
+			freeable = append(freeable, CidrInfo{
+				Cidr:          cidr.Cidr,
+				IsPrefix:      cidr.IsPrefix,
+				AddressFamily: cidr.AddressFamily,
+			})
+		}
+	}
+	return freeable
+}
+
+// IPModeMigrationStatus shows how far the node is from only having CIDRs of the current IP allocation mode attached.
+// Exported fields will be marshaled for introspection.
+type IPModeMigrationStatus struct {
+	// Mode is the current IP allocation mode: "prefix", "secondary-ip" or "hybrid"
+	Mode string
+	// LeftoverCidrs is the number of attached CIDRs of the other mode, which are not handed out to new pods
+	LeftoverCidrs int
+	// LeftoverAssignedIPs is the number of pod IPs that are still in use from leftover CIDRs
+	LeftoverAssignedIPs int
+	// Complete is true once no leftover CIDRs are attached
+	Complete bool
+}
+
+// GetIPModeMigrationStatus returns the progress of moving the node to the current IP allocation mode
+func (ds *DataStore) GetIPModeMigrationStatus() *IPModeMigrationStatus {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	status := &IPModeMigrationStatus{Mode: "secondary-ip"}
+	if ds.isHybridMode {
+		status.Mode = "hybrid"
+	} else if ds.isPDEnabled {
+		status.Mode = "prefix"
+	}
+	for _, eni := range ds.eniPool {
+		for _, cidr := range eni.AvailableIPv4Cidrs {
+			if !ds.isUsableIPv4Cidr(eni, cidr) {
+				status.LeftoverCidrs++
+				status.LeftoverAssignedIPs += cidr.AssignedIPAddressesInCidr()
+			}
+		}
+	}
+	status.Complete = status.LeftoverCidrs == 0
+	return status
+}
+
 func DivCeil(x, y int) int {
 	return (x + y - 1) / y
 }
@@ -1459,11 +1963,20 @@ func (ds *DataStore) CheckFreeableENIexists() bool {
 			continue
 		}
 
+		if eni.ENIConfig != "" || ds.isPassthroughENIUnsafe(eni.ID) {
+			continue
+		}
+
 		if eni.hasPods() {
 			ds.log.Debugf("ENI %s cannot be deleted because it has pods assigned", eni.ID)
 			continue
 		}
 
+		if len(eni.IPv6Cidrs) > 0 {
+			ds.log.Debugf("ENI %s cannot be deleted because it has IPv6 prefixes attached", eni.ID)
+			continue
+		}
+
 		if eni.IsTrunk {
 			ds.log.Debugf("ENI %s cannot be deleted because it is a trunk ENI", eni.ID)
 			continue
@@ -1510,17 +2023,43 @@ func (ds *DataStore) validateAllocationByPodVethExistence(allocation CheckpointE
 	if allocation.Metadata.K8SPodNamespace == "" || allocation.Metadata.K8SPodName == "" {
 		return nil
 	}
+	// A passthrough ENI has no host-side veth, its link returns to the host when the pod is gone
+	if allocation.PassthroughENI != "" {
+		return nil
+	}
 
-	linkNameSuffix := networkutils.GeneratePodHostVethNameSuffix(allocation.Metadata.K8SPodNamespace, allocation.Metadata.K8SPodName)
+	// The host veth is named after the pod or after the sandbox, depending on the naming scheme when the pod was set up
+	podNameSuffix := hostVethNameSuffix(&allocation)
+	sandboxSuffix := sandboxHostVethNameSuffix(&allocation)
 	for _, link := range hostNSLinks {
 		linkName := link.Attrs().Name
-		if strings.HasSuffix(linkName, linkNameSuffix) {
+		if strings.HasSuffix(linkName, podNameSuffix) || strings.HasSuffix(linkName, sandboxSuffix) {
 			return nil
 		}
 	}
 	return errors.Errorf("host-side veth not found for pod %v/%v", allocation.Metadata.K8SPodNamespace, allocation.Metadata.K8SPodName)
 }
 
+// hostVethNameSuffix returns the name suffix of the host veth of the pod interface of an allocation. Backfilled
+// allocations are from the main interface of pods.
+func hostVethNameSuffix(entry *CheckpointEntry) string {
+	ifName := entry.IfName
+	if ifName == backfillNetworkIface {
+		ifName = networkutils.PrimaryPodInterfaceName
+	}
+	return networkutils.GeneratePodInterfaceHostVethNameSuffix(entry.Metadata.K8SPodNamespace, entry.Metadata.K8SPodName, ifName)
+}
+
+// sandboxHostVethNameSuffix returns the name suffix of the host veth of the pod interface of an allocation, when the
+// host veth is named after the sandbox
+func sandboxHostVethNameSuffix(entry *CheckpointEntry) string {
+	ifName := entry.IfName
+	if ifName == backfillNetworkIface {
+		ifName = networkutils.PrimaryPodInterfaceName
+	}
+	return networkutils.GenerateSandboxHostVethNameSuffix(entry.ContainerID, ifName)
+}
+
 // For each stale allocation, cleanup leaked IP rules if they exist
 func (ds *DataStore) PruneStaleAllocations(staleAllocations []CheckpointEntry) {
 	ds.log.Info("Pruning potentially stale IP rules")
@@ -15,6 +15,7 @@ package datastore
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"os"
 	"testing"
@@ -509,6 +510,280 @@ func TestTogglePD(t *testing.T) {
 
 }
 
+func TestPrefixFallback(t *testing.T) {
+	ds := NewDataStore(Testlog, NullCheckpoint{}, true)
+	err := ds.AddENI("eni-1", 1, true, false, false)
+	assert.NoError(t, err)
+	assert.Error(t, ds.SetPrefixFallback("eni-2"))
+
+	// A secondary IP is not used in PD mode
+	for i := 1; i <= 16; i++ {
+		ipv4Addr := net.IPNet{IP: net.IPv4(10, 0, 0, byte(i)), Mask: net.IPv4Mask(255, 255, 255, 255)}
+		err = ds.AddIPv4CidrToStore("eni-1", ipv4Addr, false)
+		assert.NoError(t, err)
+	}
+	key := IPAMKey{"net0", "sandbox-1", "eth0"}
+	_, _, err = ds.AssignPodIPv4Address(key, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"})
+	assert.Error(t, err)
+	assert.Equal(t, 0, ds.GetIPStats("4").TotalIPs)
+	assert.Equal(t, 0, ds.GetFreePrefixes())
+
+	// Until the ENI falls back from prefixes
+	err = ds.SetPrefixFallback("eni-1")
+	assert.NoError(t, err)
+	assert.True(t, ds.GetENIInfos().ENIs["eni-1"].PrefixFallback)
+	assert.Equal(t, 16, ds.GetIPStats("4").TotalIPs)
+	assert.Equal(t, 1, ds.GetFreePrefixes())
+
+	ip, device, err := ds.AssignPodIPv4Address(key, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"})
+	assert.NoError(t, err)
+	assert.Contains(t, ip, "10.0.0.")
+	assert.Equal(t, 1, device)
+	assert.Equal(t, 1, ds.GetIPStats("4").AssignedIPs)
+	assert.Equal(t, 0, ds.GetFreePrefixes())
+
+	// Reverting keeps the assigned IP, but no new pod gets a secondary IP
+	err = ds.ClearPrefixFallback("eni-1")
+	assert.NoError(t, err)
+	assert.False(t, ds.GetENIInfos().ENIs["eni-1"].PrefixFallback)
+	assert.Equal(t, 0, ds.GetIPStats("4").TotalIPs)
+	_, _, err = ds.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-2", "eth0"}, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-2"})
+	assert.Error(t, err)
+	assert.Len(t, ds.FreeableIPs("eni-1"), 15)
+}
+
+func TestHybridMode(t *testing.T) {
+	ds := NewDataStore(Testlog, NullCheckpoint{}, true)
+	ds.SetHybridMode(true)
+	err := ds.AddENI("eni-1", 1, true, false, false)
+	assert.NoError(t, err)
+	err = ds.AddENI("eni-2", 2, false, false, false)
+	assert.NoError(t, err)
+
+	// Both prefixes and secondary IPs are allocation sources
+	ipv4Addr := net.IPNet{IP: net.ParseIP("1.1.1.1"), Mask: net.IPv4Mask(255, 255, 255, 255)}
+	err = ds.AddIPv4CidrToStore("eni-1", ipv4Addr, false)
+	assert.NoError(t, err)
+	ipv4Addr = net.IPNet{IP: net.ParseIP("10.0.0.0"), Mask: net.IPv4Mask(255, 255, 255, 240)}
+	err = ds.AddIPv4CidrToStore("eni-2", ipv4Addr, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 17, ds.GetIPStats("4").TotalIPs)
+
+	// Warm targets are counted in IPs across both: eni-1 holds the one IP that WARM_IP_TARGET=17 needs on top of eni-2
+	assert.True(t, ds.isRequiredForWarmIPTarget(17, ds.eniPool["eni-1"]))
+	assert.False(t, ds.isRequiredForWarmIPTarget(16, ds.eniPool["eni-1"]))
+	assert.False(t, ds.isRequiredForWarmPrefixTarget(1, ds.eniPool["eni-1"]))
+	assert.True(t, ds.isRequiredForWarmPrefixTarget(1, ds.eniPool["eni-2"]))
+
+	for i := 0; i < 17; i++ {
+		key := IPAMKey{"net0", fmt.Sprintf("sandbox-%d", i), "eth0"}
+		_, _, err = ds.AssignPodIPv4Address(key, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: fmt.Sprintf("sample-pod-%d", i)})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 17, ds.GetIPStats("4").AssignedIPs)
+}
+
+func TestIPModeMigrationStatus(t *testing.T) {
+	ds := NewDataStore(Testlog, NullCheckpoint{}, true)
+	err := ds.AddENI("eni-1", 1, true, false, false)
+	assert.NoError(t, err)
+	ipv4Addr := net.IPNet{IP: net.ParseIP("10.0.0.0"), Mask: net.IPv4Mask(255, 255, 255, 240)}
+	err = ds.AddIPv4CidrToStore("eni-1", ipv4Addr, true)
+	assert.NoError(t, err)
+	key := IPAMKey{"net0", "sandbox-1", "eth0"}
+	_, _, err = ds.AssignPodIPv4Address(key, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"})
+	assert.NoError(t, err)
+	ipv4Addr = net.IPNet{IP: net.ParseIP("10.0.1.0"), Mask: net.IPv4Mask(255, 255, 255, 240)}
+	err = ds.AddIPv4CidrToStore("eni-1", ipv4Addr, true)
+	assert.NoError(t, err)
+	assert.Equal(t, &IPModeMigrationStatus{Mode: "prefix", Complete: true}, ds.GetIPModeMigrationStatus())
+	assert.Len(t, ds.FindFreeableLeftoverCidrs("eni-1"), 0)
+
+	// After PD is disabled, both prefixes are left over, but only the unused one can be freed
+	ds.isPDEnabled = false
+	assert.Equal(t, &IPModeMigrationStatus{Mode: "secondary-ip", LeftoverCidrs: 2, LeftoverAssignedIPs: 1}, ds.GetIPModeMigrationStatus())
+	freeable := ds.FindFreeableLeftoverCidrs("eni-1")
+	assert.Len(t, freeable, 1)
+	assert.Equal(t, "10.0.1.0/28", freeable[0].Cidr.String())
+	assert.Nil(t, ds.FindFreeableLeftoverCidrs("eni-2"))
+
+	// Nothing is left over in hybrid mode
+	ds.isPDEnabled = true
+	ds.SetHybridMode(true)
+	assert.Equal(t, &IPModeMigrationStatus{Mode: "hybrid", Complete: true}, ds.GetIPModeMigrationStatus())
+}
+
+func TestIPv6Prefixes(t *testing.T) {
+	ds := NewDataStore(Testlog, NullCheckpoint{}, true)
+	err := ds.AddENI("eni-1", 1, true, false, false)
+	assert.NoError(t, err)
+	err = ds.AddENI("eni-2", 2, false, false, false)
+	assert.NoError(t, err)
+	_, prefix1, _ := net.ParseCIDR("2001:db8:0:1::/80")
+	_, prefix2, _ := net.ParseCIDR("2001:db8:0:2::/80")
+	err = ds.AddIPv6CidrToStore("eni-1", *prefix1, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "eni-2", ds.GetENINeedsIPv6Prefix(1, false).ID)
+	assert.Nil(t, ds.GetENINeedsIPv6Prefix(1, true).IPv6Cidrs)
+	err = ds.AddIPv6CidrToStore("eni-2", *prefix2, true)
+	assert.NoError(t, err)
+	assert.Nil(t, ds.GetENINeedsIPv6Prefix(1, false))
+
+	// The first pod lands on either prefix, the second one on the same prefix
+	_, _, err = ds.AssignPodIPv6Address(IPAMKey{"net0", "sandbox-1", "eth0"}, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"})
+	assert.NoError(t, err)
+	_, _, err = ds.AssignPodIPv6Address(IPAMKey{"net0", "sandbox-2", "eth0"}, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-2"})
+	assert.NoError(t, err)
+	total, free := ds.GetIPv6PrefixCounts()
+	assert.Equal(t, 2, total)
+	assert.Equal(t, 1, free)
+
+	usedENI, freeENI := "eni-1", "eni-2"
+	if ds.eniPool["eni-1"].AssignedIPv6Addresses() == 0 {
+		usedENI, freeENI = freeENI, usedENI
+	}
+	assert.Equal(t, 2, ds.eniPool[usedENI].AssignedIPv6Addresses())
+	assert.True(t, ds.eniPool[usedENI].hasPods())
+	assert.Len(t, ds.FindFreeableIPv6Cidrs(usedENI), 0)
+	freeable := ds.FindFreeableIPv6Cidrs(freeENI)
+	assert.Len(t, freeable, 1)
+
+	for _, usedCidr := range ds.eniPool[usedENI].IPv6Cidrs {
+		err = ds.DelIPv6CidrFromStore(usedENI, usedCidr.Cidr, false)
+		assert.Equal(t, IPInUseError, err.Error())
+	}
+	err = ds.DelIPv6CidrFromStore(freeENI, freeable[0].Cidr, false)
+	assert.NoError(t, err)
+	total, free = ds.GetIPv6PrefixCounts()
+	assert.Equal(t, 1, total)
+	assert.Equal(t, 0, free)
+	err = ds.RemoveENIFromDataStore(usedENI, false)
+	assert.Equal(t, ENIInUseError, err.Error())
+}
+
+func TestENIConfigENIs(t *testing.T) {
+	ds := NewDataStore(Testlog, NullCheckpoint{}, false)
+	err := ds.AddENI("eni-1", 1, true, false, false)
+	assert.NoError(t, err)
+	err = ds.AddENI("eni-2", 2, false, false, false)
+	assert.NoError(t, err)
+	err = ds.SetENIConfig("eni-2", "team-a")
+	assert.NoError(t, err)
+	err = ds.SetENIConfig("unknown-eni", "team-a")
+	assert.Error(t, err)
+
+	ipv4Addr1 := net.IPNet{IP: net.ParseIP("1.1.1.1"), Mask: net.IPv4Mask(255, 255, 255, 255)}
+	err = ds.AddIPv4CidrToStore("eni-1", ipv4Addr1, false)
+	assert.NoError(t, err)
+	ipv4Addr2 := net.IPNet{IP: net.ParseIP("1.1.2.2"), Mask: net.IPv4Mask(255, 255, 255, 255)}
+	err = ds.AddIPv4CidrToStore("eni-2", ipv4Addr2, false)
+	assert.NoError(t, err)
+
+	// Only the node's ENIs count for the node's pool
+	assert.Equal(t, 1, ds.GetIPStats("4").TotalIPs)
+	assert.Equal(t, 1, ds.GetENIConfigIPStats("4", "team-a").TotalIPs)
+	assert.Equal(t, "eni-1", ds.GetENINeedsIP(2, false).ID)
+	assert.Equal(t, "eni-2", ds.GetENIConfigENINeedsIP(2, false, "team-a").ID)
+	assert.Nil(t, ds.GetENIConfigENINeedsIP(2, false, "team-b"))
+
+	// Pods of the node and of the ENIConfig get IPs from their own ENIs
+	ip, deviceNumber, err := ds.AssignPodIPv4AddressForENIConfig(IPAMKey{"net0", "sandbox-1", "eth0"}, IPAMMetadata{}, "team-a")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.2.2", ip)
+	assert.Equal(t, 2, deviceNumber)
+	_, _, err = ds.AssignPodIPv4AddressForENIConfig(IPAMKey{"net0", "sandbox-2", "eth0"}, IPAMMetadata{}, "team-a")
+	assert.Error(t, err)
+	_, _, err = ds.AssignPodIPv4AddressForENIConfig(IPAMKey{"net0", "sandbox-2", "eth0"}, IPAMMetadata{}, "team-b")
+	assert.Error(t, err)
+	ip, deviceNumber, err = ds.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-3", "eth0"}, IPAMMetadata{})
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.1.1", ip)
+	assert.Equal(t, 1, deviceNumber)
+
+	// Warm targets do not keep an ENI of an ENIConfig, it is removed once it has no pods
+	ds.eniPool["eni-2"].createTime = time.Time{}
+	assert.Equal(t, "", ds.RemoveUnusedENIFromStore(0, 0, 0))
+	assert.Equal(t, "", ds.RemoveUnusedENIConfigENIFromStore())
+	_, _, _, err = ds.UnassignPodIPAddress(IPAMKey{"net0", "sandbox-1", "eth0"})
+	assert.NoError(t, err)
+	ds.eniPool["eni-2"].AvailableIPv4Cidrs[ipv4Addr2.String()].IPAddresses["1.1.2.2"].UnassignedTime = time.Time{}
+	assert.Equal(t, "eni-2", ds.RemoveUnusedENIConfigENIFromStore())
+	assert.Equal(t, 1, ds.GetENIs())
+}
+
+func TestDualStack(t *testing.T) {
+	checkpoint := NewTestCheckpoint(struct{}{})
+	ds := NewDataStore(Testlog, checkpoint, true)
+	err := ds.AddENI("eni-1", 1, true, false, false)
+	assert.NoError(t, err)
+	err = ds.AddENI("eni-2", 2, false, false, false)
+	assert.NoError(t, err)
+	_, v4Prefix, _ := net.ParseCIDR("10.0.0.16/28")
+	err = ds.AddIPv4CidrToStore("eni-2", *v4Prefix, true)
+	assert.NoError(t, err)
+
+	// Without a v6 prefix, the v4 address is given back
+	key1 := IPAMKey{"net0", "sandbox-1", "eth0"}
+	_, _, _, err = ds.AssignPodIPAddress(key1, IPAMMetadata{}, true, true)
+	assert.Error(t, err)
+	assert.Equal(t, 0, ds.eniPool.AssignedIPv4Addresses())
+
+	_, v6Prefix, _ := net.ParseCIDR("2001:db8:0:1::/80")
+	err = ds.AddIPv6CidrToStore("eni-1", *v6Prefix, true)
+	assert.NoError(t, err)
+	ipv4Addr, ipv6Addr, deviceNumber, err := ds.AssignPodIPAddress(key1, IPAMMetadata{}, true, true)
+	assert.NoError(t, err)
+	assert.True(t, v4Prefix.Contains(net.ParseIP(ipv4Addr)))
+	assert.True(t, v6Prefix.Contains(net.ParseIP(ipv6Addr)))
+	// The device number is the one of the v4 address
+	assert.Equal(t, 2, deviceNumber)
+	assert.Len(t, checkpoint.Data.(*CheckpointData).Allocations, 2)
+
+	// A duplicate assign returns the same addresses
+	dupIPv4Addr, dupIPv6Addr, _, err := ds.AssignPodIPAddress(key1, IPAMMetadata{}, true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, ipv4Addr, dupIPv4Addr)
+	assert.Equal(t, ipv6Addr, dupIPv6Addr)
+
+	// A lookup returns both addresses without unassigning them
+	lookupIPv4Addr, lookupIPv6Addr, deviceNumber, err := ds.LookupPodIPAddresses(key1)
+	assert.NoError(t, err)
+	assert.Equal(t, ipv4Addr, lookupIPv4Addr)
+	assert.Equal(t, ipv6Addr, lookupIPv6Addr)
+	assert.Equal(t, 2, deviceNumber)
+	_, _, _, err = ds.LookupPodIPAddresses(IPAMKey{"net0", "sandbox-2", "eth0"})
+	assert.Equal(t, ErrUnknownPod, err)
+
+	// The v4 address is unassigned first
+	_, ip, deviceNumber, err := ds.UnassignPodIPAddress(key1)
+	assert.NoError(t, err)
+	assert.Equal(t, ipv4Addr, ip)
+	assert.Equal(t, 2, deviceNumber)
+	_, ip, deviceNumber, err = ds.UnassignPodIPAddress(key1)
+	assert.NoError(t, err)
+	assert.Equal(t, ipv6Addr, ip)
+	assert.Equal(t, 1, deviceNumber)
+	_, _, _, err = ds.UnassignPodIPAddress(key1)
+	assert.Equal(t, ErrUnknownPod, err)
+	assert.Len(t, checkpoint.Data.(*CheckpointData).Allocations, 0)
+
+	// Each address family can be unassigned on its own
+	ipv4Addr, ipv6Addr, _, err = ds.AssignPodIPAddress(key1, IPAMMetadata{}, true, true)
+	assert.NoError(t, err)
+	_, ip, _, err = ds.UnassignPodIPv6Address(key1)
+	assert.NoError(t, err)
+	assert.Equal(t, ipv6Addr, ip)
+	_, _, _, err = ds.UnassignPodIPv6Address(key1)
+	assert.Equal(t, ErrUnknownPod, err)
+	_, ip, deviceNumber, err = ds.UnassignPodIPv4Address(key1)
+	assert.NoError(t, err)
+	assert.Equal(t, ipv4Addr, ip)
+	assert.Equal(t, 2, deviceNumber)
+	_, _, _, err = ds.UnassignPodIPv4Address(key1)
+	assert.Equal(t, ErrUnknownPod, err)
+	assert.Len(t, checkpoint.Data.(*CheckpointData).Allocations, 0)
+}
+
 func TestPodIPv4Address(t *testing.T) {
 	checkpoint := NewTestCheckpoint(struct{}{})
 	ds := NewDataStore(Testlog, checkpoint, false)
@@ -1437,6 +1712,36 @@ func TestDataStore_validateAllocationByPodVethExistence(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name: "one veth pair found with matching sandbox suffix",
+			args: args{
+				allocation: CheckpointEntry{
+					IPAMKey: IPAMKey{
+						ContainerID: "5a1f9118a7125f87b4b0f2f601c0b55cfab8bcf28963bcf7c4ece3109a8b6b86",
+						NetworkName: "aws-cni",
+						IfName:      "eth0",
+					},
+					IPv4: "192.168.9.106",
+					Metadata: IPAMMetadata{
+						K8SPodNamespace: "kube-system",
+						K8SPodName:      "coredns-57ff979f67-qqbdh",
+					},
+				},
+				hostNSLinks: []netlink.Link{
+					&netlink.Device{
+						LinkAttrs: netlink.LinkAttrs{
+							Name: "eth0",
+						},
+					},
+					&netlink.Veth{
+						LinkAttrs: netlink.LinkAttrs{
+							Name: "eni298555d41c1",
+						},
+					},
+				},
+			},
+			wantErr: nil,
+		},
 		{
 			name: "no veth pair found with matching suffix",
 			args: args{
@@ -0,0 +1,171 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datastore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoPassthroughENI is an error when no ENI without pods is available to move into a pod
+var ErrNoPassthroughENI = errors.New("datastore: no ENI without pods available")
+
+// PassthroughENI is an ENI that is moved into the network namespace of a pod as a whole, instead of serving pod IPs.
+// Exported fields will be marshaled for introspection.
+type PassthroughENI struct {
+	ENIID string
+	// IPv4 is the primary IP of the ENI, which the pod uses
+	IPv4         string
+	IPAMKey      IPAMKey
+	IPAMMetadata IPAMMetadata
+	AssignedTime time.Time
+	// Released is set once the pod is gone. The ENI is returned to the pool when its link is set up on the host again.
+	Released bool
+}
+
+// AssignPassthroughENI reserves an ENI without pods for the sandbox. Only the ENIs that have a primary IP in primaryIPs
+// can be reserved, the primary ENI, the trunk ENI, EFA ENIs and the ENIs of an ENIConfig never are. A sandbox that
+// already has an ENI gets the same one again.
+func (ds *DataStore) AssignPassthroughENI(ipamKey IPAMKey, ipamMetadata IPAMMetadata, primaryIPs map[string]string) (PassthroughENI, error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	if passthroughENI := ds.findPassthroughENIUnsafe(ipamKey); passthroughENI != nil {
+		ds.log.Infof("AssignPassthroughENI: duplicate pod assign for sandbox %s", ipamKey)
+		return *passthroughENI, nil
+	}
+
+	var candidates []*ENI
+	for _, eni := range ds.eniPool {
+		if eni.IsPrimary || eni.IsTrunk || eni.IsEFA || eni.ENIConfig != "" || ds.isPassthroughENIUnsafe(eni.ID) {
+			continue
+		}
+		if eni.hasPods() || primaryIPs[eni.ID] == "" {
+			continue
+		}
+		candidates = append(candidates, eni)
+	}
+	if len(candidates) == 0 {
+		ds.log.Errorf("DataStore has no ENI without pods to move into sandbox %s", ipamKey)
+		return PassthroughENI{}, ErrNoPassthroughENI
+	}
+	// The ENI with the highest device number is taken, the first ENIs are the ones the warm pool grows on
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].DeviceNumber > candidates[j].DeviceNumber
+	})
+	eni := candidates[0]
+
+	passthroughENI := &PassthroughENI{
+		ENIID:        eni.ID,
+		IPv4:         primaryIPs[eni.ID],
+		IPAMKey:      ipamKey,
+		IPAMMetadata: ipamMetadata,
+		AssignedTime: time.Now(),
+	}
+	ds.passthroughENIs[eni.ID] = passthroughENI
+	if err := ds.writeBackingStoreUnsafe(); err != nil {
+		ds.log.Warnf("Failed to update backing store: %v", err)
+		// Important! Unwind assignment
+		delete(ds.passthroughENIs, eni.ID)
+		return PassthroughENI{}, err
+	}
+	ds.log.Infof("AssignPassthroughENI: Assign ENI %s with IP %s to sandbox %s", eni.ID, passthroughENI.IPv4, ipamKey)
+	return *passthroughENI, nil
+}
+
+// ReleasePassthroughENI marks the ENI of the sandbox as released. It stays out of the pool until ReturnPassthroughENI
+// is called, once its link is set up on the host again. A released ENI is not checkpointed, after a restart it is only added
+// to the pool once its link is found on the host.
+func (ds *DataStore) ReleasePassthroughENI(ipamKey IPAMKey) (PassthroughENI, error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	passthroughENI := ds.findPassthroughENIUnsafe(ipamKey)
+	if passthroughENI == nil {
+		return PassthroughENI{}, ErrUnknownPod
+	}
+	passthroughENI.Released = true
+	if err := ds.writeBackingStoreUnsafe(); err != nil {
+		// Unwind release
+		passthroughENI.Released = false
+		return PassthroughENI{}, err
+	}
+	ds.log.Infof("ReleasePassthroughENI: sandbox %s released ENI %s", ipamKey, passthroughENI.ENIID)
+	return *passthroughENI, nil
+}
+
+// LookupPassthroughENI returns the ENI of the sandbox without releasing it
+func (ds *DataStore) LookupPassthroughENI(ipamKey IPAMKey) (PassthroughENI, error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	passthroughENI := ds.findPassthroughENIUnsafe(ipamKey)
+	if passthroughENI == nil {
+		return PassthroughENI{}, ErrUnknownPod
+	}
+	return *passthroughENI, nil
+}
+
+// GetReleasedPassthroughENIs returns the IDs of the ENIs whose pod is gone, but which are not returned to the pool yet
+func (ds *DataStore) GetReleasedPassthroughENIs() []string {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	var eniIDs []string
+	for eniID, passthroughENI := range ds.passthroughENIs {
+		if passthroughENI.Released {
+			eniIDs = append(eniIDs, eniID)
+		}
+	}
+	sort.Strings(eniIDs)
+	return eniIDs
+}
+
+// ReturnPassthroughENI returns a released ENI to the pool
+func (ds *DataStore) ReturnPassthroughENI(eniID string) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	if passthroughENI, ok := ds.passthroughENIs[eniID]; ok && passthroughENI.Released {
+		delete(ds.passthroughENIs, eniID)
+		if err := ds.writeBackingStoreUnsafe(); err != nil {
+			// The released entry is returned again after a restart
+			ds.log.Warnf("Failed to update backing store: %v", err)
+		}
+		ds.log.Infof("ReturnPassthroughENI: ENI %s is back in the pool", eniID)
+	}
+}
+
+// IsPassthroughENI returns true if the ENI is moved into a pod, or was and is not returned to the pool yet
+func (ds *DataStore) IsPassthroughENI(eniID string) bool {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	return ds.isPassthroughENIUnsafe(eniID)
+}
+
+func (ds *DataStore) isPassthroughENIUnsafe(eniID string) bool {
+	_, ok := ds.passthroughENIs[eniID]
+	return ok
+}
+
+func (ds *DataStore) findPassthroughENIUnsafe(ipamKey IPAMKey) *PassthroughENI {
+	for _, passthroughENI := range ds.passthroughENIs {
+		if !passthroughENI.Released && passthroughENI.IPAMKey == ipamKey {
+			return passthroughENI
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datastore
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPassthroughENI(t *testing.T) {
+	checkpoint := NewTestCheckpoint(struct{}{})
+	ds := NewDataStore(Testlog, checkpoint, false)
+
+	_ = ds.AddENI("eni-1", 0, true, false, false)
+	_ = ds.AddENI("eni-2", 1, false, false, false)
+	_ = ds.AddENI("eni-3", 2, false, false, false)
+	_ = ds.AddENI("eni-4", 3, false, false, false)
+	_ = ds.SetENIConfig("eni-4", "eniconfig-a")
+	_ = ds.AddIPv4CidrToStore("eni-2", net.IPNet{IP: net.ParseIP("10.0.1.10"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	_ = ds.AddIPv4CidrToStore("eni-3", net.IPNet{IP: net.ParseIP("10.0.2.10"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	primaryIPs := map[string]string{"eni-1": "10.0.0.1", "eni-2": "10.0.1.1", "eni-3": "10.0.2.1", "eni-4": "10.0.3.1"}
+
+	// The ENI with the highest device number that is not the primary ENI or one of an ENIConfig is picked
+	key1 := IPAMKey{"net0", "sandbox-1", "eth0"}
+	metadata1 := IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"}
+	passthroughENI, err := ds.AssignPassthroughENI(key1, metadata1, primaryIPs)
+	assert.NoError(t, err)
+	assert.Equal(t, "eni-3", passthroughENI.ENIID)
+	assert.Equal(t, "10.0.2.1", passthroughENI.IPv4)
+	assert.True(t, ds.IsPassthroughENI("eni-3"))
+	assert.Equal(t, "eni-3", checkpoint.Data.(*CheckpointData).Allocations[0].PassthroughENI)
+
+	// A duplicate assign gets the same ENI
+	passthroughENI, err = ds.AssignPassthroughENI(key1, metadata1, primaryIPs)
+	assert.NoError(t, err)
+	assert.Equal(t, "eni-3", passthroughENI.ENIID)
+
+	// The IPs of the ENI are not given to pods anymore
+	ip, deviceNumber, err := ds.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-2", "eth0"}, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.1.10", ip)
+	assert.Equal(t, 1, deviceNumber)
+	_, _, err = ds.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-3", "eth0"}, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-3"})
+	assert.Error(t, err)
+
+	// eni-2 has a pod now
+	_, err = ds.AssignPassthroughENI(IPAMKey{"net0", "sandbox-4", "eth0"}, IPAMMetadata{}, primaryIPs)
+	assert.Equal(t, ErrNoPassthroughENI, err)
+
+	// The ENI can not be removed from the datastore while the pod has it
+	assert.EqualError(t, ds.RemoveENIFromDataStore("eni-3", false), ENIInUseError)
+
+	lookedUp, err := ds.LookupPassthroughENI(key1)
+	assert.NoError(t, err)
+	assert.Equal(t, "eni-3", lookedUp.ENIID)
+
+	// The reservation survives a restart
+	restored := NewDataStore(Testlog, checkpoint, false)
+	assert.NoError(t, restored.ReadBackingStore(true, false))
+	lookedUp, err = restored.LookupPassthroughENI(key1)
+	assert.NoError(t, err)
+	assert.Equal(t, "eni-3", lookedUp.ENIID)
+
+	// A released ENI stays reserved until it is returned
+	released, err := ds.ReleasePassthroughENI(key1)
+	assert.NoError(t, err)
+	assert.Equal(t, "eni-3", released.ENIID)
+	_, err = ds.ReleasePassthroughENI(key1)
+	assert.Equal(t, ErrUnknownPod, err)
+	_, err = ds.LookupPassthroughENI(key1)
+	assert.Equal(t, ErrUnknownPod, err)
+	assert.Equal(t, []string{"eni-3"}, ds.GetReleasedPassthroughENIs())
+	assert.True(t, ds.IsPassthroughENI("eni-3"))
+
+	// A released ENI is not checkpointed, after a restart the reconciler adds it once its link is back on the host
+	restored = NewDataStore(Testlog, checkpoint, false)
+	assert.NoError(t, restored.ReadBackingStore(true, false))
+	assert.False(t, restored.IsPassthroughENI("eni-3"))
+
+	ds.ReturnPassthroughENI("eni-3")
+	assert.False(t, ds.IsPassthroughENI("eni-3"))
+	assert.Empty(t, ds.GetReleasedPassthroughENIs())
+	ip, deviceNumber, err = ds.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-3", "eth0"}, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-3"})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.2.10", ip)
+	assert.Equal(t, 2, deviceNumber)
+}
@@ -0,0 +1,201 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/k8sapi"
+)
+
+// egressIPState holds the egress IPs that the pods of a namespace are pinned to
+type egressIPState struct {
+	lock sync.Mutex
+	// reserved is the set of egress IPs of all namespaces. They are never assigned to pods.
+	reserved map[string]bool
+	// primaryENIIPs is the set of secondary IPs of the primary ENI
+	primaryENIIPs map[string]bool
+	// rules maps an egress IP to the pod IPs that were last SNATed to it
+	rules map[string][]string
+}
+
+// parseEgressIPConfig returns the egress IPs of each namespace. The value of each key is a list of IPs separated by
+// commas or whitespace. An IP that is listed for more than one namespace is only used by the first one.
+func parseEgressIPConfig(data map[string]string) map[string][]string {
+	namespaces := make([]string, 0, len(data))
+	for namespace := range data {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	seen := make(map[string]string)
+	egressIPs := make(map[string][]string)
+	for _, namespace := range namespaces {
+		fields := strings.FieldsFunc(data[namespace], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\n'
+		})
+		for _, field := range fields {
+			ip := net.ParseIP(field)
+			if ip == nil || ip.To4() == nil {
+				log.Warnf("Ignoring invalid egress IP %q of namespace %s", field, namespace)
+				continue
+			}
+			if other, ok := seen[ip.String()]; ok {
+				log.Warnf("Ignoring egress IP %s of namespace %s, it is already used by namespace %s", ip, namespace, other)
+				continue
+			}
+			seen[ip.String()] = namespace
+			egressIPs[namespace] = append(egressIPs[namespace], ip.String())
+		}
+	}
+	return egressIPs
+}
+
+// loadEgressIPConfig reads the egress IPs of each namespace from the ConfigMap. A missing ConfigMap means that no
+// namespace is pinned.
+func (c *IPAMContext) loadEgressIPConfig(ctx context.Context) (map[string][]string, error) {
+	var configMap corev1.ConfigMap
+	key := types.NamespacedName{Name: k8sapi.EgressIPConfigMapName, Namespace: k8sapi.EgressIPConfigMapNamespace}
+	if err := c.k8sClient.Get(ctx, key, &configMap); err != nil {
+		if k8serror.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get ConfigMap %s", k8sapi.EgressIPConfigMapName)
+	}
+	return parseEgressIPConfig(configMap.Data), nil
+}
+
+// isReservedEgressIP returns true if the IP is an egress IP of a namespace, so it must not be added to the pool
+func (c *IPAMContext) isReservedEgressIP(ip string) bool {
+	if !c.enableEgressIPPinning {
+		return false
+	}
+	c.egressIPs.lock.Lock()
+	defer c.egressIPs.lock.Unlock()
+	return c.egressIPs.reserved[ip]
+}
+
+// countReservedEgressIPs returns how many of the IPs attached to an ENI are egress IPs
+func (c *IPAMContext) countReservedEgressIPs(attachedENIIPs []*ec2.NetworkInterfacePrivateIpAddress) int {
+	count := 0
+	for _, addr := range attachedENIIPs {
+		if c.isReservedEgressIP(aws.StringValue(addr.PrivateIpAddress)) {
+			count++
+		}
+	}
+	return count
+}
+
+// setPrimaryENIIPs records the secondary IPs of the primary ENI, only those can be used as egress IPs
+func (c *IPAMContext) setPrimaryENIIPs(attachedENIIPs []*ec2.NetworkInterfacePrivateIpAddress) {
+	c.egressIPs.lock.Lock()
+	defer c.egressIPs.lock.Unlock()
+	c.egressIPs.primaryENIIPs = make(map[string]bool)
+	for _, addr := range attachedENIIPs {
+		if !aws.BoolValue(addr.Primary) {
+			c.egressIPs.primaryENIIPs[aws.StringValue(addr.PrivateIpAddress)] = true
+		}
+	}
+}
+
+// releaseReservedEgressIPs removes the egress IPs from the pool, and returns those that are still assigned to pods
+func (c *IPAMContext) releaseReservedEgressIPs() map[string]bool {
+	inUse := make(map[string]bool)
+	for eniID, eni := range c.dataStore.GetENIInfos().ENIs {
+		for cidr, cidrInfo := range eni.AvailableIPv4Cidrs {
+			ip := cidrInfo.Cidr.IP.String()
+			if cidrInfo.IsPrefix || !c.egressIPs.reserved[ip] {
+				continue
+			}
+			if err := c.dataStore.DelIPv4CidrFromStore(eniID, cidrInfo.Cidr, false); err != nil {
+				log.Infof("Egress IP %s can't be used until the pod using it is deleted: %v", cidr, err)
+				inUse[ip] = true
+				continue
+			}
+			log.Infof("Removed egress IP %s from the IP pool of ENI %s", ip, eniID)
+		}
+	}
+	return inUse
+}
+
+// updateEgressIPRules SNATs the outbound traffic of the pods of each pinned namespace to its first egress IP that is a
+// secondary IP of the primary ENI. The iptables rules are only updated when the pinned pod IPs changed.
+func (c *IPAMContext) updateEgressIPRules(ctx context.Context) {
+	c.egressIPs.lock.Lock()
+	defer c.egressIPs.lock.Unlock()
+
+	namespaces, err := c.loadEgressIPConfig(ctx)
+	if err != nil {
+		log.Warnf("Failed to load the egress IPs of namespaces: %v", err)
+		return
+	}
+	c.egressIPs.reserved = make(map[string]bool)
+	for _, ips := range namespaces {
+		for _, ip := range ips {
+			c.egressIPs.reserved[ip] = true
+		}
+	}
+	inUse := c.releaseReservedEgressIPs()
+
+	podIPs := make(map[string][]string)
+	for _, info := range c.dataStore.AllocatedIPs() {
+		namespace := info.Metadata.K8SPodNamespace
+		if _, ok := namespaces[namespace]; ok {
+			podIPs[namespace] = append(podIPs[namespace], info.IP)
+		}
+	}
+
+	rules := make(map[string][]string)
+	for namespace, ips := range namespaces {
+		if len(podIPs[namespace]) == 0 {
+			continue
+		}
+		egressIP := ""
+		for _, ip := range ips {
+			if c.egressIPs.primaryENIIPs[ip] && !inUse[ip] {
+				egressIP = ip
+				break
+			}
+		}
+		if egressIP == "" {
+			log.Debugf("None of the egress IPs %v of namespace %s is a free secondary IP of the primary ENI", ips, namespace)
+			continue
+		}
+		sort.Strings(podIPs[namespace])
+		rules[egressIP] = podIPs[namespace]
+	}
+
+	if reflect.DeepEqual(rules, c.egressIPs.rules) {
+		return
+	}
+	if err := c.networkClient.UpdateEgressIPRules(rules); err != nil {
+		log.Errorf("Failed to update the egress IP rules: %v", err)
+		ipamdErrInc("updateEgressIPRulesFailed")
+		return
+	}
+	log.Infof("Updated the egress IP rules: %v", rules)
+	c.egressIPs.rules = rules
+}
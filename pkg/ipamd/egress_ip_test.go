@@ -0,0 +1,88 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/k8sapi"
+)
+
+func TestParseEgressIPConfig(t *testing.T) {
+	egressIPs := parseEgressIPConfig(map[string]string{
+		"team-b": "10.0.0.5 10.0.0.6",
+		"team-a": "10.0.0.4,10.0.0.5, not-an-ip",
+		"team-c": "2001:db8::1",
+	})
+	assert.Equal(t, map[string][]string{
+		"team-a": {"10.0.0.4", "10.0.0.5"},
+		"team-b": {"10.0.0.6"},
+	}, egressIPs)
+}
+
+func TestUpdateEgressIPRules(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	mockContext := &IPAMContext{
+		k8sClient:             m.k8sClient,
+		networkClient:         m.network,
+		dataStore:             datastore.NewDataStore(log, datastore.NullCheckpoint{}, false),
+		enableEgressIPPinning: true,
+	}
+
+	err := m.k8sClient.Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: k8sapi.EgressIPConfigMapName, Namespace: k8sapi.EgressIPConfigMapNamespace},
+		Data:       map[string]string{"team-a": "10.0.0.4"},
+	})
+	assert.NoError(t, err)
+
+	mockContext.setPrimaryENIIPs([]*ec2.NetworkInterfacePrivateIpAddress{
+		{PrivateIpAddress: aws.String("10.0.0.1"), Primary: aws.Bool(true)},
+		{PrivateIpAddress: aws.String("10.0.0.2"), Primary: aws.Bool(false)},
+		{PrivateIpAddress: aws.String("10.0.0.3"), Primary: aws.Bool(false)},
+		{PrivateIpAddress: aws.String("10.0.0.4"), Primary: aws.Bool(false)},
+	})
+	_ = mockContext.dataStore.AddENI(primaryENIid, primaryDevice, true, false, false)
+	for _, ip := range []string{"10.0.0.2", "10.0.0.3"} {
+		_ = mockContext.dataStore.AddIPv4CidrToStore(primaryENIid, net.IPNet{IP: net.ParseIP(ip), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	}
+	podA, _, err := mockContext.dataStore.AssignPodIPv4Address(datastore.IPAMKey{NetworkName: "net0", ContainerID: "pod-a", IfName: "eth0"},
+		datastore.IPAMMetadata{K8SPodNamespace: "team-a", K8SPodName: "pod-a"})
+	assert.NoError(t, err)
+	_, _, err = mockContext.dataStore.AssignPodIPv4Address(datastore.IPAMKey{NetworkName: "net0", ContainerID: "pod-b", IfName: "eth0"},
+		datastore.IPAMMetadata{K8SPodNamespace: "team-b", K8SPodName: "pod-b"})
+	assert.NoError(t, err)
+	_ = mockContext.dataStore.AddIPv4CidrToStore(primaryENIid, net.IPNet{IP: net.ParseIP("10.0.0.4"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+
+	m.network.EXPECT().UpdateEgressIPRules(map[string][]string{"10.0.0.4": {podA}}).Return(nil)
+	mockContext.updateEgressIPRules(ctx)
+
+	// The egress IP is no longer in the pool
+	assert.True(t, mockContext.isReservedEgressIP("10.0.0.4"))
+	assert.Equal(t, 2, mockContext.dataStore.GetENIInfos().TotalIPs)
+
+	// Rules are not rewritten when nothing changed
+	mockContext.updateEgressIPRules(ctx)
+}
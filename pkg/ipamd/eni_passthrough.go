@@ -0,0 +1,202 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/awsutils"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/networkutils"
+	"github.com/aws/amazon-vpc-cni-k8s/rpc"
+)
+
+// eniPassthroughKey is the pod annotation, or the container resource, that asks for a whole ENI
+const eniPassthroughKey = "vpc.amazonaws.com/eni-passthrough"
+
+// isENIPassthroughPod returns true if the pod asks for a whole ENI, either with the annotation set to "true" or with
+// the resource in the limits of one of its containers
+func isENIPassthroughPod(pod *corev1.Pod) bool {
+	if strings.EqualFold(pod.Annotations[eniPassthroughKey], "true") {
+		return true
+	}
+	for _, container := range pod.Spec.Containers {
+		if _, ok := container.Resources.Limits[eniPassthroughKey]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// addPassthroughNetwork moves a whole ENI into a pod that asks for one
+func (s *server) addPassthroughNetwork(in *rpc.AddNetworkRequest) *rpc.AddNetworkReply {
+	if in.ContainerID == "" || in.IfName == "" || in.NetworkName == "" {
+		log.Errorf("Unable to generate IPAMKey from %+v", in)
+		return addNetworkFailure(rpc.ErrorReason_INVALID_REQUEST, "ContainerID, IfName and NetworkName are required")
+	}
+	ipamKey := datastore.IPAMKey{
+		ContainerID: in.ContainerID,
+		IfName:      in.IfName,
+		NetworkName: in.NetworkName,
+	}
+	ipamMetadata := datastore.IPAMMetadata{
+		K8SPodNamespace: in.K8S_POD_NAMESPACE,
+		K8SPodName:      in.K8S_POD_NAME,
+	}
+
+	passthroughENI, eniMetadata, err := s.ipamContext.assignPassthroughENI(ipamKey, ipamMetadata)
+	if err == datastore.ErrNoPassthroughENI {
+		log.Infof("Send AddNetworkReply: no ENI available to move into pod %s/%s", in.K8S_POD_NAMESPACE, in.K8S_POD_NAME)
+		return addNetworkFailure(rpc.ErrorReason_POOL_EMPTY, "no attached ENI without pods is available")
+	}
+	if err != nil {
+		log.Errorf("Send AddNetworkReply: Failed to assign a passthrough ENI: %v", err)
+		return addNetworkFailure(rpc.ErrorReason_INTERNAL_ERROR, err.Error())
+	}
+	subnetGW, err := subnetGateway(eniMetadata.SubnetIPv4CIDR)
+	if err != nil {
+		log.Errorf("Unable to get the gateway of ENI %s: %v", passthroughENI.ENIID, err)
+		if _, err := s.ipamContext.dataStore.ReleasePassthroughENI(ipamKey); err == nil {
+			s.ipamContext.dataStore.ReturnPassthroughENI(passthroughENI.ENIID)
+		}
+		return addNetworkFailure(rpc.ErrorReason_INTERNAL_ERROR, err.Error())
+	}
+
+	if s.ipamContext.enablePodIPAnnotation {
+		// On ADD, we pass empty string as there is no IP being released
+		if err := s.ipamContext.AnnotatePod(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE, vpccniPodIPKey, passthroughENI.IPv4, ""); err != nil {
+			log.Errorf("Failed to add the pod annotation: %v", err)
+		}
+	}
+	log.Infof("Send AddNetworkReply: IPv4Addr %s of passthrough ENI %s, MAC %s", passthroughENI.IPv4, passthroughENI.ENIID, eniMetadata.MAC)
+	return &rpc.AddNetworkReply{
+		Success:        true,
+		IPv4Addr:       passthroughENI.IPv4,
+		DeviceNumber:   -1, // The pod does not use the route table of the ENI
+		PodENIMAC:      eniMetadata.MAC,
+		PodENISubnetGW: subnetGW,
+		PassthroughENI: true,
+	}
+}
+
+// delPassthroughNetwork releases the ENI of the sandbox, so that the ENI is returned to the pool once the plugin moved
+// it back to the host. It returns nil if the sandbox has no ENI.
+func (s *server) delPassthroughNetwork(in *rpc.DelNetworkRequest, ipamKey datastore.IPAMKey) *rpc.DelNetworkReply {
+	passthroughENI, err := s.ipamContext.dataStore.ReleasePassthroughENI(ipamKey)
+	if err == datastore.ErrUnknownPod {
+		return nil
+	}
+	if err != nil {
+		log.Errorf("Send DelNetworkReply: Failed to release the passthrough ENI of sandbox %s: %v", ipamKey, err)
+		return &rpc.DelNetworkReply{Success: false, ErrorReason: rpc.ErrorReason_INTERNAL_ERROR, ErrorMessage: err.Error()}
+	}
+
+	if s.ipamContext.enablePodIPAnnotation {
+		// On DEL, we pass IP being released
+		if err := s.ipamContext.AnnotatePod(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE, vpccniPodIPKey, "", passthroughENI.IPv4); err != nil {
+			log.Errorf("Failed to delete the pod annotation: %v", err)
+		}
+	}
+	log.Infof("Send DelNetworkReply: IPv4Addr %s of passthrough ENI %s", passthroughENI.IPv4, passthroughENI.ENIID)
+	return &rpc.DelNetworkReply{Success: true, IPv4Addr: passthroughENI.IPv4, DeviceNumber: -1, PassthroughENI: true}
+}
+
+// assignPassthroughENI reserves an attached ENI without pods for the sandbox and returns its instance metadata
+func (c *IPAMContext) assignPassthroughENI(ipamKey datastore.IPAMKey, ipamMetadata datastore.IPAMMetadata) (datastore.PassthroughENI, awsutils.ENIMetadata, error) {
+	attachedENIs, err := c.awsClient.GetAttachedENIs()
+	if err != nil {
+		return datastore.PassthroughENI{}, awsutils.ENIMetadata{}, errors.Wrap(err, "failed to get attached ENIs")
+	}
+	primaryIPs := make(map[string]string)
+	for _, eni := range attachedENIs {
+		primaryIPs[eni.ENIID] = eni.PrimaryIPv4Address()
+	}
+
+	passthroughENI, err := c.dataStore.AssignPassthroughENI(ipamKey, ipamMetadata, primaryIPs)
+	if err != nil {
+		return datastore.PassthroughENI{}, awsutils.ENIMetadata{}, err
+	}
+	for _, eni := range attachedENIs {
+		if eni.ENIID == passthroughENI.ENIID {
+			return passthroughENI, eni, nil
+		}
+	}
+	// Only an attached ENI can be picked, the reservation is dropped so that the ENI is not kept out of the pool
+	if _, err := c.dataStore.ReleasePassthroughENI(ipamKey); err == nil {
+		c.dataStore.ReturnPassthroughENI(passthroughENI.ENIID)
+	}
+	return datastore.PassthroughENI{}, awsutils.ENIMetadata{}, errors.Errorf("ENI %s is not attached", passthroughENI.ENIID)
+}
+
+// returnPassthroughENIs returns the released passthrough ENIs to the pool. The network of an ENI that is still in the
+// datastore is set up again first, which fails until the plugin moved its link back to the host. An ENI that is not in
+// the datastore anymore is added back by the reconciler.
+func (c *IPAMContext) returnPassthroughENIs() {
+	eniIDs := c.dataStore.GetReleasedPassthroughENIs()
+	if len(eniIDs) == 0 {
+		return
+	}
+	attachedENIs, err := c.awsClient.GetAttachedENIs()
+	if err != nil {
+		log.Warnf("Failed to get attached ENIs to return passthrough ENIs: %v", err)
+		return
+	}
+	eniInfos := c.dataStore.GetENIInfos()
+	for _, eniID := range eniIDs {
+		if _, ok := eniInfos.ENIs[eniID]; !ok {
+			c.dataStore.ReturnPassthroughENI(eniID)
+			continue
+		}
+		var eniMetadata *awsutils.ENIMetadata
+		for i := range attachedENIs {
+			if attachedENIs[i].ENIID == eniID {
+				eniMetadata = &attachedENIs[i]
+				break
+			}
+		}
+		if eniMetadata == nil {
+			// Detached, the reconciler removes it from the datastore
+			c.dataStore.ReturnPassthroughENI(eniID)
+			continue
+		}
+		// The link comes back under its name in the pod, not the one it had on the host
+		if err := c.networkClient.RestoreLinkName(eniMetadata.MAC); err != nil {
+			log.Debugf("Passthrough ENI %s is not back on the host yet: %v", eniID, err)
+			continue
+		}
+		err := c.networkClient.SetupENINetwork(eniMetadata.PrimaryIPv4Address(), eniMetadata.MAC, eniMetadata.DeviceNumber, eniMetadata.SubnetIPv4CIDR)
+		if err != nil {
+			log.Debugf("Passthrough ENI %s is not back on the host yet: %v", eniID, err)
+			continue
+		}
+		c.dataStore.ReturnPassthroughENI(eniID)
+	}
+}
+
+// subnetGateway returns the gateway of a subnet, which is the second IP of the subnet CIDR
+func subnetGateway(subnetCIDR string) (string, error) {
+	_, subnet, err := net.ParseCIDR(subnetCIDR)
+	if err != nil {
+		return "", err
+	}
+	gw, err := networkutils.IncrementIPv4Addr(subnet.IP)
+	if err != nil {
+		return "", err
+	}
+	return gw.String(), nil
+}
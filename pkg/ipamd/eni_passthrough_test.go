@@ -0,0 +1,212 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/awsutils"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	pb "github.com/aws/amazon-vpc-cni-k8s/rpc"
+)
+
+func TestIsENIPassthroughPod(t *testing.T) {
+	tests := []struct {
+		name string
+		pod  *corev1.Pod
+		want bool
+	}{
+		{name: "no request", pod: &corev1.Pod{}, want: false},
+		{
+			name: "annotation",
+			pod:  &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{eniPassthroughKey: "true"}}},
+			want: true,
+		},
+		{
+			name: "annotation set to false",
+			pod:  &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{eniPassthroughKey: "false"}}},
+			want: false,
+		},
+		{
+			name: "resource of a sidecar container",
+			pod: &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "app"},
+				{Name: "sidecar", Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{eniPassthroughKey: resource.MustParse("1")}}},
+			}}},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isENIPassthroughPod(tt.pod))
+		})
+	}
+}
+
+func TestServer_AddNetworkPassthroughENI(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	_ = ds.AddENI(primaryENIid, primaryDevice, true, false, false)
+	_ = ds.AddIPv4CidrToStore(primaryENIid, net.IPNet{IP: net.ParseIP(ipaddr02), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	_ = ds.AddENI(secENIid, secDevice, false, false, false)
+	_ = ds.AddIPv4CidrToStore(secENIid, net.IPNet{IP: net.ParseIP(ipaddr12), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+
+	mockContext := &IPAMContext{
+		awsClient:            m.awsutils,
+		k8sClient:            m.k8sClient,
+		networkClient:        m.network,
+		enableIPv4:           true,
+		enableENIPassthrough: true,
+		dataStore:            ds,
+	}
+	s := &server{version: "1.2.3", ipamContext: mockContext}
+
+	assert.NoError(t, m.k8sClient.Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default", Annotations: map[string]string{eniPassthroughKey: "true"}},
+	}))
+	attachedENIs := []awsutils.ENIMetadata{
+		{
+			ENIID:          primaryENIid,
+			MAC:            primaryMAC,
+			DeviceNumber:   primaryDevice,
+			SubnetIPv4CIDR: primarySubnet,
+			IPv4Addresses:  []*ec2.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: aws.String(ipaddr01), Primary: aws.Bool(true)}},
+		},
+		{
+			ENIID:          secENIid,
+			MAC:            secMAC,
+			DeviceNumber:   secDevice,
+			SubnetIPv4CIDR: secSubnet,
+			IPv4Addresses:  []*ec2.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: aws.String(ipaddr11), Primary: aws.Bool(true)}},
+		},
+	}
+	m.awsutils.EXPECT().GetAttachedENIs().Return(attachedENIs, nil)
+
+	addReq := &pb.AddNetworkRequest{
+		ClientVersion:     "1.2.3",
+		K8S_POD_NAME:      "pod-1",
+		K8S_POD_NAMESPACE: "default",
+		NetworkName:       "aws-cni",
+		ContainerID:       "cid-1",
+		IfName:            "eth0",
+	}
+	resp, err := s.AddNetwork(ctx, addReq)
+	assert.NoError(t, err)
+	assert.True(t, resp.Success)
+	assert.True(t, resp.PassthroughENI)
+	assert.Equal(t, ipaddr11, resp.IPv4Addr)
+	assert.Equal(t, secMAC, resp.PodENIMAC)
+	assert.Equal(t, "10.10.20.1", resp.PodENISubnetGW)
+	assert.True(t, ds.IsPassthroughENI(secENIid))
+
+	// No ENI without pods is left for another pod
+	assert.NoError(t, m.k8sClient.Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-2", Namespace: "default", Annotations: map[string]string{eniPassthroughKey: "true"}},
+	}))
+	m.awsutils.EXPECT().GetAttachedENIs().Return(attachedENIs, nil)
+	resp, err = s.AddNetwork(ctx, &pb.AddNetworkRequest{
+		ClientVersion:     "1.2.3",
+		K8S_POD_NAME:      "pod-2",
+		K8S_POD_NAMESPACE: "default",
+		NetworkName:       "aws-cni",
+		ContainerID:       "cid-2",
+		IfName:            "eth0",
+	})
+	assert.NoError(t, err)
+	assert.False(t, resp.Success)
+	assert.Equal(t, pb.ErrorReason_POOL_EMPTY, resp.ErrorReason)
+
+	checkResp, err := s.CheckNetwork(ctx, &pb.CheckNetworkRequest{
+		ClientVersion: "1.2.3",
+		NetworkName:   "aws-cni",
+		ContainerID:   "cid-1",
+		IfName:        "eth0",
+	})
+	assert.NoError(t, err)
+	assert.True(t, checkResp.Success)
+	assert.True(t, checkResp.PassthroughENI)
+	assert.Equal(t, ipaddr11, checkResp.IPv4Addr)
+
+	delResp, err := s.DelNetwork(ctx, &pb.DelNetworkRequest{
+		ClientVersion:     "1.2.3",
+		K8S_POD_NAME:      "pod-1",
+		K8S_POD_NAMESPACE: "default",
+		NetworkName:       "aws-cni",
+		ContainerID:       "cid-1",
+		IfName:            "eth0",
+	})
+	assert.NoError(t, err)
+	assert.True(t, delResp.Success)
+	assert.True(t, delResp.PassthroughENI)
+	assert.Equal(t, ipaddr11, delResp.IPv4Addr)
+	assert.Equal(t, []string{secENIid}, ds.GetReleasedPassthroughENIs())
+
+	// The ENI stays out of the pool until its link is back on the host
+	m.awsutils.EXPECT().GetAttachedENIs().Return(attachedENIs, nil)
+	m.network.EXPECT().RestoreLinkName(secMAC).Return(errors.New("link not found"))
+	mockContext.returnPassthroughENIs()
+	assert.True(t, ds.IsPassthroughENI(secENIid))
+
+	// The link gets its name on the host back before its network is set up
+	m.awsutils.EXPECT().GetAttachedENIs().Return(attachedENIs, nil)
+	gomock.InOrder(
+		m.network.EXPECT().RestoreLinkName(secMAC).Return(nil),
+		m.network.EXPECT().SetupENINetwork(ipaddr11, secMAC, secDevice, secSubnet).Return(nil),
+	)
+	mockContext.returnPassthroughENIs()
+	assert.False(t, ds.IsPassthroughENI(secENIid))
+}
+
+func TestAssignPassthroughENIDetached(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	_ = ds.AddENI(primaryENIid, primaryDevice, true, false, false)
+	_ = ds.AddENI(secENIid, secDevice, false, false, false)
+	mockContext := &IPAMContext{
+		awsClient:            m.awsutils,
+		enableIPv4:           true,
+		enableENIPassthrough: true,
+		dataStore:            ds,
+	}
+
+	// The sandbox already has an ENI, which was detached since
+	ipamKey := datastore.IPAMKey{NetworkName: "aws-cni", ContainerID: "cid-1", IfName: "eth0"}
+	_, err := ds.AssignPassthroughENI(ipamKey, datastore.IPAMMetadata{}, map[string]string{secENIid: ipaddr11})
+	assert.NoError(t, err)
+	m.awsutils.EXPECT().GetAttachedENIs().Return([]awsutils.ENIMetadata{{
+		ENIID:         primaryENIid,
+		IPv4Addresses: []*ec2.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: aws.String(ipaddr01), Primary: aws.Bool(true)}},
+	}}, nil)
+
+	_, _, err = mockContext.assignPassthroughENI(ipamKey, datastore.IPAMMetadata{})
+	assert.Error(t, err)
+	// The ENI is not kept reserved
+	assert.False(t, ds.IsPassthroughENI(secENIid))
+}
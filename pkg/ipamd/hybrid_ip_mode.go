@@ -0,0 +1,84 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"sort"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+)
+
+// hybridTargetState returns the number of IPs the datastore is short of and over the warm targets in hybrid mode.
+// Prefixes and secondary IPs are both counted in IPs, and WARM_PREFIX_TARGET stands for the IPs in that many prefixes.
+func (c *IPAMContext) hybridTargetState() (short int, over int) {
+	if short, over, warmTargetDefined := c.datastoreTargetState(); warmTargetDefined {
+		return short, over
+	}
+
+	_, numIPsPerPrefix, _ := datastore.GetPrefixDelegationDefaults()
+	warmIPs := c.warmPrefixTarget * numIPsPerPrefix
+	available := c.dataStore.GetIPStats(ipV4AddrFamily).AvailableAddresses()
+	short = max(warmIPs-available, 0)
+	if warmIPs == 0 && available == 0 {
+		short = 1
+	}
+	over = max(available-warmIPs, 0)
+	log.Debugf("Current hybrid warm stats: warm IPs: %d, available: %d, short: %d, over: %d", warmIPs, available, short, over)
+	return short, over
+}
+
+// tryUnassignHybridCidrsFromAll frees unused prefixes and secondary IPs as long as they fit in the number of IPs over
+// the warm targets. Secondary IPs are freed first, so the node keeps preferring prefixes.
+func (c *IPAMContext) tryUnassignHybridCidrsFromAll() {
+	_, over := c.hybridTargetState()
+	if over <= 0 {
+		return
+	}
+
+	eniInfos := c.dataStore.GetENIInfos()
+	for eniID, eni := range eniInfos.ENIs {
+		if over <= 0 {
+			return
+		}
+		if eni.ENIConfig != "" || c.dataStore.IsPassthroughENI(eniID) {
+			// Not part of the node's pool
+			continue
+		}
+		cidrs := c.dataStore.FindFreeableCidrs(eniID)
+		sort.SliceStable(cidrs, func(i, j int) bool {
+			return !cidrs[i].IsPrefix && cidrs[j].IsPrefix
+		})
+
+		var deletedCidrs []datastore.CidrInfo
+		for _, toDelete := range cidrs {
+			size := toDelete.Size()
+			if size > over {
+				continue
+			}
+			// Do not force the delete, since a freeable Cidr might have been assigned to a pod
+			// before we get around to deleting it.
+			err := c.dataStore.DelIPv4CidrFromStore(eniID, toDelete.Cidr, false /* force */)
+			if err != nil {
+				log.Warnf("Failed to delete Cidr %s on ENI %s from datastore: %s", toDelete.Cidr.String(), eniID, err)
+				ipamdErrInc("decreaseIPPool")
+				continue
+			}
+			deletedCidrs = append(deletedCidrs, toDelete)
+			over -= size
+		}
+		if len(deletedCidrs) > 0 {
+			c.DeallocCidrs(eniID, deletedCidrs)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+)
+
+func testHybridContext(m *testMocks) *IPAMContext {
+	c := &IPAMContext{
+		awsClient:              m.awsutils,
+		maxIPsPerENI:           256,
+		maxPrefixesPerENI:      16,
+		maxENI:                 4,
+		warmPrefixTarget:       1,
+		enablePrefixDelegation: true,
+		enableHybridIPMode:     true,
+		dataStore:              datastore.NewDataStore(log, datastore.NullCheckpoint{}, true),
+	}
+	c.reconcileCooldownCache.cache = make(map[string]time.Time)
+	c.dataStore.SetHybridMode(true)
+	_ = c.dataStore.AddENI(primaryENIid, primaryDevice, true, false, false)
+	_, prefix, _ := net.ParseCIDR(prefix01)
+	_ = c.dataStore.AddIPv4CidrToStore(primaryENIid, *prefix, true)
+	for _, ip := range []string{ipaddr01, ipaddr02} {
+		_ = c.dataStore.AddIPv4CidrToStore(primaryENIid, net.IPNet{IP: net.ParseIP(ip), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	}
+	return c
+}
+
+func TestHybridTargetState(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	c := testHybridContext(m)
+
+	// The prefix and both secondary IPs count, WARM_PREFIX_TARGET=1 asks for 16 free IPs
+	short, over := c.hybridTargetState()
+	assert.Equal(t, 0, short)
+	assert.Equal(t, 2, over)
+	assert.False(t, c.isDatastorePoolTooLow())
+	assert.True(t, c.isDatastorePoolTooHigh())
+
+	c.warmPrefixTarget = 2
+	short, over = c.hybridTargetState()
+	assert.Equal(t, 14, short)
+	assert.Equal(t, 0, over)
+	assert.True(t, c.isDatastorePoolTooLow())
+	assert.Equal(t, 1, c.getPrefixesNeeded())
+	assert.Equal(t, 14, c.getFallbackIPsNeeded(1))
+
+	// WARM_IP_TARGET is not rounded up to whole prefixes
+	c.warmIPTarget = 20
+	short, over = c.hybridTargetState()
+	assert.Equal(t, 2, short)
+	assert.Equal(t, 0, over)
+	assert.Equal(t, 1, c.getPrefixesNeeded())
+}
+
+func TestTryUnassignHybridCidrsFromAll(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	c := testHybridContext(m)
+
+	// The secondary IPs are over the target and freed, the prefix is kept
+	m.awsutils.EXPECT().DeallocPrefixAddresses(primaryENIid, gomock.Len(0))
+	m.awsutils.EXPECT().DeallocIPAddresses(primaryENIid, gomock.Len(2))
+	c.tryUnassignHybridCidrsFromAll()
+
+	eni := c.dataStore.GetENIInfos().ENIs[primaryENIid]
+	assert.Len(t, eni.AvailableIPv4Cidrs, 1)
+	assert.Contains(t, eni.AvailableIPv4Cidrs, prefix01)
+	assert.False(t, c.isDatastorePoolTooHigh())
+}
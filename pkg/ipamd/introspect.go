@@ -85,6 +85,7 @@ func (c *IPAMContext) ServeIntrospection() {
 func (c *IPAMContext) setupIntrospectionServer() *http.Server {
 	serverFunctions := map[string]func(w http.ResponseWriter, r *http.Request){
 		"/v1/enis":                      eniV1RequestHandler(c),
+		"/v1/ip-mode-migration":         ipModeMigrationRequestHandler(c),
 		"/v1/eni-configs":               eniConfigRequestHandler(c),
 		"/v1/networkutils-env-settings": networkEnvV1RequestHandler(),
 		"/v1/ipamd-env-settings":        ipamdEnvV1RequestHandler(),
@@ -142,6 +143,18 @@ func eniV1RequestHandler(ipam *IPAMContext) func(http.ResponseWriter, *http.Requ
 	}
 }
 
+func ipModeMigrationRequestHandler(ipam *IPAMContext) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responseJSON, err := json.Marshal(ipam.dataStore.GetIPModeMigrationStatus())
+		if err != nil {
+			log.Errorf("Failed to marshal IP mode migration status: %v", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		logErr(w.Write(responseJSON))
+	}
+}
+
 func eniConfigRequestHandler(ipam *IPAMContext) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -151,7 +164,7 @@ func eniConfigRequestHandler(ipam *IPAMContext) func(http.ResponseWriter, *http.
 			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 			return
 		}
-		myENIConfig, err := eniconfig.GetNodeSpecificENIConfigName(node)
+		myENIConfig, err := eniconfig.GetNodeENIConfigName(ctx, ipam.k8sClient, ipam.awsClient, node, ipam.availabilityZone)
 		if err != nil {
 			log.Errorf("Failed to get ENI config: %v", err)
 			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
@@ -0,0 +1,52 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+)
+
+// releaseLeftoverCidrs migrates the node to the current IP allocation mode without replacing it. After
+// ENABLE_PREFIX_DELEGATION was turned on, new pods only get IPs from prefixes, and the secondary IPs from before are
+// released once their pods are gone. The same happens to prefixes after ENABLE_PREFIX_DELEGATION was turned off.
+func (c *IPAMContext) releaseLeftoverCidrs() {
+	eniInfos := c.dataStore.GetENIInfos()
+	for eniID := range eniInfos.ENIs {
+		cidrs := c.dataStore.FindFreeableLeftoverCidrs(eniID)
+		var deletedCidrs []datastore.CidrInfo
+		for _, toDelete := range cidrs {
+			// Do not force the delete, since the datastore might have changed in the meantime
+			err := c.dataStore.DelIPv4CidrFromStore(eniID, toDelete.Cidr, false /* force */)
+			if err != nil {
+				log.Warnf("Failed to delete leftover Cidr %s on ENI %s from datastore: %s", toDelete.Cidr.String(), eniID, err)
+				ipamdErrInc("releaseLeftoverCidrs")
+				continue
+			}
+			deletedCidrs = append(deletedCidrs, toDelete)
+		}
+		if len(deletedCidrs) > 0 {
+			log.Infof("Releasing %d leftover Cidrs from ENI %s", len(deletedCidrs), eniID)
+			c.DeallocCidrs(eniID, deletedCidrs)
+		}
+	}
+
+	status := c.dataStore.GetIPModeMigrationStatus()
+	if !status.Complete && !c.ipModeMigrationInProgress {
+		log.Infof("Migrating node to %s mode: %d leftover Cidrs are attached, %d of their IPs are assigned to pods",
+			status.Mode, status.LeftoverCidrs, status.LeftoverAssignedIPs)
+	} else if status.Complete && c.ipModeMigrationInProgress {
+		log.Infof("Migration of node to %s mode is complete", status.Mode)
+	}
+	c.ipModeMigrationInProgress = !status.Complete
+}
@@ -0,0 +1,72 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+)
+
+func TestReleaseLeftoverCidrs(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+
+	mockContext := &IPAMContext{
+		awsClient:              m.awsutils,
+		enablePrefixDelegation: true,
+		dataStore:              datastore.NewDataStore(log, datastore.NullCheckpoint{}, true),
+	}
+	mockContext.reconcileCooldownCache.cache = make(map[string]time.Time)
+	_ = mockContext.dataStore.AddENI(primaryENIid, primaryDevice, true, false, false)
+	for _, ip := range []string{ipaddr01, ipaddr02} {
+		_ = mockContext.dataStore.AddIPv4CidrToStore(primaryENIid, net.IPNet{IP: net.ParseIP(ip), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	}
+	// A pod got a secondary IP while the ENI was in prefix fallback, then prefixes became available again
+	_ = mockContext.dataStore.SetPrefixFallback(primaryENIid)
+	podKey := datastore.IPAMKey{ContainerID: "container1"}
+	_, _, err := mockContext.dataStore.AssignPodIPv4Address(podKey, datastore.IPAMMetadata{K8SPodName: "pod0"})
+	assert.NoError(t, err)
+	_ = mockContext.dataStore.ClearPrefixFallback(primaryENIid)
+	_, prefix, _ := net.ParseCIDR(prefix01)
+	_ = mockContext.dataStore.AddIPv4CidrToStore(primaryENIid, *prefix, true)
+
+	status := mockContext.dataStore.GetIPModeMigrationStatus()
+	assert.Equal(t, "prefix", status.Mode)
+	assert.Equal(t, 2, status.LeftoverCidrs)
+	assert.Equal(t, 1, status.LeftoverAssignedIPs)
+	assert.False(t, status.Complete)
+
+	// The unused secondary IP is released, the one used by the pod is kept
+	m.awsutils.EXPECT().DeallocPrefixAddresses(primaryENIid, gomock.Len(0))
+	m.awsutils.EXPECT().DeallocIPAddresses(primaryENIid, gomock.Len(1))
+	mockContext.releaseLeftoverCidrs()
+	assert.Equal(t, 1, mockContext.dataStore.GetIPModeMigrationStatus().LeftoverCidrs)
+	assert.True(t, mockContext.ipModeMigrationInProgress)
+
+	// Once the pod is gone, the node only has prefixes left
+	_, _, _, err = mockContext.dataStore.UnassignPodIPAddress(podKey)
+	assert.NoError(t, err)
+	m.awsutils.EXPECT().DeallocPrefixAddresses(primaryENIid, gomock.Len(0))
+	m.awsutils.EXPECT().DeallocIPAddresses(primaryENIid, gomock.Len(1))
+	mockContext.releaseLeftoverCidrs()
+	assert.True(t, mockContext.dataStore.GetIPModeMigrationStatus().Complete)
+	assert.False(t, mockContext.ipModeMigrationInProgress)
+	assert.Len(t, mockContext.dataStore.GetENIInfos().ENIs[primaryENIid].AvailableIPv4Cidrs, 1)
+}
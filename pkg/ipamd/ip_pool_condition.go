@@ -0,0 +1,220 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/utils/eventrecorder"
+	"github.com/aws/amazon-vpc-cni-k8s/rpc"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// NodeConditionIPPoolExhausted is true while ipamd has no IP addresses left to hand out and cannot grow the pool
+	NodeConditionIPPoolExhausted corev1.NodeConditionType = "NetworkIPPoolExhausted"
+
+	// Reasons used for the NetworkIPPoolExhausted node condition and the related events
+	ipPoolReasonNoAvailableIPs      = "NoAvailableIPAddresses"
+	ipPoolReasonMaxENILimitReached  = "MaxENILimitReached"
+	ipPoolReasonInsufficientSubnet  = "InsufficientSubnetAddresses"
+	ipPoolReasonRecovered           = "IPPoolRecovered"
+	podEventReasonFailedToAssignIP  = "FailedToAssignIP"
+	ipPoolConditionAction           = "IPPoolExhaustion"
+	ipPoolExhaustedMessage          = "No IP addresses are available on the node for new pods"
+	maxENILimitReachedMessage       = "No IP addresses are available and the maximum number of ENIs is already attached"
+	insufficientSubnetIPsMessage    = "No IP addresses are available and the subnet does not have enough free IP addresses or prefixes"
+	ipPoolRecoveredMessage          = "IP addresses are available on the node again"
+	podIPAssignmentFailedMessageFmt = "Failed to assign an IP address to the pod: %s"
+
+	// maxPendingIPAssignmentFailures bounds the pod events raised per run of the pool manager
+	maxPendingIPAssignmentFailures = 16
+)
+
+// hasNoFreeAddresses returns true if every address in the datastore is either assigned or in cooldown
+func (c *IPAMContext) hasNoFreeAddresses() bool {
+	// In dual-stack mode, IPv4 addresses run out long before the IPv6 prefix of the primary ENI
+	addressFamily := ipV4AddrFamily
+	if c.enableIPv6 && !c.enableIPv4 {
+		addressFamily = ipV6AddrFamily
+	}
+	stats := c.dataStore.GetIPStats(addressFamily)
+	return stats.AvailableAddresses()-stats.CooldownIPs <= 0
+}
+
+// canAssignIPs returns false if there are no free addresses in the datastore and the pool can't grow, because no
+// more ENIs can be attached and the attached ENIs already have all the IPs or prefixes they can hold
+func (c *IPAMContext) canAssignIPs() bool {
+	if !c.hasNoFreeAddresses() || c.hasRoomForEni() {
+		return true
+	}
+	if c.enableIPv6 && !c.enableIPv4 {
+		return false
+	}
+	maxPerENI := c.maxIPsPerENI
+	if c.enablePrefixDelegation {
+		maxPerENI = c.maxPrefixesPerENI
+	}
+	return c.dataStore.GetENINeedsIP(maxPerENI, c.useCustomNetworking) != nil
+}
+
+// ipAssignmentFailureReason returns why the datastore could not assign an IP address to a pod
+func (c *IPAMContext) ipAssignmentFailureReason() (rpc.ErrorReason, string) {
+	c.ipPoolExhaustedLock.Lock()
+	subnetExhausted := c.ipPoolExhaustedReason == ipPoolReasonInsufficientSubnet
+	c.ipPoolExhaustedLock.Unlock()
+	switch {
+	case subnetExhausted:
+		return rpc.ErrorReason_SUBNET_EXHAUSTED, insufficientSubnetIPsMessage
+	case !c.canAssignIPs():
+		return rpc.ErrorReason_MAX_ENIS_REACHED, maxENILimitReachedMessage
+	default:
+		return rpc.ErrorReason_POOL_EMPTY, ipPoolExhaustedMessage
+	}
+}
+
+// setIPPoolExhausted sets the NetworkIPPoolExhausted node condition and raises a node event. Nothing is sent to the
+// API server unless the reason changed since the last call, so repeated failures don't flood it. The lock only
+// guards the reason, the API server is called after it is released so that CNI requests are not held up.
+func (c *IPAMContext) setIPPoolExhausted(reason, message string) {
+	c.ipPoolExhaustedLock.Lock()
+	if c.ipPoolExhaustedReason == reason {
+		c.ipPoolExhaustedLock.Unlock()
+		return
+	}
+	c.ipPoolExhaustedReason = reason
+	c.ipPoolExhaustedLock.Unlock()
+
+	log.Warnf("IP pool exhausted (%s): %s", reason, message)
+	if err := c.setNodeIPPoolCondition(corev1.ConditionTrue, reason, message); err != nil {
+		log.Errorf("Failed to set %s node condition: %v", NodeConditionIPPoolExhausted, err)
+	}
+	if eventRecorder := eventrecorder.Get(); eventRecorder != nil {
+		eventRecorder.SendNodeEvent(corev1.EventTypeWarning, reason, ipPoolConditionAction, message)
+	}
+}
+
+// clearIPPoolExhausted resets the NetworkIPPoolExhausted node condition once addresses are available again
+func (c *IPAMContext) clearIPPoolExhausted() {
+	c.ipPoolExhaustedLock.Lock()
+	if c.ipPoolExhaustedReason == "" || c.hasNoFreeAddresses() {
+		c.ipPoolExhaustedLock.Unlock()
+		return
+	}
+	c.ipPoolExhaustedReason = ""
+	c.ipPoolExhaustedLock.Unlock()
+
+	log.Infof("IP pool recovered: %s", ipPoolRecoveredMessage)
+	if err := c.setNodeIPPoolCondition(corev1.ConditionFalse, ipPoolReasonRecovered, ipPoolRecoveredMessage); err != nil {
+		log.Errorf("Failed to reset %s node condition: %v", NodeConditionIPPoolExhausted, err)
+	}
+	if eventRecorder := eventrecorder.Get(); eventRecorder != nil {
+		eventRecorder.SendNodeEvent(corev1.EventTypeNormal, ipPoolReasonRecovered, ipPoolConditionAction, ipPoolRecoveredMessage)
+	}
+}
+
+// reportIPAssignmentFailure records a pod that could not get an IP address while the datastore has no free addresses
+// left. Nothing is sent to the API server from the CNI request, publishIPAssignmentFailures marks the pool as
+// exhausted and raises the pod events from the pool manager.
+func (c *IPAMContext) reportIPAssignmentFailure(podName, podNamespace string, assignErr error) {
+	if !c.hasNoFreeAddresses() {
+		return
+	}
+	c.ipPoolExhaustedLock.Lock()
+	defer c.ipPoolExhaustedLock.Unlock()
+	c.ipAssignmentFailed = true
+	if podName == "" || len(c.pendingIPAssignmentFailures) >= maxPendingIPAssignmentFailures {
+		return
+	}
+	if c.pendingIPAssignmentFailures == nil {
+		c.pendingIPAssignmentFailures = make(map[types.NamespacedName]string)
+	}
+	c.pendingIPAssignmentFailures[types.NamespacedName{Namespace: podNamespace, Name: podName}] = assignErr.Error()
+}
+
+// publishIPAssignmentFailures marks the pool as exhausted and raises an event on each pod that could not get an IP
+// address since the last call
+func (c *IPAMContext) publishIPAssignmentFailures() {
+	c.ipPoolExhaustedLock.Lock()
+	failed, failures := c.ipAssignmentFailed, c.pendingIPAssignmentFailures
+	c.ipAssignmentFailed, c.pendingIPAssignmentFailures = false, nil
+	c.ipPoolExhaustedLock.Unlock()
+	if !failed {
+		return
+	}
+	if c.hasNoFreeAddresses() {
+		c.setIPPoolExhausted(ipPoolReasonNoAvailableIPs, ipPoolExhaustedMessage)
+	}
+
+	eventRecorder := eventrecorder.Get()
+	if eventRecorder == nil {
+		return
+	}
+	for podKey, assignErr := range failures {
+		pod, err := c.GetPod(podKey.Name, podKey.Namespace)
+		if err != nil {
+			log.Warnf("Unable to raise IP assignment failure event for pod %s: %v", podKey, err)
+			continue
+		}
+		eventRecorder.SendEventOnPod(pod, corev1.EventTypeWarning, podEventReasonFailedToAssignIP, ipPoolConditionAction,
+			fmt.Sprintf(podIPAssignmentFailedMessageFmt, assignErr))
+	}
+}
+
+// updateIPPoolCondition reports the failed pod IP assignments since the last call, and resets the
+// NetworkIPPoolExhausted node condition once addresses are available again. It is called by the pool manager.
+func (c *IPAMContext) updateIPPoolCondition() {
+	c.publishIPAssignmentFailures()
+	c.clearIPPoolExhausted()
+}
+
+// setNodeIPPoolCondition updates the NetworkIPPoolExhausted condition in the status of this node
+func (c *IPAMContext) setNodeIPPoolCondition(status corev1.ConditionStatus, reason, message string) error {
+	ctx := context.TODO()
+	node := &corev1.Node{}
+	if err := c.k8sClient.Get(ctx, types.NamespacedName{Name: c.myNodeName}, node); err != nil {
+		return err
+	}
+
+	now := metav1.Now()
+	condition := corev1.NodeCondition{
+		Type:               NodeConditionIPPoolExhausted,
+		Status:             status,
+		LastHeartbeatTime:  now,
+		LastTransitionTime: now,
+		Reason:             reason,
+		Message:            message,
+	}
+	newNode := node.DeepCopy()
+	found := false
+	for i, existing := range newNode.Status.Conditions {
+		if existing.Type != NodeConditionIPPoolExhausted {
+			continue
+		}
+		if existing.Status == status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+		newNode.Status.Conditions[i] = condition
+		found = true
+	}
+	if !found {
+		newNode.Status.Conditions = append(newNode.Status.Conditions, condition)
+	}
+	// Conditions are merged by type, so a strategic merge patch leaves the conditions owned by kubelet untouched
+	return c.k8sClient.Status().Patch(ctx, newNode, client.StrategicMergeFrom(node))
+}
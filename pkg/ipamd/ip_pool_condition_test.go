@@ -0,0 +1,157 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/utils/eventrecorder"
+	pb "github.com/aws/amazon-vpc-cni-k8s/rpc"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func getIPPoolCondition(t *testing.T, m *testMocks) *corev1.NodeCondition {
+	node := &corev1.Node{}
+	assert.NoError(t, m.k8sClient.Get(context.Background(), types.NamespacedName{Name: myNodeName}, node))
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == NodeConditionIPPoolExhausted {
+			return &condition
+		}
+	}
+	return nil
+}
+
+func TestIPPoolExhaustedCondition(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	fakeRecorder := eventrecorder.InitMockEventRecorder()
+	ctx := context.Background()
+
+	readyCondition := corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionTrue}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: myNodeName},
+		Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{readyCondition}},
+	}
+	assert.NoError(t, m.k8sClient.Create(ctx, node))
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"}}
+	assert.NoError(t, m.k8sClient.Create(ctx, pod))
+
+	mockContext := &IPAMContext{
+		k8sClient:  m.k8sClient,
+		myNodeName: myNodeName,
+		dataStore:  datastore.NewDataStore(log, datastore.NullCheckpoint{}, false),
+	}
+	_ = mockContext.dataStore.AddENI(primaryENIid, primaryDevice, true, false, false)
+	_ = mockContext.dataStore.AddIPv4CidrToStore(primaryENIid, net.IPNet{IP: net.ParseIP(ipaddr01), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	_, _, err := mockContext.dataStore.AssignPodIPv4Address(datastore.IPAMKey{ContainerID: "container1"}, datastore.IPAMMetadata{K8SPodName: "pod0"})
+	assert.NoError(t, err)
+
+	// The only IP is assigned, so the failed assignment marks the pool as exhausted and raises node and pod events.
+	// Nothing is sent to the API server until the pool manager runs.
+	mockContext.reportIPAssignmentFailure("pod1", "default", errors.New("no available IP/Prefix addresses"))
+	assert.Nil(t, getIPPoolCondition(t, m))
+	assert.Len(t, fakeRecorder.Events, 0)
+	mockContext.updateIPPoolCondition()
+	condition := getIPPoolCondition(t, m)
+	assert.NotNil(t, condition)
+	assert.Equal(t, corev1.ConditionTrue, condition.Status)
+	assert.Equal(t, ipPoolReasonNoAvailableIPs, condition.Reason)
+	assert.Len(t, fakeRecorder.Events, 2)
+	assert.Equal(t, "Warning "+ipPoolReasonNoAvailableIPs+" "+ipPoolExhaustedMessage, <-fakeRecorder.Events)
+	assert.Contains(t, <-fakeRecorder.Events, podEventReasonFailedToAssignIP)
+
+	// Same reason again doesn't raise another node event, only the pod event
+	mockContext.reportIPAssignmentFailure("pod1", "default", errors.New("no available IP/Prefix addresses"))
+	mockContext.reportIPAssignmentFailure("pod1", "default", errors.New("no available IP/Prefix addresses"))
+	mockContext.updateIPPoolCondition()
+	assert.Len(t, fakeRecorder.Events, 1)
+	<-fakeRecorder.Events
+
+	// A new reason updates the condition
+	mockContext.setIPPoolExhausted(ipPoolReasonMaxENILimitReached, maxENILimitReachedMessage)
+	assert.Equal(t, ipPoolReasonMaxENILimitReached, getIPPoolCondition(t, m).Reason)
+	<-fakeRecorder.Events
+
+	// Still no free addresses, so the condition stays
+	mockContext.clearIPPoolExhausted()
+	assert.Equal(t, corev1.ConditionTrue, getIPPoolCondition(t, m).Status)
+	assert.Len(t, fakeRecorder.Events, 0)
+
+	_ = mockContext.dataStore.AddIPv4CidrToStore(primaryENIid, net.IPNet{IP: net.ParseIP(ipaddr02), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	mockContext.clearIPPoolExhausted()
+	condition = getIPPoolCondition(t, m)
+	assert.Equal(t, corev1.ConditionFalse, condition.Status)
+	assert.Equal(t, ipPoolReasonRecovered, condition.Reason)
+	assert.Equal(t, "Normal "+ipPoolReasonRecovered+" "+ipPoolRecoveredMessage, <-fakeRecorder.Events)
+
+	// Conditions owned by kubelet are left alone
+	updatedNode := &corev1.Node{}
+	assert.NoError(t, m.k8sClient.Get(ctx, types.NamespacedName{Name: myNodeName}, updatedNode))
+	assert.Len(t, updatedNode.Status.Conditions, 2)
+	assert.Equal(t, corev1.NodeReady, updatedNode.Status.Conditions[0].Type)
+}
+
+func TestReportIPAssignmentFailureWithFreeAddresses(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	fakeRecorder := eventrecorder.InitMockEventRecorder()
+
+	mockContext := &IPAMContext{
+		k8sClient:  m.k8sClient,
+		myNodeName: myNodeName,
+		dataStore:  datastore.NewDataStore(log, datastore.NullCheckpoint{}, false),
+	}
+	_ = mockContext.dataStore.AddENI(primaryENIid, primaryDevice, true, false, false)
+	_ = mockContext.dataStore.AddIPv4CidrToStore(primaryENIid, net.IPNet{IP: net.ParseIP(ipaddr01), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+
+	mockContext.reportIPAssignmentFailure("pod1", "default", errors.New("failed"))
+	mockContext.updateIPPoolCondition()
+	assert.Equal(t, "", mockContext.ipPoolExhaustedReason)
+	assert.Len(t, fakeRecorder.Events, 0)
+}
+
+func TestIPAssignmentFailureReason(t *testing.T) {
+	mockContext := &IPAMContext{
+		enableIPv4:   true,
+		maxENI:       1,
+		maxIPsPerENI: 2,
+		dataStore:    datastore.NewDataStore(log, datastore.NullCheckpoint{}, false),
+	}
+	_ = mockContext.dataStore.AddENI(primaryENIid, primaryDevice, true, false, false)
+	_ = mockContext.dataStore.AddIPv4CidrToStore(primaryENIid, net.IPNet{IP: net.ParseIP(ipaddr01), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	_, _, err := mockContext.dataStore.AssignPodIPv4Address(datastore.IPAMKey{ContainerID: "container1"}, datastore.IPAMMetadata{})
+	assert.NoError(t, err)
+
+	// The ENI can hold another IP
+	reason, message := mockContext.ipAssignmentFailureReason()
+	assert.Equal(t, pb.ErrorReason_POOL_EMPTY, reason)
+	assert.Equal(t, ipPoolExhaustedMessage, message)
+
+	mockContext.maxIPsPerENI = 1
+	reason, message = mockContext.ipAssignmentFailureReason()
+	assert.Equal(t, pb.ErrorReason_MAX_ENIS_REACHED, reason)
+	assert.Equal(t, maxENILimitReachedMessage, message)
+
+	mockContext.ipPoolExhaustedReason = ipPoolReasonInsufficientSubnet
+	reason, message = mockContext.ipAssignmentFailureReason()
+	assert.Equal(t, pb.ErrorReason_SUBNET_EXHAUSTED, reason)
+	assert.Equal(t, insufficientSubnetIPsMessage, message)
+}
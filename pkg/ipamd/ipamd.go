@@ -38,11 +38,14 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/retry"
 
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/apis/crd/v1alpha1"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/awsutils"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/bpfrouting"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/eniconfig"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/k8sapi"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/networkutils"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/utils/eventrecorder"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/utils/logger"
 	rcv1alpha1 "github.com/aws/amazon-vpc-resource-controller-k8s/apis/vpcresources/v1alpha1"
 )
@@ -56,8 +59,19 @@ const (
 	maxRetryCheckENI            = 5
 	eniAttachTime               = 10 * time.Second
 	nodeIPPoolReconcileInterval = 60 * time.Second
+	vpcCIDRWatchInterval        = 60 * time.Second
+	egressIPUpdateInterval      = 30 * time.Second
 	decreaseIPPoolInterval      = 30 * time.Second
 
+	// Reasons of the events raised when the IPv4 CIDRs of the VPC change
+	vpcCIDRAssociatedReason    = "VPCCIDRAssociated"
+	vpcCIDRDisassociatedReason = "VPCCIDRDisassociated"
+	vpcCIDRChangedAction       = "VPCCIDRUpdate"
+
+	// Reason of the event raised when an ENI is not set up because another ENI already uses its device number
+	eniDeviceNumberConflictReason = "ENIDeviceNumberConflict"
+	eniSetupAction                = "ENISetup"
+
 	// ipReconcileCooldown is the amount of time that an IP address must wait until it can be added to the data store
 	// during reconciliation after being discovered on the EC2 instance metadata.
 	ipReconcileCooldown = 60 * time.Second
@@ -143,12 +157,50 @@ const (
 	envWarmPrefixTarget     = "WARM_PREFIX_TARGET"
 	defaultWarmPrefixTarget = 0
 
+	//envWarmIPv6PrefixTarget is used to keep IPv6 /80 prefixes without pods attached in IPv6 mode.
+	envWarmIPv6PrefixTarget     = "WARM_IPV6_PREFIX_TARGET"
+	defaultWarmIPv6PrefixTarget = 0
+
 	//envEnableIPv4 - Env variable to enable/disable IPv4 mode
 	envEnableIPv4 = "ENABLE_IPv4"
 
 	//envEnableIPv6 - Env variable to enable/disable IPv6 mode
 	envEnableIPv6 = "ENABLE_IPv6"
 
+	// envEnableMultiNetworkCard is used to attach ENIs for pods to all network cards of the instance instead of only
+	// the default network card
+	envEnableMultiNetworkCard = "ENABLE_MULTI_NETWORK_CARD"
+
+	// envEnablePodIPResource is used to advertise the pod IP capacity of the node as the vpc.amazonaws.com/pod-ip
+	// extended resource
+	envEnablePodIPResource = "ENABLE_POD_IP_RESOURCE"
+
+	// envEnableHybridIPMode is used to allocate secondary IPs next to prefixes when prefixes are not available
+	envEnableHybridIPMode = "ENABLE_HYBRID_IP_MODE"
+
+	// envEnablePodENIConfig is used to let a pod annotation or a namespace label choose the ENIConfig of the pod
+	envEnablePodENIConfig = "ENABLE_POD_ENI_CONFIG"
+
+	// envEnableENIPassthrough is used to move a whole attached ENI into the network namespace of the pods that ask for
+	// one, instead of giving them a secondary IP
+	envEnableENIPassthrough = "ENABLE_ENI_PASSTHROUGH"
+
+	// envEnableEBPFPodRouting is used to route the traffic from pods by source IP with an eBPF program on the host veths
+	// and one rule per ENI, instead of one rule per pod IP
+	envEnableEBPFPodRouting = "ENABLE_EBPF_POD_ROUTING"
+
+	// envEnableVPCCIDRWatch is used to watch the IPv4 CIDRs of the VPC with EC2 DescribeVpcs instead of instance
+	// metadata, so that disassociated CIDRs are noticed as well
+	envEnableVPCCIDRWatch = "ENABLE_VPC_CIDR_WATCH"
+
+	// envEnableEgressIPPinning is used to SNAT the outbound traffic of the pods of a namespace to an egress IP listed in
+	// the amazon-vpc-cni-egress-ips ConfigMap instead of the primary IP of the node
+	envEnableEgressIPPinning = "ENABLE_EGRESS_IP_PINNING"
+
+	// envSecondaryNetworks maps the network names of secondary pod interfaces to the ENIConfig of their ENIs, e.g.
+	// "net-a=eniconfig-a,net-b=eniconfig-b"
+	envSecondaryNetworks = "SECONDARY_NETWORKS"
+
 	ipV4AddrFamily = "4"
 	ipV6AddrFamily = "6"
 
@@ -253,6 +305,7 @@ type IPAMContext struct {
 	warmIPTarget              int
 	minimumIPTarget           int
 	warmPrefixTarget          int
+	warmIPv6PrefixTarget      int
 	primaryIP                 map[string]string // primaryIP is a map from ENI ID to primary IP of that ENI
 	lastNodeIPPoolAction      time.Time
 	lastDecreaseIPPool        time.Time
@@ -263,10 +316,47 @@ type IPAMContext struct {
 	disableENIProvisioning    bool
 	enablePodENI              bool
 	myNodeName                string
+	availabilityZone          string
 	enablePrefixDelegation    bool
 	lastInsufficientCidrError time.Time
 	enableManageUntaggedMode  bool
 	enablePodIPAnnotation     bool
+	enableMultiNetworkCard    bool
+	// deviceNumberConflicts maps each ENI that was not set up because its device number is already used to the ENI
+	// that uses it. Those ENIs are skipped until one of the two is detached.
+	deviceNumberConflicts map[string]string
+	// ipPoolExhaustedReason is the reason of the NetworkIPPoolExhausted node condition, empty while IPs are available
+	ipPoolExhaustedReason string
+	ipPoolExhaustedLock   sync.Mutex
+	// ipAssignmentFailed and pendingIPAssignmentFailures are the failed pod IP assignments since the last run of the
+	// pool manager, which reports them to the API server
+	ipAssignmentFailed          bool
+	pendingIPAssignmentFailures map[types.NamespacedName]string
+	enablePodIPResource         bool
+	enableHybridIPMode          bool
+	// ipModeMigrationInProgress is set while CIDRs of the other IP allocation mode are attached
+	ipModeMigrationInProgress bool
+	// advertisedPodIPs is the vpc.amazonaws.com/pod-ip capacity last set on the node, -1 if the resource was removed
+	advertisedPodIPs    int
+	podIPResourceSynced bool
+	enablePodENIConfig  bool
+	// podENIConfigsShort is the set of ENIConfigs that ran out of IPs for their pods since the last pool update
+	podENIConfigsShort     map[string]bool
+	podENIConfigsShortLock sync.Mutex
+	// poolUpdateRequested wakes up the pool manager when pods ran out of IPs of their ENIConfig
+	poolUpdateRequested chan struct{}
+	podENIConfigNames   podENIConfigNameCache
+	enableVPCCIDRWatch  bool
+	// vpcCIDRWatchStarted is set once the VPC CIDRs were listed for the first time
+	vpcCIDRWatchStarted   bool
+	enableEgressIPPinning bool
+	egressIPs             egressIPState
+	// secondaryNetworks maps the network name of a secondary pod interface to the ENIConfig that its IPs come from
+	secondaryNetworks    map[string]string
+	enableENIPassthrough bool
+	enableEBPFPodRouting bool
+	// bpfRouteMap is the BPF map of the route tables of the pod IPs, nil unless eBPF pod routing is enabled
+	bpfRouteMap bpfrouting.RouteMap
 }
 
 // setUnmanagedENIs will rebuild the set of ENI IDs for ENIs tagged as "no_manage"
@@ -387,7 +477,8 @@ func New(k8sClient client.Client) (*IPAMContext, error) {
 	c.enableIPv4 = isIPv4Enabled()
 	c.enableIPv6 = isIPv6Enabled()
 	c.disableENIProvisioning = disableENIProvisioning()
-	client, err := awsutils.New(c.useCustomNetworking, disableLeakedENICleanup(), c.enableIPv4, c.enableIPv6)
+	c.enableMultiNetworkCard = enableMultiNetworkCard()
+	client, err := awsutils.New(c.useCustomNetworking, disableLeakedENICleanup(), c.enableIPv4, c.enableIPv6, c.enableMultiNetworkCard)
 	if err != nil {
 		return nil, errors.Wrap(err, "ipamd: can not initialize with AWS SDK interface")
 	}
@@ -395,14 +486,24 @@ func New(k8sClient client.Client) (*IPAMContext, error) {
 
 	c.primaryIP = make(map[string]string)
 	c.reconcileCooldownCache.cache = make(map[string]time.Time)
-	// WARM and Min IP/Prefix targets are ignored in IPv6 mode
+	c.poolUpdateRequested = make(chan struct{}, 1)
+	// WARM and Min IP/Prefix targets are ignored in IPv6 mode, which only uses WARM_IPV6_PREFIX_TARGET
 	c.warmENITarget = getWarmENITarget()
 	c.warmIPTarget = getWarmIPTarget()
 	c.minimumIPTarget = getMinimumIPTarget()
 	c.warmPrefixTarget = getWarmPrefixTarget()
+	c.warmIPv6PrefixTarget = getWarmIPv6PrefixTarget()
 	c.enablePodENI = enablePodENI()
 	c.enableManageUntaggedMode = enableManageUntaggedMode()
 	c.enablePodIPAnnotation = enablePodIPAnnotation()
+	c.enablePodIPResource = enablePodIPResource()
+	c.enableHybridIPMode = enableHybridIPMode()
+	c.enablePodENIConfig = enablePodENIConfig()
+	c.enableVPCCIDRWatch = enableVPCCIDRWatch()
+	c.enableEgressIPPinning = enableEgressIPPinning()
+	c.secondaryNetworks = getSecondaryNetworks()
+	c.enableENIPassthrough = enableENIPassthrough()
+	c.enableEBPFPodRouting = enableEBPFPodRouting()
 
 	err = c.awsClient.FetchInstanceTypeLimits()
 	if err != nil {
@@ -414,11 +515,14 @@ func New(k8sClient client.Client) (*IPAMContext, error) {
 	if !c.isConfigValid() {
 		return nil, fmt.Errorf("ipamd: failed to validate configuration")
 	}
+	c.disableUnsupportedFeatures()
 
 	c.awsClient.InitCachedPrefixDelegation(c.enablePrefixDelegation)
 	c.myNodeName = os.Getenv(envNodeName)
+	c.availabilityZone = c.awsClient.GetAvailabilityZone()
 	checkpointer := datastore.NewJSONFile(dsBackingStorePath())
 	c.dataStore = datastore.NewDataStore(log, checkpointer, c.enablePrefixDelegation)
+	c.dataStore.SetHybridMode(c.enableHybridIPMode)
 
 	if err := c.nodeInit(); err != nil {
 		return nil, err
@@ -500,37 +604,53 @@ func (c *IPAMContext) nodeInit() error {
 		}
 	}
 
-	if err := c.dataStore.ReadBackingStore(c.enableIPv6); err != nil {
+	if err := c.dataStore.ReadBackingStore(c.enableIPv4, c.enableIPv6); err != nil {
 		return err
 	}
 
-	if c.enableIPv6 {
-		// We will not support upgrading/converting an existing IPv4 cluster to operate in IPv6 mode. So, we will always
-		// start with a clean slate in IPv6 mode. We also don't have to deal with dynamic update of Prefix Delegation
-		// feature in IPv6 mode as we don't support (yet) a non-PD v6 option. In addition, we don't support custom
-		// networking & SGPP in IPv6 mode yet. So, we will skip the corresponding setup. Will save us from checking
-		// if IPv6 is enabled at multiple places. Once we start supporting these features in IPv6 mode, we can do away
-		// with this check and not change anything else in the below setup.
-		return nil
-	}
+	// We will not support upgrading/converting an existing IPv4 cluster to operate in IPv6 mode. So, we will always
+	// start with a clean slate in IPv6 mode. We also don't have to deal with dynamic update of Prefix Delegation
+	// feature in IPv6 mode as we don't support (yet) a non-PD v6 option. The IPv4 pod IP rules are not needed either.
+	if c.enableIPv4 {
+		if c.enablePrefixDelegation {
+			// During upgrade or if prefix delgation knob is disabled to enabled then we
+			// might have secondary IPs attached to ENIs so doing a cleanup if not used before moving on.
+			// In hybrid mode, secondary IPs are used next to prefixes, so they are kept.
+			if !c.enableHybridIPMode {
+				c.tryUnassignIPsFromENIs()
+			}
+		} else {
+			// When prefix delegation knob is enabled to disabled then we might
+			// have unused prefixes attached to the ENIs so need to cleanup
+			c.tryUnassignPrefixesFromENIs()
+		}
 
-	if c.enablePrefixDelegation {
-		// During upgrade or if prefix delgation knob is disabled to enabled then we
-		// might have secondary IPs attached to ENIs so doing a cleanup if not used before moving on
-		c.tryUnassignIPsFromENIs()
-	} else {
-		// When prefix delegation knob is enabled to disabled then we might
-		// have unused prefixes attached to the ENIs so need to cleanup
-		c.tryUnassignPrefixesFromENIs()
-	}
+		if err = c.configureIPRulesForPods(); err != nil {
+			return err
+		}
+		if c.enableEBPFPodRouting {
+			if c.bpfRouteMap, err = bpfrouting.Load(); err != nil {
+				log.Errorf("Failed to load the eBPF pod routing program: %v", err)
+				return err
+			}
+			c.syncBPFPodRoutes()
+		}
+		// Spawning updateCIDRsRulesOnChange go-routine
+		cidrsUpdateInterval := 30 * time.Second
+		if c.enableVPCCIDRWatch {
+			cidrsUpdateInterval = vpcCIDRWatchInterval
+		}
+		go wait.Forever(func() {
+			vpcV4CIDRs = c.updateCIDRsRulesOnChange(vpcV4CIDRs)
+		}, cidrsUpdateInterval)
 
-	if err = c.configureIPRulesForPods(); err != nil {
-		return err
+		if c.enableEgressIPPinning {
+			// Pods of pinned namespaces are also updated on every add and delete
+			go wait.Forever(func() {
+				c.updateEgressIPRules(ctx)
+			}, egressIPUpdateInterval)
+		}
 	}
-	// Spawning updateCIDRsRulesOnChange go-routine
-	go wait.Forever(func() {
-		vpcV4CIDRs = c.updateCIDRsRulesOnChange(vpcV4CIDRs)
-	}, 30*time.Second)
 
 	// RefreshSGIDs populates the ENI cache with ENI -> security group ID mappings, and so it must be called:
 	// 1. after managed/unmanaged ENIs have been determined
@@ -554,7 +674,7 @@ func (c *IPAMContext) nodeInit() error {
 		return err
 	}
 
-	eniConfigName, err := eniconfig.GetNodeSpecificENIConfigName(node)
+	eniConfigName, err := eniconfig.GetNodeENIConfigName(ctx, c.k8sClient, c.awsClient, node, c.availabilityZone)
 	if err == nil && c.useCustomNetworking && eniConfigName != "default" {
 		// Add the feature name to CNINode of this node
 		err := c.AddFeatureToCNINode(ctx, rcv1alpha1.CustomNetworking, eniConfigName)
@@ -572,6 +692,10 @@ func (c *IPAMContext) nodeInit() error {
 		c.askForTrunkENIIfNeeded(ctx)
 	}
 
+	if c.enableIPv6 && !c.enableIPv4 {
+		return c.nodeInitIPv6Pool(ctx)
+	}
+
 	// On node init, check if datastore pool needs to be increased. If so, attach CIDRs from existing ENIs and attach new ENIs.
 	if !c.disableENIProvisioning && c.isDatastorePoolTooLow() {
 		if err := c.increaseDatastorePool(ctx); err != nil {
@@ -587,6 +711,8 @@ func (c *IPAMContext) nodeInit() error {
 		}
 	}
 
+	c.syncPodIPResource(ctx)
+
 	log.Debug("node init completed successfully")
 	return nil
 }
@@ -608,6 +734,15 @@ func (c *IPAMContext) configureIPRulesForPods() error {
 		if err != nil {
 			log.Warnf("UpdateRuleListBySrc in nodeInit() failed for IP %s: %v", info.IP, err)
 		}
+
+		// Pods that were routed by eBPF pod routing have no rule, it is added back when the setting is disabled. Pods
+		// of ENIs whose route table does not fit in the mark always have one.
+		if info.DeviceNumber > 0 && (!c.enableEBPFPodRouting || !bpfrouting.Routable(info.DeviceNumber)) {
+			err = c.networkClient.SetupRuleBySrc(rules, srcIPNet, podRouteTable(info.DeviceNumber))
+			if err != nil {
+				log.Warnf("SetupRuleBySrc in nodeInit() failed for IP %s: %v", info.IP, err)
+			}
+		}
 	}
 
 	// Program IP rules for external service CIDRs and cleanup stale rules.
@@ -623,7 +758,13 @@ func (c *IPAMContext) configureIPRulesForPods() error {
 }
 
 func (c *IPAMContext) updateCIDRsRulesOnChange(oldVPCCIDRs []string) []string {
-	newVPCCIDRs, err := c.awsClient.GetVPCIPv4CIDRs()
+	var newVPCCIDRs []string
+	var err error
+	if c.enableVPCCIDRWatch {
+		newVPCCIDRs, err = c.awsClient.DescribeVPCIPv4CIDRs()
+	} else {
+		newVPCCIDRs, err = c.awsClient.GetVPCIPv4CIDRs()
+	}
 	if err != nil {
 		log.Warnf("skipping periodic update to VPC CIDRs due to error: %v", err)
 		return oldVPCCIDRs
@@ -638,10 +779,41 @@ func (c *IPAMContext) updateCIDRsRulesOnChange(oldVPCCIDRs []string) []string {
 		if err != nil {
 			log.Warnf("unable to update host iptables rules for VPC CIDRs due to error: %v", err)
 		}
+		// The first list from EC2 is compared with the one from instance metadata, which can differ for a while
+		if c.enableVPCCIDRWatch && c.vpcCIDRWatchStarted {
+			removed := old.Difference(new)
+			c.recordVPCCIDRChanges(new.Difference(old).List(), removed.List())
+			if removed.Len() > 0 {
+				if err := c.networkClient.RemoveVPCCIDRRules(removed.List()); err != nil {
+					log.Errorf("Failed to remove the rules of disassociated VPC CIDRs %v: %v", removed.List(), err)
+				}
+			}
+		}
 	}
+	c.vpcCIDRWatchStarted = true
 	return newVPCCIDRs
 }
 
+// recordVPCCIDRChanges logs and raises a node event for each IPv4 CIDR that was associated with or disassociated from
+// the VPC
+func (c *IPAMContext) recordVPCCIDRChanges(added, removed []string) {
+	eventRecorder := eventrecorder.Get()
+	for _, cidr := range added {
+		log.Infof("VPC CIDR %s was associated, updating host rules", cidr)
+		if eventRecorder != nil {
+			eventRecorder.SendNodeEvent(corev1.EventTypeNormal, vpcCIDRAssociatedReason, vpcCIDRChangedAction,
+				fmt.Sprintf("VPC CIDR %s was associated", cidr))
+		}
+	}
+	for _, cidr := range removed {
+		log.Infof("VPC CIDR %s was disassociated, removing host rules", cidr)
+		if eventRecorder != nil {
+			eventRecorder.SendNodeEvent(corev1.EventTypeNormal, vpcCIDRDisassociatedReason, vpcCIDRChangedAction,
+				fmt.Sprintf("VPC CIDR %s was disassociated", cidr))
+		}
+	}
+}
+
 func (c *IPAMContext) updateIPStats(unmanaged int) {
 	ipMax.Set(float64(c.maxIPsPerENI * (c.maxENI - unmanaged)))
 	enisMax.Set(float64(c.maxENI - unmanaged))
@@ -649,20 +821,33 @@ func (c *IPAMContext) updateIPStats(unmanaged int) {
 
 // StartNodeIPPoolManager monitors the IP pool, add or del them when it is required.
 func (c *IPAMContext) StartNodeIPPoolManager() {
-	if c.enableIPv6 {
-		//Nothing to do in IPv6 Mode. IPv6 is only supported in Prefix delegation mode
-		//and VPC CNI will only attach one V6 Prefix.
-		return
-	}
 	sleepDuration := ipPoolMonitorInterval / 2
 	ctx := context.Background()
+	if c.enableIPv6 && !c.enableIPv4 {
+		// The IPv4 reconciler and pod IP resource do not apply in IPv6 mode
+		for {
+			time.Sleep(ipPoolMonitorInterval)
+			if !c.disableENIProvisioning {
+				c.updateIPv6PoolIfRequired(ctx)
+			}
+			c.updateIPPoolCondition()
+			c.nodeIPPoolReconcile(ctx, nodeIPPoolReconcileInterval)
+		}
+	}
 	for {
 		if !c.disableENIProvisioning {
-			time.Sleep(sleepDuration)
+			c.waitForPoolUpdate(sleepDuration)
 			c.updateIPPoolIfRequired(ctx)
 		}
+		c.updateIPPoolCondition()
 		time.Sleep(sleepDuration)
 		c.nodeIPPoolReconcile(ctx, nodeIPPoolReconcileInterval)
+		c.returnPassthroughENIs()
+		if c.bpfRouteMap != nil {
+			c.syncBPFPodRoutes()
+		}
+		// The number of unmanaged ENIs is refreshed by the reconciler
+		c.syncPodIPResource(ctx)
 	}
 }
 
@@ -679,6 +864,10 @@ func (c *IPAMContext) updateIPPoolIfRequired(ctx context.Context) {
 	if c.shouldRemoveExtraENIs() {
 		c.tryFreeENI()
 	}
+	if c.useENIConfigENIs() {
+		c.updatePodENIConfigPools(ctx)
+	}
+	c.releaseLeftoverCidrs()
 }
 
 // decreaseDatastorePool runs every `interval` and attempts to return unused ENIs and IPs
@@ -715,7 +904,12 @@ func (c *IPAMContext) tryFreeENI() {
 		return
 	}
 
-	eni := c.dataStore.RemoveUnusedENIFromStore(c.warmIPTarget, c.minimumIPTarget, c.warmPrefixTarget)
+	warmIPTarget, minimumIPTarget, warmPrefixTarget := c.warmIPTarget, c.minimumIPTarget, c.warmPrefixTarget
+	if c.enableIPv6 && !c.enableIPv4 {
+		// IPv4 warm targets do not apply, an ENI is kept as long as it has IPv6 prefixes
+		warmIPTarget, minimumIPTarget, warmPrefixTarget = 0, 0, 0
+	}
+	eni := c.dataStore.RemoveUnusedENIFromStore(warmIPTarget, minimumIPTarget, warmPrefixTarget)
 	if eni == "" {
 		return
 	}
@@ -733,6 +927,10 @@ func (c *IPAMContext) tryFreeENI() {
 // tryUnassignIPsorPrefixesFromAll determines if there are IPs to free when we have extra IPs beyond the target and warmIPTargetDefined
 // is enabled, deallocate extra IP addresses
 func (c *IPAMContext) tryUnassignCidrsFromAll() {
+	if c.enableHybridIPMode {
+		c.tryUnassignHybridCidrsFromAll()
+		return
+	}
 	_, over, warmTargetDefined := c.datastoreTargetState()
 	// If WARM IP targets are not defined, check if WARM_PREFIX_TARGET is defined.
 	if !warmTargetDefined {
@@ -741,7 +939,11 @@ func (c *IPAMContext) tryUnassignCidrsFromAll() {
 
 	if over > 0 {
 		eniInfos := c.dataStore.GetENIInfos()
-		for eniID := range eniInfos.ENIs {
+		for eniID, eni := range eniInfos.ENIs {
+			if eni.ENIConfig != "" || c.dataStore.IsPassthroughENI(eniID) {
+				// Not part of the node's pool
+				continue
+			}
 			// Either returns prefixes or IPs [Cidrs]
 			cidrs := c.dataStore.FindFreeableCidrs(eniID)
 			if cidrs == nil {
@@ -822,6 +1024,9 @@ func (c *IPAMContext) increaseDatastorePool(ctx context.Context) error {
 		if containsInsufficientCIDRsOrSubnetIPs(err) {
 			log.Errorf("Unable to attach IPs/Prefixes for the ENI, subnet doesn't seem to have enough IPs/Prefixes. Consider using new subnet or carve a reserved range using create-subnet-cidr-reservation")
 			c.lastInsufficientCidrError = time.Now()
+			if c.hasNoFreeAddresses() {
+				c.setIPPoolExhausted(ipPoolReasonInsufficientSubnet, insufficientSubnetIPsMessage)
+			}
 			return nil
 		}
 		log.Errorf(err.Error())
@@ -832,14 +1037,20 @@ func (c *IPAMContext) increaseDatastorePool(ctx context.Context) error {
 	} else {
 		// If we did not add any IPs, try to allocate an ENI.
 		if c.hasRoomForEni() {
-			if err = c.tryAllocateENI(ctx); err == nil {
+			if err = c.tryAllocateENI(ctx, ""); err == nil {
 				c.updateLastNodeIPPoolAction()
 			} else {
 				// Note that no error is returned if ENI allocation fails. This is because ENI allocation failure should not cause node to be "NotReady".
 				log.Debugf("Error trying to allocate ENI: %v", err)
+				if containsInsufficientCIDRsOrSubnetIPs(err) && c.hasNoFreeAddresses() {
+					c.setIPPoolExhausted(ipPoolReasonInsufficientSubnet, insufficientSubnetIPsMessage)
+				}
 			}
 		} else {
 			log.Debugf("Skipping ENI allocation as the max ENI limit is already reached")
+			if c.hasNoFreeAddresses() {
+				c.setIPPoolExhausted(ipPoolReasonMaxENILimitReached, maxENILimitReachedMessage)
+			}
 		}
 	}
 	return nil
@@ -856,12 +1067,19 @@ func (c *IPAMContext) updateLastNodeIPPoolAction() {
 	c.logPoolStats(stats)
 }
 
-func (c *IPAMContext) tryAllocateENI(ctx context.Context) error {
+// tryAllocateENI allocates an ENI for the node's pool, or for the pods of the given ENIConfig when the name is not empty
+func (c *IPAMContext) tryAllocateENI(ctx context.Context, eniConfigName string) error {
 	var securityGroups []*string
 	var subnet string
 
 	if c.useCustomNetworking {
-		eniCfg, err := eniconfig.MyENIConfig(ctx, c.k8sClient)
+		var eniCfg *v1alpha1.ENIConfigSpec
+		var err error
+		if eniConfigName != "" {
+			eniCfg, err = eniconfig.GetENIConfigSpec(ctx, c.k8sClient, eniConfigName)
+		} else {
+			eniCfg, err = eniconfig.MyENIConfig(ctx, c.k8sClient, c.awsClient, c.availabilityZone)
+		}
 		if err != nil {
 			log.Errorf("Failed to get pod ENI config")
 			return err
@@ -875,13 +1093,17 @@ func (c *IPAMContext) tryAllocateENI(ctx context.Context) error {
 		subnet = eniCfg.Subnet
 	}
 
-	eni, err := c.awsClient.AllocENI(c.useCustomNetworking, securityGroups, subnet)
+	eni, err := c.awsClient.AllocENI(c.useCustomNetworking, securityGroups, subnet, eniConfigName)
 	if err != nil {
 		log.Errorf("Failed to increase pool size due to not able to allocate ENI %v", err)
 		ipamdErrInc("increaseIPPoolAllocENI")
 		return err
 	}
 
+	if c.enableIPv6 && !c.enableIPv4 {
+		return c.setupIPv6ENI(eni)
+	}
+
 	resourcesToAllocate := c.GetENIResourcesToAllocate()
 	_, err = c.awsClient.AllocIPAddresses(eni, resourcesToAllocate)
 	if err != nil {
@@ -901,6 +1123,7 @@ func (c *IPAMContext) tryAllocateENI(ctx context.Context) error {
 		log.Errorf("Failed to increase pool size: Unable to discover attached ENI from metadata service %v", err)
 		return err
 	}
+	eniMetadata.ENIConfigName = eniConfigName
 
 	// The CNI does not create trunk or EFA ENIs, so they will always be false here
 	err = c.setupENI(eni, eniMetadata, false, false)
@@ -999,7 +1222,7 @@ func (c *IPAMContext) assignIPv6Prefix(eniID string) (err error) {
 	log.Debugf("Assigning an IPv6Prefix for ENI: %s", eniID)
 	//Let's make an EC2 API call to get a list of IPv6 prefixes (if any) that are already attached to the
 	//current ENI. We will make this call only once during boot up/init and doing so will shield us from any
-	//IMDS out of sync issues. We only need one v6 prefix per ENI/Node.
+	//IMDS out of sync issues.
 	ec2v6Prefixes, err := c.awsClient.GetIPv6PrefixesFromEC2(eniID)
 	if err != nil {
 		log.Errorf("assignIPv6Prefix; err: %s", err)
@@ -1007,28 +1230,17 @@ func (c *IPAMContext) assignIPv6Prefix(eniID string) (err error) {
 	}
 	log.Debugf("ENI %s has %v prefixe(s) attached", eniID, len(ec2v6Prefixes))
 
-	//Note: If we find more than one v6 prefix attached to the ENI, VPC CNI will not attempt to free it. VPC CNI
-	//will only attach a single v6 prefix and it will not attempt to free the additional Prefixes.
-	//We will add all the prefixes to our datastore. TODO - Should we instead pick one of them. If we do, how to track
-	//that across restarts?
-
-	//Check if we already have v6 Prefix(es) attached
+	//All the prefixes already attached are added to our datastore. The ones that are not used by pods are released
+	//once they exceed WARM_IPV6_PREFIX_TARGET.
 	if len(ec2v6Prefixes) == 0 {
-		//Allocate and attach a v6 Prefix to Primary ENI
+		//Allocate and attach a v6 Prefix to the ENI
 		log.Debugf("No IPv6 Prefix(es) found for ENI: %s", eniID)
 		strPrefixes, err := c.awsClient.AllocIPv6Prefixes(eniID)
 		if err != nil {
 			return err
 		}
-		for _, v6Prefix := range strPrefixes {
-			ec2v6Prefixes = append(ec2v6Prefixes, &ec2.Ipv6PrefixSpecification{Ipv6Prefix: v6Prefix})
-		}
+		ec2v6Prefixes = ipv6PrefixSpecifications(strPrefixes)
 		log.Debugf("Successfully allocated an IPv6Prefix for ENI: %s", eniID)
-	} else if len(ec2v6Prefixes) > 1 {
-		//Found more than one v6 prefix attached to the ENI. VPC CNI will only attach a single v6 prefix
-		//and it will not attempt to free any additional Prefixes that are already attached.
-		//Will use the first IPv6 Prefix attached for IP address allocation.
-		ec2v6Prefixes = []*ec2.Ipv6PrefixSpecification{ec2v6Prefixes[0]}
 	}
 	c.addENIv6prefixesToDataStore(ec2v6Prefixes, eniID)
 	return nil
@@ -1040,6 +1252,9 @@ func (c *IPAMContext) tryAssignPrefixes() (increasedPool bool, err error) {
 	// ENI might not suffice the WARM_IP_TARGET/WARM_PREFIX_TARGET
 	eni := c.dataStore.GetENINeedsIP(c.maxPrefixesPerENI, c.useCustomNetworking)
 	if eni != nil {
+		if inPrefixFallback(eni) {
+			return c.tryAssignFallbackIPs(eni, c.getFallbackIPsNeeded(toAllocate))
+		}
 		currentNumberOfAllocatedPrefixes := len(eni.AvailableIPv4Cidrs)
 		resourcesToAllocate := min((c.maxPrefixesPerENI - currentNumberOfAllocatedPrefixes), toAllocate)
 		output, err := c.awsClient.AllocIPAddresses(eni.ID, resourcesToAllocate)
@@ -1047,6 +1262,10 @@ func (c *IPAMContext) tryAssignPrefixes() (increasedPool bool, err error) {
 			log.Warnf("failed to allocate all available IPv4 Prefixes on ENI %s, err: %v", eni.ID, err)
 			// Try to just get one more prefix
 			output, err = c.awsClient.AllocIPAddresses(eni.ID, 1)
+			if containsInsufficientCidrBlocksError(err) {
+				// The subnet still has free IPs, but they are too fragmented for a /28 prefix
+				return c.fallBackToSecondaryIPs(eni, c.getFallbackIPsNeeded(toAllocate))
+			}
 			if err != nil && !containsPrivateIPAddressLimitExceededError(err) {
 				ipamdErrInc("increaseIPPoolAllocIPAddressesFailed")
 				return false, errors.Wrap(err, fmt.Sprintf("failed to allocate one IPv4 prefix on ENI %s, err: %v", eni.ID, err))
@@ -1070,6 +1289,9 @@ func (c *IPAMContext) tryAssignPrefixes() (increasedPool bool, err error) {
 			ec2Prefixes = output.AssignedIpv4Prefixes
 		}
 		c.addENIv4prefixesToDataStore(ec2Prefixes, eni.ID)
+		if eni.PrefixFallback {
+			c.revertPrefixFallback(eni.ID)
+		}
 		return true, nil
 	}
 	return false, nil
@@ -1086,20 +1308,53 @@ func (c *IPAMContext) setupENI(eni string, eniMetadata awsutils.ENIMetadata, isT
 	if err != nil && err.Error() != datastore.DuplicatedENIError {
 		return errors.Wrapf(err, "failed to add ENI %s to data store", eni)
 	}
+	// ENIs created for an ENIConfig of pods join the node's pool when the feature is turned off
+	if c.useENIConfigENIs() && eniMetadata.ENIConfigName != "" {
+		if err := c.dataStore.SetENIConfig(eni, eniMetadata.ENIConfigName); err != nil {
+			return errors.Wrapf(err, "failed to set ENIConfig of ENI %s", eni)
+		}
+	}
 	// Store the primary IP of the ENI
 	c.primaryIP[eni] = eniMetadata.PrimaryIPv4Address()
 
 	if c.enableIPv6 && eni == primaryENI {
-		// In v6 PD Mode, VPC CNI will only manage primary ENI. Once we start supporting secondary IP and custom
-		// networking modes for v6, we will relax this restriction. We filter out all the ENIs except Primary ENI
-		// in v6 mode (prior to landing here), but included the primary ENI check as a safety net.
-		err := c.assignIPv6Prefix(eni)
+		// With custom networking, pods only get addresses from the secondary ENIs
+		if !c.useCustomNetworking {
+			err := c.assignIPv6Prefix(eni)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to allocate IPv6 Prefixes to Primary ENI")
+			}
+		}
+	} else if c.enableIPv6 && !c.enableIPv4 {
+		err = c.networkClient.SetupIPv6ENINetwork(eniMetadata.MAC, eniMetadata.DeviceNumber)
 		if err != nil {
-			return errors.Wrapf(err, "Failed to allocate IPv6 Prefixes to Primary ENI")
+			errRemove := c.dataStore.RemoveENIFromDataStore(eni, true)
+			if errRemove != nil {
+				log.Warnf("failed to remove ENI %s: %v", eni, errRemove)
+			}
+			delete(c.primaryIP, eni)
+			return errors.Wrapf(err, "failed to set up ENI %s IPv6 network", eni)
 		}
-	} else {
+		log.Infof("Found ENI having %d IPv6 Prefixes", len(eniMetadata.IPv6Prefixes))
+		c.addENIv6prefixesToDataStore(eniMetadata.IPv6Prefixes, eni)
+	}
+
+	// In dual-stack mode, the IPv4 pool is set up next to the IPv6 prefixes of the primary ENI
+	if c.enableIPv4 || !c.enableIPv6 {
 		// For secondary ENIs, set up the network
 		if eni != primaryENI {
+			// The route table of an ENI is derived from its device number, so ENIs on different network cards must
+			// not share one.
+			if otherENI, inUse := c.dataStore.DeviceNumberInUse(eniMetadata.DeviceNumber, eni); inUse {
+				errRemove := c.dataStore.RemoveENIFromDataStore(eni, true)
+				if errRemove != nil {
+					log.Warnf("failed to remove ENI %s: %v", eni, errRemove)
+				}
+				delete(c.primaryIP, eni)
+				c.recordDeviceNumberConflict(eni, otherENI, eniMetadata)
+				return errors.Errorf("device number %d of ENI %s on network card %d is already used by ENI %s",
+					eniMetadata.DeviceNumber, eni, eniMetadata.NetworkCard, otherENI)
+			}
 			err = c.networkClient.SetupENINetwork(c.primaryIP[eni], eniMetadata.MAC, eniMetadata.DeviceNumber, eniMetadata.SubnetIPv4CIDR)
 			if err != nil {
 				// Failed to set up the ENI
@@ -1112,6 +1367,9 @@ func (c *IPAMContext) setupENI(eni string, eniMetadata awsutils.ENIMetadata, isT
 			}
 		}
 		log.Infof("Found ENIs having %d secondary IPs and %d Prefixes", len(eniMetadata.IPv4Addresses), len(eniMetadata.IPv4Prefixes))
+		if c.enableEgressIPPinning && eni == primaryENI {
+			c.setPrimaryENIIPs(eniMetadata.IPv4Addresses)
+		}
 		// Either case add the IPs and prefixes to datastore.
 		c.addENIsecondaryIPsToDataStore(eniMetadata.IPv4Addresses, eni)
 		c.addENIv4prefixesToDataStore(eniMetadata.IPv4Prefixes, eni)
@@ -1123,7 +1381,7 @@ func (c *IPAMContext) setupENI(eni string, eniMetadata awsutils.ENIMetadata, isT
 func (c *IPAMContext) addENIsecondaryIPsToDataStore(ec2PrivateIpAddrs []*ec2.NetworkInterfacePrivateIpAddress, eni string) {
 	// Add all the secondary IPs
 	for _, ec2PrivateIpAddr := range ec2PrivateIpAddrs {
-		if aws.BoolValue(ec2PrivateIpAddr.Primary) {
+		if aws.BoolValue(ec2PrivateIpAddr.Primary) || c.isReservedEgressIP(aws.StringValue(ec2PrivateIpAddr.PrivateIpAddress)) {
 			continue
 		}
 		cidr := net.IPNet{IP: net.ParseIP(aws.StringValue(ec2PrivateIpAddr.PrivateIpAddress)), Mask: net.IPv4Mask(255, 255, 255, 255)}
@@ -1183,8 +1441,17 @@ func (c *IPAMContext) addENIv6prefixesToDataStore(ec2PrefixAddrs []*ec2.Ipv6Pref
 // getMaxENI returns the maximum number of ENIs to attach to this instance. This is calculated as the lesser of
 // the limit for the instance type and the value configured via the MAX_ENI environment variable. If the value of
 // the environment variable is 0 or less, it will be ignored and the maximum for the instance is returned.
+// With ENABLE_MULTI_NETWORK_CARD, the limit for the instance type covers the ENIs of all network cards.
 func (c *IPAMContext) getMaxENI() (int, error) {
 	instanceMaxENI := c.awsClient.GetENILimit()
+	if c.enableMultiNetworkCard {
+		if networkCards := c.awsClient.GetNetworkCards(); len(networkCards) > 1 {
+			instanceMaxENI = 0
+			for _, card := range networkCards {
+				instanceMaxENI += int(card.MaximumNetworkInterfaces)
+			}
+		}
+	}
 
 	inputStr, found := os.LookupEnv(envMaxENI)
 	envMax := defaultMaxENI
@@ -1218,6 +1485,20 @@ func getWarmENITarget() int {
 	return defaultWarmENITarget
 }
 
+func getWarmIPv6PrefixTarget() int {
+	inputStr, found := os.LookupEnv(envWarmIPv6PrefixTarget)
+
+	if !found {
+		return defaultWarmIPv6PrefixTarget
+	}
+
+	if input, err := strconv.Atoi(inputStr); err == nil && input >= 0 {
+		log.Debugf("Using WARM_IPV6_PREFIX_TARGET %v", input)
+		return input
+	}
+	return defaultWarmIPv6PrefixTarget
+}
+
 func getWarmPrefixTarget() int {
 	inputStr, found := os.LookupEnv(envWarmPrefixTarget)
 
@@ -1354,7 +1635,7 @@ func (c *IPAMContext) nodeIPPoolReconcile(ctx context.Context, interval time.Dur
 	// Check if a new ENI was added, if so we need to update the tags.
 	needToUpdateTags := false
 	for _, attachedENI := range attachedENIs {
-		if _, ok := currentENIs[attachedENI.ENIID]; !ok {
+		if _, ok := currentENIs[attachedENI.ENIID]; !ok && !c.dataStore.IsPassthroughENI(attachedENI.ENIID) {
 			needToUpdateTags = true
 			break
 		}
@@ -1384,9 +1665,26 @@ func (c *IPAMContext) nodeIPPoolReconcile(ctx context.Context, interval time.Dur
 
 	// Mark phase
 	for _, attachedENI := range attachedENIs {
+		if c.enableIPv6 && !c.enableIPv4 {
+			if eniIPv6PrefixPool, err := c.dataStore.GetENIIPv6CIDRs(attachedENI.ENIID); err == nil {
+				log.Debugf("Reconcile existing ENI %s IPv6 prefixes", attachedENI.ENIID)
+				c.eniIPv6PrefixPoolReconcile(eniIPv6PrefixPool, attachedENI, attachedENI.ENIID)
+				delete(currentENIs, attachedENI.ENIID)
+				continue
+			}
+		}
+		// ENIs created for the pods of an ENIConfig keep serving them, also when they were attached by an earlier ipamd
+		if eniConfigName, ok := eniTagMap[attachedENI.ENIID][awsutils.ENIConfigTagKey]; ok {
+			attachedENI.ENIConfigName = eniConfigName
+		}
 		eniIPPool, eniPrefixPool, err := c.dataStore.GetENICIDRs(attachedENI.ENIID)
 		if err == nil {
 			// If the attached ENI is in the data store
+			if c.useENIConfigENIs() && attachedENI.ENIConfigName != "" && currentENIs[attachedENI.ENIID].ENIConfig != attachedENI.ENIConfigName {
+				if err := c.dataStore.SetENIConfig(attachedENI.ENIID, attachedENI.ENIConfigName); err != nil {
+					log.Errorf("IP pool reconcile: failed to set ENIConfig of ENI %s: %v", attachedENI.ENIID, err)
+				}
+			}
 			log.Debugf("Reconcile existing ENI %s IP pool", attachedENI.ENIID)
 			// Reconcile IP pool
 			c.eniIPPoolReconcile(eniIPPool, attachedENI, attachedENI.ENIID)
@@ -1399,6 +1697,12 @@ func (c *IPAMContext) nodeIPPoolReconcile(ctx context.Context, interval time.Dur
 			continue
 		}
 
+		if c.dataStore.IsPassthroughENI(attachedENI.ENIID) {
+			// The link of the ENI is in the network namespace of a pod, it is set up once it is back on the host
+			log.Debugf("Skip ENI %s, it is moved into a pod", attachedENI.ENIID)
+			continue
+		}
+
 		isTrunkENI := attachedENI.ENIID == trunkENI
 		isEFAENI := efaENIs[attachedENI.ENIID]
 		if !isTrunkENI && !c.disableENIProvisioning {
@@ -1438,7 +1742,11 @@ func (c *IPAMContext) nodeIPPoolReconcile(ctx context.Context, interval time.Dur
 	c.lastNodeIPPoolAction = time.Now()
 
 	log.Debug("Successfully Reconciled ENI/IP pool")
-	c.logPoolStats(c.dataStore.GetIPStats(ipV4AddrFamily))
+	if c.enableIPv6 && !c.enableIPv4 {
+		c.logPoolStats(c.dataStore.GetIPStats(ipV6AddrFamily))
+	} else {
+		c.logPoolStats(c.dataStore.GetIPStats(ipV4AddrFamily))
+	}
 }
 
 func (c *IPAMContext) eniIPPoolReconcile(ipPool []string, attachedENI awsutils.ENIMetadata, eni string) {
@@ -1446,7 +1754,8 @@ func (c *IPAMContext) eniIPPoolReconcile(ipPool []string, attachedENI awsutils.E
 	needEC2Reconcile := true
 	// Here we can't trust attachedENI since the IMDS metadata can be stale. We need to check with EC2 API.
 	// +1 is for the primary IP of the ENI that is not added to the ipPool and not available for pods to use.
-	if 1+len(ipPool) != len(attachedENIIPs) {
+	// Egress IPs are not added to the ipPool either.
+	if 1+len(ipPool)+c.countReservedEgressIPs(attachedENIIPs) != len(attachedENIIPs) {
 		log.Warnf("Instance metadata does not match data store! ipPool: %v, metadata: %v", ipPool, attachedENIIPs)
 		log.Debugf("We need to check the ENI status by calling the EC2 control plane.")
 		// Call EC2 to verify IPs on this ENI
@@ -1459,6 +1768,10 @@ func (c *IPAMContext) eniIPPoolReconcile(ipPool []string, attachedENI awsutils.E
 		needEC2Reconcile = false
 	}
 
+	if c.enableEgressIPPinning && eni == c.awsClient.GetPrimaryENI() {
+		c.setPrimaryENIIPs(attachedENIIPs)
+	}
+
 	// Add all known attached IPs to the datastore
 	seenIPs := c.verifyAndAddIPsToDatastore(eni, attachedENIIPs, needEC2Reconcile)
 
@@ -1539,6 +1852,12 @@ func (c *IPAMContext) verifyAndAddIPsToDatastore(eni string, attachedENIIPs []*e
 			log.Infof("Reconcile and skip primary IP %s on ENI %s", strPrivateIPv4, eni)
 			continue
 		}
+		if c.isReservedEgressIP(strPrivateIPv4) {
+			// Keep the IP in the datastore while a pod still uses it, it is removed once the pod is gone
+			log.Debugf("Reconcile and skip egress IP %s on ENI %s", strPrivateIPv4, eni)
+			seenIPs[strPrivateIPv4] = true
+			continue
+		}
 
 		// Check if this IP was recently freed
 		ipv4Addr := net.IPNet{IP: net.ParseIP(strPrivateIPv4), Mask: net.IPv4Mask(255, 255, 255, 255)}
@@ -1759,6 +2078,10 @@ func isIPv6Enabled() bool {
 	return getEnvBoolWithDefault(envEnableIPv6, false)
 }
 
+func enableMultiNetworkCard() bool {
+	return getEnvBoolWithDefault(envEnableMultiNetworkCard, false)
+}
+
 func enableManageUntaggedMode() bool {
 	return getEnvBoolWithDefault(envManageUntaggedENI, true)
 }
@@ -1767,20 +2090,79 @@ func enablePodIPAnnotation() bool {
 	return getEnvBoolWithDefault(envAnnotatePodIP, false)
 }
 
+func enablePodIPResource() bool {
+	return getEnvBoolWithDefault(envEnablePodIPResource, false)
+}
+
+func enableHybridIPMode() bool {
+	return getEnvBoolWithDefault(envEnableHybridIPMode, false)
+}
+
+func enablePodENIConfig() bool {
+	return getEnvBoolWithDefault(envEnablePodENIConfig, false)
+}
+
+func enableENIPassthrough() bool {
+	return getEnvBoolWithDefault(envEnableENIPassthrough, false)
+}
+
+func enableEBPFPodRouting() bool {
+	return getEnvBoolWithDefault(envEnableEBPFPodRouting, false)
+}
+
+func enableVPCCIDRWatch() bool {
+	return getEnvBoolWithDefault(envEnableVPCCIDRWatch, false)
+}
+
+func enableEgressIPPinning() bool {
+	return getEnvBoolWithDefault(envEnableEgressIPPinning, false)
+}
+
+// recordDeviceNumberConflict remembers that ENI eni was not set up because ENI otherENI uses its device number, so
+// that the reconcile doesn't retry it every time. The conflict is reported once.
+func (c *IPAMContext) recordDeviceNumberConflict(eni, otherENI string, eniMetadata awsutils.ENIMetadata) {
+	if c.deviceNumberConflicts == nil {
+		c.deviceNumberConflicts = make(map[string]string)
+	}
+	if _, ok := c.deviceNumberConflicts[eni]; ok {
+		return
+	}
+	c.deviceNumberConflicts[eni] = otherENI
+	message := fmt.Sprintf("ENI %s on network card %d is not used for pods, its device number %d is already used by ENI %s",
+		eni, eniMetadata.NetworkCard, eniMetadata.DeviceNumber, otherENI)
+	log.Errorf("%s", message)
+	ipamdErrInc("eniDeviceNumberConflict")
+	if eventRecorder := eventrecorder.Get(); eventRecorder != nil {
+		eventRecorder.SendNodeEvent(corev1.EventTypeWarning, eniDeviceNumberConflictReason, eniSetupAction, message)
+	}
+}
+
+// pruneDeviceNumberConflicts forgets the device number conflicts of ENIs that are no longer attached, or whose
+// conflicting ENI is no longer attached, so that they are set up again by the next reconcile
+func (c *IPAMContext) pruneDeviceNumberConflicts(enis []awsutils.ENIMetadata) {
+	if len(c.deviceNumberConflicts) == 0 {
+		return
+	}
+	attached := make(map[string]bool, len(enis))
+	for _, eni := range enis {
+		attached[eni.ENIID] = true
+	}
+	for eni, otherENI := range c.deviceNumberConflicts {
+		if !attached[eni] || !attached[otherENI] {
+			log.Infof("Forgetting the device number conflict of ENI %s with ENI %s", eni, otherENI)
+			delete(c.deviceNumberConflicts, eni)
+		}
+	}
+}
+
 // filterUnmanagedENIs filters out ENIs marked with the "node.k8s.amazonaws.com/no_manage" tag
 func (c *IPAMContext) filterUnmanagedENIs(enis []awsutils.ENIMetadata) []awsutils.ENIMetadata {
 	numFiltered := 0
+	c.pruneDeviceNumberConflicts(enis)
 	ret := make([]awsutils.ENIMetadata, 0, len(enis))
 	for _, eni := range enis {
-		//Filter out any Unmanaged ENIs. VPC CNI will only work with Primary ENI in IPv6 Prefix Delegation mode until
-		//we open up IPv6 support in Secondary IP and Custom networking modes. Filtering out the ENIs here will
-		//help us avoid myriad of if/else loops elsewhere in the code.
-		if c.enableIPv6 && !c.awsClient.IsPrimaryENI(eni.ENIID) {
-			log.Debugf("Skipping ENI %s: IPv6 Mode is enabled and VPC CNI will only manage Primary ENI in v6 PD mode",
-				eni.ENIID)
-			numFiltered++
-			continue
-		} else if c.awsClient.IsUnmanagedENI(eni.ENIID) {
+		//Filter out any Unmanaged ENIs
+		if c.awsClient.IsUnmanagedENI(eni.ENIID) {
 			log.Debugf("Skipping ENI %s: since it is unmanaged", eni.ENIID)
 			numFiltered++
 			continue
@@ -1788,6 +2170,10 @@ func (c *IPAMContext) filterUnmanagedENIs(enis []awsutils.ENIMetadata) []awsutil
 			log.Debugf("Skipping ENI %s: since on non-zero network card", eni.ENIID)
 			numFiltered++
 			continue
+		} else if otherENI, ok := c.deviceNumberConflicts[eni.ENIID]; ok {
+			log.Debugf("Skipping ENI %s: since its device number is used by ENI %s", eni.ENIID, otherENI)
+			numFiltered++
+			continue
 		}
 		ret = append(ret, eni)
 	}
@@ -1820,7 +2206,8 @@ func (c *IPAMContext) datastoreTargetState() (short int, over int, enabled bool)
 	// over is less than the warm IP target alone if it would imply reducing total IPs below the minimum target
 	over = max(min(over, stats.TotalIPs-c.minimumIPTarget), 0)
 
-	if c.enablePrefixDelegation {
+	// In hybrid mode, short and over stay in IPs since both prefixes and secondary IPs can be allocated and freed
+	if c.enablePrefixDelegation && !c.enableHybridIPMode {
 		// short : number of IPs short to reach warm targets
 		// over : number of IPs over the warm targets
 		_, numIPsPerPrefix, _ := datastore.GetPrefixDelegationDefaults()
@@ -1852,6 +2239,11 @@ func (c *IPAMContext) datastorePrefixTargetState() (short int, enabled bool) {
 	if !c.warmPrefixTargetDefined() {
 		return 0, false
 	}
+	if c.enableHybridIPMode {
+		shortIPs, _ := c.hybridTargetState()
+		_, numIPsPerPrefix, _ := datastore.GetPrefixDelegationDefaults()
+		return datastore.DivCeil(shortIPs, numIPsPerPrefix), true
+	}
 	// /28 will consume 16 IPs so let's not allocate if not needed.
 	freePrefixesInStore := c.dataStore.GetFreePrefixes()
 	toAllocate := max(c.warmPrefixTarget-freePrefixesInStore, 0)
@@ -2159,6 +2551,11 @@ func (c *IPAMContext) isDatastorePoolTooHigh() bool {
 		return over > 0
 	}
 
+	if c.enableHybridIPMode && c.warmPrefixTargetDefined() {
+		_, over := c.hybridTargetState()
+		return over > 0
+	}
+
 	//For the existing ENIs check if we can cleanup prefixes
 	if c.warmPrefixTargetDefined() {
 		freePrefixes := c.dataStore.GetFreePrefixes()
@@ -2219,7 +2616,11 @@ func (c *IPAMContext) getPrefixesNeeded() int {
 	shortPrefixes, warmPrefixTargetDefined := c.datastorePrefixTargetState()
 
 	//WARM_IP_TARGET takes precendence over WARM_PREFIX_TARGET
-	if warmIPTargetDefined {
+	if warmIPTargetDefined && c.enableHybridIPMode {
+		// short is in IPs in hybrid mode
+		_, numIPsPerPrefix, _ := datastore.GetPrefixDelegationDefaults()
+		toAllocate = max(toAllocate, datastore.DivCeil(short, numIPsPerPrefix))
+	} else if warmIPTargetDefined {
 		toAllocate = max(toAllocate, short)
 	} else if warmPrefixTargetDefined {
 		toAllocate = max(toAllocate, shortPrefixes)
@@ -2229,41 +2630,47 @@ func (c *IPAMContext) getPrefixesNeeded() int {
 }
 
 func (c *IPAMContext) initENIAndIPLimits() (err error) {
-	if c.enableIPv4 {
-		nodeMaxENI, err := c.getMaxENI()
-		if err != nil {
-			log.Error("Failed to get ENI limit")
-			return err
-		}
-		c.maxENI = nodeMaxENI
-
-		c.maxIPsPerENI, c.maxPrefixesPerENI, err = c.GetIPv4Limit()
-		if err != nil {
-			return err
-		}
-		log.Debugf("Max ip per ENI %d and max prefixes per ENI %d", c.maxIPsPerENI, c.maxPrefixesPerENI)
+	nodeMaxENI, err := c.getMaxENI()
+	if err != nil {
+		log.Error("Failed to get ENI limit")
+		return err
 	}
+	c.maxENI = nodeMaxENI
 
-	// WARM and MAX ENI & IP/Prefix counts are no-op in IPv6 Prefix delegation mode. Once we start supporting IPv6 in
-	// Secondary IP mode, these variables will play the same role as they currently do in IPv4 mode. So, for now we
-	// leave them at their default values.
+	// IPv6 prefixes use the same address slots of an ENI as IPv4 prefixes, so the limits are shared
+	c.maxIPsPerENI, c.maxPrefixesPerENI, err = c.GetIPv4Limit()
+	if err != nil {
+		return err
+	}
+	log.Debugf("Max ip per ENI %d and max prefixes per ENI %d", c.maxIPsPerENI, c.maxPrefixesPerENI)
 	return nil
 }
 
 func (c *IPAMContext) isConfigValid() bool {
-	//Validate that only one among v4 and v6 is enabled.
-	if c.enableIPv4 && c.enableIPv6 {
-		log.Errorf("IPv4 and IPv6 are both enabled. VPC CNI currently doesn't support dual stack mode")
-		return false
-	} else if !c.enableIPv4 && !c.enableIPv6 {
+	//Validate that at least one among v4 and v6 is enabled.
+	if !c.enableIPv4 && !c.enableIPv6 {
 		log.Errorf("IPv4 and IPv6 are both disabled. One of them have to be enabled")
 		return false
 	}
 
-	//Validate PD mode is enabled if VPC CNI is operating in IPv6 mode. SGPP and Custom networking are not supported in IPv6 mode.
-	if c.enableIPv6 && (c.enablePodENI || c.useCustomNetworking || !c.enablePrefixDelegation) {
-		log.Errorf("IPv6 is supported only in Prefix Delegation mode. Security Group Per Pod and " +
-			"Custom Networking are not supported in IPv6 mode. Please set the env variables accordingly.")
+	//Validate that custom networking is not used in dual-stack mode. IPv6 addresses of dual-stack pods come from the
+	//primary ENI, which is not used for pods with custom networking.
+	if c.enableIPv4 && c.enableIPv6 && c.useCustomNetworking {
+		log.Errorf("Custom networking is not supported in dual-stack mode. Please set the env variables accordingly.")
+		return false
+	}
+
+	//Validate PD mode is enabled if VPC CNI is operating in IPv6 mode. SGPP is not supported in IPv6 mode, since
+	//branch ENI pods only get an IPv4 address.
+	if c.enableIPv6 && (c.enablePodENI || !c.enablePrefixDelegation) {
+		log.Errorf("IPv6 is supported only in Prefix Delegation mode. Security Group Per Pod is " +
+			"not supported in IPv6 mode. Please set the env variables accordingly.")
+		return false
+	}
+
+	//Validate that ENIs on multiple network cards are only requested in IPv4 mode.
+	if c.enableIPv6 && c.enableMultiNetworkCard {
+		log.Errorf("Multiple network cards are not supported in IPv6 mode. Please set the env variables accordingly.")
 		return false
 	}
 
@@ -2280,6 +2687,59 @@ func (c *IPAMContext) isConfigValid() bool {
 	return true
 }
 
+// disableUnsupportedFeatures turns off the optional features that don't apply to the configured IP mode. It is called
+// once isConfigValid has settled whether prefix delegation is used.
+func (c *IPAMContext) disableUnsupportedFeatures() {
+	//The pod IP extended resource is only meaningful when the number of IPs is limited by the ENIs, so it is ignored in IPv6 mode.
+	if c.enableIPv6 && !c.enableIPv4 && c.enablePodIPResource {
+		log.Warnf("%s is not supported in IPv6 mode, the pod IP extended resource will not be advertised", envEnablePodIPResource)
+		c.enablePodIPResource = false
+	}
+
+	//Hybrid mode mixes prefixes and secondary IPs, so it needs Prefix Delegation and is not supported in IPv6 mode.
+	if c.enableHybridIPMode && (!c.enablePrefixDelegation || c.enableIPv6) {
+		log.Warnf("%s is only supported with IPv4 Prefix Delegation, falling back to a single IP allocation mode", envEnableHybridIPMode)
+		c.enableHybridIPMode = false
+	}
+
+	//An ENIConfig of pods picks the subnet and security groups of secondary ENIs, so it needs custom networking. It is IPv4 only.
+	if c.enablePodENIConfig && (!c.useCustomNetworking || c.enableIPv6) {
+		log.Warnf("%s is only supported with IPv4 custom networking, pods will use the ENIConfig of the node", envEnablePodENIConfig)
+		c.enablePodENIConfig = false
+	}
+
+	if c.enableEgressIPPinning && c.enableIPv6 {
+		log.Warnf("%s is only supported in IPv4 mode", envEnableEgressIPPinning)
+		c.enableEgressIPPinning = false
+	}
+
+	//Secondary networks get their IPs from the ENIs of an ENIConfig as well.
+	if len(c.secondaryNetworks) > 0 && (!c.useCustomNetworking || c.enableIPv6) {
+		log.Warnf("%s is only supported with IPv4 custom networking, secondary networks are ignored", envSecondaryNetworks)
+		c.secondaryNetworks = nil
+	}
+
+	//A passthrough pod gets the primary IPv4 address of the ENI.
+	if c.enableENIPassthrough && c.enableIPv6 {
+		log.Warnf("%s is only supported in IPv4 mode", envEnableENIPassthrough)
+		c.enableENIPassthrough = false
+	}
+
+	//The BPF map is keyed by IPv4 address.
+	if c.enableEBPFPodRouting && c.enableIPv6 {
+		log.Warnf("%s is only supported in IPv4 mode", envEnableEBPFPodRouting)
+		c.enableEBPFPodRouting = false
+	}
+
+	//The route table in the packet mark must not clobber the connmark.
+	if c.enableEBPFPodRouting {
+		if err := networkutils.ValidatePodRoutingMark(); err != nil {
+			log.Warnf("%s is disabled: %v", envEnableEBPFPodRouting, err)
+			c.enableEBPFPodRouting = false
+		}
+	}
+}
+
 func (c *IPAMContext) AddFeatureToCNINode(ctx context.Context, featureName rcv1alpha1.FeatureName, featureValue string) error {
 	cniNode := &rcv1alpha1.CNINode{}
 	if err := c.k8sClient.Get(ctx, types.NamespacedName{Name: c.myNodeName}, cniNode); err != nil {
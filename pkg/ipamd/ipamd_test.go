@@ -46,6 +46,8 @@ import (
 	mock_eniconfig "github.com/aws/amazon-vpc-cni-k8s/pkg/eniconfig/mocks"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
 	mock_networkutils "github.com/aws/amazon-vpc-cni-k8s/pkg/networkutils/mocks"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/utils/eventrecorder"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/vpc"
 	rcscheme "github.com/aws/amazon-vpc-resource-controller-k8s/apis/vpcresources/v1alpha1"
 )
 
@@ -302,6 +304,8 @@ func TestNodeInitwithPDenabledIPv6Mode(t *testing.T) {
 	eni1 := getDummyENIMetadataWithV6Prefix()
 
 	var cidrs []string
+	m.awsutils.EXPECT().GetENILimit().Return(1)
+	m.awsutils.EXPECT().GetENIIPv4Limit().Return(14)
 	m.awsutils.EXPECT().IsUnmanagedENI(eni1.ENIID).Return(false).AnyTimes()
 	m.awsutils.EXPECT().TagENI(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	m.awsutils.EXPECT().IsCNIUnmanagedENI(eni1.ENIID).Return(false).AnyTimes()
@@ -331,6 +335,7 @@ func TestNodeInitwithPDenabledIPv6Mode(t *testing.T) {
 		Status:     v1.NodeStatus{},
 	}
 	m.k8sClient.Create(ctx, &fakeNode)
+	os.Setenv("MY_NODE_NAME", myNodeName)
 
 	err := mockContext.nodeInit()
 	assert.NoError(t, err)
@@ -452,6 +457,48 @@ func getDummyENIMetadataWithV6Prefix() awsutils.ENIMetadata {
 	return eni1
 }
 
+func TestUpdateCIDRsRulesOnChangeWithVPCCIDRWatch(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	fakeRecorder := eventrecorder.InitMockEventRecorder()
+
+	mockContext := &IPAMContext{
+		awsClient:          m.awsutils,
+		networkClient:      m.network,
+		enableIPv4:         true,
+		enableVPCCIDRWatch: true,
+		dataStore:          datastore.NewDataStore(log, datastore.NullCheckpoint{}, false),
+	}
+	primaryIP := net.ParseIP(ipaddr01)
+	oldCIDRs := []string{"10.0.0.0/16", "100.64.0.0/16"}
+	m.awsutils.EXPECT().GetLocalIPv4().Return(primaryIP).AnyTimes()
+	m.awsutils.EXPECT().GetPrimaryENImac().Return(primaryMAC).AnyTimes()
+
+	// The first list from EC2 only updates the SNAT rules, it can differ from the one in instance metadata
+	imdsCIDRs := []string{"10.0.0.0/16", "100.64.0.0/16", "100.65.0.0/16"}
+	m.awsutils.EXPECT().DescribeVPCIPv4CIDRs().Return(oldCIDRs, nil)
+	m.network.EXPECT().UpdateHostIptablesRules(oldCIDRs, primaryMAC, &primaryIP, true, false).Return(nil)
+	assert.Equal(t, oldCIDRs, mockContext.updateCIDRsRulesOnChange(imdsCIDRs))
+	assert.Len(t, fakeRecorder.Events, 0)
+
+	// Nothing is touched while the CIDRs of the VPC stay the same
+	m.awsutils.EXPECT().DescribeVPCIPv4CIDRs().Return([]string{"100.64.0.0/16", "10.0.0.0/16"}, nil)
+	assert.ElementsMatch(t, oldCIDRs, mockContext.updateCIDRsRulesOnChange(oldCIDRs))
+
+	// The previous CIDRs are kept when EC2 can't be reached
+	m.awsutils.EXPECT().DescribeVPCIPv4CIDRs().Return(nil, errors.New("throttled"))
+	assert.Equal(t, oldCIDRs, mockContext.updateCIDRsRulesOnChange(oldCIDRs))
+
+	// A disassociated CIDR is removed from the SNAT exclusions and the IP rules of pods
+	newCIDRs := []string{"10.0.0.0/16"}
+	m.awsutils.EXPECT().DescribeVPCIPv4CIDRs().Return(newCIDRs, nil)
+	m.network.EXPECT().UpdateHostIptablesRules(newCIDRs, primaryMAC, &primaryIP, true, false).Return(nil)
+	m.network.EXPECT().RemoveVPCCIDRRules([]string{"100.64.0.0/16"}).Return(nil)
+	assert.Equal(t, newCIDRs, mockContext.updateCIDRsRulesOnChange(oldCIDRs))
+	assert.Len(t, fakeRecorder.Events, 1)
+	assert.Equal(t, "Normal "+vpcCIDRDisassociatedReason+" VPC CIDR 100.64.0.0/16 was disassociated", <-fakeRecorder.Events)
+}
+
 func TestIncreaseIPPoolDefault(t *testing.T) {
 	_ = os.Unsetenv(envCustomNetworkCfg)
 	testIncreaseIPPool(t, false, false)
@@ -603,9 +650,9 @@ func assertAllocationExternalCalls(shouldCall bool, useENIConfig bool, m *testMo
 	}
 
 	if useENIConfig {
-		m.awsutils.EXPECT().AllocENI(true, sg, podENIConfig.Subnet).Times(callCount).Return(eni2, nil)
+		m.awsutils.EXPECT().AllocENI(true, sg, podENIConfig.Subnet, "").Times(callCount).Return(eni2, nil)
 	} else {
-		m.awsutils.EXPECT().AllocENI(false, nil, "").Times(callCount).Return(eni2, nil)
+		m.awsutils.EXPECT().AllocENI(false, nil, "", "").Times(callCount).Return(eni2, nil)
 	}
 	m.awsutils.EXPECT().GetPrimaryENI().Times(callCount).Return(primaryENIid)
 	m.awsutils.EXPECT().WaitForENIAndIPsAttached(secENIid, 14).Times(callCount).Return(eniMetadata[1], nil)
@@ -664,9 +711,9 @@ func testIncreasePrefixPool(t *testing.T, useENIConfig bool) {
 	}
 
 	if useENIConfig {
-		m.awsutils.EXPECT().AllocENI(true, sg, podENIConfig.Subnet).Return(eni2, nil)
+		m.awsutils.EXPECT().AllocENI(true, sg, podENIConfig.Subnet, "").Return(eni2, nil)
 	} else {
-		m.awsutils.EXPECT().AllocENI(false, nil, "").Return(eni2, nil)
+		m.awsutils.EXPECT().AllocENI(false, nil, "", "").Return(eni2, nil)
 	}
 
 	eniMetadata := []awsutils.ENIMetadata{
@@ -823,7 +870,7 @@ func TestTryAddIPToENI(t *testing.T) {
 
 	mockContext.dataStore = testDatastore()
 
-	m.awsutils.EXPECT().AllocENI(false, nil, "").Return(secENIid, nil)
+	m.awsutils.EXPECT().AllocENI(false, nil, "", "").Return(secENIid, nil)
 	m.awsutils.EXPECT().AllocIPAddresses(secENIid, warmIPTarget)
 	eniMetadata := []awsutils.ENIMetadata{
 		{
@@ -2006,6 +2053,71 @@ func TestIPAMContext_askForTrunkENIIfNeeded(t *testing.T) {
 	assert.Equal(t, 1, len(cniNode.Spec.Features))
 }
 
+func TestGetMaxENIMultiNetworkCard(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+
+	m.awsutils.EXPECT().GetENILimit().Return(15).AnyTimes()
+	m.awsutils.EXPECT().GetNetworkCards().Return([]vpc.NetworkCard{
+		{MaximumNetworkInterfaces: 15, NetworkCardIndex: 0},
+		{MaximumNetworkInterfaces: 15, NetworkCardIndex: 1},
+	}).AnyTimes()
+
+	mockContext := &IPAMContext{awsClient: m.awsutils}
+	maxENI, err := mockContext.getMaxENI()
+	assert.NoError(t, err)
+	assert.Equal(t, 15, maxENI)
+
+	mockContext.enableMultiNetworkCard = true
+	maxENI, err = mockContext.getMaxENI()
+	assert.NoError(t, err)
+	assert.Equal(t, 30, maxENI)
+
+	t.Setenv(envMaxENI, "20")
+	maxENI, err = mockContext.getMaxENI()
+	assert.NoError(t, err)
+	assert.Equal(t, 20, maxENI)
+}
+
+func TestSetupENIDeviceNumberInUse(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+
+	mockContext := &IPAMContext{
+		awsClient:     m.awsutils,
+		networkClient: m.network,
+		primaryIP:     make(map[string]string),
+		dataStore:     datastore.NewDataStore(log, datastore.NullCheckpoint{}, false),
+	}
+	m.awsutils.EXPECT().GetPrimaryENI().Return(primaryENIid).AnyTimes()
+	_ = mockContext.dataStore.AddENI(secENIid, secDevice, false, false, false)
+
+	// An ENI on another network card reporting the same device number must not reuse the route table
+	eniMetadata := awsutils.ENIMetadata{
+		ENIID:          terENIid,
+		MAC:            terMAC,
+		DeviceNumber:   secDevice,
+		NetworkCard:    1,
+		SubnetIPv4CIDR: terSubnet,
+		IPv4Addresses:  []*ec2.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: aws.String(ipaddr21), Primary: aws.Bool(true)}},
+	}
+	err := mockContext.setupENI(terENIid, eniMetadata, false, false)
+	assert.Error(t, err)
+	assert.Equal(t, 1, mockContext.dataStore.GetENIs())
+	assert.Equal(t, map[string]string{terENIid: secENIid}, mockContext.deviceNumberConflicts)
+
+	// The reconcile skips the ENI while the conflicting ENI is attached, and sets it up again once it is detached
+	m.awsutils.EXPECT().IsUnmanagedENI(gomock.Any()).Return(false).AnyTimes()
+	m.awsutils.EXPECT().IsCNIUnmanagedENI(gomock.Any()).Return(false).AnyTimes()
+	enis := mockContext.filterUnmanagedENIs([]awsutils.ENIMetadata{{ENIID: secENIid}, eniMetadata})
+	assert.Equal(t, []awsutils.ENIMetadata{{ENIID: secENIid}}, enis)
+	assert.Equal(t, 1, mockContext.unmanagedENI)
+
+	enis = mockContext.filterUnmanagedENIs([]awsutils.ENIMetadata{eniMetadata})
+	assert.Equal(t, []awsutils.ENIMetadata{eniMetadata}, enis)
+	assert.Empty(t, mockContext.deviceNumberConflicts)
+}
+
 func TestIsConfigValid(t *testing.T) {
 	m := setup(t)
 	defer m.ctrl.Finish()
@@ -2065,7 +2177,7 @@ func TestIsConfigValid(t *testing.T) {
 			want: false,
 		},
 		{
-			name: "both v4 and v6 enabled",
+			name: "both v4 and v6 enabled in non-PD mode",
 			fields: fields{
 				ipV4Enabled:     true,
 				ipV6Enabled:     true,
@@ -2073,6 +2185,27 @@ func TestIsConfigValid(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "both v4 and v6 enabled in PD mode",
+			fields: fields{
+				ipV4Enabled:             true,
+				ipV6Enabled:             true,
+				prefixDelegationEnabled: true,
+				isNitroInstance:         true,
+			},
+			want: true,
+		},
+		{
+			name: "both v4 and v6 enabled with custom networking",
+			fields: fields{
+				ipV4Enabled:             true,
+				ipV6Enabled:             true,
+				prefixDelegationEnabled: true,
+				customNetworkingEnabled: true,
+				isNitroInstance:         true,
+			},
+			want: false,
+		},
 		{
 			name: "v4 disabled and v6 enabled in PD mode on Non-Nitro instance",
 			fields: fields{
@@ -2112,7 +2245,8 @@ func TestIsConfigValid(t *testing.T) {
 			m := setup(t)
 			defer m.ctrl.Finish()
 
-			if tt.fields.prefixDelegationEnabled && !(tt.fields.podENIEnabled && tt.fields.ipV6Enabled) {
+			dualStackCustomNetworking := tt.fields.ipV4Enabled && tt.fields.ipV6Enabled && tt.fields.customNetworkingEnabled
+			if tt.fields.prefixDelegationEnabled && !(tt.fields.podENIEnabled && tt.fields.ipV6Enabled) && !dualStackCustomNetworking {
 				if tt.fields.isNitroInstance {
 					m.awsutils.EXPECT().IsPrefixDelegationSupported().Return(true)
 				} else {
@@ -2140,6 +2274,37 @@ func TestIsConfigValid(t *testing.T) {
 
 }
 
+func TestDisableUnsupportedFeatures(t *testing.T) {
+	mockContext := &IPAMContext{
+		enableIPv6:             true,
+		enablePrefixDelegation: true,
+		enablePodIPResource:    true,
+		enableHybridIPMode:     true,
+		enableEgressIPPinning:  true,
+		enableENIPassthrough:   true,
+		enableEBPFPodRouting:   true,
+	}
+	mockContext.disableUnsupportedFeatures()
+	assert.False(t, mockContext.enablePodIPResource)
+	assert.False(t, mockContext.enableHybridIPMode)
+	assert.False(t, mockContext.enableEgressIPPinning)
+	assert.False(t, mockContext.enableENIPassthrough)
+	assert.False(t, mockContext.enableEBPFPodRouting)
+
+	// Hybrid mode is kept with IPv4 prefix delegation, the pod ENIConfig and secondary networks need custom networking
+	mockContext = &IPAMContext{
+		enableIPv4:             true,
+		enablePrefixDelegation: true,
+		enableHybridIPMode:     true,
+		enablePodENIConfig:     true,
+		secondaryNetworks:      map[string]string{"net1": "eniconfig-net1"},
+	}
+	mockContext.disableUnsupportedFeatures()
+	assert.True(t, mockContext.enableHybridIPMode)
+	assert.False(t, mockContext.enablePodENIConfig)
+	assert.Nil(t, mockContext.secondaryNetworks)
+}
+
 func TestAnnotatePod(t *testing.T) {
 	m := setup(t)
 	defer m.ctrl.Finish()
@@ -0,0 +1,262 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/awsutils"
+)
+
+// ipv6PrefixTargetState returns the number of IPv6 prefixes the node is short of and over WARM_IPV6_PREFIX_TARGET.
+// A prefix is free when no pod uses it. The node always keeps at least one prefix.
+func (c *IPAMContext) ipv6PrefixTargetState() (short int, over int) {
+	total, free := c.dataStore.GetIPv6PrefixCounts()
+	short = max(c.warmIPv6PrefixTarget-free, 0)
+	if total == 0 {
+		short = max(short, 1)
+	}
+	over = max(min(free-c.warmIPv6PrefixTarget, total-1), 0)
+	log.Debugf("Current IPv6 prefix stats: warm target: %d, total: %d, free: %d, short: %d, over: %d",
+		c.warmIPv6PrefixTarget, total, free, short, over)
+	return short, over
+}
+
+// nodeInitIPv6Pool attaches the IPv6 prefixes and ENIs the node needs at startup
+func (c *IPAMContext) nodeInitIPv6Pool(ctx context.Context) error {
+	if !c.disableENIProvisioning {
+		if err := c.increaseIPv6Pool(ctx); err != nil {
+			podENIErrInc("nodeInit")
+			return errors.Wrap(err, "error while trying to increase IPv6 prefix pool")
+		}
+	}
+	// If custom networking is enabled and no secondary ENI got a prefix, there is a misconfiguration and the node
+	// should not become ready.
+	if total, _ := c.dataStore.GetIPv6PrefixCounts(); c.useCustomNetworking && total == 0 {
+		podENIErrInc("nodeInit")
+		return errors.New("Failed to attach any ENIs for custom networking")
+	}
+	log.Debug("node init completed successfully")
+	return nil
+}
+
+// updateIPv6PoolIfRequired attaches or releases IPv6 prefixes to keep WARM_IPV6_PREFIX_TARGET prefixes free
+func (c *IPAMContext) updateIPv6PoolIfRequired(ctx context.Context) {
+	if short, over := c.ipv6PrefixTargetState(); short > 0 {
+		if err := c.increaseIPv6Pool(ctx); err != nil {
+			log.Errorf("Failed to increase IPv6 prefix pool: %v", err)
+		}
+	} else if over > 0 {
+		c.decreaseIPv6Pool(decreaseIPPoolInterval)
+	}
+	c.tryFreeENI()
+}
+
+// increaseIPv6Pool adds IPv6 prefixes until the node is no longer short of WARM_IPV6_PREFIX_TARGET. Prefixes are
+// added to ENIs with free address slots first, then on new ENIs.
+func (c *IPAMContext) increaseIPv6Pool(ctx context.Context) error {
+	ipamdActionsInprogress.WithLabelValues("increaseIPv6Pool").Add(float64(1))
+	defer ipamdActionsInprogress.WithLabelValues("increaseIPv6Pool").Sub(float64(1))
+
+	if c.isTerminating() {
+		log.Debug("AWS CNI is terminating, will not try to attach any new IPv6 prefixes or ENIs right now")
+		return nil
+	}
+	if !c.manageENIsNonScheduleable && c.isNodeNonSchedulable() {
+		log.Debug("AWS CNI is on a non schedulable node, will not try to attach any new IPv6 prefixes or ENIs right now")
+		return nil
+	}
+
+	short, _ := c.ipv6PrefixTargetState()
+	for ; short > 0; short-- {
+		// With custom networking, the primary ENI is not used for pods
+		eni := c.dataStore.GetENINeedsIPv6Prefix(c.maxPrefixesPerENI, c.useCustomNetworking)
+		if eni != nil {
+			if err := c.tryAssignIPv6Prefix(eni.ID); err != nil {
+				return err
+			}
+		} else if c.hasRoomForEni() {
+			if err := c.tryAllocateENI(ctx, ""); err != nil {
+				// Failing to allocate an ENI should not cause the node to be "NotReady"
+				log.Debugf("Error trying to allocate ENI: %v", err)
+				return nil
+			}
+		} else {
+			log.Debugf("Skipping ENI allocation as the max ENI limit is already reached")
+			return nil
+		}
+		c.lastNodeIPPoolAction = time.Now()
+	}
+	return nil
+}
+
+// tryAssignIPv6Prefix allocates one more IPv6 prefix on an ENI that is already in the datastore
+func (c *IPAMContext) tryAssignIPv6Prefix(eniID string) error {
+	strPrefixes, err := c.awsClient.AllocIPv6Prefixes(eniID)
+	if err != nil {
+		ipamdErrInc("increaseIPv6PoolAllocIPv6PrefixFailed")
+		return errors.Wrapf(err, "failed to allocate an IPv6 prefix on ENI %s", eniID)
+	}
+	c.addENIv6prefixesToDataStore(ipv6PrefixSpecifications(strPrefixes), eniID)
+	return nil
+}
+
+// setupIPv6ENI attaches an IPv6 prefix to a newly allocated ENI and adds the ENI to the datastore
+func (c *IPAMContext) setupIPv6ENI(eni string) error {
+	if _, err := c.awsClient.AllocIPv6Prefixes(eni); err != nil {
+		log.Errorf("Failed to allocate an IPv6 prefix on ENI %s: %v", eni, err)
+		ipamdErrInc("increaseIPv6PoolAllocIPv6PrefixFailed")
+		return err
+	}
+
+	eniMetadata, err := c.awsClient.WaitForENIAndIPsAttached(eni, 1)
+	if err != nil {
+		ipamdErrInc("increaseIPPoolwaitENIAttachedFailed")
+		log.Errorf("Failed to increase IPv6 prefix pool: Unable to discover attached ENI from metadata service %v", err)
+		return err
+	}
+
+	// The CNI does not create trunk or EFA ENIs, so they will always be false here
+	if err = c.setupENI(eni, eniMetadata, false, false); err != nil {
+		ipamdErrInc("increaseIPPoolsetupENIFailed")
+		log.Errorf("Failed to increase IPv6 prefix pool: %v", err)
+		return err
+	}
+	return nil
+}
+
+// decreaseIPv6Pool runs every `interval` and releases the IPv6 prefixes over WARM_IPV6_PREFIX_TARGET. ENIs that are
+// left without prefixes are freed by tryFreeENI.
+func (c *IPAMContext) decreaseIPv6Pool(interval time.Duration) {
+	ipamdActionsInprogress.WithLabelValues("decreaseIPv6Pool").Add(float64(1))
+	defer ipamdActionsInprogress.WithLabelValues("decreaseIPv6Pool").Sub(float64(1))
+
+	now := time.Now()
+	if timeSinceLast := now.Sub(c.lastDecreaseIPPool); timeSinceLast <= interval {
+		log.Debugf("Skipping decrease IPv6 prefix pool because time since last %v <= %v", timeSinceLast, interval)
+		return
+	}
+
+	c.tryUnassignIPv6PrefixesFromAll()
+	c.lastDecreaseIPPool = now
+	c.lastNodeIPPoolAction = now
+}
+
+// tryUnassignIPv6PrefixesFromAll releases free IPv6 prefixes as long as the node is over WARM_IPV6_PREFIX_TARGET.
+// Prefixes on secondary ENIs are released first, so that their ENIs can be freed.
+func (c *IPAMContext) tryUnassignIPv6PrefixesFromAll() {
+	_, over := c.ipv6PrefixTargetState()
+	if over <= 0 {
+		return
+	}
+
+	eniInfos := c.dataStore.GetENIInfos()
+	for _, primaryPass := range []bool{false, true} {
+		for eniID, eni := range eniInfos.ENIs {
+			if eni.IsPrimary != primaryPass {
+				continue
+			}
+			var deletedPrefixes []string
+			for _, toDelete := range c.dataStore.FindFreeableIPv6Cidrs(eniID) {
+				if over <= 0 {
+					break
+				}
+				// Do not force the delete, since a freeable prefix might have been assigned to a pod
+				// before we get around to deleting it.
+				if err := c.dataStore.DelIPv6CidrFromStore(eniID, toDelete.Cidr, false /* force */); err != nil {
+					log.Warnf("Failed to delete IPv6 prefix %s on ENI %s from datastore: %s", toDelete.Cidr.String(), eniID, err)
+					ipamdErrInc("decreaseIPv6Pool")
+					continue
+				}
+				deletedPrefixes = append(deletedPrefixes, toDelete.Cidr.String())
+				c.reconcileCooldownCache.Add(toDelete.Cidr.String())
+				over--
+			}
+			if len(deletedPrefixes) > 0 {
+				if err := c.awsClient.DeallocIPv6Prefixes(eniID, deletedPrefixes); err != nil {
+					log.Warnf("Failed to free IPv6 Prefixes %v from ENI %s: %s", deletedPrefixes, eniID, err)
+				}
+			}
+			if over <= 0 {
+				return
+			}
+		}
+	}
+}
+
+// eniIPv6PrefixPoolReconcile adds the IPv6 prefixes attached to an ENI to the datastore, and removes the ones that are
+// no longer attached. The instance metadata can be stale, so EC2 is asked when it does not match the datastore.
+func (c *IPAMContext) eniIPv6PrefixPoolReconcile(prefixPool []string, attachedENI awsutils.ENIMetadata, eni string) {
+	attachedPrefixes := attachedENI.IPv6Prefixes
+	if len(prefixPool) != len(attachedPrefixes) {
+		log.Warnf("Instance metadata does not match data store! IPv6 prefix pool: %v, metadata: %v", prefixPool, attachedPrefixes)
+		ec2Prefixes, err := c.awsClient.GetIPv6PrefixesFromEC2(eni)
+		if err != nil {
+			log.Errorf("Failed to fetch ENI IPv6 prefixes! Aborting reconcile of ENI %s", eni)
+			return
+		}
+		attachedPrefixes = ec2Prefixes
+	}
+
+	seenPrefixes := make(map[string]bool)
+	var addedPrefixes []*ec2.Ipv6PrefixSpecification
+	for _, attachedPrefix := range attachedPrefixes {
+		_, ipv6Cidr, err := net.ParseCIDR(aws.StringValue(attachedPrefix.Ipv6Prefix))
+		if err != nil {
+			continue
+		}
+		if found, recentlyFreed := c.reconcileCooldownCache.RecentlyFreed(ipv6Cidr.String()); found && recentlyFreed {
+			log.Debugf("Reconcile skipping IPv6 prefix %s on ENI %s because it was recently unassigned from the ENI.", ipv6Cidr, eni)
+			continue
+		}
+		seenPrefixes[ipv6Cidr.String()] = true
+		addedPrefixes = append(addedPrefixes, attachedPrefix)
+	}
+	c.addENIv6prefixesToDataStore(addedPrefixes, eni)
+
+	// Sweep phase, delete the prefixes that are no longer attached to the ENI
+	for _, existingPrefix := range prefixPool {
+		if seenPrefixes[existingPrefix] {
+			continue
+		}
+		log.Debugf("Reconcile and delete IPv6 prefix %s on ENI %s", existingPrefix, eni)
+		_, ipv6Cidr, err := net.ParseCIDR(existingPrefix)
+		if err != nil {
+			continue
+		}
+		// Force the delete, the prefix is gone from the ENI
+		if err := c.dataStore.DelIPv6CidrFromStore(eni, *ipv6Cidr, true /* force */); err != nil {
+			log.Errorf("Failed to reconcile and delete IPv6 prefix %s on ENI %s, %v", existingPrefix, eni, err)
+			ipamdErrInc("ipReconcileDel")
+			continue
+		}
+		reconcileCnt.With(prometheus.Labels{"fn": "eniIPv6PrefixPoolReconcileDel"}).Inc()
+	}
+}
+
+// ipv6PrefixSpecifications converts the IPv6 prefixes returned by EC2 for adding them to the datastore
+func ipv6PrefixSpecifications(prefixes []*string) []*ec2.Ipv6PrefixSpecification {
+	var specs []*ec2.Ipv6PrefixSpecification
+	for _, prefix := range prefixes {
+		specs = append(specs, &ec2.Ipv6PrefixSpecification{Ipv6Prefix: aws.String(aws.StringValue(prefix))})
+	}
+	return specs
+}
@@ -0,0 +1,174 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/awsutils"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+)
+
+const v6prefix02 = "2001:db8:0:1::/80"
+
+func testIPv6Context(m *testMocks) *IPAMContext {
+	c := &IPAMContext{
+		awsClient:              m.awsutils,
+		k8sClient:              m.k8sClient,
+		networkClient:          m.network,
+		maxPrefixesPerENI:      1,
+		maxENI:                 2,
+		warmIPv6PrefixTarget:   1,
+		primaryIP:              make(map[string]string),
+		myNodeName:             myNodeName,
+		enablePrefixDelegation: true,
+		enableIPv6:             true,
+		dataStore:              datastore.NewDataStore(log, datastore.NullCheckpoint{}, true),
+	}
+	c.reconcileCooldownCache.cache = make(map[string]time.Time)
+	_ = c.dataStore.AddENI(primaryENIid, primaryDevice, true, false, false)
+	_, prefix, _ := net.ParseCIDR(v6prefix01)
+	_ = c.dataStore.AddIPv6CidrToStore(primaryENIid, *prefix, true)
+	return c
+}
+
+func TestIPv6PrefixTargetState(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	c := testIPv6Context(m)
+
+	short, over := c.ipv6PrefixTargetState()
+	assert.Equal(t, 0, short)
+	assert.Equal(t, 0, over)
+
+	c.warmIPv6PrefixTarget = 2
+	short, over = c.ipv6PrefixTargetState()
+	assert.Equal(t, 1, short)
+	assert.Equal(t, 0, over)
+
+	// The last prefix is kept even without a warm target
+	c.warmIPv6PrefixTarget = 0
+	short, over = c.ipv6PrefixTargetState()
+	assert.Equal(t, 0, short)
+	assert.Equal(t, 0, over)
+
+	// A pod makes the prefix used
+	_, _, err := c.dataStore.AssignPodIPv6Address(datastore.IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
+		datastore.IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"})
+	assert.NoError(t, err)
+	c.warmIPv6PrefixTarget = 1
+	short, over = c.ipv6PrefixTargetState()
+	assert.Equal(t, 1, short)
+	assert.Equal(t, 0, over)
+}
+
+func TestIncreaseIPv6Pool(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	c := testIPv6Context(m)
+	c.warmIPv6PrefixTarget = 2
+
+	// The primary ENI has no free slot left, so the second prefix comes with a new ENI
+	eni2 := awsutils.ENIMetadata{
+		ENIID:        secENIid,
+		MAC:          secMAC,
+		DeviceNumber: secDevice,
+		IPv6Prefixes: []*ec2.Ipv6PrefixSpecification{{Ipv6Prefix: aws.String(v6prefix02)}},
+	}
+	m.awsutils.EXPECT().AllocENI(false, nil, "", "").Return(secENIid, nil)
+	m.awsutils.EXPECT().AllocIPv6Prefixes(secENIid).Return([]*string{aws.String(v6prefix02)}, nil)
+	m.awsutils.EXPECT().WaitForENIAndIPsAttached(secENIid, 1).Return(eni2, nil)
+	m.awsutils.EXPECT().GetPrimaryENI().Return(primaryENIid)
+	m.network.EXPECT().SetupIPv6ENINetwork(secMAC, secDevice).Return(nil)
+
+	err := c.increaseIPv6Pool(context.Background())
+	assert.NoError(t, err)
+	total, free := c.dataStore.GetIPv6PrefixCounts()
+	assert.Equal(t, 2, total)
+	assert.Equal(t, 2, free)
+
+	// The node is at its ENI limit, so nothing else is allocated
+	c.warmIPv6PrefixTarget = 3
+	err = c.increaseIPv6Pool(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestTryUnassignIPv6PrefixesFromAll(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	c := testIPv6Context(m)
+	c.warmIPv6PrefixTarget = 0
+	_ = c.dataStore.AddENI(secENIid, secDevice, false, false, false)
+	_, prefix, _ := net.ParseCIDR(v6prefix02)
+	_ = c.dataStore.AddIPv6CidrToStore(secENIid, *prefix, true)
+
+	// Only one of the free prefixes is released, starting with the secondary ENI
+	m.awsutils.EXPECT().DeallocIPv6Prefixes(secENIid, []string{v6prefix02}).Return(nil)
+	c.tryUnassignIPv6PrefixesFromAll()
+
+	eniInfos := c.dataStore.GetENIInfos()
+	assert.Len(t, eniInfos.ENIs[primaryENIid].IPv6Cidrs, 1)
+	assert.Len(t, eniInfos.ENIs[secENIid].IPv6Cidrs, 0)
+	_, over := c.ipv6PrefixTargetState()
+	assert.Equal(t, 0, over)
+}
+
+func TestNodeIPPoolReconcileIPv6(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+	c := testIPv6Context(m)
+
+	m.awsutils.EXPECT().GetPrimaryENI().AnyTimes().Return(primaryENIid)
+	m.awsutils.EXPECT().IsUnmanagedENI(primaryENIid).AnyTimes().Return(false)
+	m.awsutils.EXPECT().IsCNIUnmanagedENI(primaryENIid).AnyTimes().Return(false)
+	m.awsutils.EXPECT().TagENI(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	// A second prefix was attached to the primary ENI outside of ipamd
+	twoPrefixes := awsutils.ENIMetadata{
+		ENIID:        primaryENIid,
+		MAC:          primaryMAC,
+		DeviceNumber: primaryDevice,
+		IPv6Prefixes: []*ec2.Ipv6PrefixSpecification{
+			{Ipv6Prefix: aws.String(v6prefix01)},
+			{Ipv6Prefix: aws.String(v6prefix02)},
+		},
+	}
+	m.awsutils.EXPECT().GetAttachedENIs().Return([]awsutils.ENIMetadata{twoPrefixes}, nil)
+	m.awsutils.EXPECT().GetIPv6PrefixesFromEC2(primaryENIid).Return(twoPrefixes.IPv6Prefixes, nil)
+	c.nodeIPPoolReconcile(ctx, 0)
+
+	eniInfos := c.dataStore.GetENIInfos()
+	assert.Len(t, eniInfos.ENIs[primaryENIid].IPv6Cidrs, 2)
+
+	// The first prefix is gone from the ENI
+	onePrefix := twoPrefixes
+	onePrefix.IPv6Prefixes = twoPrefixes.IPv6Prefixes[1:]
+	m.awsutils.EXPECT().GetAttachedENIs().Return([]awsutils.ENIMetadata{onePrefix}, nil)
+	m.awsutils.EXPECT().GetIPv6PrefixesFromEC2(primaryENIid).Return(onePrefix.IPv6Prefixes, nil)
+	c.nodeIPPoolReconcile(ctx, 0)
+
+	eniInfos = c.dataStore.GetENIInfos()
+	assert.Len(t, eniInfos.ENIs[primaryENIid].IPv6Cidrs, 1)
+	_, ok := eniInfos.ENIs[primaryENIid].IPv6Cidrs[v6prefix02]
+	assert.True(t, ok)
+}
@@ -0,0 +1,230 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/eniconfig"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/k8sapi"
+)
+
+// podENIConfigNameCacheTTL is how long the ENIConfig names of namespaces and of the node are reused for pods
+const podENIConfigNameCacheTTL = 30 * time.Second
+
+// nodeENIConfigNameKey is the key of the node's ENIConfig name in the podENIConfigNameCache
+const nodeENIConfigNameKey = "/node"
+
+// podENIConfigNameCache keeps the ENIConfig names of namespaces and of the node for a short time, so that a pod ADD
+// does not read the namespace, the node and the ENIConfigs again
+type podENIConfigNameCache struct {
+	sync.Mutex
+	names   map[string]string
+	expires map[string]time.Time
+}
+
+// get returns the cached ENIConfig name of the key, if it did not expire
+func (n *podENIConfigNameCache) get(key string) (string, bool) {
+	n.Lock()
+	defer n.Unlock()
+	if expiry, ok := n.expires[key]; !ok || time.Now().After(expiry) {
+		return "", false
+	}
+	return n.names[key], true
+}
+
+// set caches the ENIConfig name of the key for podENIConfigNameCacheTTL
+func (n *podENIConfigNameCache) set(key, eniConfigName string) {
+	n.Lock()
+	defer n.Unlock()
+	if n.names == nil {
+		n.names = make(map[string]string)
+		n.expires = make(map[string]time.Time)
+	}
+	n.names[key] = eniConfigName
+	n.expires[key] = time.Now().Add(podENIConfigNameCacheTTL)
+}
+
+// getPodENIConfigName returns the name of the ENIConfig a pod asks for with an annotation or the label of its
+// namespace. It is empty when the pod uses the node's ENIs, which is also the case when it asks for the ENIConfig of
+// the node. The pod is read from the informer cache of the pods on this node, the namespace label and the node's
+// ENIConfig come from a short-lived cache.
+func (c *IPAMContext) getPodENIConfigName(ctx context.Context, podName, podNamespace string) (string, error) {
+	pod, err := c.GetPod(podName, podNamespace)
+	if err != nil {
+		return "", err
+	}
+	eniConfigName, ok := eniconfig.GetPodAnnotatedENIConfigName(*pod)
+	if !ok {
+		if eniConfigName, err = c.getNamespaceENIConfigName(ctx, podNamespace); err != nil {
+			return "", err
+		}
+	}
+	if eniConfigName == "" {
+		return "", nil
+	}
+	nodeENIConfigName, err := c.getNodeENIConfigNameCached(ctx)
+	if err != nil {
+		return "", err
+	}
+	if nodeENIConfigName == eniConfigName {
+		return "", nil
+	}
+	return eniConfigName, nil
+}
+
+// getNamespaceENIConfigName returns the ENIConfig named by the label of the namespace
+func (c *IPAMContext) getNamespaceENIConfigName(ctx context.Context, podNamespace string) (string, error) {
+	if eniConfigName, ok := c.podENIConfigNames.get(podNamespace); ok {
+		return eniConfigName, nil
+	}
+	var namespace corev1.Namespace
+	if err := c.k8sClient.Get(ctx, types.NamespacedName{Name: podNamespace}, &namespace); err != nil {
+		return "", fmt.Errorf("error while trying to retrieve namespace info: %s", err.Error())
+	}
+	eniConfigName := eniconfig.GetNamespaceENIConfigName(namespace)
+	c.podENIConfigNames.set(podNamespace, eniConfigName)
+	return eniConfigName, nil
+}
+
+// getNodeENIConfigNameCached returns the ENIConfig of the node, or an empty string when the node has none
+func (c *IPAMContext) getNodeENIConfigNameCached(ctx context.Context) (string, error) {
+	if eniConfigName, ok := c.podENIConfigNames.get(nodeENIConfigNameKey); ok {
+		return eniConfigName, nil
+	}
+	node, err := k8sapi.GetNode(ctx, c.k8sClient)
+	if err != nil {
+		return "", err
+	}
+	eniConfigName, err := eniconfig.GetNodeENIConfigName(ctx, c.k8sClient, c.awsClient, node, c.availabilityZone)
+	if err != nil {
+		eniConfigName = ""
+	}
+	c.podENIConfigNames.set(nodeENIConfigNameKey, eniConfigName)
+	return eniConfigName, nil
+}
+
+// setPodENIConfigShort records that a pod of the ENIConfig could not get an IP, so that the next pool update allocates
+// more IPs or an ENI for it
+func (c *IPAMContext) setPodENIConfigShort(eniConfigName string) {
+	c.podENIConfigsShortLock.Lock()
+	defer c.podENIConfigsShortLock.Unlock()
+	if c.podENIConfigsShort == nil {
+		c.podENIConfigsShort = make(map[string]bool)
+	}
+	c.podENIConfigsShort[eniConfigName] = true
+	c.requestPoolUpdate()
+}
+
+// requestPoolUpdate wakes up the pool manager, so that it does not wait for its next tick
+func (c *IPAMContext) requestPoolUpdate() {
+	select {
+	case c.poolUpdateRequested <- struct{}{}:
+	default:
+	}
+}
+
+// waitForPoolUpdate sleeps for the duration, or until a pool update is requested
+func (c *IPAMContext) waitForPoolUpdate(duration time.Duration) {
+	select {
+	case <-time.After(duration):
+	case <-c.poolUpdateRequested:
+	}
+}
+
+// takePodENIConfigsShort returns the ENIConfigs that ran out of IPs and resets the set
+func (c *IPAMContext) takePodENIConfigsShort() map[string]bool {
+	c.podENIConfigsShortLock.Lock()
+	defer c.podENIConfigsShortLock.Unlock()
+	short := c.podENIConfigsShort
+	c.podENIConfigsShort = nil
+	return short
+}
+
+// updatePodENIConfigPools grows the ENIs of the ENIConfigs that ran out of IPs, and frees an ENI of an ENIConfig
+// once it has no pods. ENIs of pod ENIConfigs are not part of the node's pool, so warm targets do not apply to them.
+func (c *IPAMContext) updatePodENIConfigPools(ctx context.Context) {
+	if c.isTerminating() {
+		return
+	}
+	if eni := c.dataStore.RemoveUnusedENIConfigENIFromStore(); eni != "" {
+		log.Debugf("Start freeing ENI %s of a pod ENIConfig", eni)
+		if err := c.awsClient.FreeENI(eni); err != nil {
+			ipamdErrInc("decreaseIPPoolFreeENIFailed")
+			log.Errorf("Failed to free ENI %s, err: %v", eni, err)
+		}
+	}
+
+	if !c.manageENIsNonScheduleable && c.isNodeNonSchedulable() {
+		return
+	}
+	for eniConfigName := range c.takePodENIConfigsShort() {
+		if c.dataStore.GetENIConfigIPStats(ipV4AddrFamily, eniConfigName).AvailableAddresses() > 0 {
+			continue
+		}
+		if err := c.increasePodENIConfigPool(ctx, eniConfigName); err != nil {
+			log.Warnf("Failed to increase the IP pool of ENIConfig %s: %v", eniConfigName, err)
+		}
+	}
+}
+
+// increasePodENIConfigPool allocates IPs or prefixes on an ENI of the ENIConfig, or a new ENI when all of its ENIs
+// are full
+func (c *IPAMContext) increasePodENIConfigPool(ctx context.Context, eniConfigName string) error {
+	maxCidrsPerENI := c.maxIPsPerENI
+	if c.enablePrefixDelegation {
+		maxCidrsPerENI = c.maxPrefixesPerENI
+	}
+	eni := c.dataStore.GetENIConfigENINeedsIP(maxCidrsPerENI, false, eniConfigName)
+	if eni == nil {
+		if !c.hasRoomForEni() {
+			return errors.New("max ENI limit is already reached")
+		}
+		log.Infof("Allocating an ENI for pods of ENIConfig %s", eniConfigName)
+		return c.tryAllocateENI(ctx, eniConfigName)
+	}
+
+	// A prefix holds enough IPs for a while, secondary IPs are allocated up to the ENI limit
+	resourcesToAllocate := maxCidrsPerENI - len(eni.AvailableIPv4Cidrs)
+	if c.enablePrefixDelegation {
+		resourcesToAllocate = 1
+	}
+	output, err := c.awsClient.AllocIPAddresses(eni.ID, resourcesToAllocate)
+	if err != nil {
+		ipamdErrInc("increaseIPPoolAllocIPAddressesFailed")
+		return errors.Wrapf(err, "failed to allocate IP addresses on ENI %s", eni.ID)
+	}
+	if output == nil {
+		return nil
+	}
+	if c.enablePrefixDelegation {
+		c.addENIv4prefixesToDataStore(output.AssignedIpv4Prefixes, eni.ID)
+		return nil
+	}
+	var ec2ip4s []*ec2.NetworkInterfacePrivateIpAddress
+	for _, ec2Addr := range output.AssignedPrivateIpAddresses {
+		ec2ip4s = append(ec2ip4s, &ec2.NetworkInterfacePrivateIpAddress{PrivateIpAddress: aws.String(aws.StringValue(ec2Addr.PrivateIpAddress))})
+	}
+	c.addENIsecondaryIPsToDataStore(ec2ip4s, eni.ID)
+	return nil
+}
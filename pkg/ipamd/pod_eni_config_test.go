@@ -0,0 +1,194 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/awsutils"
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+)
+
+func TestGetPodENIConfigName(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+	_ = os.Setenv("MY_NODE_NAME", myNodeName)
+
+	eniConfigKey := "k8s.amazonaws.com/eniConfig"
+	objects := []*corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{eniConfigKey: "team-a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-node", Labels: map[string]string{eniConfigKey: "node-config"}}},
+	}
+	for _, namespace := range objects {
+		assert.NoError(t, m.k8sClient.Create(ctx, namespace))
+	}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: myNodeName, Labels: map[string]string{eniConfigKey: "node-config"}}}
+	assert.NoError(t, m.k8sClient.Create(ctx, node))
+
+	pods := []*corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "plain", Namespace: "default"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "annotated", Namespace: "default", Annotations: map[string]string{eniConfigKey: "team-b"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "labelled-namespace", Namespace: "team-a"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-config", Namespace: "team-node"}},
+	}
+	for _, pod := range pods {
+		assert.NoError(t, m.k8sClient.Create(ctx, pod))
+	}
+
+	mockContext := &IPAMContext{k8sClient: m.k8sClient}
+	tests := []struct {
+		podName      string
+		podNamespace string
+		want         string
+		wantErr      bool
+	}{
+		{podName: "plain", podNamespace: "default", want: ""},
+		{podName: "annotated", podNamespace: "default", want: "team-b"},
+		{podName: "labelled-namespace", podNamespace: "team-a", want: "team-a"},
+		// The ENIConfig of the node is served by the node's ENIs
+		{podName: "node-config", podNamespace: "team-node", want: ""},
+		{podName: "missing", podNamespace: "default", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.podName, func(t *testing.T) {
+			got, err := mockContext.getPodENIConfigName(ctx, tt.podName, tt.podNamespace)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// The label of the namespace is cached for a while
+	objects[1].Labels[eniConfigKey] = "team-c"
+	assert.NoError(t, m.k8sClient.Update(ctx, objects[1]))
+	got, err := mockContext.getPodENIConfigName(ctx, "labelled-namespace", "team-a")
+	assert.NoError(t, err)
+	assert.Equal(t, "team-a", got)
+}
+
+func TestSetPodENIConfigShortRequestsPoolUpdate(t *testing.T) {
+	mockContext := &IPAMContext{poolUpdateRequested: make(chan struct{}, 1)}
+	mockContext.setPodENIConfigShort("team-a")
+	mockContext.setPodENIConfigShort("team-b")
+
+	// The pool manager does not wait for its next tick
+	done := make(chan struct{})
+	go func() {
+		mockContext.waitForPoolUpdate(time.Hour)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pool update was not requested")
+	}
+	assert.Len(t, mockContext.takePodENIConfigsShort(), 2)
+}
+
+func TestUpdatePodENIConfigPools(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	mockContext := &IPAMContext{
+		awsClient:                 m.awsutils,
+		k8sClient:                 m.k8sClient,
+		maxIPsPerENI:              14,
+		maxENI:                    4,
+		primaryIP:                 make(map[string]string),
+		manageENIsNonScheduleable: true,
+		enablePodENIConfig:        true,
+		dataStore:                 datastore.NewDataStore(log, datastore.NullCheckpoint{}, false),
+	}
+	_ = mockContext.dataStore.AddENI(primaryENIid, primaryDevice, true, false, false)
+	_ = mockContext.dataStore.AddENI(secENIid, secDevice, false, false, false)
+	_ = mockContext.dataStore.SetENIConfig(secENIid, "team-a")
+	ipv4Addr := net.IPNet{IP: net.ParseIP(ipaddr01), Mask: net.IPv4Mask(255, 255, 255, 255)}
+	_ = mockContext.dataStore.AddIPv4CidrToStore(secENIid, ipv4Addr, false)
+
+	// Nothing is allocated until a pod of the ENIConfig runs out of IPs
+	mockContext.updatePodENIConfigPools(ctx)
+
+	key := datastore.IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"}
+	_, _, err := mockContext.dataStore.AssignPodIPv4AddressForENIConfig(key, datastore.IPAMMetadata{}, "team-a")
+	assert.NoError(t, err)
+	mockContext.setPodENIConfigShort("team-a")
+
+	// The ENI of the ENIConfig is filled up to its limit
+	m.awsutils.EXPECT().AllocIPAddresses(secENIid, 13).Return(&ec2.AssignPrivateIpAddressesOutput{
+		AssignedPrivateIpAddresses: []*ec2.AssignedPrivateIpAddress{
+			{PrivateIpAddress: aws.String(ipaddr02)},
+			{PrivateIpAddress: aws.String(ipaddr03)},
+		},
+	}, nil)
+	mockContext.updatePodENIConfigPools(ctx)
+	assert.Equal(t, 3, mockContext.dataStore.GetENIConfigIPStats(ipV4AddrFamily, "team-a").TotalIPs)
+	assert.Equal(t, 0, mockContext.dataStore.GetIPStats(ipV4AddrFamily).TotalIPs)
+	assert.Empty(t, mockContext.takePodENIConfigsShort())
+}
+
+func TestNodeIPPoolReconcileENIConfigTag(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	mockContext := &IPAMContext{
+		awsClient:                m.awsutils,
+		networkClient:            m.network,
+		primaryIP:                make(map[string]string),
+		enableManageUntaggedMode: true,
+		enablePodENIConfig:       true,
+		dataStore:                testDatastore(),
+	}
+	m.awsutils.EXPECT().GetPrimaryENI().AnyTimes().Return(primaryENIid)
+	m.awsutils.EXPECT().IsUnmanagedENI(gomock.Any()).AnyTimes().Return(false)
+	m.awsutils.EXPECT().IsCNIUnmanagedENI(gomock.Any()).AnyTimes().Return(false)
+	m.awsutils.EXPECT().TagENI(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	m.awsutils.EXPECT().SetUnmanagedENIs(gomock.Any()).AnyTimes()
+	m.awsutils.EXPECT().SetCNIUnmanagedENIs(gomock.Any()).AnyTimes()
+
+	// The ENI of the ENIConfig is only known from its tag
+	twoENIs := []awsutils.ENIMetadata{getPrimaryENIMetadata(), getSecondaryENIMetadata()}
+	m.awsutils.EXPECT().GetAttachedENIs().Return(twoENIs, nil)
+	m.awsutils.EXPECT().DescribeAllENIs().Return(awsutils.DescribeAllENIsResult{
+		ENIMetadata: twoENIs,
+		TagMap: map[string]awsutils.TagMap{
+			secENIid: {awsutils.ENIConfigTagKey: "team-a"},
+		},
+		EFAENIs: make(map[string]bool),
+	}, nil)
+	m.network.EXPECT().SetupENINetwork(gomock.Any(), secMAC, secDevice, primarySubnet)
+	mockContext.nodeIPPoolReconcile(ctx, 0)
+
+	eniInfos := mockContext.dataStore.GetENIInfos()
+	assert.Equal(t, "", eniInfos.ENIs[primaryENIid].ENIConfig)
+	assert.Equal(t, "team-a", eniInfos.ENIs[secENIid].ENIConfig)
+	assert.Equal(t, 1, mockContext.dataStore.GetENIConfigIPStats(ipV4AddrFamily, "team-a").TotalIPs)
+}
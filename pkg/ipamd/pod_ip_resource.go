@@ -0,0 +1,91 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PodIPResourceName is the extended resource advertised on the node when ENABLE_POD_IP_RESOURCE is set. Pods that
+// request one unit of it are only scheduled to nodes that can still hand out an IP address.
+const PodIPResourceName corev1.ResourceName = "vpc.amazonaws.com/pod-ip"
+
+// getPodIPCapacity returns the number of pod IP addresses the node can hold once every ENI it may attach is full
+func (c *IPAMContext) getPodIPCapacity() int {
+	enis := c.maxENI - c.unmanagedENI
+	if c.enablePodENI {
+		// One ENI is reserved for the trunk ENI
+		enis--
+	}
+	if c.useCustomNetworking {
+		// The primary ENI is not used for pods
+		enis--
+	}
+	if enis <= 0 {
+		return 0
+	}
+	return enis * c.maxIPsPerENI
+}
+
+// syncPodIPResource updates the vpc.amazonaws.com/pod-ip capacity of the node when it changed since the last call.
+// When the feature is disabled, a capacity left over from an earlier configuration is removed.
+func (c *IPAMContext) syncPodIPResource(ctx context.Context) {
+	capacity := -1
+	if c.enablePodIPResource {
+		capacity = c.getPodIPCapacity()
+	}
+	if c.podIPResourceSynced && capacity == c.advertisedPodIPs {
+		return
+	}
+	if err := c.setPodIPResourceCapacity(ctx, capacity); err != nil {
+		log.Errorf("Failed to update %s capacity of node %s: %v", PodIPResourceName, c.myNodeName, err)
+		return
+	}
+	c.advertisedPodIPs = capacity
+	c.podIPResourceSynced = true
+}
+
+// setPodIPResourceCapacity sets the vpc.amazonaws.com/pod-ip capacity in the node status. A negative capacity removes
+// the resource. Kubelet copies the capacity of extended resources to the allocatable resources of the node.
+func (c *IPAMContext) setPodIPResourceCapacity(ctx context.Context, capacity int) error {
+	node := &corev1.Node{}
+	if err := c.k8sClient.Get(ctx, types.NamespacedName{Name: c.myNodeName}, node); err != nil {
+		return err
+	}
+	current, found := node.Status.Capacity[PodIPResourceName]
+	if capacity < 0 && !found {
+		return nil
+	}
+	if capacity >= 0 && found && current.Value() == int64(capacity) {
+		return nil
+	}
+
+	newNode := node.DeepCopy()
+	if capacity < 0 {
+		delete(newNode.Status.Capacity, PodIPResourceName)
+		log.Infof("Removing %s from node %s", PodIPResourceName, c.myNodeName)
+	} else {
+		if newNode.Status.Capacity == nil {
+			newNode.Status.Capacity = corev1.ResourceList{}
+		}
+		newNode.Status.Capacity[PodIPResourceName] = *resource.NewQuantity(int64(capacity), resource.DecimalSI)
+		log.Infof("Advertising %d %s on node %s", capacity, PodIPResourceName, c.myNodeName)
+	}
+	return c.k8sClient.Status().Patch(ctx, newNode, client.MergeFrom(node))
+}
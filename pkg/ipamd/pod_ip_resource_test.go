@@ -0,0 +1,102 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestGetPodIPCapacity(t *testing.T) {
+	tests := []struct {
+		name                string
+		maxENI              int
+		unmanagedENI        int
+		maxIPsPerENI        int
+		enablePodENI        bool
+		useCustomNetworking bool
+		want                int
+	}{
+		{name: "all ENIs", maxENI: 3, maxIPsPerENI: 9, want: 27},
+		{name: "unmanaged ENI", maxENI: 3, unmanagedENI: 1, maxIPsPerENI: 9, want: 18},
+		{name: "trunk ENI", maxENI: 3, maxIPsPerENI: 9, enablePodENI: true, want: 18},
+		{name: "custom networking", maxENI: 3, maxIPsPerENI: 9, useCustomNetworking: true, want: 18},
+		{name: "prefix delegation", maxENI: 3, maxIPsPerENI: 9 * 16, want: 432},
+		{name: "no ENIs left", maxENI: 1, maxIPsPerENI: 9, useCustomNetworking: true, enablePodENI: true, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &IPAMContext{
+				maxENI:              tt.maxENI,
+				unmanagedENI:        tt.unmanagedENI,
+				maxIPsPerENI:        tt.maxIPsPerENI,
+				enablePodENI:        tt.enablePodENI,
+				useCustomNetworking: tt.useCustomNetworking,
+			}
+			assert.Equal(t, tt.want, c.getPodIPCapacity())
+		})
+	}
+}
+
+func TestSyncPodIPResource(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: myNodeName},
+		Status: corev1.NodeStatus{Capacity: corev1.ResourceList{
+			corev1.ResourcePods: resource.MustParse("110"),
+		}},
+	}
+	assert.NoError(t, m.k8sClient.Create(ctx, node))
+	getCapacity := func() corev1.ResourceList {
+		node := &corev1.Node{}
+		assert.NoError(t, m.k8sClient.Get(ctx, types.NamespacedName{Name: myNodeName}, node))
+		return node.Status.Capacity
+	}
+
+	mockContext := &IPAMContext{
+		k8sClient:           m.k8sClient,
+		myNodeName:          myNodeName,
+		maxENI:              3,
+		maxIPsPerENI:        9,
+		enablePodIPResource: true,
+	}
+	mockContext.syncPodIPResource(ctx)
+	capacity := getCapacity()
+	podIPs := capacity[PodIPResourceName]
+	assert.Equal(t, int64(27), podIPs.Value())
+	assert.Contains(t, capacity, corev1.ResourcePods)
+
+	// An unmanaged ENI showed up
+	mockContext.unmanagedENI = 1
+	mockContext.syncPodIPResource(ctx)
+	podIPs = getCapacity()[PodIPResourceName]
+	assert.Equal(t, int64(18), podIPs.Value())
+	assert.Equal(t, 18, mockContext.advertisedPodIPs)
+
+	// Disabling the feature removes the resource again
+	mockContext.enablePodIPResource = false
+	mockContext.syncPodIPResource(ctx)
+	capacity = getCapacity()
+	assert.NotContains(t, capacity, PodIPResourceName)
+	assert.Contains(t, capacity, corev1.ResourcePods)
+}
@@ -0,0 +1,109 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+)
+
+// prefixFallbackRetryInterval is how long an ENI that fell back to secondary IPs keeps allocating them before
+// ipamd tries to allocate a prefix on it again
+const prefixFallbackRetryInterval = 5 * time.Minute
+
+// containsInsufficientCidrBlocksError returns whether the subnet has free addresses, but no contiguous /28 block left
+// for a prefix
+func containsInsufficientCidrBlocksError(err error) bool {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return awsErr.Code() == INSUFFICIENT_CIDR_BLOCKS
+	}
+	return false
+}
+
+// inPrefixFallback returns whether secondary IPs should still be allocated on the ENI instead of prefixes
+func inPrefixFallback(eni *datastore.ENI) bool {
+	return eni.PrefixFallback && time.Since(eni.PrefixFallbackTime) < prefixFallbackRetryInterval
+}
+
+// getFallbackIPsNeeded returns the number of secondary IPs to allocate instead of prefixesNeeded prefixes
+func (c *IPAMContext) getFallbackIPsNeeded(prefixesNeeded int) int {
+	if c.enableHybridIPMode {
+		// Warm targets are already counted in IPs
+		short, _ := c.hybridTargetState()
+		return max(short, 1)
+	}
+	_, numIPsPerPrefix, _ := datastore.GetPrefixDelegationDefaults()
+	return prefixesNeeded * numIPsPerPrefix
+}
+
+// fallBackToSecondaryIPs records in the datastore that no prefix could be allocated on the ENI, and allocates
+// ipsNeeded secondary IPs on it instead
+func (c *IPAMContext) fallBackToSecondaryIPs(eni *datastore.ENI, ipsNeeded int) (increasedPool bool, err error) {
+	log.Warnf("Subnet of ENI %s has no free /28 block for a prefix, falling back to secondary IPs", eni.ID)
+	if err := c.dataStore.SetPrefixFallback(eni.ID); err != nil {
+		return false, err
+	}
+	return c.tryAssignFallbackIPs(eni, ipsNeeded)
+}
+
+// tryAssignFallbackIPs allocates secondary IPs on an ENI that fell back from prefixes. Since each secondary IP uses
+// the same slot on the ENI as a prefix, at most the number of free slots is allocated.
+func (c *IPAMContext) tryAssignFallbackIPs(eni *datastore.ENI, ipsNeeded int) (increasedPool bool, err error) {
+	resourcesToAllocate := min(ipsNeeded, c.maxPrefixesPerENI-len(eni.AvailableIPv4Cidrs))
+	output, err := c.awsClient.AllocSecondaryIPAddresses(eni.ID, resourcesToAllocate)
+	if err != nil && !containsPrivateIPAddressLimitExceededError(err) {
+		ipamdErrInc("increaseIPPoolAllocIPAddressesFailed")
+		return false, errors.Wrap(err, fmt.Sprintf("failed to allocate secondary IP addresses on ENI %s in prefix fallback", eni.ID))
+	}
+
+	var ec2ip4s []*ec2.NetworkInterfacePrivateIpAddress
+	if containsPrivateIPAddressLimitExceededError(err) {
+		// This call to EC2 is needed to verify which IPs got attached to this ENI.
+		ec2ip4s, err = c.awsClient.GetIPv4sFromEC2(eni.ID)
+		if err != nil {
+			ipamdErrInc("increaseIPPoolGetENIaddressesFailed")
+			return true, errors.Wrap(err, "failed to get ENI IP addresses during IP allocation")
+		}
+	} else {
+		if output == nil {
+			ipamdErrInc("increaseIPPoolGetENIaddressesFailed")
+			return true, errors.Wrap(err, "failed to get ENI IP addresses during IP allocation")
+		}
+		for _, ec2Addr := range output.AssignedPrivateIpAddresses {
+			ec2ip4s = append(ec2ip4s, &ec2.NetworkInterfacePrivateIpAddress{PrivateIpAddress: aws.String(aws.StringValue(ec2Addr.PrivateIpAddress))})
+		}
+	}
+	c.addENIsecondaryIPsToDataStore(ec2ip4s, eni.ID)
+	return true, nil
+}
+
+// revertPrefixFallback is called once a prefix could be allocated on an ENI that fell back to secondary IPs. The
+// secondary IPs that are not assigned to pods are released right away, unless they are still used in hybrid mode.
+func (c *IPAMContext) revertPrefixFallback(eniID string) {
+	if err := c.dataStore.ClearPrefixFallback(eniID); err != nil {
+		log.Warnf("Failed to clear prefix fallback of ENI %s: %v", eniID, err)
+		return
+	}
+	if !c.enableHybridIPMode {
+		c.tryUnassignIPFromENI(eniID)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+)
+
+func TestContainsInsufficientCidrBlocksError(t *testing.T) {
+	assert.True(t, containsInsufficientCidrBlocksError(awserr.New(INSUFFICIENT_CIDR_BLOCKS, "no /28 left", nil)))
+	assert.False(t, containsInsufficientCidrBlocksError(awserr.New(INSUFFICIENT_FREE_IP_SUBNET, "subnet is full", nil)))
+	assert.False(t, containsInsufficientCidrBlocksError(errors.New("some error")))
+	assert.False(t, containsInsufficientCidrBlocksError(nil))
+}
+
+func TestTryAssignPrefixesFallback(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+
+	mockContext := &IPAMContext{
+		awsClient:              m.awsutils,
+		maxIPsPerENI:           256,
+		maxPrefixesPerENI:      16,
+		maxENI:                 4,
+		warmPrefixTarget:       1,
+		primaryIP:              make(map[string]string),
+		enablePrefixDelegation: true,
+		dataStore:              datastore.NewDataStore(log, datastore.NullCheckpoint{}, true),
+	}
+	_ = mockContext.dataStore.AddENI(primaryENIid, primaryDevice, true, false, false)
+
+	// The subnet is too fragmented for a prefix, so secondary IPs are allocated instead
+	insufficientCidrErr := awserr.New(INSUFFICIENT_CIDR_BLOCKS, "There are not enough free cidr blocks in the specified subnet", nil)
+	m.awsutils.EXPECT().AllocIPAddresses(primaryENIid, 1).Return(nil, insufficientCidrErr).Times(2)
+	m.awsutils.EXPECT().AllocSecondaryIPAddresses(primaryENIid, 16).Return(&ec2.AssignPrivateIpAddressesOutput{
+		AssignedPrivateIpAddresses: []*ec2.AssignedPrivateIpAddress{
+			{PrivateIpAddress: aws.String(ipaddr01)},
+			{PrivateIpAddress: aws.String(ipaddr02)},
+		},
+	}, nil)
+
+	increasedPool, err := mockContext.tryAssignPrefixes()
+	assert.NoError(t, err)
+	assert.True(t, increasedPool)
+	eni := mockContext.dataStore.GetENIInfos().ENIs[primaryENIid]
+	assert.True(t, eni.PrefixFallback)
+	assert.Equal(t, 2, mockContext.dataStore.GetIPStats(ipV4AddrFamily).TotalIPs)
+
+	// Within the retry interval, more secondary IPs are allocated without trying a prefix first
+	m.awsutils.EXPECT().AllocSecondaryIPAddresses(primaryENIid, 14).Return(&ec2.AssignPrivateIpAddressesOutput{
+		AssignedPrivateIpAddresses: []*ec2.AssignedPrivateIpAddress{{PrivateIpAddress: aws.String(ipaddr03)}},
+	}, nil)
+	increasedPool, err = mockContext.tryAssignPrefixes()
+	assert.NoError(t, err)
+	assert.True(t, increasedPool)
+	assert.Equal(t, 3, mockContext.dataStore.GetIPStats(ipV4AddrFamily).TotalIPs)
+
+	// Once a prefix can be allocated again, the fallback is reverted and the unused secondary IPs are released
+	mockContext.dataStore.GetENINeedsIP(mockContext.maxPrefixesPerENI, false).PrefixFallbackTime = time.Now().Add(-prefixFallbackRetryInterval)
+	m.awsutils.EXPECT().AllocIPAddresses(primaryENIid, 1).Return(&ec2.AssignPrivateIpAddressesOutput{
+		AssignedIpv4Prefixes: []*ec2.Ipv4PrefixSpecification{{Ipv4Prefix: aws.String(prefix01)}},
+	}, nil)
+	m.awsutils.EXPECT().DeallocIPAddresses(primaryENIid, gomock.Any()).Do(func(_ string, ips []string) {
+		assert.ElementsMatch(t, []string{ipaddr01, ipaddr02, ipaddr03}, ips)
+	})
+	increasedPool, err = mockContext.tryAssignPrefixes()
+	assert.NoError(t, err)
+	assert.True(t, increasedPool)
+	eni = mockContext.dataStore.GetENIInfos().ENIs[primaryENIid]
+	assert.False(t, eni.PrefixFallback)
+	assert.Len(t, eni.AvailableIPv4Cidrs, 1)
+	assert.Equal(t, 16, mockContext.dataStore.GetIPStats(ipV4AddrFamily).TotalIPs)
+}
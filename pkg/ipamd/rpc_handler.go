@@ -15,10 +15,13 @@ package ipamd
 
 import (
 	"encoding/json"
+	"fmt"
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/pkg/errors"
@@ -34,6 +37,7 @@ import (
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/networkutils"
 	"github.com/aws/amazon-vpc-cni-k8s/rpc"
+	corev1 "k8s.io/api/core/v1"
 	k8serror "k8s.io/apimachinery/pkg/api/errors"
 )
 
@@ -41,6 +45,19 @@ const (
 	ipamdgRPCaddress      = "127.0.0.1:50051"
 	grpcHealthServiceName = "grpc.health.v1.aws-node"
 
+	// ipamdgRPCSocketPath is the Unix socket that the CNI plugin calls ipamd on, only root can connect to it
+	ipamdgRPCSocketPath = "/var/run/aws-node/ipamd.sock"
+
+	// envEnableIpamdTCPListener is used to also serve the CNI backend on ipamdgRPCaddress, for plugins that are not
+	// configured with the Unix socket. The TCP listener always serves the health service.
+	envEnableIpamdTCPListener = "ENABLE_IPAMD_TCP_LISTENER"
+
+	// envHostCniConfDirPath is the CNI config directory of the host, as mounted in the aws-node container
+	envHostCniConfDirPath     = "HOST_CNI_CONFDIR_PATH"
+	defaultHostCniConfDirPath = "/host/etc/cni/net.d"
+	awsConflistFile           = "10-aws.conflist"
+	cniBackendMethodPrefix    = "/rpc.CNIBackend/"
+
 	vpccniPodIPKey = "vpc.amazonaws.com/pod-ips"
 )
 
@@ -72,17 +89,33 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 		return nil, err
 	}
 
-	failureResponse := rpc.AddNetworkReply{Success: false}
 	var deviceNumber, vlanID, trunkENILinkIndex int
 	var ipv4Addr, ipv6Addr, branchENIMAC, podENISubnetGW string
+	var errorReason rpc.ErrorReason
+	var errorMessage string
 	var err error
-	if !s.ipamContext.enableIPv6 && s.ipamContext.enablePodENI {
-		// Check pod spec for Branch ENI
-		pod, err := s.ipamContext.GetPod(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE)
+	// A secondary network gets its IPs from the ENIs of its own ENIConfig, branch ENIs only serve the pod's main interface
+	secondaryENIConfigName := s.ipamContext.getSecondaryNetworkENIConfig(in.NetworkName)
+	checkPassthroughENI := s.ipamContext.enableENIPassthrough && secondaryENIConfigName == ""
+	checkBranchENI := !s.ipamContext.enableIPv6 && s.ipamContext.enablePodENI && secondaryENIConfigName == ""
+	var pod *corev1.Pod
+	if checkPassthroughENI || checkBranchENI {
+		// The pod spec is fetched once for both checks
+		pod, err = s.ipamContext.GetPod(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE)
 		if err != nil {
 			log.Warnf("Send AddNetworkReply: Failed to get pod: %v", err)
-			return &failureResponse, nil
+			if k8serror.IsNotFound(err) {
+				return addNetworkFailure(rpc.ErrorReason_POD_NOT_FOUND, err.Error()), nil
+			}
+			return addNetworkFailure(rpc.ErrorReason_INTERNAL_ERROR, err.Error()), nil
 		}
+	}
+	if checkPassthroughENI && isENIPassthroughPod(pod) {
+		// A pod that asks for a whole ENI gets no secondary IP
+		return s.addPassthroughNetwork(in), nil
+	}
+	if checkBranchENI {
+		// Check pod spec for Branch ENI
 		limits := pod.Spec.Containers[0].Resources.Limits
 		for resName := range limits {
 			if strings.HasPrefix(string(resName), "vpc.amazonaws.com/pod-eni") {
@@ -90,12 +123,12 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 				trunkENI := s.ipamContext.dataStore.GetTrunkENI()
 				if trunkENI == "" {
 					log.Warn("Send AddNetworkReply: No trunk ENI found, cannot add a pod ENI")
-					return &failureResponse, nil
+					return addNetworkFailure(rpc.ErrorReason_TRUNK_ENI_MISSING, "no trunk ENI is attached to the node"), nil
 				}
 				trunkENILinkIndex, err = s.ipamContext.getTrunkLinkIndex()
 				if err != nil {
 					log.Warn("Send AddNetworkReply: No trunk ENI Link Index found, cannot add a pod ENI")
-					return &failureResponse, nil
+					return addNetworkFailure(rpc.ErrorReason_TRUNK_ENI_MISSING, "the link of the trunk ENI is not found"), nil
 				}
 				val, branch := pod.Annotations["vpc.amazonaws.com/pod-eni"]
 				if branch {
@@ -104,7 +137,7 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 					err := json.Unmarshal([]byte(val), &podENIData)
 					if err != nil || len(podENIData) < 1 {
 						log.Errorf("Failed to unmarshal PodENIData JSON: %v", err)
-						return &failureResponse, nil
+						return addNetworkFailure(rpc.ErrorReason_INVALID_REQUEST, "malformed pod-eni annotation"), nil
 					}
 					firstENI := podENIData[0]
 					ipv4Addr = firstENI.PrivateIP
@@ -114,20 +147,20 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 
 					if ipv4Addr == "" || branchENIMAC == "" || vlanID == 0 {
 						log.Errorf("Failed to parse pod-ENI annotation: %s", val)
-						return &failureResponse, nil
+						return addNetworkFailure(rpc.ErrorReason_INVALID_REQUEST, "malformed pod-eni annotation"), nil
 					}
 					currentGW := strings.Split(firstENI.SubnetCIDR, "/")[0]
 					// Increment value CIDR value
 					nextGWIP, err := networkutils.IncrementIPv4Addr(net.ParseIP(currentGW))
 					if err != nil {
 						log.Errorf("Unable to get next Gateway IP for branch ENI from %s: %v", currentGW, err)
-						return &failureResponse, nil
+						return addNetworkFailure(rpc.ErrorReason_INTERNAL_ERROR, err.Error()), nil
 					}
 					podENISubnetGW = nextGWIP.String()
 					deviceNumber = -1 // Not needed for branch ENI, they depend on trunkENIDeviceIndex
 				} else {
 					log.Infof("Send AddNetworkReply: failed to get Branch ENI resource")
-					return &failureResponse, nil
+					return addNetworkFailure(rpc.ErrorReason_BRANCH_ENI_ANNOTATION_MISSING, "the pod-eni annotation is not set on the pod yet"), nil
 				}
 			}
 		}
@@ -137,7 +170,7 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 		s.ipamContext.enableIPv6 && ipv6Addr == "" {
 		if in.ContainerID == "" || in.IfName == "" || in.NetworkName == "" {
 			log.Errorf("Unable to generate IPAMKey from %+v", in)
-			return &failureResponse, nil
+			return addNetworkFailure(rpc.ErrorReason_INVALID_REQUEST, "ContainerID, IfName and NetworkName are required"), nil
 		}
 		ipamKey := datastore.IPAMKey{
 			ContainerID: in.ContainerID,
@@ -148,7 +181,29 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 			K8SPodNamespace: in.K8S_POD_NAMESPACE,
 			K8SPodName:      in.K8S_POD_NAME,
 		}
-		ipv4Addr, ipv6Addr, deviceNumber, err = s.ipamContext.dataStore.AssignPodIPAddress(ipamKey, ipamMetadata, s.ipamContext.enableIPv4, s.ipamContext.enableIPv6)
+		eniConfigName := secondaryENIConfigName
+		if eniConfigName == "" && s.ipamContext.enablePodENIConfig {
+			eniConfigName, err = s.ipamContext.getPodENIConfigName(ctx, in.K8S_POD_NAME, in.K8S_POD_NAMESPACE)
+			if err != nil {
+				log.Warnf("Send AddNetworkReply: Failed to get ENIConfig of pod: %v", err)
+				return addNetworkFailure(rpc.ErrorReason_INTERNAL_ERROR, err.Error()), nil
+			}
+		}
+		if eniConfigName != "" {
+			// The pod gets its IP from the ENIs of its own ENIConfig, which are grown on demand
+			ipv4Addr, deviceNumber, err = s.ipamContext.dataStore.AssignPodIPv4AddressForENIConfig(ipamKey, ipamMetadata, eniConfigName)
+			if err != nil {
+				log.Infof("No IP available for ENIConfig %s of pod %s/%s yet", eniConfigName, in.K8S_POD_NAMESPACE, in.K8S_POD_NAME)
+				s.ipamContext.setPodENIConfigShort(eniConfigName)
+				errorReason, errorMessage = rpc.ErrorReason_POOL_EMPTY, fmt.Sprintf("No IP addresses are available for ENIConfig %s yet", eniConfigName)
+			}
+		} else {
+			ipv4Addr, ipv6Addr, deviceNumber, err = s.ipamContext.dataStore.AssignPodIPAddress(ipamKey, ipamMetadata, s.ipamContext.enableIPv4, s.ipamContext.enableIPv6)
+			if err != nil {
+				errorReason, errorMessage = s.ipamContext.ipAssignmentFailureReason()
+				s.ipamContext.reportIPAssignmentFailure(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE, err)
+			}
+		}
 	}
 
 	var pbVPCV4cidrs, pbVPCV6cidrs []string
@@ -168,7 +223,8 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 				pbVPCV4cidrs = append(pbVPCV4cidrs, cidr)
 			}
 		}
-	} else if s.ipamContext.enableIPv6 && ipv6Addr != "" {
+	}
+	if s.ipamContext.enableIPv6 && ipv6Addr != "" {
 		pbVPCV6cidrs, err = s.ipamContext.awsClient.GetVPCIPv6CIDRs()
 		if err != nil {
 			return nil, err
@@ -178,6 +234,16 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 		}
 	}
 
+	if s.ipamContext.enableEgressIPPinning && ipv4Addr != "" {
+		// The SNAT rule has to be in place before the pod sends its first packet
+		s.ipamContext.updateEgressIPRules(ctx)
+	}
+
+	// Branch ENI pods are routed by their VLAN, they have no device number
+	if s.ipamContext.bpfRouteMap != nil && ipv4Addr != "" && deviceNumber >= 0 {
+		s.ipamContext.addBPFPodRoute(ipv4Addr, deviceNumber)
+	}
+
 	if s.ipamContext.enablePodIPAnnotation {
 		// On ADD, we pass empty string as there is no IP being released
 		err = s.ipamContext.AnnotatePod(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE, vpccniPodIPKey, ipv4Addr, "")
@@ -197,12 +263,26 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 		PodENIMAC:       branchENIMAC,
 		PodENISubnetGW:  podENISubnetGW,
 		ParentIfIndex:   int32(trunkENILinkIndex),
+		// Only networks with an ENIConfig of their own are secondary, other extra interfaces are set up like eth0
+		SecondaryInterface: secondaryENIConfigName != "",
+	}
+	if err != nil {
+		if errorReason == rpc.ErrorReason_NONE {
+			errorReason, errorMessage = rpc.ErrorReason_INTERNAL_ERROR, err.Error()
+		}
+		resp.ErrorReason = errorReason
+		resp.ErrorMessage = errorMessage
 	}
 
 	log.Infof("Send AddNetworkReply: IPv4Addr %s, IPv6Addr: %s, DeviceNumber: %d, err: %v", ipv4Addr, ipv6Addr, deviceNumber, err)
 	return &resp, nil
 }
 
+// addNetworkFailure returns the reply of a failed AddNetwork request
+func addNetworkFailure(reason rpc.ErrorReason, message string) *rpc.AddNetworkReply {
+	return &rpc.AddNetworkReply{Success: false, ErrorReason: reason, ErrorMessage: message}
+}
+
 func (s *server) validateVersion(clientVersion string) error {
 	if s.version != clientVersion {
 		return status.Errorf(codes.FailedPrecondition, "wrong client version %q (!= %q)", clientVersion, s.version)
@@ -227,7 +307,27 @@ func (s *server) DelNetwork(ctx context.Context, in *rpc.DelNetworkRequest) (*rp
 		IfName:      in.IfName,
 		NetworkName: in.NetworkName,
 	}
-	eni, ip, deviceNumber, err := s.ipamContext.dataStore.UnassignPodIPAddress(ipamKey)
+	// Released even if passthrough was disabled since, so that the ENI is returned to the pool
+	if resp := s.delPassthroughNetwork(in, ipamKey); resp != nil {
+		return resp, nil
+	}
+	var eni *datastore.ENI
+	var ip string
+	var deviceNumber int
+	var err error
+	if s.ipamContext.enableIPv4 && s.ipamContext.enableIPv6 {
+		// In dual-stack mode, each address family of the pod is unassigned on its own
+		eni, ip, deviceNumber, err = s.ipamContext.dataStore.UnassignPodIPv4Address(ipamKey)
+		if err == nil {
+			_, ipv6Addr, _, err = s.ipamContext.dataStore.UnassignPodIPv6Address(ipamKey)
+			if err == datastore.ErrUnknownPod {
+				log.Warnf("No IPv6 address found for dual-stack sandbox %s", ipamKey)
+				err = nil
+			}
+		}
+	} else {
+		eni, ip, deviceNumber, err = s.ipamContext.dataStore.UnassignPodIPAddress(ipamKey)
+	}
 	if s.ipamContext.enableIPv4 {
 		ipv4Addr = ip
 		cidr := net.IPNet{IP: net.ParseIP(ip), Mask: net.IPv4Mask(255, 255, 255, 255)}
@@ -239,10 +339,11 @@ func (s *server) DelNetwork(ctx context.Context, in *rpc.DelNetworkRequest) (*rp
 	if s.ipamContext.enableIPv4 && eni != nil {
 		//cidrStr will be pod IP i.e, IP/32 for v4 (or) IP/128 for v6.
 		// Case 1: PD is enabled but IP/32 key in AvailableIPv4Cidrs[cidrStr] exists, this means it is a secondary IP. Added IsPrefix check just for sanity.
-		// So this IP should be released immediately.
+		// So this IP should be released immediately, unless the ENI fell back to secondary IPs because the subnet has no free /28 block
+		// or secondary IPs are used next to prefixes in hybrid mode.
 		// Case 2: PD is disabled then IP/32 key in AvailableIPv4Cidrs[cidrStr] will not exists since key to AvailableIPv4Cidrs will be either /28 prefix or /32
 		// secondary IP. Hence now see if we need free up a prefix is no other pods are using it.
-		if s.ipamContext.enablePrefixDelegation && eni.AvailableIPv4Cidrs[cidrStr] != nil && eni.AvailableIPv4Cidrs[cidrStr].IsPrefix == false {
+		if s.ipamContext.enablePrefixDelegation && !s.ipamContext.enableHybridIPMode && eni.AvailableIPv4Cidrs[cidrStr] != nil && eni.AvailableIPv4Cidrs[cidrStr].IsPrefix == false && !eni.PrefixFallback {
 			log.Debugf("IP belongs to secondary pool with PD enabled so free IP from EC2")
 			s.ipamContext.tryUnassignIPFromENI(eni.ID)
 		} else if !s.ipamContext.enablePrefixDelegation && eni.AvailableIPv4Cidrs[cidrStr] == nil {
@@ -259,7 +360,7 @@ func (s *server) DelNetwork(ctx context.Context, in *rpc.DelNetworkRequest) (*rp
 				return &rpc.DelNetworkReply{Success: true}, nil
 			}
 			log.Warnf("Send DelNetworkReply: Failed to get pod spec: %v", err)
-			return &rpc.DelNetworkReply{Success: false}, err
+			return &rpc.DelNetworkReply{Success: false, ErrorReason: rpc.ErrorReason_INTERNAL_ERROR, ErrorMessage: err.Error()}, nil
 		}
 		val, branch := pod.Annotations["vpc.amazonaws.com/pod-eni"]
 		if branch {
@@ -268,37 +369,168 @@ func (s *server) DelNetwork(ctx context.Context, in *rpc.DelNetworkRequest) (*rp
 			err := json.Unmarshal([]byte(val), &podENIData)
 			if err != nil || len(podENIData) < 1 {
 				log.Errorf("Failed to unmarshal PodENIData JSON: %v", err)
+				return &rpc.DelNetworkReply{Success: false, ErrorReason: rpc.ErrorReason_INTERNAL_ERROR,
+					ErrorMessage: fmt.Sprintf("invalid pod-eni annotation: %v", err)}, nil
 			}
 			return &rpc.DelNetworkReply{
 				Success:   true,
 				PodVlanId: int32(podENIData[0].VlanID),
-				IPv4Addr:  podENIData[0].PrivateIP}, err
+				IPv4Addr:  podENIData[0].PrivateIP}, nil
 		}
 	}
 
+	if s.ipamContext.enableEgressIPPinning && ipv4Addr != "" && err == nil {
+		s.ipamContext.updateEgressIPRules(ctx)
+	}
+
+	if s.ipamContext.bpfRouteMap != nil && ipv4Addr != "" && err == nil {
+		s.ipamContext.deleteBPFPodRoute(ipv4Addr)
+	}
+
 	if s.ipamContext.enablePodIPAnnotation {
-		// On DEL, we pass IP being released
-		err = s.ipamContext.AnnotatePod(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE, vpccniPodIPKey, "", ip)
-		if err != nil {
-			log.Errorf("Failed to delete the pod annotation: %v", err)
+		// On DEL, we pass IP being released. A failed annotation doesn't fail the DEL request.
+		if errAnnotate := s.ipamContext.AnnotatePod(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE, vpccniPodIPKey, "", ip); errAnnotate != nil {
+			log.Errorf("Failed to delete the pod annotation: %v", errAnnotate)
 		}
 	}
 
-	log.Infof("Send DelNetworkReply: IPv4Addr %s, DeviceNumber: %d, err: %v", ip, deviceNumber, err)
+	log.Infof("Send DelNetworkReply: IPv4Addr %s, IPv6Addr: %s, DeviceNumber: %d, err: %v", ipv4Addr, ipv6Addr, deviceNumber, err)
 
-	return &rpc.DelNetworkReply{Success: err == nil, IPv4Addr: ipv4Addr, IPv6Addr: ipv6Addr, DeviceNumber: int32(deviceNumber)}, err
+	resp := &rpc.DelNetworkReply{Success: err == nil, IPv4Addr: ipv4Addr, IPv6Addr: ipv6Addr, DeviceNumber: int32(deviceNumber)}
+	if err == datastore.ErrUnknownPod {
+		resp.ErrorReason, resp.ErrorMessage = rpc.ErrorReason_POD_NOT_FOUND, err.Error()
+	} else if err != nil {
+		resp.ErrorReason, resp.ErrorMessage = rpc.ErrorReason_INTERNAL_ERROR, err.Error()
+	}
+	// gRPC drops the reply of a failed call, so the reason is only sent in the reply
+	return resp, nil
+}
+
+// CheckNetwork processes CNI check network request and returns the addresses that are still assigned to the container
+func (s *server) CheckNetwork(ctx context.Context, in *rpc.CheckNetworkRequest) (*rpc.CheckNetworkReply, error) {
+	log.Infof("Received CheckNetwork for Sandbox %s, ifname %s", in.ContainerID, in.IfName)
+	log.Debugf("CheckNetworkRequest: %s", in)
+
+	// Do this early, but after logging trace
+	if err := s.validateVersion(in.ClientVersion); err != nil {
+		log.Warnf("Rejecting CheckNetwork request: %v", err)
+		return nil, err
+	}
+
+	ipamKey := datastore.IPAMKey{
+		ContainerID: in.ContainerID,
+		IfName:      in.IfName,
+		NetworkName: in.NetworkName,
+	}
+	if passthroughENI, err := s.ipamContext.dataStore.LookupPassthroughENI(ipamKey); err == nil {
+		log.Infof("Send CheckNetworkReply: IPv4Addr %s of passthrough ENI %s", passthroughENI.IPv4, passthroughENI.ENIID)
+		return &rpc.CheckNetworkReply{Success: true, IPv4Addr: passthroughENI.IPv4, DeviceNumber: -1, PassthroughENI: true}, nil
+	}
+	ipv4Addr, ipv6Addr, deviceNumber, err := s.ipamContext.dataStore.LookupPodIPAddresses(ipamKey)
+	if err == datastore.ErrUnknownPod && s.ipamContext.enablePodENI {
+		// Branch ENI pods get their address from the pod annotation, not from the datastore
+		pod, err := s.ipamContext.GetPod(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE)
+		if err != nil {
+			log.Warnf("Send CheckNetworkReply: Failed to get pod: %v", err)
+			return &rpc.CheckNetworkReply{Success: false}, nil
+		}
+		if val, branch := pod.Annotations["vpc.amazonaws.com/pod-eni"]; branch {
+			var podENIData []PodENIData
+			if err := json.Unmarshal([]byte(val), &podENIData); err != nil || len(podENIData) < 1 {
+				log.Errorf("Failed to unmarshal PodENIData JSON: %v", err)
+				return &rpc.CheckNetworkReply{Success: false}, nil
+			}
+			log.Infof("Send CheckNetworkReply: IPv4Addr %s, PodVlanId %d", podENIData[0].PrivateIP, podENIData[0].VlanID)
+			return &rpc.CheckNetworkReply{
+				Success:   true,
+				IPv4Addr:  podENIData[0].PrivateIP,
+				PodVlanId: int32(podENIData[0].VlanID)}, nil
+		}
+	}
+	if err != nil {
+		log.Warnf("Send CheckNetworkReply: No address assigned to sandbox %s: %v", in.ContainerID, err)
+		return &rpc.CheckNetworkReply{Success: false}, nil
+	}
+
+	log.Infof("Send CheckNetworkReply: IPv4Addr %s, IPv6Addr: %s, DeviceNumber: %d", ipv4Addr, ipv6Addr, deviceNumber)
+	return &rpc.CheckNetworkReply{Success: true, IPv4Addr: ipv4Addr, IPv6Addr: ipv6Addr, DeviceNumber: int32(deviceNumber),
+		SecondaryInterface: s.ipamContext.getSecondaryNetworkENIConfig(in.NetworkName) != ""}, nil
 }
 
 // RunRPCHandler handles request from gRPC
 func (c *IPAMContext) RunRPCHandler(version string) error {
-	log.Infof("Serving RPC Handler version %s on %s", version, ipamdgRPCaddress)
+	log.Infof("Serving RPC Handler version %s on %s", version, ipamdgRPCSocketPath)
+	socketListener, err := listenUnixSocket(ipamdgRPCSocketPath)
+	if err != nil {
+		log.Errorf("Failed to listen on gRPC socket: %v", err)
+		return errors.Wrap(err, "ipamd: failed to listen on gRPC socket")
+	}
 	listener, err := net.Listen("tcp", ipamdgRPCaddress)
 	if err != nil {
 		log.Errorf("Failed to listen gRPC port: %v", err)
 		return errors.Wrap(err, "ipamd: failed to listen to gRPC port")
 	}
-	grpcServer := grpc.NewServer()
-	rpc.RegisterCNIBackendServer(grpcServer, &server{version: version, ipamContext: c})
+
+	backend := &server{version: version, ipamContext: c}
+	socketServer := newGRPCServer(backend)
+	// The health service stays on the TCP port for grpc-health-probe
+	var tcpBackend rpc.CNIBackendServer
+	var tcpOpts []grpc.ServerOption
+	if enableIpamdTCPListener() {
+		log.Infof("Serving RPC Handler version %s on %s", version, ipamdgRPCaddress)
+		tcpBackend = backend
+	} else if gate := newTCPBackendGate(hostCNIConflistPath()); gate.open() {
+		// The plugins of the previous version call the port until the new CNI config file is written
+		log.Infof("Serving RPC Handler version %s on %s until %s uses the socket", version, ipamdgRPCaddress, gate.conflistPath)
+		tcpBackend = backend
+		tcpOpts = append(tcpOpts, grpc.UnaryInterceptor(gate.unaryInterceptor))
+	}
+	grpcServer := newGRPCServer(tcpBackend, tcpOpts...)
+
+	var tlsServer *grpc.Server
+	var tlsListener net.Listener
+	if tlsConfig := getIpamdTLSConfig(); tlsConfig != nil {
+		reloader, err := newCertReloader(tlsConfig)
+		if err != nil {
+			log.Errorf("Failed to load the TLS certificate: %v", err)
+			return errors.Wrap(err, "ipamd: failed to load the TLS certificate")
+		}
+		log.Infof("Serving RPC Handler version %s with mutual TLS on %s", version, tlsConfig.address)
+		tlsListener, err = net.Listen("tcp", tlsConfig.address)
+		if err != nil {
+			log.Errorf("Failed to listen gRPC TLS port: %v", err)
+			return errors.Wrap(err, "ipamd: failed to listen to gRPC TLS port")
+		}
+		tlsServer = newGRPCServer(backend, grpc.Creds(reloader.serverCredentials()))
+	}
+
+	// Add shutdown hook
+	go c.shutdownListener()
+	errs := make(chan error, 3)
+	if tlsServer != nil {
+		go func() {
+			errs <- errors.Wrap(tlsServer.Serve(tlsListener), "TLS port")
+		}()
+	}
+	go func() {
+		errs <- errors.Wrap(socketServer.Serve(socketListener), "socket")
+	}()
+	go func() {
+		errs <- errors.Wrap(grpcServer.Serve(listener), "port")
+	}()
+	if err := <-errs; err != nil {
+		log.Errorf("Failed to start server on gRPC %v", err)
+		return errors.Wrap(err, "ipamd: failed to start server on gPRC")
+	}
+	return nil
+}
+
+// newGRPCServer returns a server with the health and reflection services, and the CNI backend if it is not nil
+func newGRPCServer(backend rpc.CNIBackendServer, opts ...grpc.ServerOption) *grpc.Server {
+	grpcServer := grpc.NewServer(opts...)
+	if backend != nil {
+		rpc.RegisterCNIBackendServer(grpcServer, backend)
+	}
 	healthServer := health.NewServer()
 	// If ipamd can talk to the API server and to the EC2 API, the pod is healthy.
 	// No need to ever change this to HealthCheckResponse_NOT_SERVING since it's a local service only
@@ -307,13 +539,105 @@ func (c *IPAMContext) RunRPCHandler(version string) error {
 
 	// Register reflection service on gRPC server.
 	reflection.Register(grpcServer)
-	// Add shutdown hook
-	go c.shutdownListener()
-	if err := grpcServer.Serve(listener); err != nil {
-		log.Errorf("Failed to start server on gRPC port: %v", err)
-		return errors.Wrap(err, "ipamd: failed to start server on gPRC port")
+	return grpcServer
+}
+
+// listenUnixSocket listens on a Unix socket that only root can connect to. The socket is created in a directory that
+// only root can enter and moved to path once its permissions are set, so that it is never reachable by other users.
+// The socket file of a previous ipamd is replaced.
+func listenUnixSocket(path string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".ipamd-sock-")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create a private directory for socket %s", path)
 	}
-	return nil
+	defer os.RemoveAll(dir)
+
+	tmpPath := filepath.Join(dir, filepath.Base(path))
+	listener, err := net.Listen("unix", tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	// The socket file is moved, ipamd doesn't remove it on close and replaces it on the next start
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmpPath, 0600); err != nil {
+		listener.Close()
+		return nil, errors.Wrapf(err, "failed to set the permissions of socket %s", path)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		listener.Close()
+		return nil, errors.Wrapf(err, "failed to move socket to %s", path)
+	}
+	return listener, nil
+}
+
+// tcpBackendGate serves the CNI backend on the TCP port until the CNI config file on the host calls ipamd on the
+// Unix socket. Pods are still added by the plugin of the previous version, which calls the port, until aws-node
+// writes the new config file after ipamd is up.
+type tcpBackendGate struct {
+	conflistPath string
+	closed       int32
+}
+
+func newTCPBackendGate(conflistPath string) *tcpBackendGate {
+	return &tcpBackendGate{conflistPath: conflistPath}
+}
+
+// open returns true while the CNI config file doesn't use the Unix socket. Once it does, the gate stays closed.
+func (g *tcpBackendGate) open() bool {
+	if atomic.LoadInt32(&g.closed) == 1 {
+		return false
+	}
+	if !conflistUsesIpamdSocket(g.conflistPath) {
+		return true
+	}
+	if atomic.CompareAndSwapInt32(&g.closed, 0, 1) {
+		log.Infof("%s calls ipamd on %s, no longer serving the CNI backend on %s", g.conflistPath, ipamdgRPCSocketPath, ipamdgRPCaddress)
+	}
+	return false
+}
+
+// unaryInterceptor rejects CNI backend calls on the TCP port once the gate is closed, like a server without the service
+func (g *tcpBackendGate) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if strings.HasPrefix(info.FullMethod, cniBackendMethodPrefix) && !g.open() {
+		return nil, status.Errorf(codes.Unimplemented, "unknown method %s", info.FullMethod)
+	}
+	return handler(ctx, req)
+}
+
+// conflistUsesIpamdSocket returns true if the aws-cni plugin in the CNI config file calls ipamd on its Unix socket
+func conflistUsesIpamdSocket(conflistPath string) bool {
+	data, err := os.ReadFile(conflistPath)
+	if err != nil {
+		return false
+	}
+	var conflist struct {
+		Plugins []struct {
+			Type            string `json:"type"`
+			IpamdSocketPath string `json:"ipamdSocketPath"`
+		} `json:"plugins"`
+	}
+	if err := json.Unmarshal(data, &conflist); err != nil {
+		return false
+	}
+	for _, plugin := range conflist.Plugins {
+		if plugin.Type == "aws-cni" && plugin.IpamdSocketPath != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func hostCNIConflistPath() string {
+	dir := defaultHostCniConfDirPath
+	if value := os.Getenv(envHostCniConfDirPath); value != "" {
+		dir = value
+	}
+	return filepath.Join(dir, awsConflistFile)
+}
+
+func enableIpamdTCPListener() bool {
+	return getEnvBoolWithDefault(envEnableIpamdTCPListener, false)
 }
 
 // shutdownListener - Listen to signals and set ipamd to be in status "terminating"
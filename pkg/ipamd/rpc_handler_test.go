@@ -16,6 +16,8 @@ package ipamd
 import (
 	"context"
 	"net"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
@@ -23,6 +25,9 @@ import (
 	pb "github.com/aws/amazon-vpc-cni-k8s/rpc"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestServer_VersionCheck(t *testing.T) {
@@ -31,6 +36,7 @@ func TestServer_VersionCheck(t *testing.T) {
 
 	mockContext := &IPAMContext{
 		awsClient:     m.awsutils,
+		k8sClient:     m.k8sClient,
 		maxIPsPerENI:  14,
 		maxENI:        4,
 		warmENITarget: 1,
@@ -66,8 +72,11 @@ func TestServer_VersionCheck(t *testing.T) {
 		ContainerID:   "cid",
 		IfName:        "eni",
 	}
-	_, err = rpcServer.DelNetwork(context.TODO(), delReq)
-	assert.EqualError(t, err, datastore.ErrUnknownPod.Error())
+	delResp, err := rpcServer.DelNetwork(context.TODO(), delReq)
+	assert.NoError(t, err)
+	assert.False(t, delResp.Success)
+	assert.Equal(t, pb.ErrorReason_POD_NOT_FOUND, delResp.ErrorReason)
+	assert.Equal(t, datastore.ErrUnknownPod.Error(), delResp.ErrorMessage)
 
 	// Sad path
 
@@ -182,6 +191,8 @@ func TestServer_AddNetwork(t *testing.T) {
 			want: &pb.AddNetworkReply{
 				Success:      false,
 				DeviceNumber: int32(-1),
+				ErrorReason:  pb.ErrorReason_POOL_EMPTY,
+				ErrorMessage: ipPoolExhaustedMessage,
 			},
 		},
 		{
@@ -217,6 +228,8 @@ func TestServer_AddNetwork(t *testing.T) {
 			want: &pb.AddNetworkReply{
 				Success:      false,
 				DeviceNumber: int32(-1),
+				ErrorReason:  pb.ErrorReason_POOL_EMPTY,
+				ErrorMessage: ipPoolExhaustedMessage,
 			},
 		},
 		{
@@ -233,6 +246,8 @@ func TestServer_AddNetwork(t *testing.T) {
 				Success:      false,
 				IPv6Addr:     "",
 				DeviceNumber: int32(-1),
+				ErrorReason:  pb.ErrorReason_POOL_EMPTY,
+				ErrorMessage: ipPoolExhaustedMessage,
 			},
 		},
 	}
@@ -271,6 +286,7 @@ func TestServer_AddNetwork(t *testing.T) {
 
 			mockContext := &IPAMContext{
 				awsClient:              m.awsutils,
+				k8sClient:              m.k8sClient,
 				maxIPsPerENI:           14,
 				maxENI:                 4,
 				warmENITarget:          1,
@@ -305,3 +321,107 @@ func TestServer_AddNetwork(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_CheckNetwork(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	ds.AddENI(secENIid, 1, false, false, false)
+	ds.AddIPv4CidrToStore(secENIid, net.IPNet{IP: net.ParseIP(ipaddr01), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	_, _, err := ds.AssignPodIPv4Address(datastore.IPAMKey{NetworkName: "net0", ContainerID: "cid", IfName: "eth0"}, datastore.IPAMMetadata{})
+	assert.NoError(t, err)
+
+	s := &server{
+		version: "1.2.3",
+		ipamContext: &IPAMContext{
+			awsClient:     m.awsutils,
+			k8sClient:     m.k8sClient,
+			networkClient: m.network,
+			enableIPv4:    true,
+			dataStore:     ds,
+		},
+	}
+
+	req := &pb.CheckNetworkRequest{
+		ClientVersion: "1.2.3",
+		NetworkName:   "net0",
+		ContainerID:   "cid",
+		IfName:        "eth0",
+	}
+	resp, err := s.CheckNetwork(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, &pb.CheckNetworkReply{Success: true, IPv4Addr: ipaddr01, DeviceNumber: 1}, resp)
+
+	// The allocation is not touched by the check
+	resp, err = s.CheckNetwork(context.Background(), req)
+	assert.NoError(t, err)
+	assert.True(t, resp.Success)
+
+	// Unknown sandbox
+	req.ContainerID = "other"
+	resp, err = s.CheckNetwork(context.Background(), req)
+	assert.NoError(t, err)
+	assert.False(t, resp.Success)
+
+	// Wrong client version
+	req.ClientVersion = "1.2.4"
+	_, err = s.CheckNetwork(context.Background(), req)
+	assert.Error(t, err)
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipamd.sock")
+	// The socket of a previous ipamd is replaced
+	err := os.WriteFile(path, nil, 0644)
+	assert.NoError(t, err)
+
+	listener, err := listenUnixSocket(path)
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.ModeSocket|0600, info.Mode())
+
+	conn, err := net.Dial("unix", path)
+	assert.NoError(t, err)
+	conn.Close()
+
+	// Only the socket is left in the directory
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestTCPBackendGate(t *testing.T) {
+	conflistPath := filepath.Join(t.TempDir(), "10-aws.conflist")
+	gate := newTCPBackendGate(conflistPath)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "handled", nil
+	}
+	addNetwork := &grpc.UnaryServerInfo{FullMethod: "/rpc.CNIBackend/AddNetwork"}
+	healthCheck := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+
+	// Without a config file, or with one of the previous version, the backend is served
+	assert.True(t, gate.open())
+	err := os.WriteFile(conflistPath, []byte(`{"plugins":[{"type":"aws-cni","vethPrefix":"eni"}]}`), 0644)
+	assert.NoError(t, err)
+	resp, err := gate.unaryInterceptor(context.Background(), nil, addNetwork, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "handled", resp)
+
+	// Once the config file uses the socket, only the health service is served
+	err = os.WriteFile(conflistPath, []byte(`{"plugins":[{"type":"aws-cni","ipamdSocketPath":"/var/run/aws-node/ipamd.sock"}]}`), 0644)
+	assert.NoError(t, err)
+	_, err = gate.unaryInterceptor(context.Background(), nil, addNetwork, handler)
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	resp, err = gate.unaryInterceptor(context.Background(), nil, healthCheck, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "handled", resp)
+
+	// The gate stays closed
+	err = os.Remove(conflistPath)
+	assert.NoError(t, err)
+	assert.False(t, gate.open())
+}
@@ -0,0 +1,212 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	// envIpamdTLSCertFile, envIpamdTLSKeyFile and envIpamdTLSCAFile are the certificate and key of ipamd, and the CA
+	// that signs the certificates of clients. The CNI backend is served with mutual TLS when all three are set.
+	envIpamdTLSCertFile = "IPAMD_TLS_CERT_FILE"
+	envIpamdTLSKeyFile  = "IPAMD_TLS_KEY_FILE"
+	envIpamdTLSCAFile   = "IPAMD_TLS_CA_FILE"
+
+	// envIpamdTLSAddress is the address that the CNI backend is served on with mutual TLS
+	envIpamdTLSAddress     = "IPAMD_TLS_ADDRESS"
+	defaultIpamdTLSAddress = "127.0.0.1:50052"
+
+	// envIpamdTLSAllowedClients is a comma separated list of the identities that can call the CNI backend with
+	// mutual TLS. The identity of a client is the common name or any DNS or URI SAN of its certificate.
+	envIpamdTLSAllowedClients     = "IPAMD_TLS_ALLOWED_CLIENTS"
+	defaultIpamdTLSAllowedClients = "aws-cni"
+)
+
+// ipamdTLSConfig is the mutual TLS configuration of the CNI backend
+type ipamdTLSConfig struct {
+	address        string
+	certFile       string
+	keyFile        string
+	caFile         string
+	allowedClients map[string]bool
+}
+
+// getIpamdTLSConfig returns nil if mutual TLS is not configured
+func getIpamdTLSConfig() *ipamdTLSConfig {
+	certFile := os.Getenv(envIpamdTLSCertFile)
+	keyFile := os.Getenv(envIpamdTLSKeyFile)
+	caFile := os.Getenv(envIpamdTLSCAFile)
+	if certFile == "" || keyFile == "" || caFile == "" {
+		if certFile != "" || keyFile != "" || caFile != "" {
+			log.Warnf("Mutual TLS is disabled, %s, %s and %s must all be set", envIpamdTLSCertFile, envIpamdTLSKeyFile, envIpamdTLSCAFile)
+		}
+		return nil
+	}
+
+	address := os.Getenv(envIpamdTLSAddress)
+	if address == "" {
+		address = defaultIpamdTLSAddress
+	}
+	allowedClients := os.Getenv(envIpamdTLSAllowedClients)
+	if allowedClients == "" {
+		allowedClients = defaultIpamdTLSAllowedClients
+	}
+	config := &ipamdTLSConfig{
+		address:        address,
+		certFile:       certFile,
+		keyFile:        keyFile,
+		caFile:         caFile,
+		allowedClients: make(map[string]bool),
+	}
+	for _, client := range strings.Split(allowedClients, ",") {
+		if client = strings.TrimSpace(client); client != "" {
+			config.allowedClients[client] = true
+		}
+	}
+	return config
+}
+
+// certReloader reloads the certificate and the CA of ipamd when their files change, so that rotated certificates are
+// used for new connections without restarting ipamd
+type certReloader struct {
+	config *ipamdTLSConfig
+
+	lock    sync.Mutex
+	modTime map[string]time.Time
+	cert    *tls.Certificate
+	caPool  *x509.CertPool
+}
+
+func newCertReloader(config *ipamdTLSConfig) (*certReloader, error) {
+	r := &certReloader{config: config}
+	if _, _, err := r.get(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// get returns the current certificate and CA pool. The files are read again if any of them was modified. If the new
+// files can't be loaded, for example in the middle of a rotation, the previous ones are kept.
+func (r *certReloader) get() (*tls.Certificate, *x509.CertPool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	modTime := make(map[string]time.Time)
+	for _, file := range []string{r.config.certFile, r.config.keyFile, r.config.caFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			if r.cert != nil {
+				log.Warnf("Keep the current TLS certificate, failed to stat %s: %v", file, err)
+				return r.cert, r.caPool, nil
+			}
+			return nil, nil, errors.Wrapf(err, "failed to stat %s", file)
+		}
+		modTime[file] = info.ModTime()
+	}
+	if r.cert != nil && equalModTimes(modTime, r.modTime) {
+		return r.cert, r.caPool, nil
+	}
+
+	cert, caPool, err := loadCertAndCA(r.config.certFile, r.config.keyFile, r.config.caFile)
+	if err != nil {
+		if r.cert != nil {
+			log.Warnf("Keep the current TLS certificate, failed to reload: %v", err)
+			return r.cert, r.caPool, nil
+		}
+		return nil, nil, err
+	}
+	log.Infof("Loaded the TLS certificate %s and the CA %s", r.config.certFile, r.config.caFile)
+	r.cert, r.caPool, r.modTime = &cert, caPool, modTime
+	return r.cert, r.caPool, nil
+}
+
+func equalModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, modTime := range a {
+		if !modTime.Equal(b[file]) {
+			return false
+		}
+	}
+	return true
+}
+
+func loadCertAndCA(certFile, keyFile, caFile string) (tls.Certificate, *x509.CertPool, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, nil, errors.Wrap(err, "failed to load the TLS certificate")
+	}
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return tls.Certificate{}, nil, errors.Wrap(err, "failed to read the CA")
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caPEM) {
+		return tls.Certificate{}, nil, errors.Errorf("no certificate found in the CA %s", caFile)
+	}
+	return cert, caPool, nil
+}
+
+// serverTLSConfig requires a client certificate signed by the CA, with one of the allowed identities
+func (r *certReloader) serverTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, caPool, err := r.get()
+			if err != nil {
+				return nil, err
+			}
+			return &tls.Config{
+				MinVersion:            tls.VersionTLS12,
+				Certificates:          []tls.Certificate{*cert},
+				ClientCAs:             caPool,
+				ClientAuth:            tls.RequireAndVerifyClientCert,
+				VerifyPeerCertificate: r.verifyClientIdentity,
+			}, nil
+		},
+	}
+}
+
+// verifyClientIdentity is called after the client certificate was verified against the CA
+func (r *certReloader) verifyClientIdentity(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+	if len(verifiedChains) == 0 || len(verifiedChains[0]) == 0 {
+		return errors.New("no verified client certificate")
+	}
+	leaf := verifiedChains[0][0]
+	identities := append([]string{leaf.Subject.CommonName}, leaf.DNSNames...)
+	for _, uri := range leaf.URIs {
+		identities = append(identities, uri.String())
+	}
+	for _, identity := range identities {
+		if r.config.allowedClients[identity] {
+			return nil
+		}
+	}
+	log.Warnf("Rejecting TLS client %v, it is not in %s", identities, envIpamdTLSAllowedClients)
+	return errors.Errorf("client %s is not allowed", leaf.Subject.CommonName)
+}
+
+func (r *certReloader) serverCredentials() credentials.TransportCredentials {
+	return credentials.NewTLS(r.serverTLSConfig())
+}
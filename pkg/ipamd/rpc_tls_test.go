@@ -0,0 +1,171 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/grpcwrapper"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+// writeCert writes the certificate and key of name, signed by the CA, to dir
+func (ca *testCA) writeCert(t *testing.T, dir, name string, serial int64) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+func (ca *testCA) writeCA(t *testing.T, dir string) string {
+	caFile := filepath.Join(dir, "ca.crt")
+	assert.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0600))
+	return caFile
+}
+
+func TestGetIpamdTLSConfig(t *testing.T) {
+	os.Setenv(envIpamdTLSCertFile, "/etc/ipamd/tls.crt")
+	os.Setenv(envIpamdTLSKeyFile, "/etc/ipamd/tls.key")
+	defer os.Unsetenv(envIpamdTLSCertFile)
+	defer os.Unsetenv(envIpamdTLSKeyFile)
+	assert.Nil(t, getIpamdTLSConfig())
+
+	os.Setenv(envIpamdTLSCAFile, "/etc/ipamd/ca.crt")
+	defer os.Unsetenv(envIpamdTLSCAFile)
+	config := getIpamdTLSConfig()
+	assert.Equal(t, defaultIpamdTLSAddress, config.address)
+	assert.Equal(t, map[string]bool{defaultIpamdTLSAllowedClients: true}, config.allowedClients)
+
+	os.Setenv(envIpamdTLSAllowedClients, "agent-a, spiffe://cluster/agent-b")
+	defer os.Unsetenv(envIpamdTLSAllowedClients)
+	config = getIpamdTLSConfig()
+	assert.Equal(t, map[string]bool{"agent-a": true, "spiffe://cluster/agent-b": true}, config.allowedClients)
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := ca.writeCA(t, dir)
+	certFile, keyFile := ca.writeCert(t, dir, "ipamd", 2)
+	allowedCertFile, allowedKeyFile := ca.writeCert(t, dir, "aws-cni", 3)
+	otherCertFile, otherKeyFile := ca.writeCert(t, dir, "other-agent", 4)
+
+	reloader, err := newCertReloader(&ipamdTLSConfig{
+		certFile:       certFile,
+		keyFile:        keyFile,
+		caFile:         caFile,
+		allowedClients: map[string]bool{"aws-cni": true},
+	})
+	assert.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	grpcServer := newGRPCServer(nil, grpc.Creds(reloader.serverCredentials()))
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	checkHealth := func(clientCertFile, clientKeyFile string) error {
+		tlsOption, err := grpcwrapper.WithTLS(&grpcwrapper.TLSConfig{
+			CertFile:   clientCertFile,
+			KeyFile:    clientKeyFile,
+			CAFile:     caFile,
+			ServerName: "ipamd",
+		})
+		assert.NoError(t, err)
+		conn, err := grpc.Dial(listener.Addr().String(), tlsOption)
+		assert.NoError(t, err)
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: grpcHealthServiceName})
+		return err
+	}
+
+	assert.NoError(t, checkHealth(allowedCertFile, allowedKeyFile))
+	// The client certificate is signed by the CA, but the client is not allowed
+	assert.Error(t, checkHealth(otherCertFile, otherKeyFile))
+
+	// A rotated certificate is used for new connections
+	cert, _, err := reloader.get()
+	assert.NoError(t, err)
+	ca.writeCert(t, dir, "ipamd", 5)
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(certFile, later, later))
+	rotated, _, err := reloader.get()
+	assert.NoError(t, err)
+	assert.NotEqual(t, cert.Certificate[0], rotated.Certificate[0])
+	assert.NoError(t, checkHealth(allowedCertFile, allowedKeyFile))
+
+	// A broken certificate keeps the current one
+	assert.NoError(t, os.WriteFile(certFile, []byte("garbage"), 0600))
+	current, _, err := reloader.get()
+	assert.NoError(t, err)
+	assert.Equal(t, rotated, current)
+}
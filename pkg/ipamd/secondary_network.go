@@ -0,0 +1,59 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"os"
+	"strings"
+)
+
+// getSecondaryNetworks returns the ENIConfig of each secondary network. Entries without a network name or an ENIConfig
+// are ignored.
+func getSecondaryNetworks() map[string]string {
+	return parseSecondaryNetworks(os.Getenv(envSecondaryNetworks))
+}
+
+func parseSecondaryNetworks(value string) map[string]string {
+	networks := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		networkName, eniConfigName, found := strings.Cut(entry, "=")
+		networkName, eniConfigName = strings.TrimSpace(networkName), strings.TrimSpace(eniConfigName)
+		if !found || networkName == "" || eniConfigName == "" {
+			log.Warnf("Ignoring invalid secondary network %q in %s, expected network=eniconfig", entry, envSecondaryNetworks)
+			continue
+		}
+		if other, ok := networks[networkName]; ok {
+			log.Warnf("Ignoring ENIConfig %s of secondary network %s, it already uses ENIConfig %s", eniConfigName, networkName, other)
+			continue
+		}
+		networks[networkName] = eniConfigName
+	}
+	return networks
+}
+
+// getSecondaryNetworkENIConfig returns the ENIConfig that the IPs of a secondary network come from, or an empty string
+// if the network is the one of the node's ENIs
+func (c *IPAMContext) getSecondaryNetworkENIConfig(networkName string) string {
+	return c.secondaryNetworks[networkName]
+}
+
+// useENIConfigENIs returns true if ENIs are allocated for ENIConfigs other than the one of the node, either for pods
+// that ask for an ENIConfig or for secondary networks
+func (c *IPAMContext) useENIConfigENIs() bool {
+	return c.enablePodENIConfig || len(c.secondaryNetworks) > 0
+}
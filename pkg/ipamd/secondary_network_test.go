@@ -0,0 +1,128 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	pb "github.com/aws/amazon-vpc-cni-k8s/rpc"
+)
+
+func TestParseSecondaryNetworks(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  map[string]string
+	}{
+		{name: "empty", value: "", want: map[string]string{}},
+		{name: "single network", value: "net-a=eniconfig-a", want: map[string]string{"net-a": "eniconfig-a"}},
+		{
+			name:  "several networks with spaces",
+			value: " net-a = eniconfig-a , net-b=eniconfig-b,",
+			want:  map[string]string{"net-a": "eniconfig-a", "net-b": "eniconfig-b"},
+		},
+		{
+			name:  "invalid entries are ignored",
+			value: "net-a,=eniconfig-b,net-c=,net-d=eniconfig-d",
+			want:  map[string]string{"net-d": "eniconfig-d"},
+		},
+		{
+			name:  "the first ENIConfig of a network is used",
+			value: "net-a=eniconfig-a,net-a=eniconfig-b",
+			want:  map[string]string{"net-a": "eniconfig-a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseSecondaryNetworks(tt.value))
+		})
+	}
+}
+
+func TestServer_AddNetworkSecondaryNetwork(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	_ = ds.AddENI(primaryENIid, primaryDevice, true, false, false)
+	_ = ds.AddIPv4CidrToStore(primaryENIid, net.IPNet{IP: net.ParseIP(ipaddr01), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	_ = ds.AddENI(secENIid, secDevice, false, false, false)
+	_ = ds.SetENIConfig(secENIid, "eniconfig-a")
+	_ = ds.AddIPv4CidrToStore(secENIid, net.IPNet{IP: net.ParseIP(ipaddr11), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+
+	mockContext := &IPAMContext{
+		awsClient:         m.awsutils,
+		k8sClient:         m.k8sClient,
+		networkClient:     m.network,
+		enableIPv4:        true,
+		secondaryNetworks: map[string]string{"net-a": "eniconfig-a", "net-b": "eniconfig-b"},
+		dataStore:         ds,
+	}
+	s := &server{version: "1.2.3", ipamContext: mockContext}
+
+	m.awsutils.EXPECT().GetVPCIPv4CIDRs().Return([]string{"10.10.0.0/16"}, nil).Times(2)
+	m.network.EXPECT().UseExternalSNAT().Return(true).Times(2)
+
+	// The main interface gets its IP from the node's ENIs
+	resp, err := s.AddNetwork(context.Background(), &pb.AddNetworkRequest{
+		ClientVersion: "1.2.3",
+		NetworkName:   "aws-cni",
+		ContainerID:   "cid",
+		IfName:        "eth0",
+	})
+	assert.NoError(t, err)
+	assert.True(t, resp.Success)
+	assert.Equal(t, ipaddr01, resp.IPv4Addr)
+	assert.Equal(t, int32(primaryDevice), resp.DeviceNumber)
+	assert.False(t, resp.SecondaryInterface)
+
+	// The secondary interface gets its IP from the ENIs of the ENIConfig of its network
+	resp, err = s.AddNetwork(context.Background(), &pb.AddNetworkRequest{
+		ClientVersion: "1.2.3",
+		NetworkName:   "net-a",
+		ContainerID:   "cid",
+		IfName:        "net1",
+	})
+	assert.NoError(t, err)
+	assert.True(t, resp.Success)
+	assert.Equal(t, ipaddr11, resp.IPv4Addr)
+	assert.Equal(t, int32(secDevice), resp.DeviceNumber)
+	assert.True(t, resp.SecondaryInterface)
+
+	check, err := s.CheckNetwork(context.Background(), &pb.CheckNetworkRequest{
+		ClientVersion: "1.2.3",
+		NetworkName:   "net-a",
+		ContainerID:   "cid",
+		IfName:        "net1",
+	})
+	assert.NoError(t, err)
+	assert.True(t, check.SecondaryInterface)
+
+	// The ENIs of another network are grown on demand
+	resp, err = s.AddNetwork(context.Background(), &pb.AddNetworkRequest{
+		ClientVersion: "1.2.3",
+		NetworkName:   "net-b",
+		ContainerID:   "cid",
+		IfName:        "net2",
+	})
+	assert.NoError(t, err)
+	assert.False(t, resp.Success)
+	assert.Equal(t, pb.ErrorReason_POOL_EMPTY, resp.ErrorReason)
+	assert.Equal(t, map[string]bool{"eniconfig-b": true}, mockContext.takePodENIConfigsShort())
+}
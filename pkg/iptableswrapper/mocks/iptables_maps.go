@@ -44,7 +44,12 @@ func (ipt *MockIptables) Insert(table, chain string, pos int, rulespec ...string
 	if ipt.DataplaneState[table] == nil {
 		ipt.DataplaneState[table] = map[string][][]string{}
 	}
-	ipt.DataplaneState[table][chain] = append(ipt.DataplaneState[table][chain], rulespec)
+	rules := ipt.DataplaneState[table][chain]
+	if pos < 1 || pos > len(rules)+1 {
+		return errors.Errorf("index of insertion too big: %d", pos)
+	}
+	rules = append(rules[:pos-1], append([][]string{rulespec}, rules[pos-1:]...)...)
+	ipt.DataplaneState[table][chain] = rules
 	return nil
 }
 
@@ -109,10 +114,14 @@ func (ipt *MockIptables) NewChain(table, chain string) error {
 }
 
 func (ipt *MockIptables) ClearChain(table, chain string) error {
+	if _, ok := ipt.DataplaneState[table][chain]; ok {
+		ipt.DataplaneState[table][chain] = [][]string{}
+	}
 	return nil
 }
 
 func (ipt *MockIptables) DeleteChain(table, chain string) error {
+	delete(ipt.DataplaneState[table], chain)
 	return nil
 }
 
@@ -26,19 +26,29 @@ import (
 
 const (
 	awsNode = "aws-node"
+
+	// EgressIPConfigMapName is the ConfigMap that lists the egress IPs of each namespace
+	EgressIPConfigMapName = "amazon-vpc-cni-egress-ips"
+	// EgressIPConfigMapNamespace is the namespace of the egress IP ConfigMap
+	EgressIPConfigMapNamespace = "kube-system"
 )
 
 var log = logger.Get()
 
 // Get cache filters for IPAMD
 func getIPAMDCacheFilters() map[client.Object]cache.ByObject {
+	filters := map[client.Object]cache.ByObject{
+		// The egress IP ConfigMap is the only ConfigMap read by IPAMD
+		&corev1.ConfigMap{}: {
+			Namespaces: map[string]cache.Config{EgressIPConfigMapNamespace: {}},
+			Field:      fields.Set{"metadata.name": EgressIPConfigMapName}.AsSelector(),
+		}}
 	if nodeName := os.Getenv("MY_NODE_NAME"); nodeName != "" {
-		return map[client.Object]cache.ByObject{
-			&corev1.Pod{}: {
-				Field: fields.Set{"spec.nodeName": nodeName}.AsSelector(),
-			}}
+		filters[&corev1.Pod{}] = cache.ByObject{
+			Field: fields.Set{"spec.nodeName": nodeName}.AsSelector(),
+		}
 	}
-	return nil
+	return filters
 }
 
 // Get cache filters for CNI Metrics Helper
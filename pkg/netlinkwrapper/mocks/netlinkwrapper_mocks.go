@@ -91,6 +91,35 @@ func (mr *MockNetLinkMockRecorder) AddrList(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddrList", reflect.TypeOf((*MockNetLink)(nil).AddrList), arg0, arg1)
 }
 
+// FilterList mocks base method.
+func (m *MockNetLink) FilterList(arg0 netlink.Link, arg1 uint32) ([]netlink.Filter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FilterList", arg0, arg1)
+	ret0, _ := ret[0].([]netlink.Filter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FilterList indicates an expected call of FilterList.
+func (mr *MockNetLinkMockRecorder) FilterList(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilterList", reflect.TypeOf((*MockNetLink)(nil).FilterList), arg0, arg1)
+}
+
+// FilterReplace mocks base method.
+func (m *MockNetLink) FilterReplace(arg0 netlink.Filter) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FilterReplace", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FilterReplace indicates an expected call of FilterReplace.
+func (mr *MockNetLinkMockRecorder) FilterReplace(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilterReplace", reflect.TypeOf((*MockNetLink)(nil).FilterReplace), arg0)
+}
+
 // LinkAdd mocks base method.
 func (m *MockNetLink) LinkAdd(arg0 netlink.Link) error {
 	m.ctrl.T.Helper()
@@ -149,6 +178,20 @@ func (mr *MockNetLinkMockRecorder) LinkList() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkList", reflect.TypeOf((*MockNetLink)(nil).LinkList))
 }
 
+// LinkSetAlias mocks base method.
+func (m *MockNetLink) LinkSetAlias(arg0 netlink.Link, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkSetAlias", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LinkSetAlias indicates an expected call of LinkSetAlias.
+func (mr *MockNetLinkMockRecorder) LinkSetAlias(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkSetAlias", reflect.TypeOf((*MockNetLink)(nil).LinkSetAlias), arg0, arg1)
+}
+
 // LinkSetDown mocks base method.
 func (m *MockNetLink) LinkSetDown(arg0 netlink.Link) error {
 	m.ctrl.T.Helper()
@@ -177,6 +220,20 @@ func (mr *MockNetLinkMockRecorder) LinkSetMTU(arg0, arg1 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkSetMTU", reflect.TypeOf((*MockNetLink)(nil).LinkSetMTU), arg0, arg1)
 }
 
+// LinkSetName mocks base method.
+func (m *MockNetLink) LinkSetName(arg0 netlink.Link, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkSetName", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LinkSetName indicates an expected call of LinkSetName.
+func (mr *MockNetLinkMockRecorder) LinkSetName(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkSetName", reflect.TypeOf((*MockNetLink)(nil).LinkSetName), arg0, arg1)
+}
+
 // LinkSetNsFd mocks base method.
 func (m *MockNetLink) LinkSetNsFd(arg0 netlink.Link, arg1 int) error {
 	m.ctrl.T.Helper()
@@ -248,6 +305,20 @@ func (mr *MockNetLinkMockRecorder) ParseAddr(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseAddr", reflect.TypeOf((*MockNetLink)(nil).ParseAddr), arg0)
 }
 
+// QdiscReplace mocks base method.
+func (m *MockNetLink) QdiscReplace(arg0 netlink.Qdisc) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QdiscReplace", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// QdiscReplace indicates an expected call of QdiscReplace.
+func (mr *MockNetLinkMockRecorder) QdiscReplace(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QdiscReplace", reflect.TypeOf((*MockNetLink)(nil).QdiscReplace), arg0)
+}
+
 // RouteAdd mocks base method.
 func (m *MockNetLink) RouteAdd(arg0 *netlink.Route) error {
 	m.ctrl.T.Helper()
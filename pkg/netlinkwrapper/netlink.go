@@ -64,6 +64,16 @@ type NetLink interface {
 	RuleList(family int) ([]netlink.Rule, error)
 	// LinkSetMTU is equivalent to `ip link set dev $link mtu $mtu`
 	LinkSetMTU(link netlink.Link, mtu int) error
+	// LinkSetName is equivalent to `ip link set dev $link name $name`
+	LinkSetName(link netlink.Link, name string) error
+	// LinkSetAlias is equivalent to `ip link set dev $link alias $alias`
+	LinkSetAlias(link netlink.Link, alias string) error
+	// QdiscReplace is equivalent to `tc qdisc replace`
+	QdiscReplace(qdisc netlink.Qdisc) error
+	// FilterReplace is equivalent to `tc filter replace`
+	FilterReplace(filter netlink.Filter) error
+	// FilterList is equivalent to `tc filter show dev $link parent $parent`
+	FilterList(link netlink.Link, parent uint32) ([]netlink.Filter, error)
 }
 
 type netLink struct {
@@ -158,6 +168,26 @@ func (*netLink) LinkSetMTU(link netlink.Link, mtu int) error {
 	return netlink.LinkSetMTU(link, mtu)
 }
 
+func (*netLink) LinkSetName(link netlink.Link, name string) error {
+	return netlink.LinkSetName(link, name)
+}
+
+func (*netLink) LinkSetAlias(link netlink.Link, alias string) error {
+	return netlink.LinkSetAlias(link, alias)
+}
+
+func (*netLink) QdiscReplace(qdisc netlink.Qdisc) error {
+	return netlink.QdiscReplace(qdisc)
+}
+
+func (*netLink) FilterReplace(filter netlink.Filter) error {
+	return netlink.FilterReplace(filter)
+}
+
+func (*netLink) FilterList(link netlink.Link, parent uint32) ([]netlink.Filter, error) {
+	return netlink.FilterList(link, parent)
+}
+
 // IsNotExistsError returns true if the error type is syscall.ESRCH
 // This helps us determine if we should ignore this error as the route
 // that we want to cleanup has been deleted already routing table
@@ -123,6 +123,34 @@ func (mr *MockNetworkAPIsMockRecorder) GetRuleListBySrc(arg0, arg1 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRuleListBySrc", reflect.TypeOf((*MockNetworkAPIs)(nil).GetRuleListBySrc), arg0, arg1)
 }
 
+// RemoveVPCCIDRRules mocks base method.
+func (m *MockNetworkAPIs) RemoveVPCCIDRRules(arg0 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveVPCCIDRRules", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveVPCCIDRRules indicates an expected call of RemoveVPCCIDRRules.
+func (mr *MockNetworkAPIsMockRecorder) RemoveVPCCIDRRules(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveVPCCIDRRules", reflect.TypeOf((*MockNetworkAPIs)(nil).RemoveVPCCIDRRules), arg0)
+}
+
+// RestoreLinkName mocks base method.
+func (m *MockNetworkAPIs) RestoreLinkName(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreLinkName", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreLinkName indicates an expected call of RestoreLinkName.
+func (mr *MockNetworkAPIsMockRecorder) RestoreLinkName(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreLinkName", reflect.TypeOf((*MockNetworkAPIs)(nil).RestoreLinkName), arg0)
+}
+
 // SetupENINetwork mocks base method.
 func (m *MockNetworkAPIs) SetupENINetwork(arg0, arg1 string, arg2 int, arg3 string) error {
 	m.ctrl.T.Helper()
@@ -137,6 +165,20 @@ func (mr *MockNetworkAPIsMockRecorder) SetupENINetwork(arg0, arg1, arg2, arg3 in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetupENINetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).SetupENINetwork), arg0, arg1, arg2, arg3)
 }
 
+// SetupIPv6ENINetwork mocks base method.
+func (m *MockNetworkAPIs) SetupIPv6ENINetwork(arg0 string, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetupIPv6ENINetwork", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetupIPv6ENINetwork indicates an expected call of SetupIPv6ENINetwork.
+func (mr *MockNetworkAPIsMockRecorder) SetupIPv6ENINetwork(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetupIPv6ENINetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).SetupIPv6ENINetwork), arg0, arg1)
+}
+
 // SetupHostNetwork mocks base method.
 func (m *MockNetworkAPIs) SetupHostNetwork(arg0 []string, arg1 string, arg2 *net.IP, arg3, arg4, arg5 bool) error {
 	m.ctrl.T.Helper()
@@ -151,6 +193,34 @@ func (mr *MockNetworkAPIsMockRecorder) SetupHostNetwork(arg0, arg1, arg2, arg3,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetupHostNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).SetupHostNetwork), arg0, arg1, arg2, arg3, arg4, arg5)
 }
 
+// SetupRuleBySrc mocks base method.
+func (m *MockNetworkAPIs) SetupRuleBySrc(arg0 []netlink.Rule, arg1 net.IPNet, arg2 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetupRuleBySrc", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetupRuleBySrc indicates an expected call of SetupRuleBySrc.
+func (mr *MockNetworkAPIsMockRecorder) SetupRuleBySrc(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetupRuleBySrc", reflect.TypeOf((*MockNetworkAPIs)(nil).SetupRuleBySrc), arg0, arg1, arg2)
+}
+
+// UpdateEgressIPRules mocks base method.
+func (m *MockNetworkAPIs) UpdateEgressIPRules(arg0 map[string][]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEgressIPRules", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateEgressIPRules indicates an expected call of UpdateEgressIPRules.
+func (mr *MockNetworkAPIsMockRecorder) UpdateEgressIPRules(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEgressIPRules", reflect.TypeOf((*MockNetworkAPIs)(nil).UpdateEgressIPRules), arg0)
+}
+
 // UpdateExternalServiceIpRules mocks base method.
 func (m *MockNetworkAPIs) UpdateExternalServiceIpRules(arg0 []netlink.Rule, arg1 []string) error {
 	m.ctrl.T.Helper()
@@ -179,6 +249,20 @@ func (mr *MockNetworkAPIsMockRecorder) UpdateHostIptablesRules(arg0, arg1, arg2,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateHostIptablesRules", reflect.TypeOf((*MockNetworkAPIs)(nil).UpdateHostIptablesRules), arg0, arg1, arg2, arg3, arg4)
 }
 
+// UpdatePodRoutingMarkRules mocks base method.
+func (m *MockNetworkAPIs) UpdatePodRoutingMarkRules(arg0 []netlink.Rule, arg1 []int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePodRoutingMarkRules", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePodRoutingMarkRules indicates an expected call of UpdatePodRoutingMarkRules.
+func (mr *MockNetworkAPIsMockRecorder) UpdatePodRoutingMarkRules(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePodRoutingMarkRules", reflect.TypeOf((*MockNetworkAPIs)(nil).UpdatePodRoutingMarkRules), arg0, arg1)
+}
+
 // UpdateRuleListBySrc mocks base method.
 func (m *MockNetworkAPIs) UpdateRuleListBySrc(arg0 []netlink.Rule, arg1 net.IPNet) error {
 	m.ctrl.T.Helper()
@@ -19,3 +19,60 @@ func GeneratePodHostVethNameSuffix(podNamespace string, podName string) string {
 	h.Write([]byte(fmt.Sprintf("%s.%s", podNamespace, podName)))
 	return hex.EncodeToString(h.Sum(nil))[:11]
 }
+
+// PrimaryPodInterfaceName is the name of the main interface of pods
+const PrimaryPodInterfaceName = "eth0"
+
+// isPrimaryPodInterface returns true if the interface is the main interface of a pod. Whether an additional interface
+// is routed as a secondary one is up to ipamd, this only decides its name.
+func isPrimaryPodInterface(ifName string) bool {
+	return ifName == "" || ifName == PrimaryPodInterfaceName
+}
+
+// GeneratePodInterfaceHostVethName generates the name for the host-side veth device of a pod interface.
+// The main interface keeps the name from GeneratePodHostVethName, each additional interface gets its own.
+func GeneratePodInterfaceHostVethName(prefix string, podNamespace string, podName string, ifName string) string {
+	suffix := GeneratePodInterfaceHostVethNameSuffix(podNamespace, podName, ifName)
+	return fmt.Sprintf("%s%s", prefix, suffix)
+}
+
+// GeneratePodInterfaceHostVethNameSuffix generates the name suffix for the hostVeth of a pod interface.
+func GeneratePodInterfaceHostVethNameSuffix(podNamespace string, podName string, ifName string) string {
+	if isPrimaryPodInterface(ifName) {
+		return GeneratePodHostVethNameSuffix(podNamespace, podName)
+	}
+	h := sha1.New()
+	h.Write([]byte(fmt.Sprintf("%s.%s.%s", podNamespace, podName, ifName)))
+	return hex.EncodeToString(h.Sum(nil))[:11]
+}
+
+// VethNameScheme is the way the hostVeth of a pod interface is named
+type VethNameScheme string
+
+const (
+	// VethNameSchemePodName names the hostVeth after the pod, so all sandboxes of a pod get the same name
+	VethNameSchemePodName VethNameScheme = "pod-name"
+	// VethNameSchemeContainerID names the hostVeth after the sandbox, so a re-created pod can't collide with its old sandbox
+	VethNameSchemeContainerID VethNameScheme = "container-id"
+)
+
+// VethNameSchemes are all the naming schemes of hostVeths. Pods set up before the scheme of a node changed keep the
+// name of the former scheme, so lookups have to try each of them.
+var VethNameSchemes = []VethNameScheme{VethNameSchemePodName, VethNameSchemeContainerID}
+
+// GenerateSandboxHostVethName generates the name for the host-side veth device of a pod interface, from the ID of the
+// sandbox container instead of the pod.
+func GenerateSandboxHostVethName(prefix string, containerID string, ifName string) string {
+	suffix := GenerateSandboxHostVethNameSuffix(containerID, ifName)
+	return fmt.Sprintf("%s%s", prefix, suffix)
+}
+
+// GenerateSandboxHostVethNameSuffix generates the name suffix for the hostVeth of a pod interface from its sandbox.
+func GenerateSandboxHostVethNameSuffix(containerID string, ifName string) string {
+	if ifName == "" {
+		ifName = PrimaryPodInterfaceName
+	}
+	h := sha1.New()
+	h.Write([]byte(fmt.Sprintf("%s.%s", containerID, ifName)))
+	return hex.EncodeToString(h.Sum(nil))[:11]
+}
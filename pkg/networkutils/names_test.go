@@ -86,3 +86,64 @@ func TestGeneratePodHostVethNameSuffix(t *testing.T) {
 		})
 	}
 }
+
+func TestGeneratePodInterfaceHostVethName(t *testing.T) {
+	tests := []struct {
+		name   string
+		ifName string
+		want   string
+	}{
+		{
+			name:   "main interface keeps the name of the pod",
+			ifName: "eth0",
+			want:   "enib5faff8a083",
+		},
+		{
+			name:   "empty interface name",
+			ifName: "",
+			want:   "enib5faff8a083",
+		},
+		{
+			name:   "secondary interface",
+			ifName: "net1",
+			want:   "enibb07af34158",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GeneratePodInterfaceHostVethName("eni", "kube-system", "coredns-57ff979f67-qqbdh", tt.ifName)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGenerateSandboxHostVethName(t *testing.T) {
+	containerID := "5a1f9118a7125f87b4b0f2f601c0b55cfab8bcf28963bcf7c4ece3109a8b6b86"
+	tests := []struct {
+		name   string
+		ifName string
+		want   string
+	}{
+		{
+			name:   "main interface",
+			ifName: "eth0",
+			want:   "eni298555d41c1",
+		},
+		{
+			name:   "empty interface name",
+			ifName: "",
+			want:   "eni298555d41c1",
+		},
+		{
+			name:   "secondary interface",
+			ifName: "net1",
+			want:   "eni6e3a2c12366",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GenerateSandboxHostVethName("eni", containerID, tt.ifName)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
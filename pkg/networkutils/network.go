@@ -29,6 +29,7 @@ import (
 
 	"github.com/coreos/go-iptables/iptables"
 
+	"github.com/aws/amazon-vpc-cni-k8s/pkg/bpfrouting"
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/sgpp"
 
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -127,6 +128,13 @@ const (
 	// envEnIpv6Egress is the environment variable to enable IPv6 egress support on EKS v4 cluster
 	envEnIpv6Egress = "ENABLE_V6_EGRESS"
 
+	// envEgressIPPinning is used to SNAT the outbound non-VPC traffic of selected pods to a secondary IP of the
+	// primary ENI instead of the primary IP. Defaults to false.
+	envEgressIPPinning = "ENABLE_EGRESS_IP_PINNING"
+
+	// egressIPChain holds the SNAT rules of the pods that are pinned to an egress IP
+	egressIPChain = "AWS-EGRESS-IP-CHAIN"
+
 	// Range of MTU for each ENI and veth pair. Defaults to maximumMTU
 	minimumMTU = 576
 	maximumMTU = 9001
@@ -142,6 +150,9 @@ const (
 	retryLinkByMacInterval = 3 * time.Second
 )
 
+// ipv6VPCRouter is the link-local address of the VPC router, used as IPv6 gateway of secondary ENIs
+var ipv6VPCRouter = net.ParseIP("fe80::1")
+
 var log = logger.Get()
 
 // NetworkAPIs defines the host level and the ENI level network related operations
@@ -151,16 +162,28 @@ type NetworkAPIs interface {
 		v4Enabled bool, v6Enabled bool) error
 	// SetupENINetwork performs ENI level network configuration. Not needed on the primary ENI
 	SetupENINetwork(eniIP string, mac string, deviceNumber int, subnetCIDR string) error
+	// SetupIPv6ENINetwork performs ENI level IPv6 network configuration. Not needed on the primary ENI
+	SetupIPv6ENINetwork(mac string, deviceNumber int) error
 	// UpdateHostIptablesRules updates the nat table iptables rules on the host
 	UpdateHostIptablesRules(vpcCIDRs []string, primaryMAC string, primaryAddr *net.IP, v4Enabled bool, v6Enabled bool) error
+	// UpdateEgressIPRules updates the SNAT rules of the pods that are pinned to an egress IP
+	UpdateEgressIPRules(egressIPs map[string][]string) error
+	// RemoveVPCCIDRRules removes the iptables rules and IP rules that still match VPC CIDRs which were disassociated
+	RemoveVPCCIDRRules(cidrs []string) error
 	UseExternalSNAT() bool
 	GetExcludeSNATCIDRs() []string
 	GetExternalServiceCIDRs() []string
 	GetRuleList() ([]netlink.Rule, error)
 	GetRuleListBySrc(ruleList []netlink.Rule, src net.IPNet) ([]netlink.Rule, error)
 	UpdateRuleListBySrc(ruleList []netlink.Rule, src net.IPNet) error
+	// SetupRuleBySrc adds the rule of the traffic from a pod IP to a route table, unless the pod IP has a rule already
+	SetupRuleBySrc(ruleList []netlink.Rule, src net.IPNet, table int) error
 	UpdateExternalServiceIpRules(ruleList []netlink.Rule, externalIPs []string) error
+	// UpdatePodRoutingMarkRules reconciles the rules that match the route table that eBPF pod routing puts in the mark
+	UpdatePodRoutingMarkRules(ruleList []netlink.Rule, tables []int) error
 	GetLinkByMac(mac string, retryInterval time.Duration) (netlink.Link, error)
+	// RestoreLinkName renames the link of an ENI that left a pod network namespace back to its name on the host
+	RestoreLinkName(mac string) error
 }
 
 type linuxNetwork struct {
@@ -173,6 +196,7 @@ type linuxNetwork struct {
 	mtu                    int
 	vethPrefix             string
 	podSGEnforcingMode     sgpp.EnforcingMode
+	egressIPPinning        bool
 
 	netLink     netlinkwrapper.NetLink
 	ns          nswrapper.NS
@@ -201,6 +225,7 @@ func New() NetworkAPIs {
 		mtu:                    GetEthernetMTU(""),
 		vethPrefix:             getVethPrefixName(),
 		podSGEnforcingMode:     sgpp.LoadEnforcingModeFromEnv(),
+		egressIPPinning:        egressIPPinningEnabled(),
 
 		netLink: netlinkwrapper.NewNetLink(),
 		ns:      nswrapper.NewNS(),
@@ -303,7 +328,11 @@ func (n *linuxNetwork) SetupHostNetwork(vpcv4CIDRs []string, primaryMAC string,
 
 	ipFamily := unix.AF_INET
 	if v6Enabled {
-		ipFamily = unix.AF_INET6
+		// In dual-stack mode, the v6 addresses of pods are behind the primary ENI, so the main ENI rule is only
+		// needed for v4 traffic.
+		if !v4Enabled {
+			ipFamily = unix.AF_INET6
+		}
 		if err := n.enableIPv6(); err != nil {
 			return errors.Wrapf(err, "failed to enable IPv6")
 		}
@@ -378,8 +407,9 @@ func (n *linuxNetwork) updateHostIptablesRules(vpcCIDRs []string, primaryMAC str
 		return errors.Wrapf(err, "failed to SetupHostNetwork")
 	}
 
+	// In dual-stack mode, the v4 SNAT rules are installed and the pods use their v6 addresses as is.
 	ipProtocol := iptables.ProtocolIPv4
-	if v6Enabled {
+	if v6Enabled && !v4Enabled {
 		// Essentially a stub function for now in V6 mode. We will need it when we support v6 in secondary IP and
 		// custom networking modes. We don't need to install any SNAT rules in v6 mode and currently there is no need
 		// to mark packets entering via Primary ENI as all the pods in v6 mode will be behind primary ENI. Will have to
@@ -400,6 +430,12 @@ func (n *linuxNetwork) updateHostIptablesRules(vpcCIDRs []string, primaryMAC str
 		if err := n.updateIptablesRules(iptablesSNATRules, ipt); err != nil {
 			return err
 		}
+		// The jump to the egress IP chain is gone once pinning is turned off, so the chain can be removed as well
+		if !n.egressIPPinning || n.useExternalSNAT {
+			if err := removeEgressIPChain(ipt); err != nil {
+				return err
+			}
+		}
 
 		iptablesConnmarkRules, err := n.buildIptablesConnmarkRules(vpcCIDRs, ipt)
 		if err != nil {
@@ -412,6 +448,125 @@ func (n *linuxNetwork) updateHostIptablesRules(vpcCIDRs []string, primaryMAC str
 	return nil
 }
 
+// UpdateEgressIPRules SNATs the outbound non-VPC traffic of pods to an egress IP instead of the primary IP. egressIPs
+// maps an egress IP to the pod IPs or CIDRs that use it. Rules of sources that are no longer pinned are removed.
+func (n *linuxNetwork) UpdateEgressIPRules(egressIPs map[string][]string) error {
+	if !n.egressIPPinning || n.useExternalSNAT {
+		return nil
+	}
+	ipt, err := n.newIptables(iptables.ProtocolIPv4)
+	if err != nil {
+		return errors.Wrap(err, "egress IP rules: failed to create iptables")
+	}
+	if err := ipt.NewChain("nat", egressIPChain); err != nil && !containChainExistErr(err) {
+		return errors.Wrapf(err, "egress IP rules: failed to add chain %s", egressIPChain)
+	}
+
+	randomizationFlags := n.snatRandomizationFlags(ipt)
+	var iptableRules []iptablesRule
+	for _, egressIP := range sets.StringKeySet(egressIPs).List() {
+		for _, src := range sets.NewString(egressIPs[egressIP]...).List() {
+			log.Debugf("Setup Host Network: iptables -A %s -s %s -t nat -j SNAT --to-source %s", egressIPChain, src, egressIP)
+			rule := []string{"-s", src, "-m", "comment", "--comment", "AWS, SNAT EGRESS IP", "-j", "SNAT", "--to-source", egressIP}
+			iptableRules = append(iptableRules, iptablesRule{
+				name:        fmt.Sprintf("SNAT %s to egress IP %s", src, egressIP),
+				shouldExist: true,
+				table:       "nat",
+				chain:       egressIPChain,
+				rule:        append(rule, randomizationFlags...),
+			})
+		}
+	}
+	staleRules, err := computeStaleIptablesRules(ipt, "nat", egressIPChain, iptableRules, []string{egressIPChain})
+	if err != nil {
+		return err
+	}
+	return n.updateIptablesRules(append(iptableRules, staleRules...), ipt)
+}
+
+// RemoveVPCCIDRRules removes the SNAT and connmark iptables rules and the IP rules whose destination is one of the
+// CIDRs. It is called when CIDRs were disassociated from the VPC, so that no pod traffic is still routed or excluded
+// from SNAT for them. CIDRs in AWS_VPC_K8S_CNI_EXCLUDE_SNAT_CIDRS keep their rules, since they are still excluded
+// from SNAT.
+func (n *linuxNetwork) RemoveVPCCIDRRules(cidrs []string) error {
+	removed := sets.NewString(cidrs...).Delete(n.GetExcludeSNATCIDRs()...)
+	if removed.Len() == 0 {
+		return nil
+	}
+	ipt, err := n.newIptables(iptables.ProtocolIPv4)
+	if err != nil {
+		return errors.Wrap(err, "remove VPC CIDR rules: failed to create iptables")
+	}
+	var staleRules []iptablesRule
+	for _, chainPrefix := range []string{"AWS-SNAT-CHAIN", "AWS-CONNMARK-CHAIN"} {
+		existingRules, err := listCurrentIptablesRules(ipt, "nat", chainPrefix)
+		if err != nil {
+			return err
+		}
+		for _, rule := range existingRules {
+			for i := 0; i+1 < len(rule.rule); i++ {
+				if rule.rule[i] == "-d" && removed.Has(rule.rule[i+1]) {
+					staleRules = append(staleRules, rule)
+					break
+				}
+			}
+		}
+	}
+	if err := n.updateIptablesRules(staleRules, ipt); err != nil {
+		return err
+	}
+
+	rules, err := n.GetRuleList()
+	if err != nil {
+		return errors.Wrap(err, "remove VPC CIDR rules: failed to list IP rules")
+	}
+	for _, rule := range rules {
+		if rule.Dst == nil || !removed.Has(rule.Dst.String()) {
+			continue
+		}
+		if err := n.netLink.RuleDel(&rule); err != nil && !containsNoSuchRule(err) {
+			return errors.Wrapf(err, "remove VPC CIDR rules: failed to delete IP rule to %s", rule.Dst)
+		}
+		log.Infof("Removed IP rule [%v] of disassociated VPC CIDR %s", rule, rule.Dst)
+	}
+	return nil
+}
+
+// removeEgressIPChain flushes and deletes the chain of the egress IP rules, if it exists
+func removeEgressIPChain(ipt iptableswrapper.IPTablesIface) error {
+	chains, err := ipt.ListChains("nat")
+	if err != nil {
+		return errors.Wrap(err, "egress IP rules: failed to list chains")
+	}
+	if !sets.NewString(chains...).Has(egressIPChain) {
+		return nil
+	}
+	log.Infof("Egress IP pinning is disabled, removing chain %s", egressIPChain)
+	if err := ipt.ClearChain("nat", egressIPChain); err != nil {
+		return errors.Wrapf(err, "egress IP rules: failed to flush chain %s", egressIPChain)
+	}
+	if err := ipt.DeleteChain("nat", egressIPChain); err != nil {
+		return errors.Wrapf(err, "egress IP rules: failed to delete chain %s", egressIPChain)
+	}
+	return nil
+}
+
+// snatRandomizationFlags returns the port randomization flags of SNAT rules, as set by AWS_VPC_K8S_CNI_RANDOMIZESNAT
+func (n *linuxNetwork) snatRandomizationFlags(ipt iptableswrapper.IPTablesIface) []string {
+	switch n.typeOfSNAT {
+	case randomHashSNAT:
+		return []string{"--random"}
+	case randomPRNGSNAT:
+		if ipt.HasRandomFully() {
+			return []string{"--random-fully"}
+		}
+		log.Warn("prng (--random-fully) requested, but iptables version does not support it. " +
+			"Falling back to hashrandom (--random)")
+		return []string{"--random"}
+	}
+	return nil
+}
+
 func (n *linuxNetwork) buildIptablesSNATRules(vpcCIDRs []string, primaryAddr *net.IP, primaryIntf string, ipt iptableswrapper.IPTablesIface) ([]iptablesRule, error) {
 	type snatCIDR struct {
 		cidr        string
@@ -477,20 +632,28 @@ func (n *linuxNetwork) buildIptablesSNATRules(vpcCIDRs []string, primaryAddr *ne
 		"-m", "comment", "--comment", "AWS, SNAT",
 		"-m", "addrtype", "!", "--dst-type", "LOCAL",
 		"-j", "SNAT", "--to-source", primaryAddr.String()}
-	if n.typeOfSNAT == randomHashSNAT {
-		snatRule = append(snatRule, "--random")
-	}
-	if n.typeOfSNAT == randomPRNGSNAT {
-		if ipt.HasRandomFully() {
-			snatRule = append(snatRule, "--random-fully")
-		} else {
-			log.Warn("prng (--random-fully) requested, but iptables version does not support it. " +
-				"Falling back to hashrandom (--random)")
-			snatRule = append(snatRule, "--random")
+	snatRule = append(snatRule, n.snatRandomizationFlags(ipt)...)
+
+	lastChain := chains[len(chains)-1]
+	if n.egressIPPinning {
+		if err := ipt.NewChain("nat", egressIPChain); err != nil && !containChainExistErr(err) {
+			log.Errorf("ipt.NewChain error for chain [%s]: %v", egressIPChain, err)
+			return []iptablesRule{}, errors.Wrapf(err, "host network setup: failed to add chain")
 		}
 	}
+	// Pinned pods have to be matched before the SNAT rule to the primary IP
+	iptableRules = append(iptableRules, iptablesRule{
+		name:        "jump to egress IP SNAT rules",
+		shouldExist: !n.useExternalSNAT && n.egressIPPinning,
+		table:       "nat",
+		chain:       lastChain,
+		rule: []string{"!", "-o", "vlan+",
+			"-m", "comment", "--comment", "AWS, SNAT EGRESS IP",
+			"-m", "addrtype", "!", "--dst-type", "LOCAL",
+			"-j", egressIPChain},
+		insert: true,
+	})
 
-	lastChain := chains[len(chains)-1]
 	iptableRules = append(iptableRules, iptablesRule{
 		name:        "last SNAT rule for non-VPC outbound traffic",
 		shouldExist: !n.useExternalSNAT,
@@ -664,7 +827,11 @@ func (n *linuxNetwork) updateIptablesRules(iptableRules []iptablesRule, ipt ipta
 		}
 
 		if !exists && rule.shouldExist {
-			err = ipt.Append(rule.table, rule.chain, rule.rule...)
+			if rule.insert {
+				err = ipt.Insert(rule.table, rule.chain, 1, rule.rule...)
+			} else {
+				err = ipt.Append(rule.table, rule.chain, rule.rule...)
+			}
 			if err != nil {
 				log.Errorf("host network setup: failed to add %v, %v", rule, err)
 				return errors.Wrapf(err, "host network setup: failed to add %v", rule)
@@ -753,6 +920,8 @@ type iptablesRule struct {
 	shouldExist  bool
 	table, chain string
 	rule         []string
+	// insert adds the rule at the top of the chain instead of appending it
+	insert bool
 }
 
 func (r iptablesRule) String() string {
@@ -819,6 +988,10 @@ func useExternalSNAT() bool {
 	return getBoolEnvVar(envExternalSNAT, false)
 }
 
+func egressIPPinningEnabled() bool {
+	return getBoolEnvVar(envEgressIPPinning, false)
+}
+
 func (n *linuxNetwork) Ipv6EgressEnabled() bool {
 	return ipV6EgressEnabled()
 }
@@ -917,11 +1090,48 @@ func getConnmark() uint32 {
 	return defaultConnmark
 }
 
+// ValidatePodRoutingMark returns an error if the connmark overlaps the mark that eBPF pod routing puts the route table in
+func ValidatePodRoutingMark() error {
+	if connmark := getConnmark(); connmark&bpfrouting.MarkMask != 0 {
+		return errors.Errorf("%s %#x overlaps the eBPF pod routing mark %#x", envConnmark, connmark, bpfrouting.MarkMask)
+	}
+	return nil
+}
+
 // GetLinkByMac returns linux netlink based on interface MAC
 func (n *linuxNetwork) GetLinkByMac(mac string, retryInterval time.Duration) (netlink.Link, error) {
 	return linkByMac(mac, n.netLink, retryInterval)
 }
 
+// RestoreLinkName renames the link of an ENI back to the name that the plugin kept in its alias when it moved the link
+// into a pod. The kernel returns the link under its name in the pod, or as devN when that name is taken on the host.
+// An error is returned while the link is not back on the host.
+func (n *linuxNetwork) RestoreLinkName(mac string) error {
+	links, err := n.netLink.LinkList()
+	if err != nil {
+		return errors.Wrap(err, "RestoreLinkName: failed to list links")
+	}
+	for _, link := range links {
+		if link.Attrs().HardwareAddr.String() != mac {
+			continue
+		}
+		name, alias := link.Attrs().Name, link.Attrs().Alias
+		if alias == "" || alias == name {
+			return nil
+		}
+		log.Infof("Renaming link %s of MAC address %s back to %s", name, mac, alias)
+		// A link can only be renamed while it is down, setupENINetwork brings it up again
+		if err := n.netLink.LinkSetDown(link); err != nil {
+			return errors.Wrapf(err, "RestoreLinkName: failed to set link %s down", name)
+		}
+		if err := n.netLink.LinkSetName(link, alias); err != nil {
+			return errors.Wrapf(err, "RestoreLinkName: failed to rename link %s to %s", name, alias)
+		}
+		return nil
+	}
+	return errors.Errorf("RestoreLinkName: no interface found which uses mac address %s", mac)
+}
+
 // linkByMac returns linux netlink based on interface MAC
 func linkByMac(mac string, netLink netlinkwrapper.NetLink, retryInterval time.Duration) (netlink.Link, error) {
 	// The adapter might not be immediately available, so we perform retries
@@ -1075,6 +1285,51 @@ func setupENINetwork(eniIP string, eniMAC string, deviceNumber int, eniSubnetCID
 	return nil
 }
 
+// SetupIPv6ENINetwork adds a default IPv6 route via the VPC router to route table (eni-<eni_table>), so it does not
+// need to be called on the primary ENI
+func (n *linuxNetwork) SetupIPv6ENINetwork(eniMAC string, deviceNumber int) error {
+	return setupIPv6ENINetwork(eniMAC, deviceNumber, n.netLink, retryLinkByMacInterval, retryRouteAddInterval, n.mtu)
+}
+
+func setupIPv6ENINetwork(eniMAC string, deviceNumber int, netLink netlinkwrapper.NetLink, retryLinkByMacInterval time.Duration,
+	retryRouteAddInterval time.Duration, mtu int) error {
+	if deviceNumber == 0 {
+		return errors.New("setupIPv6ENINetwork should never be called on the primary ENI")
+	}
+	tableNumber := deviceNumber + 1
+	log.Infof("Setting up IPv6 network for an ENI with MAC address %s and route table %d", eniMAC, tableNumber)
+	link, err := linkByMac(eniMAC, netLink, retryLinkByMacInterval)
+	if err != nil {
+		return errors.Wrapf(err, "setupIPv6ENINetwork: failed to find the link which uses MAC address %s", eniMAC)
+	}
+
+	if err = netLink.LinkSetMTU(link, mtu); err != nil {
+		return errors.Wrapf(err, "setupIPv6ENINetwork: failed to set MTU to %d for %s", mtu, eniMAC)
+	}
+
+	if err = netLink.LinkSetUp(link); err != nil {
+		return errors.Wrapf(err, "setupIPv6ENINetwork: failed to bring up ENI %s", eniMAC)
+	}
+
+	// The VPC router is reachable through its link-local address on every ENI
+	route := netlink.Route{
+		LinkIndex: link.Attrs().Index,
+		Dst:       &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)},
+		Scope:     netlink.SCOPE_UNIVERSE,
+		Gw:        ipv6VPCRouter,
+		Table:     tableNumber,
+	}
+	log.Debugf("Setting up ENI's IPv6 default gateway %v, table %d", ipv6VPCRouter, tableNumber)
+	return retry.NWithBackoff(retry.NewSimpleBackoff(500*time.Millisecond, retryRouteAddInterval, 0.15, 2.0), maxRetryRouteAdd, func() error {
+		if err := netLink.RouteReplace(&route); err != nil {
+			log.Debugf("Not able to set route ::/0 via %s table %d", ipv6VPCRouter.String(), tableNumber)
+			return errors.Wrap(err, "setupIPv6ENINetwork: unable to replace IPv6 default route")
+		}
+		log.Debugf("Successfully added/replaced route to be ::/0")
+		return nil
+	})
+}
+
 // IncrementIPv4Addr returns incremented IPv4 address
 func IncrementIPv4Addr(ip net.IP) (net.IP, error) {
 	ip4 := ip.To4()
@@ -1153,6 +1408,29 @@ func (n *linuxNetwork) UpdateRuleListBySrc(ruleList []netlink.Rule, src net.IPNe
 	return nil
 }
 
+// SetupRuleBySrc adds the rule that sends the traffic from a pod IP to a route table, when no IP rule has a matching
+// source IP. The rule is missing for pods that were routed by eBPF pod routing before it was disabled.
+func (n *linuxNetwork) SetupRuleBySrc(ruleList []netlink.Rule, src net.IPNet, table int) error {
+	srcRuleList, err := n.GetRuleListBySrc(ruleList, src)
+	if err != nil {
+		return err
+	}
+	if len(srcRuleList) > 0 {
+		return nil
+	}
+
+	podRule := n.netLink.NewRule()
+	podRule.Src = &src
+	podRule.Table = table
+	podRule.Priority = FromPodRulePriority
+	if err := n.netLink.RuleAdd(podRule); err != nil && !isRuleExistsError(err) {
+		log.Errorf("Failed to add pod IP rule: %v", err)
+		return errors.Wrapf(err, "SetupRuleBySrc: failed to add pod rule")
+	}
+	log.Infof("SetupRuleBySrc: Successfully added pod rule[%v]", podRule)
+	return nil
+}
+
 // UpdateExternalServiceIpRules reconciles existing set of IP rules for external IPs with new set
 func (n *linuxNetwork) UpdateExternalServiceIpRules(ruleList []netlink.Rule, externalServiceCidrs []string) error {
 	log.Debugf("Update Rule List with set %v", externalServiceCidrs)
@@ -1189,6 +1467,48 @@ func (n *linuxNetwork) UpdateExternalServiceIpRules(ruleList []netlink.Rule, ext
 	return nil
 }
 
+// UpdatePodRoutingMarkRules reconciles the rules that send the traffic marked by eBPF pod routing to the route table
+// in the mark, there is one rule per route table instead of one per pod IP
+func (n *linuxNetwork) UpdatePodRoutingMarkRules(ruleList []netlink.Rule, tables []int) error {
+	log.Debugf("Update pod routing mark rules with route tables %v", tables)
+
+	wanted := make(map[int]bool)
+	for _, table := range tables {
+		wanted[table] = true
+	}
+	for _, rule := range ruleList {
+		if rule.Priority != FromPodRulePriority || rule.Mask != bpfrouting.MarkMask {
+			continue
+		}
+		if wanted[rule.Table] && rule.Mark == bpfrouting.Mark(rule.Table) {
+			delete(wanted, rule.Table)
+			continue
+		}
+		if err := n.netLink.RuleDel(&rule); err != nil && !containsNoSuchRule(err) {
+			log.Errorf("Failed to cleanup old IP rule: %v", err)
+			return errors.Wrapf(err, "UpdatePodRoutingMarkRules: failed to delete old rule")
+		}
+	}
+
+	for _, table := range tables {
+		if !wanted[table] {
+			continue
+		}
+		markRule := n.netLink.NewRule()
+		markRule.Mark = bpfrouting.Mark(table)
+		markRule.Mask = bpfrouting.MarkMask
+		markRule.Table = table
+		markRule.Priority = FromPodRulePriority
+		if err := n.netLink.RuleAdd(markRule); err != nil && !isRuleExistsError(err) {
+			log.Errorf("Failed to add pod routing mark rule: %v", err)
+			return errors.Wrapf(err, "UpdatePodRoutingMarkRules: failed to add rule")
+		}
+		log.Infof("UpdatePodRoutingMarkRules: successfully added rule[%v]", markRule)
+		delete(wanted, table)
+	}
+	return nil
+}
+
 // GetEthernetMTU gets the MTU setting from AWS_VPC_ENI_MTU if set, or takes the passed in string. Defaults to 9001 if not set.
 func GetEthernetMTU(envMTUValue string) int {
 	inputStr, found := os.LookupEnv(envMTU)
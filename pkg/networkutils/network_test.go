@@ -142,6 +142,63 @@ func TestSetupENINetworkErrorOnPrimaryENI(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestRestoreLinkName(t *testing.T) {
+	ctrl, mockNetLink, _, _, _ := setup(t)
+	defer ctrl.Finish()
+
+	hwAddr, err := net.ParseMAC(testMAC2)
+	assert.NoError(t, err)
+	ln := &linuxNetwork{netLink: mockNetLink}
+
+	// The kernel named the link after the interface of the pod
+	link := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "dev3", Alias: "eth2", HardwareAddr: hwAddr}}
+	mockNetLink.EXPECT().LinkList().Return([]netlink.Link{link}, nil)
+	gomock.InOrder(
+		mockNetLink.EXPECT().LinkSetDown(link).Return(nil),
+		mockNetLink.EXPECT().LinkSetName(link, "eth2").Return(nil),
+	)
+	assert.NoError(t, ln.RestoreLinkName(testMAC2))
+
+	// A link that has its name on the host is left alone
+	link = &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "eth2", Alias: "eth2", HardwareAddr: hwAddr}}
+	mockNetLink.EXPECT().LinkList().Return([]netlink.Link{link}, nil)
+	assert.NoError(t, ln.RestoreLinkName(testMAC2))
+
+	// The link is still in the pod
+	mockNetLink.EXPECT().LinkList().Return([]netlink.Link{}, nil)
+	assert.Error(t, ln.RestoreLinkName(testMAC2))
+}
+
+func TestSetupIPv6ENINetwork(t *testing.T) {
+	ctrl, mockNetLink, _, _, _ := setup(t)
+	defer ctrl.Finish()
+
+	hwAddr, err := net.ParseMAC(testMAC2)
+	assert.NoError(t, err)
+	mockLinkAttrs := &netlink.LinkAttrs{
+		HardwareAddr: hwAddr,
+		Index:        3,
+	}
+	eth1 := mock_netlink.NewMockLink(ctrl)
+	mockNetLink.EXPECT().LinkList().Return([]netlink.Link{eth1}, nil)
+	eth1.EXPECT().Attrs().Return(mockLinkAttrs).AnyTimes()
+	mockNetLink.EXPECT().LinkSetMTU(eth1, testMTU).Return(nil)
+	mockNetLink.EXPECT().LinkSetUp(eth1).Return(nil)
+	mockNetLink.EXPECT().RouteReplace(&netlink.Route{
+		LinkIndex: 3,
+		Dst:       &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)},
+		Scope:     netlink.SCOPE_UNIVERSE,
+		Gw:        net.ParseIP("fe80::1"),
+		Table:     testTable + 1,
+	}).Return(nil)
+
+	err = setupIPv6ENINetwork(testMAC2, testTable, mockNetLink, 0*time.Second, 0*time.Second, testMTU)
+	assert.NoError(t, err)
+
+	err = setupIPv6ENINetwork(testMAC2, 0, mockNetLink, 0*time.Second, 0*time.Second, testMTU)
+	assert.Error(t, err)
+}
+
 func TestSetupHostNetworkNodePortDisabledAndSNATDisabled(t *testing.T) {
 	ctrl, mockNetLink, _, mockNS, mockIptables := setup(t)
 	defer ctrl.Finish()
@@ -742,6 +799,106 @@ func TestUpdateHostIptablesRules(t *testing.T) {
 			},
 		}, mockIptables.(*mock_iptables.MockIptables).DataplaneState)
 }
+func TestUpdateEgressIPRules(t *testing.T) {
+	ctrl, mockNetLink, _, mockNS, mockIptables := setup(t)
+	defer ctrl.Finish()
+
+	ln := &linuxNetwork{
+		useExternalSNAT:        false,
+		nodePortSupportEnabled: false,
+		egressIPPinning:        true,
+		mainENIMark:            defaultConnmark,
+		mtu:                    testMTU,
+		vethPrefix:             eniPrefix,
+
+		netLink: mockNetLink,
+		ns:      mockNS,
+		newIptables: func(iptables.Protocol) (iptableswrapper.IPTablesIface, error) {
+			return mockIptables, nil
+		},
+	}
+	setupNetLinkMocks(ctrl, mockNetLink)
+
+	snatRule := []string{"!", "-o", "vlan+", "-m", "comment", "--comment", "AWS, SNAT", "-m", "addrtype", "!", "--dst-type", "LOCAL", "-j", "SNAT", "--to-source", "10.10.10.20"}
+	_ = mockIptables.Append("nat", "AWS-SNAT-CHAIN-1", snatRule...)
+
+	vpcCIDRs := []string{"10.10.0.0/16"}
+	err := ln.SetupHostNetwork(vpcCIDRs, loopback, &testENINetIP, false, true, false)
+	assert.NoError(t, err)
+	// The jump to the egress IP rules has to come before the SNAT rule to the primary IP
+	assert.Equal(t, [][]string{
+		{"!", "-o", "vlan+", "-m", "comment", "--comment", "AWS, SNAT EGRESS IP", "-m", "addrtype", "!", "--dst-type", "LOCAL", "-j", "AWS-EGRESS-IP-CHAIN"},
+		snatRule,
+	}, mockIptables.(*mock_iptables.MockIptables).DataplaneState["nat"]["AWS-SNAT-CHAIN-1"])
+
+	err = ln.UpdateEgressIPRules(map[string][]string{
+		"10.10.10.30": {"10.10.1.5", "10.10.1.4"},
+		"10.10.10.31": {"10.10.2.0/28"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"-s", "10.10.1.4", "-m", "comment", "--comment", "AWS, SNAT EGRESS IP", "-j", "SNAT", "--to-source", "10.10.10.30"},
+		{"-s", "10.10.1.5", "-m", "comment", "--comment", "AWS, SNAT EGRESS IP", "-j", "SNAT", "--to-source", "10.10.10.30"},
+		{"-s", "10.10.2.0/28", "-m", "comment", "--comment", "AWS, SNAT EGRESS IP", "-j", "SNAT", "--to-source", "10.10.10.31"},
+	}, mockIptables.(*mock_iptables.MockIptables).DataplaneState["nat"]["AWS-EGRESS-IP-CHAIN"])
+
+	// Rules of pods that are gone or no longer pinned are removed
+	err = ln.UpdateEgressIPRules(map[string][]string{
+		"10.10.10.30": {"10.10.1.5"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"-s", "10.10.1.5", "-m", "comment", "--comment", "AWS, SNAT EGRESS IP", "-j", "SNAT", "--to-source", "10.10.10.30"},
+	}, mockIptables.(*mock_iptables.MockIptables).DataplaneState["nat"]["AWS-EGRESS-IP-CHAIN"])
+
+	// The jump and the chain are removed once pinning is turned off
+	ln.egressIPPinning = false
+	err = ln.UpdateHostIptablesRules(vpcCIDRs, loopback, &testENINetIP, true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{snatRule}, mockIptables.(*mock_iptables.MockIptables).DataplaneState["nat"]["AWS-SNAT-CHAIN-1"])
+	assert.NotContains(t, mockIptables.(*mock_iptables.MockIptables).DataplaneState["nat"], "AWS-EGRESS-IP-CHAIN")
+}
+
+func TestRemoveVPCCIDRRules(t *testing.T) {
+	ctrl, mockNetLink, _, mockNS, mockIptables := setup(t)
+	defer ctrl.Finish()
+
+	ln := &linuxNetwork{
+		mainENIMark: defaultConnmark,
+		netLink:     mockNetLink,
+		ns:          mockNS,
+		newIptables: func(iptables.Protocol) (iptableswrapper.IPTablesIface, error) {
+			return mockIptables, nil
+		},
+	}
+	keptSNATRule := []string{"!", "-d", "10.10.0.0/16", "-m", "comment", "--comment", "AWS SNAT CHAIN", "-j", "AWS-SNAT-CHAIN-1"}
+	removedSNATRule := []string{"!", "-d", "100.64.0.0/16", "-m", "comment", "--comment", "AWS SNAT CHAIN", "-j", "AWS-SNAT-CHAIN-2"}
+	removedConnmarkRule := []string{"-d", "100.64.0.0/16", "-m", "comment", "--comment", "AWS CONNMARK CHAIN, VPC CIDR", "-j", "RETURN"}
+	_ = mockIptables.Append("nat", "AWS-SNAT-CHAIN-0", keptSNATRule...)
+	_ = mockIptables.Append("nat", "AWS-SNAT-CHAIN-1", removedSNATRule...)
+	_ = mockIptables.Append("nat", "AWS-CONNMARK-CHAIN-1", removedConnmarkRule...)
+
+	_, keptCIDR, _ := net.ParseCIDR("10.10.0.0/16")
+	_, removedCIDR, _ := net.ParseCIDR("100.64.0.0/16")
+	keptRule := netlink.Rule{Dst: keptCIDR, Priority: FromPodRulePriority, Table: 2}
+	removedRule := netlink.Rule{Src: testENINetIPNet, Dst: removedCIDR, Priority: FromPodRulePriority, Table: 2}
+	mockNetLink.EXPECT().RuleList(gomock.Any()).Return([]netlink.Rule{keptRule, removedRule}, nil)
+	mockNetLink.EXPECT().RuleDel(&removedRule).Return(nil)
+
+	// The rules of a CIDR that is excluded from SNAT are kept
+	t.Setenv(envExcludeSNATCIDRs, "10.10.0.0/16")
+	err := ln.RemoveVPCCIDRRules([]string{"100.64.0.0/16", "10.10.0.0/16"})
+	assert.NoError(t, err)
+	dataplane := mockIptables.(*mock_iptables.MockIptables).DataplaneState["nat"]
+	assert.Equal(t, [][]string{keptSNATRule}, dataplane["AWS-SNAT-CHAIN-0"])
+	assert.Empty(t, dataplane["AWS-SNAT-CHAIN-1"])
+	assert.Empty(t, dataplane["AWS-CONNMARK-CHAIN-1"])
+
+	// Nothing is removed if all CIDRs are excluded from SNAT
+	err = ln.RemoveVPCCIDRRules([]string{"10.10.0.0/16"})
+	assert.NoError(t, err)
+}
+
 func TestSetupHostNetworkMultipleCIDRs(t *testing.T) {
 	ctrl, mockNetLink, _, mockNS, mockIptables := setup(t)
 	defer ctrl.Finish()
@@ -804,6 +961,64 @@ func TestSetupHostNetworkWithIPv6Enabled(t *testing.T) {
 	}, mockIptables.(*mock_iptables.MockIptables).DataplaneState)
 }
 
+func TestSetupHostNetworkDualStack(t *testing.T) {
+	ctrl, mockNetLink, _, mockNS, mockIptables := setup(t)
+	defer ctrl.Finish()
+
+	var protocols []iptables.Protocol
+	ln := &linuxNetwork{
+		useExternalSNAT:        false,
+		nodePortSupportEnabled: false,
+		mainENIMark:            defaultConnmark,
+		mtu:                    testMTU,
+		vethPrefix:             eniPrefix,
+
+		netLink: mockNetLink,
+		ns:      mockNS,
+		newIptables: func(protocol iptables.Protocol) (iptableswrapper.IPTablesIface, error) {
+			protocols = append(protocols, protocol)
+			return mockIptables, nil
+		},
+	}
+	setupNetLinkMocks(ctrl, mockNetLink)
+
+	var vpcCIDRs []string
+	err := ln.SetupHostNetwork(vpcCIDRs, loopback, &testENINetIP, false, true, true)
+	assert.NoError(t, err)
+
+	// The SNAT rules of the v4 addresses are installed with iptables
+	assert.Equal(t, []iptables.Protocol{iptables.ProtocolIPv4, iptables.ProtocolIPv4}, protocols)
+	assert.Equal(t, map[string]map[string][][]string{
+		"filter": {
+			"FORWARD": [][]string{
+				{
+					"-d", "169.254.172.0/22",
+					"-m", "conntrack", "--ctstate", "NEW",
+					"-m", "comment", "--comment", "Block Node Local Pod access via IPv4",
+					"-j", "REJECT",
+				},
+			},
+		},
+		"nat": {
+			"AWS-SNAT-CHAIN-0":     [][]string{{"!", "-o", "vlan+", "-m", "comment", "--comment", "AWS, SNAT", "-m", "addrtype", "!", "--dst-type", "LOCAL", "-j", "SNAT", "--to-source", "10.10.10.20"}},
+			"POSTROUTING":          [][]string{{"-m", "comment", "--comment", "AWS SNAT CHAIN", "-j", "AWS-SNAT-CHAIN-0"}},
+			"AWS-CONNMARK-CHAIN-0": [][]string{{"-m", "comment", "--comment", "AWS, CONNMARK", "-j", "CONNMARK", "--set-xmark", "0x80/0x80"}},
+			"PREROUTING": [][]string{
+				{"-i", "eni+", "-m", "comment", "--comment", "AWS, outbound connections", "-j", "AWS-CONNMARK-CHAIN-0"},
+				{"-m", "comment", "--comment", "AWS, CONNMARK", "-j", "CONNMARK", "--restore-mark", "--mask", "0x80"},
+			},
+		},
+		"mangle": {
+			"PREROUTING": [][]string{
+				{
+					"-m", "comment", "--comment", "AWS, primary ENI",
+					"-i", "eni+", "-j", "CONNMARK", "--restore-mark", "--mask", "0x80",
+				},
+			},
+		},
+	}, mockIptables.(*mock_iptables.MockIptables).DataplaneState)
+}
+
 func TestIncrementIPv4Addr(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -1053,6 +1268,59 @@ func TestUpdateExternalIpRules(t *testing.T) {
 	}
 }
 
+func TestUpdatePodRoutingMarkRules(t *testing.T) {
+	ctrl, mockNetLink, _, _, _ := setup(t)
+	defer ctrl.Finish()
+
+	ln := &linuxNetwork{netLink: mockNetLink}
+	_, podIP, _ := net.ParseCIDR("10.0.1.10/32")
+	podRule := netlink.Rule{Src: podIP, Priority: FromPodRulePriority, Table: 2, Mark: -1, Mask: -1}
+	table2Rule := netlink.Rule{Priority: FromPodRulePriority, Table: 2, Mark: 0x0200, Mask: 0x3f00}
+	table3Rule := netlink.Rule{Priority: FromPodRulePriority, Table: 3, Mark: 0x0300, Mask: 0x3f00}
+	existingRules := []netlink.Rule{podRule, table2Rule, table3Rule}
+
+	// The rule of table 3 is stale, table 4 gets a rule, the rule of table 2 and the rule of the pod IP are kept
+	mockNetLink.EXPECT().RuleDel(&existingRules[2])
+	var newRule netlink.Rule
+	mockNetLink.EXPECT().NewRule().Return(&newRule)
+	mockNetLink.EXPECT().RuleAdd(&newRule)
+
+	err := ln.UpdatePodRoutingMarkRules(existingRules, []int{2, 4, 4})
+	assert.NoError(t, err)
+	assert.Equal(t, netlink.Rule{Priority: FromPodRulePriority, Table: 4, Mark: 0x0400, Mask: 0x3f00}, newRule)
+}
+
+func TestSetupRuleBySrc(t *testing.T) {
+	ctrl, mockNetLink, _, _, _ := setup(t)
+	defer ctrl.Finish()
+
+	ln := &linuxNetwork{netLink: mockNetLink}
+	_, podIP, _ := net.ParseCIDR("10.0.1.10/32")
+	_, otherPodIP, _ := net.ParseCIDR("10.0.1.11/32")
+	existingRules := []netlink.Rule{{Src: podIP, Priority: FromPodRulePriority, Table: 2}}
+
+	// The pod IP has a rule already
+	err := ln.SetupRuleBySrc(existingRules, *podIP, 2)
+	assert.NoError(t, err)
+
+	var newRule netlink.Rule
+	mockNetLink.EXPECT().NewRule().Return(&newRule)
+	mockNetLink.EXPECT().RuleAdd(&newRule)
+
+	err = ln.SetupRuleBySrc(existingRules, *otherPodIP, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, netlink.Rule{Src: otherPodIP, Priority: FromPodRulePriority, Table: 3}, newRule)
+}
+
+func TestValidatePodRoutingMark(t *testing.T) {
+	_ = os.Unsetenv(envConnmark)
+	assert.NoError(t, ValidatePodRoutingMark())
+
+	_ = os.Setenv(envConnmark, "0x100")
+	defer os.Unsetenv(envConnmark)
+	assert.Error(t, ValidatePodRoutingMark())
+}
+
 func setupNetLinkMocks(ctrl *gomock.Controller, mockNetLink *mock_netlinkwrapper.MockNetLink) {
 	mockPrimaryInterfaceLookup(ctrl, mockNetLink)
 	mockNetLink.EXPECT().LinkSetMTU(gomock.Any(), testMTU).Return(nil)
@@ -157,9 +157,9 @@ func (p *cloudWatchPublisher) Publish(metricDataPoints ...*cloudwatch.MetricDatu
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	// NOTE: Iteration is used to override the metric dimensions
+	// NOTE: Iteration is used to add the cluster dimension in front of the dimensions set by the caller, if any
 	for _, metricDatum := range metricDataPoints {
-		metricDatum.Dimensions = dimensions
+		metricDatum.Dimensions = append(dimensions[:len(dimensions):len(dimensions)], metricDatum.Dimensions...)
 		p.localMetricData = append(p.localMetricData, metricDatum)
 	}
 }
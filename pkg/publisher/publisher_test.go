@@ -64,6 +64,33 @@ func TestCloudWatchPublisherWithSingleDatum(t *testing.T) {
 	assert.Empty(t, cloudwatchPublisher.localMetricData)
 }
 
+func TestCloudWatchPublisherKeepsDatumDimensions(t *testing.T) {
+	cloudwatchPublisher := getCloudWatchPublisher(t)
+
+	subnetDimension := &cloudwatch.Dimension{Name: aws.String("SubnetId"), Value: aws.String("subnet-1")}
+	testCloudwatchMetricDatum := &cloudwatch.MetricDatum{
+		MetricName: aws.String(testMetricOne),
+		Unit:       aws.String(cloudwatch.StandardUnitCount),
+		Value:      aws.Float64(1.0),
+		Dimensions: []*cloudwatch.Dimension{subnetDimension},
+	}
+	otherCloudwatchMetricDatum := &cloudwatch.MetricDatum{
+		MetricName: aws.String(testMetricOne),
+		Unit:       aws.String(cloudwatch.StandardUnitCount),
+		Value:      aws.Float64(2.0),
+	}
+
+	cloudwatchPublisher.Publish(testCloudwatchMetricDatum, otherCloudwatchMetricDatum)
+	assert.Len(t, cloudwatchPublisher.localMetricData, 2)
+	assert.Equal(t, []*cloudwatch.Dimension{
+		{Name: aws.String(clusterIDDimension), Value: aws.String(testClusterID)},
+		subnetDimension,
+	}, cloudwatchPublisher.localMetricData[0].Dimensions)
+	assert.Equal(t, []*cloudwatch.Dimension{
+		{Name: aws.String(clusterIDDimension), Value: aws.String(testClusterID)},
+	}, cloudwatchPublisher.localMetricData[1].Dimensions)
+}
+
 func TestCloudWatchPublisherWithMultipleDatum(t *testing.T) {
 	cloudwatchPublisher := getCloudWatchPublisher(t)
 
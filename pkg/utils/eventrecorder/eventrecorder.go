@@ -84,6 +84,24 @@ func (e *EventRecorder) SendPodEvent(eventType, reason, action, message string)
 	log.Debugf("Sent pod event: eventType: %s, reason: %s, message: %s", eventType, reason, message)
 }
 
+// SendNodeEvent will raise event on the node aws-node is running on with given type, reason, & message
+func (e *EventRecorder) SendNodeEvent(eventType, reason, action, message string) {
+	// Node events are looked up by name, so the node name is used as UID the same way kubelet does
+	node := &corev1.ObjectReference{
+		Kind: "Node",
+		Name: MyNodeName,
+		UID:  types.UID(MyNodeName),
+	}
+	e.Recorder.Eventf(node, nil, eventType, reason, action, message)
+	log.Debugf("Sent node event: eventType: %s, reason: %s, message: %s", eventType, reason, message)
+}
+
+// SendEventOnPod will raise event on the given pod with given type, reason, & message
+func (e *EventRecorder) SendEventOnPod(pod *corev1.Pod, eventType, reason, action, message string) {
+	e.Recorder.Eventf(pod, nil, eventType, reason, action, message)
+	log.Debugf("Sent event on pod %s/%s: eventType: %s, reason: %s, message: %s", pod.Namespace, pod.Name, eventType, reason, message)
+}
+
 func findMyPod(k8sClient client.Client) (corev1.Pod, error) {
 	var pod corev1.Pod
 	// Find my aws-node pod
@@ -62,3 +62,35 @@ func TestSendPodEvent(t *testing.T) {
 	got := <-fakeRecorder.Events
 	assert.Equal(t, expected, got)
 }
+
+func TestSendNodeEvent(t *testing.T) {
+	ctrl := setup(t)
+	defer ctrl.Finish()
+	MyNodeName = "ip-192-168-1-1.ec2.internal"
+	mockEventRecorder := Get()
+
+	reason := "NetworkIPPoolExhausted"
+	msg := "No IP addresses available"
+	mockEventRecorder.SendNodeEvent(v1.EventTypeWarning, reason, "AddNetwork", msg)
+	assert.Len(t, fakeRecorder.Events, 1)
+
+	expected := fmt.Sprintf("%s %s %s", v1.EventTypeWarning, reason, msg)
+	got := <-fakeRecorder.Events
+	assert.Equal(t, expected, got)
+}
+
+func TestSendEventOnPod(t *testing.T) {
+	ctrl := setup(t)
+	defer ctrl.Finish()
+	mockEventRecorder := Get()
+
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"}}
+	reason := "FailedToAssignIP"
+	msg := "No IP addresses available"
+	mockEventRecorder.SendEventOnPod(pod, v1.EventTypeWarning, reason, "AddNetwork", msg)
+	assert.Len(t, fakeRecorder.Events, 1)
+
+	expected := fmt.Sprintf("%s %s %s", v1.EventTypeWarning, reason, msg)
+	got := <-fakeRecorder.Events
+	assert.Equal(t, expected, got)
+}
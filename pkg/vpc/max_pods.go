@@ -0,0 +1,124 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package vpc
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// hostNetworkPods is the number of host networking pods (aws-node and kube-proxy) that are expected on every
+	// node and do not need an IP address from the pool
+	hostNetworkPods = 2
+
+	// ipsPerIPv4Prefix is the number of IP addresses in the /28 prefix assigned with prefix delegation
+	ipsPerIPv4Prefix = 16
+
+	// smallInstanceMaxPods and largeInstanceMaxPods are the CPU based caps used by the EKS AMI
+	smallInstanceMaxPods = 110
+	largeInstanceMaxPods = 250
+	largeInstanceMinCPUs = 30
+)
+
+// ErrNoPodENIs is returned when the given configuration leaves no ENIs to assign pod IPs from
+var ErrNoPodENIs = errors.New("no ENIs left for pod IP addresses")
+
+// MaxPodsOptions describes the node configuration that affects how many pods can get an IP address
+type MaxPodsOptions struct {
+	// PrefixDelegation assigns a /28 prefix instead of a single secondary IP to each ENI slot
+	PrefixDelegation bool
+	// CustomNetworking leaves the primary ENI unused for pod IP addresses
+	CustomNetworking bool
+	// PodENI reserves one ENI on the default network card for the trunk ENI used by security groups for pods
+	PodENI bool
+	// AllNetworkCards counts the ENIs of every network card instead of only the default network card
+	AllNetworkCards bool
+	// CPUs is the number of vCPUs of the instance. When set, max pods is capped at 110 below 30 vCPUs and at 250 otherwise.
+	CPUs int
+	// MaxPodsCap is an explicit upper bound on max pods. It is ignored when 0.
+	MaxPodsCap int
+}
+
+// MaxPodsResult is the outcome of CalculateMaxPods
+type MaxPodsResult struct {
+	InstanceType string `json:"instanceType"`
+	// ENIs is the number of ENIs that pod IP addresses are assigned from
+	ENIs int `json:"enis"`
+	// IPsPerENI is the number of pod IP addresses available on each of those ENIs
+	IPsPerENI int `json:"ipsPerENI"`
+	// PodIPs is the total number of pod IP addresses the node can hold
+	PodIPs int `json:"podIPs"`
+	// Cap is the upper bound that was applied, 0 if none
+	Cap int `json:"cap,omitempty"`
+	// MaxPods is the recommended value of the kubelet --max-pods flag
+	MaxPods int `json:"maxPods"`
+}
+
+// CalculateMaxPods returns the recommended kubelet max pods for the instance type with the given configuration
+func CalculateMaxPods(instanceType string, opts MaxPodsOptions) (MaxPodsResult, error) {
+	instance, ok := GetInstance(instanceType)
+	if !ok {
+		return MaxPodsResult{}, ErrInstanceTypeNotExist
+	}
+	return instance.MaxPods(instanceType, opts)
+}
+
+// MaxPods returns the recommended kubelet max pods for these limits with the given configuration
+func (l InstanceTypeLimits) MaxPods(instanceType string, opts MaxPodsOptions) (MaxPodsResult, error) {
+	result := MaxPodsResult{InstanceType: instanceType}
+
+	enis := l.ENILimit
+	if opts.AllNetworkCards && len(l.NetworkCards) > 0 {
+		enis = 0
+		for _, card := range l.NetworkCards {
+			enis += int(card.MaximumNetworkInterfaces)
+		}
+	}
+	// The primary ENI and the trunk ENI both live on the default network card
+	if opts.CustomNetworking {
+		enis--
+	}
+	if opts.PodENI {
+		enis--
+	}
+	if enis <= 0 || l.IPv4Limit <= 1 {
+		return result, fmt.Errorf("%s: %w", instanceType, ErrNoPodENIs)
+	}
+
+	// The primary IP address of each ENI is never used for pods
+	ipsPerENI := l.IPv4Limit - 1
+	if opts.PrefixDelegation {
+		ipsPerENI *= ipsPerIPv4Prefix
+	}
+
+	result.ENIs = enis
+	result.IPsPerENI = ipsPerENI
+	result.PodIPs = enis * ipsPerENI
+	result.MaxPods = result.PodIPs + hostNetworkPods
+
+	if opts.CPUs > 0 {
+		result.Cap = smallInstanceMaxPods
+		if opts.CPUs >= largeInstanceMinCPUs {
+			result.Cap = largeInstanceMaxPods
+		}
+	}
+	if opts.MaxPodsCap > 0 && (result.Cap == 0 || opts.MaxPodsCap < result.Cap) {
+		result.Cap = opts.MaxPodsCap
+	}
+	if result.Cap > 0 && result.MaxPods > result.Cap {
+		result.MaxPods = result.Cap
+	}
+	return result, nil
+}
@@ -0,0 +1,70 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package vpc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateMaxPods(t *testing.T) {
+	_, err := CalculateMaxPods("large", MaxPodsOptions{})
+	assert.Equal(t, ErrInstanceTypeNotExist, err)
+
+	// m5.large: 3 ENIs with 10 IPv4 addresses each
+	result, err := CalculateMaxPods("m5.large", MaxPodsOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 29, result.MaxPods)
+	assert.Equal(t, 3, result.ENIs)
+	assert.Equal(t, 9, result.IPsPerENI)
+}
+
+func TestInstanceTypeLimitsMaxPods(t *testing.T) {
+	limits := New(4, 15, 0, []NetworkCard{
+		{MaximumNetworkInterfaces: 4, NetworkCardIndex: 0},
+		{MaximumNetworkInterfaces: 4, NetworkCardIndex: 1},
+	}, "nitro", false)
+
+	tests := []struct {
+		name    string
+		opts    MaxPodsOptions
+		maxPods int
+		cap     int
+	}{
+		{"secondary IPs", MaxPodsOptions{}, 4*14 + 2, 0},
+		{"custom networking", MaxPodsOptions{CustomNetworking: true}, 3*14 + 2, 0},
+		{"pod ENI", MaxPodsOptions{PodENI: true}, 3*14 + 2, 0},
+		{"custom networking and pod ENI", MaxPodsOptions{CustomNetworking: true, PodENI: true}, 2*14 + 2, 0},
+		{"all network cards", MaxPodsOptions{AllNetworkCards: true}, 8*14 + 2, 0},
+		{"prefix delegation", MaxPodsOptions{PrefixDelegation: true}, 4*14*16 + 2, 0},
+		{"prefix delegation small instance", MaxPodsOptions{PrefixDelegation: true, CPUs: 8}, 110, 110},
+		{"prefix delegation large instance", MaxPodsOptions{PrefixDelegation: true, CPUs: 48}, 250, 250},
+		{"explicit cap", MaxPodsOptions{PrefixDelegation: true, CPUs: 48, MaxPodsCap: 200}, 200, 200},
+		{"cap above max pods", MaxPodsOptions{CPUs: 8}, 4*14 + 2, 110},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := limits.MaxPods("test.large", tt.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.maxPods, result.MaxPods)
+			assert.Equal(t, tt.cap, result.Cap)
+		})
+	}
+
+	single := New(1, 4, 0, nil, "nitro", false)
+	_, err := single.MaxPods("single.large", MaxPodsOptions{CustomNetworking: true})
+	assert.True(t, errors.Is(err, ErrNoPodENIs))
+}
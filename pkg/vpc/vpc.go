@@ -13,27 +13,31 @@
 package vpc
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/aws/amazon-vpc-cni-k8s/pkg/utils/logger"
 )
 
 type NetworkCard struct {
 	// max number of interfaces supported per card
-	MaximumNetworkInterfaces int64
+	MaximumNetworkInterfaces int64 `json:"maximumNetworkInterfaces"`
 	// the index of current card
-	NetworkCardIndex   int64
-	NetworkPerformance string
+	NetworkCardIndex   int64  `json:"networkCardIndex"`
+	NetworkPerformance string `json:"networkPerformance,omitempty"`
 }
 
 // InstanceTypeLimits keeps track of limits for an instance type
 type InstanceTypeLimits struct {
-	ENILimit                int
-	IPv4Limit               int
-	DefaultNetworkCardIndex int
-	NetworkCards            []NetworkCard
-	HypervisorType          string
-	IsBareMetal             bool
+	ENILimit                int           `json:"eniLimit"`
+	IPv4Limit               int           `json:"ipv4Limit"`
+	DefaultNetworkCardIndex int           `json:"defaultNetworkCardIndex"`
+	NetworkCards            []NetworkCard `json:"networkCards,omitempty"`
+	HypervisorType          string        `json:"hypervisorType,omitempty"`
+	IsBareMetal             bool          `json:"isBareMetal"`
 }
 
 var ErrInstanceTypeNotExist = errors.New("instance type does not exist")
@@ -123,3 +127,70 @@ func SetInstance(instanceType string, eniLimit int, ipv4Limit int, defaultNetwor
 	instanceNetworkingLimits[instanceType] = New(eniLimit, ipv4Limit, defaultNetworkCardIndex, networkCards,
 		hypervisorType, isBareMetalInstance)
 }
+
+// IsValid returns whether the limits are usable for IP address management
+func (l InstanceTypeLimits) IsValid() bool {
+	return l.ENILimit > 0 && l.IPv4Limit > 0
+}
+
+// LoadLimitsFile reads a JSON file mapping instance types to their limits. The same format is used for the
+// operator supplied override file and for the on-node cache of limits fetched from EC2.
+func LoadLimitsFile(path string) (map[string]InstanceTypeLimits, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	limits := make(map[string]InstanceTypeLimits)
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return nil, fmt.Errorf("failed to parse instance limits file %s: %v", path, err)
+	}
+	return limits, nil
+}
+
+// SaveLimitsFile writes the limits for the given instance types to a JSON file. The file is written to a temporary
+// file first and renamed, so readers never see a partially written file.
+func SaveLimitsFile(path string, limits map[string]InstanceTypeLimits) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(f).Encode(limits); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// LoadInstanceFromFile looks up instanceType in the limits file at path and, if valid limits are found, adds them to
+// the in-memory table. It returns whether the instance type is now known.
+func LoadInstanceFromFile(path string, instanceType string) (bool, error) {
+	limits, err := LoadLimitsFile(path)
+	if err != nil {
+		return false, err
+	}
+	instance, ok := limits[instanceType]
+	if !ok {
+		return false, nil
+	}
+	if !instance.IsValid() {
+		return false, fmt.Errorf("invalid limits for %s in %s: ENI limit %d, IPv4 limit %d", instanceType, path,
+			instance.ENILimit, instance.IPv4Limit)
+	}
+	instanceNetworkingLimits[instanceType] = instance
+	return true, nil
+}
@@ -13,6 +13,8 @@
 package vpc
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -192,3 +194,35 @@ func TestSetInstance(t *testing.T) {
 	})
 	assert.True(t, ok)
 }
+
+func TestLimitsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+
+	_, err := LoadInstanceFromFile(path, "new.large")
+	assert.True(t, os.IsNotExist(err))
+
+	limits := map[string]InstanceTypeLimits{
+		"new.large": New(3, 10, 0, []NetworkCard{{MaximumNetworkInterfaces: 3, NetworkCardIndex: 0}}, "nitro", false),
+		"bad.large": New(0, 10, 0, nil, "nitro", false),
+	}
+	assert.NoError(t, SaveLimitsFile(path, limits))
+
+	loaded, err := LoadLimitsFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, limits, loaded)
+
+	found, err := LoadInstanceFromFile(path, "other.large")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	found, err = LoadInstanceFromFile(path, "bad.large")
+	assert.Error(t, err)
+	assert.False(t, found)
+
+	found, err = LoadInstanceFromFile(path, "new.large")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	eniLimit, err := GetENILimit("new.large")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, eniLimit)
+}